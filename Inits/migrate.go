@@ -0,0 +1,52 @@
+package Inits
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+)
+
+// InitTables 自动创建/更新功能表结构
+// domains、email_accounts 等基础表仍由部署脚本维护，这里只负责后续新增的功能表与基础表的新增字段
+func InitTables() {
+	err := global.PsqlDB.AutoMigrate(
+		&models.VacationResponder{},
+		&models.VacationReplyLog{},
+		&models.FilterRule{},
+		&models.ForwardingRule{},
+		&models.Contact{},
+		&models.CalendarEvent{},
+		&models.AttachmentGrant{},
+		&models.AttachmentShareLink{},
+		&models.UploadSession{},
+		&models.DomainPolicy{},
+		&models.AttachmentReaperReport{},
+		&models.SenderPreference{},
+		&models.MailboxJob{},
+		&models.ImapMigration{},
+		&models.ImapMigrationFolder{},
+		&models.InboundMessage{},
+		&models.InboundDelivery{},
+		&models.InboundDeadLetter{},
+		&models.MailEvent{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.DeliveryStatus{},
+		&models.Suppression{},
+		&models.OutboundMessage{},
+		&models.AuthLockout{},
+		&models.AppPassword{},
+		&models.TwoFactor{},
+		&models.TwoFactorRecoveryCode{},
+	)
+	if err != nil {
+		panic("Psql 数据表迁移失败: " + err.Error())
+	}
+	if err := dao.MigrateBaseTables(); err != nil {
+		panic("Psql 基础表迁移失败: " + err.Error())
+	}
+	if err := dao.MigrateUserEmailTables(); err != nil {
+		panic("Psql 账户邮件表迁移失败: " + err.Error())
+	}
+	global.Log.Info("Psql数据表迁移完成...")
+}
@@ -0,0 +1,11 @@
+package config
+
+type AuthLockout struct {
+	AccountMaxFailures   int `yaml:"account_max_failures"`   // 同一账户连续失败达到该次数后锁定，为0时不锁定
+	IPMaxFailures        int `yaml:"ip_max_failures"`        // 同一 IP 连续失败达到该次数后锁定，为0时不锁定
+	FailureWindowMinutes int `yaml:"failure_window_minutes"` // 超过该时长(分钟)没有失败时重新计数
+	LockoutMinutes       int `yaml:"lockout_minutes"`        // 首次锁定时长(分钟)，之后每次加倍
+	MaxLockoutMinutes    int `yaml:"max_lockout_minutes"`    // 最长锁定时长(分钟)
+	DelayMilliseconds    int `yaml:"delay_milliseconds"`     // 失败后的响应延迟(毫秒)，每次失败加倍
+	MaxDelayMilliseconds int `yaml:"max_delay_milliseconds"` // 最长响应延迟(毫秒)
+}
@@ -11,4 +11,15 @@ type Config struct {
 	RequestsApi       AIRequestsApi      `yaml:"AIRequestsApi"`
 	OtherInfo         OtherInfo          `yaml:"OtherInfo"`
 	Dovecot           DOVECOT            `yaml:"Dovecot"`
+	AttachmentReaper  AttachmentReaper   `yaml:"AttachmentReaper"`
+	Scanner           Scanner            `yaml:"Scanner"`
+	ImageProxy        ImageProxy         `yaml:"ImageProxy"`
+	MailboxTransfer   MailboxTransfer    `yaml:"MailboxTransfer"`
+	Ingest            Ingest             `yaml:"Ingest"`
+	EventStream       EventStream        `yaml:"EventStream"`
+	Webhook           Webhook            `yaml:"Webhook"`
+	Suppression       Suppression        `yaml:"Suppression"`
+	OutboundQueue     OutboundQueue      `yaml:"OutboundQueue"`
+	Smtp              Smtp               `yaml:"Smtp"`
+	AuthLockout       AuthLockout        `yaml:"AuthLockout"`
 }
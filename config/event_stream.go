@@ -0,0 +1,6 @@
+package config
+
+type EventStream struct {
+	RetentionHours   int `yaml:"retention_hours"`   // 事件保留时长(小时)，客户端断线超过该时长需重新拉取列表
+	HeartbeatSeconds int `yaml:"heartbeat_seconds"` // 心跳间隔(秒)，避免代理断开空闲连接
+}
@@ -0,0 +1,8 @@
+package config
+
+type ImageProxy struct {
+	Enabled        bool `yaml:"enabled"`         // 是否经由服务器代理加载邮件中的远程图片，隐藏读者 IP
+	MaxSizeMB      int  `yaml:"max_size_mb"`     // 单张图片最大大小(MB)
+	TimeoutSeconds int  `yaml:"timeout_seconds"` // 获取远程图片超时时间(秒)
+	LinkTTLHours   int  `yaml:"link_ttl_hours"`  // 代理链接有效期(小时)
+}
@@ -0,0 +1,5 @@
+package config
+
+type Ingest struct {
+	MaxAttempts int `yaml:"max_attempts"` // 收信处理失败的最大尝试次数，超过后转入死信
+}
@@ -0,0 +1,7 @@
+package config
+
+type MailboxTransfer struct {
+	Workers         int `yaml:"workers"`            // 同时执行的导入导出任务数
+	MaxImportSizeMB int `yaml:"max_import_size_mb"` // 导入文件最大大小(MB)
+	MaxExportSizeMB int `yaml:"max_export_size_mb"` // 导出文件最大大小(MB)
+}
@@ -0,0 +1,6 @@
+package config
+
+type OutboundQueue struct {
+	DelayWarningHours []int `yaml:"delay_warning_hours"` // 邮件在队列中停留达到这些时长(小时)时发送延迟提醒
+	MaxQueueHours     int   `yaml:"max_queue_hours"`     // 最长重试时间(小时)，超过后放弃并发送退信
+}
@@ -0,0 +1,9 @@
+package config
+
+type AttachmentReaper struct {
+	IntervalMinutes int `yaml:"interval_minutes"` // 附件回收任务执行间隔(分钟)，为 0 时不启动
+	BatchSize       int `yaml:"batch_size"`       // 每批检查的附件数量
+	// 没有任何授权记录(无法确定所属域名)的附件，如授权表上线前网页发信或上传的附件
+	ReapUnowned       bool `yaml:"reap_unowned"`        // 是否回收不再被引用的无归属附件
+	UnownedGraceHours int  `yaml:"unowned_grace_hours"` // 无归属附件过期后的保留时长(小时)
+}
@@ -0,0 +1,9 @@
+package config
+
+type Scanner struct {
+	Type           string `yaml:"type"`            // 扫描器类型：clamd / fake，为空时不扫描
+	Network        string `yaml:"network"`         // clamd 连接方式：tcp / unix
+	Address        string `yaml:"address"`         // clamd 地址，如 127.0.0.1:3310 或 /run/clamav/clamd.sock
+	TimeoutSeconds int    `yaml:"timeout_seconds"` // 单个附件扫描超时时间(秒)
+	FailClosed     bool   `yaml:"fail_closed"`     // 扫描失败的附件是否按感染处理
+}
@@ -0,0 +1,27 @@
+package config
+
+type Smtp struct {
+	Domain         string         `yaml:"domain"`          // SMTP 服务器域名(EHLO 问候中使用)，也用于补全的 Message-ID
+	CertFile       string         `yaml:"cert_file"`       // TLS 证书文件
+	KeyFile        string         `yaml:"key_file"`        // TLS 私钥文件
+	MinTLSVersion  string         `yaml:"min_tls_version"` // 最低 TLS 版本：1.2 / 1.3
+	CipherSuites   []string       `yaml:"cipher_suites"`   // TLS 1.2 使用的加密套件，为空时使用 Go 默认值
+	MaxConnections int            `yaml:"max_connections"` // 每个监听端口的最大连接数，为0时不限制
+	Debug          bool           `yaml:"debug"`           // 是否记录 SMTP 会话内容(认证信息会被隐藏)
+	Listeners      []SmtpListener `yaml:"listeners"`       // 监听端口
+
+	MaxConnectionsPerIP      int    `yaml:"max_connections_per_ip"`      // 单个 IP 的最大连接数(所有端口合计)，为0时不限制
+	MessagesPerHour          int    `yaml:"messages_per_hour"`           // 每个账户每小时最多发送的邮件数，为0时不限制
+	RecipientsPerHour        int    `yaml:"recipients_per_hour"`         // 每个账户每小时最多发送的收件人数，为0时不限制
+	AuthFailuresPerIP        int    `yaml:"auth_failures_per_ip"`        // 单个 IP 在时间窗口内允许的认证失败次数，超过后暂时拒绝认证，为0时不限制
+	AuthFailureWindowMinutes int    `yaml:"auth_failure_window_minutes"` // 认证失败计数的时间窗口(分钟)
+	MetricsAddr              string `yaml:"metrics_addr"`                // expvar 指标监听地址，为空时不启动
+}
+
+type SmtpListener struct {
+	Name              string `yaml:"name"`                // 名称，用于日志
+	Addr              string `yaml:"addr"`                // 监听地址
+	Mode              string `yaml:"mode"`                // starttls：明文连接后通过 STARTTLS 升级 / implicit：连接即 TLS(465)
+	AllowInsecureAuth bool   `yaml:"allow_insecure_auth"` // 是否允许未加密时认证与发信(只应在本机调试时开启)
+	MaxConnections    int    `yaml:"max_connections"`     // 最大连接数，为0时使用全局配置
+}
@@ -0,0 +1,5 @@
+package config
+
+type Suppression struct {
+	GlobalAdmins []string `yaml:"global_admins"` // 可以管理全局禁止发送列表的账户
+}
@@ -1,9 +1,37 @@
 package config
 
 type DatabaseTableNames struct {
-	Domains       string `yaml:"domains"`
-	EmailAccounts string `yaml:"email_accounts"`
-	RecivedEmails string `yaml:"received_emails"`
-	SentEmails    string `yaml:"sent_emails"`
-	Attachments   string `yaml:"attachments"`
+	Domains                string `yaml:"domains"`
+	EmailAccounts          string `yaml:"email_accounts"`
+	RecivedEmails          string `yaml:"received_emails"`
+	SentEmails             string `yaml:"sent_emails"`
+	Attachments            string `yaml:"attachments"`
+	VacationResponders     string `yaml:"vacation_responders"`
+	VacationReplyLogs      string `yaml:"vacation_reply_logs"`
+	FilterRules            string `yaml:"filter_rules"`
+	ForwardingRules        string `yaml:"forwarding_rules"`
+	Contacts               string `yaml:"contacts"`
+	CalendarEvents         string `yaml:"calendar_events"`
+	AttachmentGrants       string `yaml:"attachment_grants"`
+	AttachmentShareLinks   string `yaml:"attachment_share_links"`
+	UploadSessions         string `yaml:"upload_sessions"`
+	DomainPolicies         string `yaml:"domain_policies"`
+	ReaperReports          string `yaml:"attachment_reaper_reports"`
+	SenderPreferences      string `yaml:"sender_preferences"`
+	MailboxJobs            string `yaml:"mailbox_jobs"`
+	ImapMigrations         string `yaml:"imap_migrations"`
+	ImapMigrationFolders   string `yaml:"imap_migration_folders"`
+	InboundMessages        string `yaml:"inbound_messages"`
+	InboundDeliveries      string `yaml:"inbound_deliveries"`
+	InboundDeadLetters     string `yaml:"inbound_dead_letters"`
+	MailEvents             string `yaml:"mail_events"`
+	Webhooks               string `yaml:"webhooks"`
+	WebhookDeliveries      string `yaml:"webhook_deliveries"`
+	DeliveryStatuses       string `yaml:"delivery_statuses"`
+	Suppressions           string `yaml:"suppressions"`
+	OutboundQueue          string `yaml:"outbound_queue"`
+	AuthLockouts           string `yaml:"auth_lockouts"`
+	AppPasswords           string `yaml:"app_passwords"`
+	TwoFactors             string `yaml:"two_factors"`
+	TwoFactorRecoveryCodes string `yaml:"two_factor_recovery_codes"`
 }
@@ -0,0 +1,8 @@
+package config
+
+type Webhook struct {
+	Workers             int  `yaml:"workers"`               // 同时推送的请求数
+	TimeoutSeconds      int  `yaml:"timeout_seconds"`       // 单次推送超时时间(秒)
+	MaxAttempts         int  `yaml:"max_attempts"`          // 最大推送次数，按指数退避重试
+	AllowPrivateTargets bool `yaml:"allow_private_targets"` // 是否允许推送到内网地址
+}
@@ -4,6 +4,8 @@ import (
 	"email/controller/response"
 	"email/dao"
 	"email/models"
+	"email/service"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -45,3 +47,257 @@ func (AccountController) UpdateAccountPassword(c *gin.Context) {
 	}
 	response.SuccessReq(c, nil)
 }
+
+// GetVacation 获取自动回复设置
+func (AccountController) GetVacation(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	v, err := service.GetVacationProcess(reqAccount.UserID)
+	if err != nil {
+		response.FailedReq(c, response.GetVacationFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, v)
+}
+
+// UpdateVacation 更新自动回复设置
+func (AccountController) UpdateVacation(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.UpdateVacationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	v, err := service.UpdateVacationProcess(reqAccount.UserID, req)
+	if err != nil {
+		response.FailedReq(c, response.UpdateVacationFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, v)
+}
+
+// GetForwarding 获取自动转发设置
+func (AccountController) GetForwarding(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	f, err := service.GetForwardingProcess(reqAccount.UserID)
+	if err != nil {
+		response.FailedReq(c, response.GetForwardingFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, f)
+}
+
+// UpdateForwarding 更新自动转发设置
+func (AccountController) UpdateForwarding(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.UpdateForwardingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	f, err := service.UpdateForwardingProcess(reqAccount.UserID, reqAccount.EmailAddress, req)
+	if err != nil {
+		response.FailedReq(c, response.UpdateForwardingFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, f)
+}
+
+// GetAppPasswords 获取应用专用密码列表
+func (AccountController) GetAppPasswords(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	list, err := service.GetAppPasswordsProcess(reqAccount)
+	if err != nil {
+		response.FailedReq(c, response.GetAppPasswordsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// CreateAppPassword 创建应用专用密码
+func (AccountController) CreateAppPassword(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.CreateAppPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	created, err := service.CreateAppPasswordProcess(reqAccount, req)
+	if err != nil {
+		response.FailedReq(c, response.CreateAppPasswordFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, created)
+}
+
+// DeleteAppPassword 撤销应用专用密码
+func (AccountController) DeleteAppPassword(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DeleteAppPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.DeleteAppPasswordProcess(reqAccount, req); err != nil {
+		response.FailedReq(c, response.DeleteAppPasswordFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// UpdateAppPasswordSettings 更新应用专用密码设置
+func (AccountController) UpdateAppPasswordSettings(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.AppPasswordSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.UpdateAppPasswordSettingsProcess(reqAccount, req); err != nil {
+		response.FailedReq(c, response.UpdateAppPasswordSettingsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// GetTwoFactor 获取两步验证状态
+func (AccountController) GetTwoFactor(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	status, err := service.GetTwoFactorStatusProcess(reqAccount)
+	if err != nil {
+		response.FailedReq(c, response.GetTwoFactorFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, status)
+}
+
+// SetupTwoFactor 开始设置两步验证
+func (AccountController) SetupTwoFactor(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	setup, err := service.SetupTwoFactorProcess(reqAccount)
+	if err != nil {
+		response.FailedReq(c, response.SetupTwoFactorFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, setup)
+}
+
+// EnableTwoFactor 确认并启用两步验证
+func (AccountController) EnableTwoFactor(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	codes, err := service.EnableTwoFactorProcess(reqAccount, req)
+	if err != nil {
+		response.FailedReq(c, response.EnableTwoFactorFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, codes)
+}
+
+// DisableTwoFactor 关闭两步验证
+func (AccountController) DisableTwoFactor(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	err = service.DisableTwoFactorProcess(reqAccount, req, c.ClientIP())
+	if errors.Is(err, service.ErrAuthLocked) {
+		response.FailedReq(c, response.AccountLockedCode, err.Error())
+		return
+	}
+	if err != nil {
+		response.FailedReq(c, response.DisableTwoFactorFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (AccountController) RegenerateRecoveryCodes(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	codes, err := service.RegenerateRecoveryCodesProcess(reqAccount, req, c.ClientIP())
+	if errors.Is(err, service.ErrAuthLocked) {
+		response.FailedReq(c, response.AccountLockedCode, err.Error())
+		return
+	}
+	if err != nil {
+		response.FailedReq(c, response.RegenerateRecoveryCodesFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, codes)
+}
@@ -4,6 +4,7 @@ import (
 	"email/controller/response"
 	"email/models"
 	"email/service"
+	"errors"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,15 +23,77 @@ func (AuthController) Login(c *gin.Context) {
 		response.FailedReq(c, response.MissingCriticalLoginParametersCode)
 		return
 	}
-	at, e := service.LoginProcess(loginReq)
+	at, challenge, e := service.LoginProcess(loginReq, c.ClientIP())
+	if errors.Is(e, service.ErrAuthLocked) {
+		response.FailedReq(c, response.AccountLockedCode, e.Error())
+		return
+	}
 	if e != nil {
 		response.FailedReq(c, response.LoginFailedCode, e.Error())
 		return
 	}
+	// 需要两步验证时返回临时令牌
+	if challenge != nil {
+		response.SuccessReq(c, challenge)
+		return
+	}
 	response.SuccessReq(c, at)
 	return
 }
 
+// LoginTwoFactor 两步验证登录的第二步
+func (AuthController) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	at, err := service.TwoFactorLoginProcess(req, c.ClientIP())
+	if errors.Is(err, service.ErrAuthLocked) {
+		response.FailedReq(c, response.AccountLockedCode, err.Error())
+		return
+	}
+	if err != nil {
+		response.FailedReq(c, response.TwoFactorLoginFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, at)
+}
+
+// LoginTwoFactorSetup 域名要求两步验证时，在登录过程中获取设置信息
+func (AuthController) LoginTwoFactorSetup(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	setup, err := service.TwoFactorLoginSetupProcess(req)
+	if err != nil {
+		response.FailedReq(c, response.SetupTwoFactorFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, setup)
+}
+
+// LoginTwoFactorEnable 在登录过程中确认两步验证设置并完成登录
+func (AuthController) LoginTwoFactorEnable(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	result, err := service.TwoFactorLoginEnableProcess(req, c.ClientIP())
+	if errors.Is(err, service.ErrAuthLocked) {
+		response.FailedReq(c, response.AccountLockedCode, err.Error())
+		return
+	}
+	if err != nil {
+		response.FailedReq(c, response.EnableTwoFactorFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, result)
+}
+
 // 退出登录
 func (AuthController) Logout() {
 
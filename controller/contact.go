@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"email/controller/response"
+	"email/models"
+	"email/service"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// vCard 导入文件大小上限
+const maxVCardSize = 5 * 1024 * 1024
+
+type ContactController struct{}
+
+// GetContactList 获取联系人列表
+func (ContactController) GetContactList(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	page := c.DefaultQuery("page", "1")
+	list, err := service.GetContactListProcess(reqAccount.UserID, page, c.Query("group"), c.Query("recent"))
+	if err != nil {
+		response.FailedReq(c, response.GetContactsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// GetContactDetails 获取联系人详情
+func (ContactController) GetContactDetails(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil || id <= 0 {
+		response.FailedReq(c, response.InvalidParametersCode, "invalid contact id")
+		return
+	}
+	contact, err := service.GetContactDetailsProcess(reqAccount.UserID, uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.FailedReq(c, response.ContactNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.GetContactsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, contact)
+}
+
+// AddContact 新增联系人
+func (ContactController) AddContact(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.ContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	contact, err := service.AddContactProcess(reqAccount.UserID, req)
+	if err != nil {
+		response.FailedReq(c, response.SaveContactFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, contact)
+}
+
+// UpdateContact 更新联系人
+func (ContactController) UpdateContact(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.ContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	contact, err := service.UpdateContactProcess(reqAccount.UserID, req)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.FailedReq(c, response.ContactNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.SaveContactFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, contact)
+}
+
+// DeleteContact 删除联系人
+func (ContactController) DeleteContact(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DeleteContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.DeleteContactProcess(reqAccount.UserID, req.ID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.FailedReq(c, response.ContactNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.DeleteContactFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// AutocompleteContacts 联系人自动补全
+func (ContactController) AutocompleteContacts(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	list, err := service.AutocompleteContactsProcess(reqAccount.UserID, c.Query("q"), c.Query("limit"))
+	if err != nil {
+		response.FailedReq(c, response.GetContactsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// ImportVCard 导入 vCard，支持表单文件 file 或直接以请求体上传
+func (ContactController) ImportVCard(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var reader io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		src, err := file.Open()
+		if err != nil {
+			response.FailedReq(c, response.ImportContactsFailedCode, err.Error())
+			return
+		}
+		defer src.Close()
+		reader = src
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxVCardSize+1))
+	if err != nil {
+		response.FailedReq(c, response.ImportContactsFailedCode, err.Error())
+		return
+	}
+	if len(data) > maxVCardSize {
+		response.FailedReq(c, response.ImportContactsFailedCode, "vcard file is too large")
+		return
+	}
+	n, err := service.ImportVCardProcess(reqAccount.UserID, string(data))
+	if err != nil {
+		response.FailedReq(c, response.ImportContactsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, gin.H{"imported": n})
+}
+
+// ExportVCard 导出联系人为 vCard 文件
+func (ContactController) ExportVCard(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	data, err := service.ExportVCardProcess(reqAccount.UserID)
+	if err != nil {
+		response.FailedReq(c, response.ExportContactsFailedCode, err.Error())
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="contacts.vcf"`)
+	c.Data(http.StatusOK, "text/vcard; charset=utf-8", []byte(data))
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"email/global"
+	"email/models"
+	"email/service"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DAV 请求体大小上限
+const maxDavBodySize = 5 * 1024 * 1024
+
+// DavController CardDAV / CalDAV 接口
+type DavController struct{}
+
+// Options 声明支持的 DAV 能力
+func (DavController) Options(c *gin.Context) {
+	c.Header("DAV", "1, 3, addressbook, calendar-access")
+	c.Header("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, PROPPATCH, REPORT")
+	c.Status(http.StatusOK)
+}
+
+// Propfind 查询资源属性
+func (DavController) Propfind(c *gin.Context) {
+	account, body, ok := davRequest(c)
+	if !ok {
+		return
+	}
+	depth := c.GetHeader("Depth")
+	if depth == "" {
+		depth = "infinity"
+	}
+	r, err := service.DavPropfindProcess(account, c.Param("path"), depth, body)
+	writeDavResult(c, r, err)
+}
+
+// Proppatch 修改资源属性
+func (DavController) Proppatch(c *gin.Context) {
+	account, body, ok := davRequest(c)
+	if !ok {
+		return
+	}
+	r, err := service.DavProppatchProcess(account, c.Param("path"), body)
+	writeDavResult(c, r, err)
+}
+
+// Report 处理 multiget / query 报告
+func (DavController) Report(c *gin.Context) {
+	account, body, ok := davRequest(c)
+	if !ok {
+		return
+	}
+	r, err := service.DavReportProcess(account, c.Param("path"), body)
+	writeDavResult(c, r, err)
+}
+
+// Get 获取 vCard / iCalendar，同时处理 HEAD
+func (DavController) Get(c *gin.Context) {
+	account, _, ok := davRequest(c)
+	if !ok {
+		return
+	}
+	r, err := service.DavGetProcess(account, c.Param("path"))
+	if err == nil && c.Request.Method == http.MethodHead {
+		r.Body = nil
+	}
+	writeDavResult(c, r, err)
+}
+
+// Put 上传 vCard / iCalendar
+func (DavController) Put(c *gin.Context) {
+	account, body, ok := davRequest(c)
+	if !ok {
+		return
+	}
+	r, err := service.DavPutProcess(account, c.Param("path"), body, c.GetHeader("If-Match"), c.GetHeader("If-None-Match"))
+	writeDavResult(c, r, err)
+}
+
+// Delete 删除联系人 / 事件
+func (DavController) Delete(c *gin.Context) {
+	account, _, ok := davRequest(c)
+	if !ok {
+		return
+	}
+	r, err := service.DavDeleteProcess(account, c.Param("path"), c.GetHeader("If-Match"))
+	writeDavResult(c, r, err)
+}
+
+// WellKnown 服务发现 (RFC 6764)，重定向到 DAV 根路径
+func (DavController) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, service.DavPrefix+"/")
+}
+
+// davRequest 读取认证账户与请求体
+func davRequest(c *gin.Context) (*models.EmailAccount, []byte, bool) {
+	accountInterface, exists := c.Get("davAccount")
+	account, ok := accountInterface.(*models.EmailAccount)
+	if !exists || !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxDavBodySize+1))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if len(body) > maxDavBodySize {
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return nil, nil, false
+	}
+	return account, body, true
+}
+
+// writeDavResult 输出 DAV 处理结果
+func writeDavResult(c *gin.Context, r *service.DavResult, err error) {
+	if err != nil {
+		global.Log.Errorf("DAV 请求 [ %s %s ] 处理失败: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if r.ETag != "" {
+		c.Header("ETag", r.ETag)
+	}
+	if r.Body == nil {
+		c.Status(r.Status)
+		return
+	}
+	c.Data(r.Status, r.ContentType, r.Body)
+}
@@ -55,3 +55,207 @@ func (DomainController) AddDomainEmailAccount(c *gin.Context) {
 	}
 	response.SuccessReq(c, acd)
 }
+
+// GetDomainPolicy 获取域名策略
+func (DomainController) GetDomainPolicy(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	policy, err := service.GetDomainPolicyProcess(reqAccount.EmailAddress)
+	if err != nil {
+		response.FailedReq(c, response.GetDomainPolicyFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, policy)
+}
+
+// UpdateDomainPolicy 更新域名策略
+func (DomainController) UpdateDomainPolicy(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.UpdateDomainPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	policy, err := service.UpdateDomainPolicyProcess(reqAccount.EmailAddress, req)
+	if err != nil {
+		response.FailedReq(c, response.UpdateDomainPolicyFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, policy)
+}
+
+// GetReaperReports 获取附件回收报告
+func (DomainController) GetReaperReports(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	list, err := service.GetReaperReportsProcess(reqAccount.EmailAddress, c.DefaultQuery("page", "1"))
+	if err != nil {
+		response.FailedReq(c, response.GetReaperReportsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// GetDeadLetters 获取收信死信
+func (DomainController) GetDeadLetters(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	list, err := service.GetDeadLettersProcess(reqAccount.EmailAddress, c.DefaultQuery("page", "1"), c.Query("status"))
+	if err != nil {
+		response.FailedReq(c, response.GetDeadLettersFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// ReprocessDeadLetters 重新处理收信死信
+func (DomainController) ReprocessDeadLetters(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DeadLetterIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	result, err := service.ReprocessDeadLettersProcess(reqAccount.EmailAddress, req)
+	if err != nil {
+		response.FailedReq(c, response.ReprocessDeadLettersFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, result)
+}
+
+// DiscardDeadLetters 丢弃收信死信
+func (DomainController) DiscardDeadLetters(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DeadLetterIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	result, err := service.DiscardDeadLettersProcess(reqAccount.EmailAddress, req)
+	if err != nil {
+		response.FailedReq(c, response.DiscardDeadLettersFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, result)
+}
+
+// GetSuppressions 获取禁止发送列表
+func (DomainController) GetSuppressions(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	list, err := service.GetSuppressionsProcess(reqAccount.EmailAddress, c.Query("scope"), c.Query("address"), c.DefaultQuery("page", "1"))
+	if err != nil {
+		response.FailedReq(c, response.GetSuppressionsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// RemoveSuppressions 移除禁止发送列表记录
+func (DomainController) RemoveSuppressions(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.RemoveSuppressionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	result, err := service.RemoveSuppressionsProcess(reqAccount.EmailAddress, req)
+	if err != nil {
+		response.FailedReq(c, response.RemoveSuppressionsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, result)
+}
+
+// GetLockedAccounts 获取域名下锁定的账户
+func (DomainController) GetLockedAccounts(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	list, err := service.GetLockedAccountsProcess(reqAccount.EmailAddress)
+	if err != nil {
+		response.FailedReq(c, response.GetLockedAccountsFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// UnlockAccount 解除账户锁定
+func (DomainController) UnlockAccount(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.UnlockAccountProcess(reqAccount.EmailAddress, req); err != nil {
+		response.FailedReq(c, response.UnlockAccountFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// ResetTwoFactor 重置账户的两步验证
+func (DomainController) ResetTwoFactor(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.ResetTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.ResetTwoFactorProcess(reqAccount.EmailAddress, req); err != nil {
+		response.FailedReq(c, response.ResetTwoFactorFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
@@ -5,7 +5,9 @@ import (
 	"email/dao"
 	"email/models"
 	"email/service"
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 
 	"gorm.io/gorm"
@@ -90,6 +92,10 @@ func (EmailController) SendNewEmail(c *gin.Context) {
 	// 进入service层开始具体处理
 	eid, err := service.SendNewEmailProcess(reqAccount.UserID, reqAccount.EmailAddress, sendNewEmailReq)
 	if err != nil {
+		if errors.Is(err, service.ErrRecipientSuppressed) {
+			response.FailedReq(c, response.RecipientSuppressedCode, err.Error())
+			return
+		}
 		response.FailedReq(c, response.SendEmailFailedCode, err.Error())
 		return
 	}
@@ -118,6 +124,14 @@ func (EmailController) WebSendNewEmail(c *gin.Context) {
 	// 进入service层开始具体处理
 	eid, err := service.WebSendNewEmailProcess(reqAccount.UserID, reqAccount.EmailAddress, &webSendEmailReq)
 	if err != nil {
+		if errors.Is(err, service.ErrAttachmentInfected) {
+			response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrRecipientSuppressed) {
+			response.FailedReq(c, response.RecipientSuppressedCode, err.Error())
+			return
+		}
 		response.FailedReq(c, response.SendEmailFailedCode, err.Error())
 		return
 	}
@@ -145,7 +159,7 @@ func (EmailController) GetEmailDetails(c *gin.Context) {
 		return
 	}
 	//进入service层开始具体处理
-	eDetail, err := service.GetEmailDetailsProcess(reqAccount.UserID, emailIDInt)
+	eDetail, err := service.GetEmailDetailsProcess(reqAccount.UserID, emailIDInt, c.Query("load_images") == "true")
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.FailedReq(c, response.EmailNotFoundCode)
@@ -179,6 +193,10 @@ func (EmailController) ReplyEmail(c *gin.Context) {
 	// 进入service层开始具体处理
 	eid, err := service.ReplyEmailProcess(reqAccount.UserID, reqAccount.EmailAddress, reqAccount.UserName, replyEmailReq)
 	if err != nil {
+		if errors.Is(err, service.ErrRecipientSuppressed) {
+			response.FailedReq(c, response.RecipientSuppressedCode, err.Error())
+			return
+		}
 		response.FailedReq(c, response.ReplyEmailFailedCode, err.Error())
 		return
 	}
@@ -312,3 +330,142 @@ func (EmailController) MakeEmailUnreadByEmailID(c *gin.Context) {
 	}
 	response.SuccessReq(c, nil)
 }
+
+// GetSenderPreferences 获取发件人偏好设置列表
+func (EmailController) GetSenderPreferences(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	prefs, err := service.GetSenderPreferencesProcess(reqAccount.UserID)
+	if err != nil {
+		response.FailedReq(c, response.GetSenderPreferencesFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, prefs)
+}
+
+// SaveSenderPreference 保存发件人偏好设置
+func (EmailController) SaveSenderPreference(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.SenderPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	pref, err := service.SaveSenderPreferenceProcess(reqAccount.UserID, req)
+	if err != nil {
+		response.FailedReq(c, response.SaveSenderPreferenceFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, pref)
+}
+
+// DeleteSenderPreference 删除发件人偏好设置
+func (EmailController) DeleteSenderPreference(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DeleteSenderPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.DeleteSenderPreferenceProcess(reqAccount.UserID, req.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.SenderPreferenceNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.DeleteSenderPreferenceFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// GetRawEmail 下载邮件原文(.eml)，内容与存储的字节完全一致
+func (EmailController) GetRawEmail(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	emailID, err := strconv.Atoi(c.Query("email_id"))
+	if err != nil || emailID <= 0 {
+		response.FailedReq(c, response.IncorrectEmailIDParameterCode)
+		return
+	}
+	out, err := service.GetRawEmailProcess(reqAccount.UserID, emailID)
+	if err != nil {
+		failedRawEmailReq(c, err)
+		return
+	}
+	defer out.Body.Close()
+	var size int64 = -1
+	if out.ContentLength != nil {
+		size = *out.ContentLength
+	}
+	c.DataFromReader(http.StatusOK, size, "message/rfc822", out.Body, map[string]string{
+		"Content-Disposition":    fmt.Sprintf(`attachment; filename="%d.eml"`, emailID),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// GetEmailHeaders 获取邮件原文中的全部头部
+func (EmailController) GetEmailHeaders(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	emailID, err := strconv.Atoi(c.Query("email_id"))
+	if err != nil || emailID <= 0 {
+		response.FailedReq(c, response.IncorrectEmailIDParameterCode)
+		return
+	}
+	headers, err := service.GetEmailHeadersProcess(reqAccount.UserID, emailID)
+	if err != nil {
+		failedRawEmailReq(c, err)
+		return
+	}
+	response.SuccessReq(c, gin.H{"email_id": emailID, "headers": headers})
+}
+
+// failedRawEmailReq 邮件原文相关接口的错误响应
+func failedRawEmailReq(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.FailedReq(c, response.EmailNotFoundCode)
+	case errors.Is(err, service.ErrRawMessageNotFound):
+		response.FailedReq(c, response.RawMessageNotFoundCode)
+	default:
+		response.FailedReq(c, response.GetRawMessageFailedCode, err.Error())
+	}
+}
+
+// ImageProxy 经由服务器获取邮件中的远程图片，以签名校验代替登录认证，便于 <img> 直接引用
+func (EmailController) ImageProxy(c *gin.Context) {
+	img, err := service.ImageProxyProcess(c.Query("url"), c.Query("exp"), c.Query("sig"))
+	if err != nil {
+		if errors.Is(err, service.ErrImageProxyDisabled) || errors.Is(err, service.ErrImageProxySignature) {
+			response.FailedReq(c, response.ImageProxyForbiddenCode, err.Error())
+			return
+		}
+		response.FailedReq(c, response.ImageProxyFailedCode, err.Error())
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, img.ContentType, img.Data)
+}
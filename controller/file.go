@@ -2,138 +2,360 @@ package controller
 
 import (
 	"email/controller/response"
-	"email/dao"
 	"email/models"
+	"email/service"
 	"email/service/aws"
-	"email/service/shortlink"
-	"email/utils"
-	"time"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type FileManageController struct{}
 
-// RedirectToOriginal 重定向到原始 S3 链接
+// DownloadFile 校验访问权限后返回附件的 S3 预签名链接
 func (FileManageController) DownloadFile(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
 	code := c.Param("code")
 	//判断code是否存在
 	if code == "" {
 		response.FailedReq(c, response.MissingParametersCode, "code is required")
 		return
 	}
-	//根据code获取附件信息
-	att, err := dao.GetAttachmentByCode(code)
-	if err != nil {
-		response.FailedReq(c, response.DatabaseQueryFailedCode, err.Error())
-		return
-	}
-	if att == nil {
-		response.FailedReq(c, response.GetEmailAttachmentsFailedCode, "attachment not found")
-		return
-	}
-
-	//根据附件信息获取文件
-	url, err := aws.GeneratePresignedURL(att.S3StoragePath)
+	url, err := service.GetAttachmentDownloadURLProcess(reqAccount.UserID, code)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.AttachmentNotFoundCode)
+			return
+		}
+		if errors.Is(err, service.ErrAttachmentExpired) {
+			response.FailedReq(c, response.AttachmentExpiredCode)
+			return
+		}
+		if errors.Is(err, service.ErrAttachmentInfected) {
+			response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+			return
+		}
 		response.FailedReq(c, response.GetEmailAttachmentsFailedCode, err.Error())
 		return
 	}
 	response.SuccessReq(c, gin.H{
 		"url": url,
 	})
-	return
+}
 
+// CreateShareLink 创建附件公开分享链接
+func (FileManageController) CreateShareLink(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	link, err := service.CreateShareLinkProcess(reqAccount.UserID, req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.AttachmentNotFoundCode)
+			return
+		}
+		if errors.Is(err, service.ErrAttachmentExpired) {
+			response.FailedReq(c, response.AttachmentExpiredCode)
+			return
+		}
+		if errors.Is(err, service.ErrAttachmentInfected) {
+			response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+			return
+		}
+		response.FailedReq(c, response.CreateShareLinkFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, link)
 }
 
-// 上传文件Controller
-func (FileManageController) UploadFile(c *gin.Context) {
-	file, err := c.FormFile("file")
+// GetShareLinks 获取当前账户创建的分享链接
+func (FileManageController) GetShareLinks(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
 	if err != nil {
-		response.FailedReq(c, response.GetEmailAttachmentsFailedCode, err.Error())
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	links, err := service.GetShareLinksProcess(reqAccount.UserID, c.Query("code"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.AttachmentNotFoundCode)
+			return
+		}
+		if errors.Is(err, service.ErrAttachmentExpired) {
+			response.FailedReq(c, response.AttachmentExpiredCode)
+			return
+		}
+		if errors.Is(err, service.ErrAttachmentInfected) {
+			response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+			return
+		}
+		response.FailedReq(c, response.GetShareLinksFailedCode, err.Error())
 		return
 	}
+	response.SuccessReq(c, links)
+}
 
-	// 获取文件类型和大小
-	contentType := file.Header.Get("Content-Type")
-	fileSize := file.Size
-	// 检查文件大小是否超过33MB
-	if fileSize > 33*1024*1024 {
-		response.FailedReq(c, response.GetEmailAttachmentsFailedCode, "文件大小不能超过33MB")
+// RevokeShareLink 撤销分享链接
+func (FileManageController) RevokeShareLink(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
 		return
 	}
+	var req models.RevokeShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.RevokeShareLinkProcess(reqAccount.UserID, req.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.ShareLinkNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.RevokeShareLinkFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
 
-	// 打开文件
-	src, err := file.Open()
+// OpenShareLink 公开分享链接，无需登录，校验有效期后重定向到 S3 预签名链接
+func (FileManageController) OpenShareLink(c *gin.Context) {
+	url, err := service.OpenShareLinkProcess(c.Param("token"))
 	if err != nil {
-		response.FailedReq(c, response.GetEmailAttachmentsFailedCode, err.Error())
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.FailedReq(c, response.ShareLinkNotFoundCode)
+		case errors.Is(err, service.ErrShareLinkInactive):
+			response.FailedReq(c, response.ShareLinkInactiveCode)
+		case errors.Is(err, service.ErrAttachmentInfected):
+			response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+		default:
+			response.FailedReq(c, response.GetEmailAttachmentsFailedCode, err.Error())
+		}
 		return
 	}
-	defer src.Close()
+	c.Redirect(http.StatusFound, url)
+}
 
-	// 读取文件内容
-	buffer := make([]byte, fileSize)
-	_, err = src.Read(buffer)
+// 上传文件Controller，以流的方式读取表单中的 file 字段并上传到 S3，不在内存中保留整个文件
+func (FileManageController) UploadFile(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	reader, err := c.Request.MultipartReader()
 	if err != nil {
 		response.FailedReq(c, response.GetEmailAttachmentsFailedCode, err.Error())
 		return
 	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			response.FailedReq(c, response.MissingParametersCode, "file is required")
+			return
+		}
+		if err != nil {
+			response.FailedReq(c, response.GetEmailAttachmentsFailedCode, err.Error())
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		att, err := service.UploadAttachmentProcess(reqAccount.UserID, part.FileName(), part.Header.Get("Content-Type"), part)
+		part.Close()
+		if err != nil {
+			if errors.Is(err, aws.ErrFileTooLarge) {
+				response.FailedReq(c, response.FileTooLargeCode)
+				return
+			}
+			if errors.Is(err, service.ErrAttachmentInfected) {
+				response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+				return
+			}
+			response.FailedReq(c, response.UploadAttachmentToS3FailedCode, err.Error())
+			return
+		}
+		response.SuccessReq(c, att)
+		return
+	}
+}
 
-	attachmentHash := utils.ComputeContentHash(buffer)
-	exist, err := dao.GetAttachmentByHash(attachmentHash)
-
+// CreateUploadSession 创建断点续传会话，之后按 chunk_size 依次 PUT 分片
+func (FileManageController) CreateUploadSession(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
 	if err != nil {
-		response.FailedReq(c, response.DatabaseQueryFailedCode, err.Error())
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
 	}
-	if exist != nil {
-		response.SuccessReq(c, gin.H{
-			"filename": file.Filename,
-			"size":     fileSize,
-			"type":     contentType,
-			"code":     exist.ShortUrlCode,
-		})
+	var req models.CreateUploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
 		return
 	}
-	// 生成S3对象键
-	objectKey := aws.GenerateS3ObjectKey(file.Filename)
-
-	// 保存到S3
-	err = aws.SaveAttachmentToS3(objectKey, buffer, contentType)
+	session, err := service.CreateUploadSessionProcess(reqAccount.UserID, req)
 	if err != nil {
-		response.FailedReq(c, response.UploadAttachmentToS3FailedCode, err.Error())
+		if errors.Is(err, aws.ErrFileTooLarge) {
+			response.FailedReq(c, response.FileTooLargeCode)
+			return
+		}
+		response.FailedReq(c, response.CreateUploadSessionFailedCode, err.Error())
 		return
 	}
+	response.SuccessReq(c, session)
+}
 
-	// 生成预签名 URL
-	presignedURL, err := aws.GeneratePresignedURL(objectKey)
+// GetUploadSession 查询上传会话，received_bytes 即续传的偏移量
+func (FileManageController) GetUploadSession(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
 	if err != nil {
-		response.FailedReq(c, response.UploadAttachmentToS3FailedCode, err.Error())
+		response.FailedReq(c, response.InvalidAccessTokenCode)
 		return
 	}
-
-	// 构造 Attachment 结构体并追加
-	att := models.Attachment{
-		FileHash:       attachmentHash,
-		FileName:       file.Filename,
-		FileType:       contentType,
-		FileSize:       fileSize,
-		S3FromEmailKey: "WEB_SEND",
-		ShortUrlCode:   shortlink.CreateShortLinkCode(objectKey),
-		DownloadURL:    presignedURL,
-		S3StoragePath:  objectKey,
-		ExpireTime:     time.Now().AddDate(0, 0, 6),
+	session, err := service.GetUploadSessionProcess(reqAccount.UserID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.UploadSessionNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.DatabaseQueryFailedCode, err.Error())
+		return
 	}
+	c.Header("Upload-Offset", strconv.FormatInt(session.ReceivedBytes, 10))
+	response.SuccessReq(c, session)
+}
 
-	err = dao.AddAttachmentToDBBatchPostgres([]models.Attachment{att})
+// UploadChunk 上传分片，请求头 Upload-Offset 为该分片在文件中的偏移量，请求体为分片内容
+func (FileManageController) UploadChunk(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
 	if err != nil {
-		response.FailedReq(c, response.UploadAttachmentToS3FailedCode, err.Error())
+		response.FailedReq(c, response.InvalidAccessTokenCode)
 		return
 	}
-	//返回的时候，移除att部分参数
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.FailedReq(c, response.InvalidParametersCode, "invalid Upload-Offset header")
+		return
+	}
+	session, att, err := service.UploadChunkProcess(reqAccount.UserID, c.Param("id"), offset, c.Request.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.FailedReq(c, response.UploadSessionNotFoundCode)
+		case errors.Is(err, service.ErrUploadSessionClosed):
+			response.FailedReq(c, response.UploadSessionClosedCode)
+		case errors.Is(err, service.ErrUploadOffsetMismatch):
+			if session != nil {
+				c.Header("Upload-Offset", strconv.FormatInt(session.ReceivedBytes, 10))
+			}
+			response.FailedReq(c, response.UploadOffsetMismatchCode)
+		case errors.Is(err, service.ErrAttachmentInfected):
+			response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+		default:
+			response.FailedReq(c, response.UploadChunkFailedCode, err.Error())
+		}
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(session.ReceivedBytes, 10))
 	response.SuccessReq(c, gin.H{
-		"filename": file.Filename,
-		"size":     fileSize,
-		"type":     contentType,
-		"code":     att.ShortUrlCode,
+		"session":    session,
+		"attachment": att,
 	})
 }
+
+// AbortUploadSession 取消上传会话
+func (FileManageController) AbortUploadSession(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	if err := service.AbortUploadSessionProcess(reqAccount.UserID, c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.FailedReq(c, response.UploadSessionNotFoundCode)
+		case errors.Is(err, service.ErrUploadSessionClosed):
+			response.FailedReq(c, response.UploadSessionClosedCode)
+		default:
+			response.FailedReq(c, response.UploadChunkFailedCode, err.Error())
+		}
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// StreamFile 由服务端代理下载附件，支持 Range 断点下载，适用于无法直接访问 S3 预签名链接的客户端
+func (FileManageController) StreamFile(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	att, out, err := service.StreamAttachmentProcess(reqAccount.UserID, c.Param("code"), c.GetHeader("Range"))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.FailedReq(c, response.AttachmentNotFoundCode)
+		case errors.Is(err, service.ErrAttachmentExpired):
+			response.FailedReq(c, response.AttachmentExpiredCode)
+		case errors.Is(err, service.ErrAttachmentInfected):
+			response.FailedReq(c, response.AttachmentInfectedCode, err.Error())
+		case errors.Is(err, aws.ErrInvalidRange) && att != nil:
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", att.FileSize))
+			c.Status(http.StatusRequestedRangeNotSatisfiable)
+		default:
+			response.FailedReq(c, response.GetEmailAttachmentsFailedCode, err.Error())
+		}
+		return
+	}
+	defer out.Body.Close()
+	status := http.StatusOK
+	headers := map[string]string{
+		"Accept-Ranges":       "bytes",
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": att.FileName}),
+	}
+	if out.ContentRange != nil {
+		status = http.StatusPartialContent
+		headers["Content-Range"] = *out.ContentRange
+	}
+	if out.ETag != nil {
+		headers["ETag"] = *out.ETag
+	}
+	contentType := att.FileType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	var length int64 = -1
+	if out.ContentLength != nil {
+		length = *out.ContentLength
+	}
+	c.DataFromReader(status, length, contentType, out.Body, headers)
+}
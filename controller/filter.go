@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"email/controller/response"
+	"email/models"
+	"email/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FilterController struct{}
+
+// GetFilterRules 获取过滤规则列表
+func (FilterController) GetFilterRules(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	rules, err := service.GetFilterRulesProcess(reqAccount.UserID)
+	if err != nil {
+		response.FailedReq(c, response.GetFilterRulesFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, rules)
+}
+
+// AddFilterRule 新增过滤规则
+func (FilterController) AddFilterRule(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	rule, err := service.AddFilterRuleProcess(reqAccount.UserID, req)
+	if err != nil {
+		response.FailedReq(c, response.SaveFilterRuleFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, rule)
+}
+
+// UpdateFilterRule 更新过滤规则
+func (FilterController) UpdateFilterRule(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	rule, err := service.UpdateFilterRuleProcess(reqAccount.UserID, req)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.FailedReq(c, response.FilterRuleNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.SaveFilterRuleFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, rule)
+}
+
+// DeleteFilterRule 删除过滤规则
+func (FilterController) DeleteFilterRule(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DeleteFilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.DeleteFilterRuleProcess(reqAccount.UserID, req.ID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.FailedReq(c, response.FilterRuleNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.DeleteFilterRuleFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// ImportSieve 导入 Sieve 脚本
+func (FilterController) ImportSieve(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.ImportSieveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	rules, err := service.ImportSieveProcess(reqAccount.UserID, req)
+	if err != nil {
+		response.FailedReq(c, response.ImportSieveFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, rules)
+}
+
+// ExportSieve 导出 Sieve 脚本
+func (FilterController) ExportSieve(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	script, err := service.ExportSieveProcess(reqAccount.UserID)
+	if err != nil {
+		response.FailedReq(c, response.GetFilterRulesFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, gin.H{"script": script})
+}
+
+// TestFilterRule 使用指定邮件试运行过滤规则
+func (FilterController) TestFilterRule(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.TestFilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	res, err := service.TestFilterRuleProcess(reqAccount.UserID, req)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.FailedReq(c, response.TestFilterRuleFailedCode, "email or rule not found")
+			return
+		}
+		response.FailedReq(c, response.TestFilterRuleFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, res)
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"email/controller/response"
+	"email/models"
+	"email/service"
+	"email/utils"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventStream 以 Server-Sent Events 推送账户的邮件事件(新邮件、已读状态、移动、删除)
+// 断线重连时浏览器会自动携带 Last-Event-ID，也可通过 cursor 参数指定从哪个事件之后继续
+func (EmailController) EventStream(c *gin.Context) {
+	// EventSource 无法设置请求头，允许通过 access_token 参数传递 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil && c.GetHeader("Authorization") == "" && c.Query("access_token") != "" {
+		reqAccount, err = utils.ParseJwtToken(c.Query("access_token"))
+	}
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	cursorStr := c.GetHeader("Last-Event-ID")
+	if cursorStr == "" {
+		cursorStr = c.Query("cursor")
+	}
+	cursor, reset, err := service.MailEventStartCursorProcess(cursorStr)
+	if err != nil {
+		response.FailedReq(c, response.GetMailEventsFailedCode, err.Error())
+		return
+	}
+	sub := service.SubscribeMailEvents(reqAccount.UserID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	if reset {
+		writeMailEvent(c, cursor, models.MailEventReset, gin.H{"cursor": cursor})
+	}
+	// 订阅后先补读一次，避免遗漏订阅前产生的事件
+	sub.Wake()
+	heartbeat := time.NewTicker(service.MailEventHeartbeat())
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-sub.C:
+			for {
+				events, err := service.GetMailEventsProcess(reqAccount.UserID, cursor)
+				if err != nil {
+					return
+				}
+				for i := range events {
+					writeMailEvent(c, events[i].ID, events[i].Type, &events[i])
+					cursor = events[i].ID
+				}
+				if len(events) < service.MailEventBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// writeMailEvent 写入一条 SSE 事件，id 为客户端重连时使用的游标
+func writeMailEvent(c *gin.Context, id uint64, eventType string, data interface{}) {
+	b, _ := json.Marshal(data)
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", id, eventType, b)
+	c.Writer.Flush()
+}
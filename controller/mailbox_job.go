@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"email/controller/response"
+	"email/models"
+	"email/service"
+	"email/service/aws"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MailboxJobController struct{}
+
+// CreateImportJob 上传 mbox、Maildir/EML zip 或单个 .eml 文件并创建导入任务
+// 文件以流的方式上传，format 与 folder 通过查询参数传递
+func (MailboxJobController) CreateImportJob(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		response.FailedReq(c, response.CreateMailboxJobFailedCode, err.Error())
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			response.FailedReq(c, response.MissingParametersCode, "file is required")
+			return
+		}
+		if err != nil {
+			response.FailedReq(c, response.CreateMailboxJobFailedCode, err.Error())
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		job, err := service.CreateImportJobProcess(reqAccount.UserID, part.FileName(), c.Query("format"), c.Query("folder"), part)
+		part.Close()
+		if err != nil {
+			if errors.Is(err, aws.ErrFileTooLarge) {
+				response.FailedReq(c, response.FileTooLargeCode)
+				return
+			}
+			response.FailedReq(c, response.CreateMailboxJobFailedCode, err.Error())
+			return
+		}
+		response.SuccessReq(c, job)
+		return
+	}
+}
+
+// CreateExportJob 创建导出任务，完成后通过 download_url 下载
+func (MailboxJobController) CreateExportJob(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.CreateExportJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.InvalidParametersCode, err.Error())
+		return
+	}
+	job, err := service.CreateExportJobProcess(reqAccount.UserID, req)
+	if err != nil {
+		response.FailedReq(c, response.CreateMailboxJobFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, job)
+}
+
+// GetMailboxJobs 获取导入导出任务列表
+func (MailboxJobController) GetMailboxJobs(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	list, err := service.GetMailboxJobsProcess(reqAccount.UserID, page)
+	if err != nil {
+		response.FailedReq(c, response.GetMailboxJobFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// GetMailboxJob 获取任务进度
+func (MailboxJobController) GetMailboxJob(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailedReq(c, response.InvalidParametersCode, "invalid id")
+		return
+	}
+	job, err := service.GetMailboxJobProcess(reqAccount.UserID, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.MailboxJobNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.GetMailboxJobFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, job)
+}
+
+// DownloadMailboxExport 下载已完成的导出文件
+func (MailboxJobController) DownloadMailboxExport(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailedReq(c, response.InvalidParametersCode, "invalid id")
+		return
+	}
+	job, out, err := service.OpenMailboxExportProcess(reqAccount.UserID, uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.FailedReq(c, response.MailboxJobNotFoundCode)
+		case errors.Is(err, service.ErrMailboxExportNotReady):
+			response.FailedReq(c, response.MailboxExportNotReadyCode)
+		default:
+			response.FailedReq(c, response.DownloadMailboxExportFailedCode, err.Error())
+		}
+		return
+	}
+	defer out.Body.Close()
+	c.DataFromReader(http.StatusOK, job.ResultSize, "application/zip", out.Body, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="mailbox-export-%d-%s.zip"`, job.ID, job.Format),
+	})
+}
+
+// CreateImapMigration 创建 IMAP 迁移任务，创建前会验证能否登录远程服务器
+func (MailboxJobController) CreateImapMigration(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.CreateImapMigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.InvalidParametersCode, err.Error())
+		return
+	}
+	m, err := service.CreateImapMigrationProcess(reqAccount.UserID, req)
+	if err != nil {
+		response.FailedReq(c, response.CreateImapMigrationFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, m)
+}
+
+// GetImapMigrations 获取 IMAP 迁移任务列表
+func (MailboxJobController) GetImapMigrations(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	list, err := service.GetImapMigrationsProcess(reqAccount.UserID, page)
+	if err != nil {
+		response.FailedReq(c, response.GetImapMigrationFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// GetImapMigration 获取 IMAP 迁移任务及各文件夹进度
+func (MailboxJobController) GetImapMigration(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailedReq(c, response.InvalidParametersCode, "invalid id")
+		return
+	}
+	m, err := service.GetImapMigrationProcess(reqAccount.UserID, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailedReq(c, response.ImapMigrationNotFoundCode)
+			return
+		}
+		response.FailedReq(c, response.GetImapMigrationFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, m)
+}
+
+// ResumeImapMigration 恢复失败或已取消的 IMAP 迁移任务
+func (MailboxJobController) ResumeImapMigration(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.ImapMigrationActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.InvalidParametersCode, err.Error())
+		return
+	}
+	if err := service.ResumeImapMigrationProcess(reqAccount.UserID, req); err != nil {
+		failedImapMigrationReq(c, err)
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// CancelImapMigration 取消 IMAP 迁移任务，已迁移的邮件保留
+func (MailboxJobController) CancelImapMigration(c *gin.Context) {
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.ImapMigrationActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.InvalidParametersCode, err.Error())
+		return
+	}
+	if err := service.CancelImapMigrationProcess(reqAccount.UserID, req.ID); err != nil {
+		failedImapMigrationReq(c, err)
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// failedImapMigrationReq 恢复与取消迁移任务的错误响应
+func failedImapMigrationReq(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.FailedReq(c, response.ImapMigrationNotFoundCode)
+		return
+	}
+	response.FailedReq(c, response.UpdateImapMigrationFailedCode, err.Error())
+}
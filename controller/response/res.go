@@ -112,6 +112,158 @@ var (
 	UploadAttachmentToS3FailedCode = ErrorCodeInfo{7041, http.StatusInternalServerError, "Failed to upload attachment"}
 	//修改密码失败 [详情见报错]
 	UpdatePasswordFailedCode = ErrorCodeInfo{7042, http.StatusInternalServerError, "Failed to update password"}
+	//获取自动回复设置失败 [详情见报错]
+	GetVacationFailedCode = ErrorCodeInfo{7043, http.StatusInternalServerError, "Failed to retrieve vacation responder"}
+	//更新自动回复设置失败 [详情见报错]
+	UpdateVacationFailedCode = ErrorCodeInfo{7044, http.StatusInternalServerError, "Failed to update vacation responder"}
+	//获取过滤规则失败 [详情见报错]
+	GetFilterRulesFailedCode = ErrorCodeInfo{7045, http.StatusInternalServerError, "Failed to retrieve filter rules"}
+	//保存过滤规则失败 [规则不合法或数据库错误]
+	SaveFilterRuleFailedCode = ErrorCodeInfo{7046, http.StatusBadRequest, "Failed to save filter rule"}
+	//过滤规则不存在 [过滤规则不存在]
+	FilterRuleNotFoundCode = ErrorCodeInfo{7047, http.StatusNotFound, "Filter rule not found"}
+	//删除过滤规则失败 [详情见报错]
+	DeleteFilterRuleFailedCode = ErrorCodeInfo{7048, http.StatusInternalServerError, "Failed to delete filter rule"}
+	//导入Sieve脚本失败 [脚本语法不支持或数据库错误]
+	ImportSieveFailedCode = ErrorCodeInfo{7049, http.StatusBadRequest, "Failed to import sieve script"}
+	//测试过滤规则失败 [详情见报错]
+	TestFilterRuleFailedCode = ErrorCodeInfo{7050, http.StatusBadRequest, "Failed to test filter rule"}
+	//获取自动转发设置失败 [详情见报错]
+	GetForwardingFailedCode = ErrorCodeInfo{7051, http.StatusInternalServerError, "Failed to retrieve forwarding settings"}
+	//更新自动转发设置失败 [详情见报错]
+	UpdateForwardingFailedCode = ErrorCodeInfo{7052, http.StatusInternalServerError, "Failed to update forwarding settings"}
+	//获取联系人失败 [详情见报错]
+	GetContactsFailedCode = ErrorCodeInfo{7053, http.StatusInternalServerError, "Failed to retrieve contacts"}
+	//保存联系人失败 [联系人信息不合法或数据库错误]
+	SaveContactFailedCode = ErrorCodeInfo{7054, http.StatusBadRequest, "Failed to save contact"}
+	//联系人不存在 [联系人不存在]
+	ContactNotFoundCode = ErrorCodeInfo{7055, http.StatusNotFound, "Contact not found"}
+	//删除联系人失败 [详情见报错]
+	DeleteContactFailedCode = ErrorCodeInfo{7056, http.StatusInternalServerError, "Failed to delete contact"}
+	//导入联系人失败 [vCard格式错误或数据库错误]
+	ImportContactsFailedCode = ErrorCodeInfo{7057, http.StatusBadRequest, "Failed to import contacts"}
+	//导出联系人失败 [详情见报错]
+	ExportContactsFailedCode = ErrorCodeInfo{7058, http.StatusInternalServerError, "Failed to export contacts"}
+	//附件不存在 [附件不存在或当前账户无权访问]
+	AttachmentNotFoundCode = ErrorCodeInfo{7059, http.StatusNotFound, "Attachment not found"}
+	//创建分享链接失败 [参数不合法或数据库错误]
+	CreateShareLinkFailedCode = ErrorCodeInfo{7060, http.StatusBadRequest, "Failed to create share link"}
+	//获取分享链接失败 [详情见报错]
+	GetShareLinksFailedCode = ErrorCodeInfo{7061, http.StatusInternalServerError, "Failed to retrieve share links"}
+	//分享链接不存在 [分享链接不存在]
+	ShareLinkNotFoundCode = ErrorCodeInfo{7062, http.StatusNotFound, "Share link not found"}
+	//分享链接已失效 [分享链接已过期或已撤销]
+	ShareLinkInactiveCode = ErrorCodeInfo{7063, http.StatusGone, "Share link has expired or been revoked"}
+	//撤销分享链接失败 [详情见报错]
+	RevokeShareLinkFailedCode = ErrorCodeInfo{7064, http.StatusInternalServerError, "Failed to revoke share link"}
+	//创建上传会话失败 [参数不合法或S3错误]
+	CreateUploadSessionFailedCode = ErrorCodeInfo{7065, http.StatusBadRequest, "Failed to create upload session"}
+	//上传会话不存在 [上传会话不存在]
+	UploadSessionNotFoundCode = ErrorCodeInfo{7066, http.StatusNotFound, "Upload session not found"}
+	//上传分片失败 [分片大小错误或S3错误]
+	UploadChunkFailedCode = ErrorCodeInfo{7067, http.StatusBadRequest, "Failed to upload chunk"}
+	//分片偏移量不一致 [需先查询会话获取已接收的字节数]
+	UploadOffsetMismatchCode = ErrorCodeInfo{7068, http.StatusConflict, "Upload offset mismatch"}
+	//文件过大 [超过 AWS.MaxFileSize]
+	FileTooLargeCode = ErrorCodeInfo{7069, http.StatusRequestEntityTooLarge, "File is too large"}
+	//上传会话已结束 [已完成、已取消或已过期]
+	UploadSessionClosedCode = ErrorCodeInfo{7070, http.StatusGone, "Upload session is no longer active"}
+	//获取域名策略失败 [非域名管理员或数据库错误]
+	GetDomainPolicyFailedCode = ErrorCodeInfo{7071, http.StatusInternalServerError, "Failed to retrieve domain policy"}
+	//更新域名策略失败 [非域名管理员或参数不合法]
+	UpdateDomainPolicyFailedCode = ErrorCodeInfo{7072, http.StatusBadRequest, "Failed to update domain policy"}
+	//获取附件回收报告失败 [非域名管理员或数据库错误]
+	GetReaperReportsFailedCode = ErrorCodeInfo{7073, http.StatusInternalServerError, "Failed to retrieve attachment reaper reports"}
+	//附件已过期 [附件短码已过期且未被任何邮件引用，需重新上传]
+	AttachmentExpiredCode = ErrorCodeInfo{7074, http.StatusGone, "Attachment has expired"}
+	//附件未通过病毒扫描 [附件已被隔离，禁止下载与发送]
+	AttachmentInfectedCode = ErrorCodeInfo{7075, http.StatusUnprocessableEntity, "Attachment failed virus scanning"}
+	//获取发件人偏好设置失败 [详情见报错]
+	GetSenderPreferencesFailedCode = ErrorCodeInfo{7076, http.StatusInternalServerError, "Failed to retrieve sender preferences"}
+	//保存发件人偏好设置失败 [发件人地址不合法或数据库错误]
+	SaveSenderPreferenceFailedCode = ErrorCodeInfo{7077, http.StatusBadRequest, "Failed to save sender preference"}
+	//发件人偏好设置不存在 [发件人偏好设置不存在]
+	SenderPreferenceNotFoundCode = ErrorCodeInfo{7078, http.StatusNotFound, "Sender preference not found"}
+	//删除发件人偏好设置失败 [详情见报错]
+	DeleteSenderPreferenceFailedCode = ErrorCodeInfo{7079, http.StatusInternalServerError, "Failed to delete sender preference"}
+	//图片代理拒绝请求 [未开启图片代理或链接签名无效、已过期]
+	ImageProxyForbiddenCode = ErrorCodeInfo{7080, http.StatusForbidden, "Image proxy request is not allowed"}
+	//图片代理获取失败 [远程图片不可用、类型不支持或超过大小限制]
+	ImageProxyFailedCode = ErrorCodeInfo{7081, http.StatusBadGateway, "Failed to fetch remote image"}
+	//邮件原文不存在 [邮件未保存原文，如早期发送的邮件]
+	RawMessageNotFoundCode = ErrorCodeInfo{7082, http.StatusNotFound, "Raw message is not available"}
+	//获取邮件原文失败 [S3 读取失败]
+	GetRawMessageFailedCode = ErrorCodeInfo{7083, http.StatusInternalServerError, "Failed to get raw message"}
+	//创建邮箱导入导出任务失败 [格式或分类无效、文件上传失败]
+	CreateMailboxJobFailedCode = ErrorCodeInfo{7084, http.StatusBadRequest, "Failed to create mailbox job"}
+	//获取邮箱导入导出任务失败 [数据库错误]
+	GetMailboxJobFailedCode = ErrorCodeInfo{7085, http.StatusInternalServerError, "Failed to get mailbox job"}
+	//邮箱导入导出任务不存在 [任务不存在或不属于当前账户]
+	MailboxJobNotFoundCode = ErrorCodeInfo{7086, http.StatusNotFound, "Mailbox job not found"}
+	//导出文件尚不可下载 [不是导出任务或任务未完成]
+	MailboxExportNotReadyCode = ErrorCodeInfo{7087, http.StatusConflict, "Export is not ready for download"}
+	//下载导出文件失败 [S3 读取失败]
+	DownloadMailboxExportFailedCode = ErrorCodeInfo{7088, http.StatusInternalServerError, "Failed to download export"}
+	//创建 IMAP 迁移任务失败 [参数无效、无法连接或登录远程服务器]
+	CreateImapMigrationFailedCode = ErrorCodeInfo{7089, http.StatusBadRequest, "Failed to create IMAP migration"}
+	//获取 IMAP 迁移任务失败 [数据库错误]
+	GetImapMigrationFailedCode = ErrorCodeInfo{7090, http.StatusInternalServerError, "Failed to get IMAP migration"}
+	//IMAP 迁移任务不存在 [任务不存在或不属于当前账户]
+	ImapMigrationNotFoundCode = ErrorCodeInfo{7091, http.StatusNotFound, "IMAP migration not found"}
+	//更新 IMAP 迁移任务失败 [任务当前状态不允许恢复或取消、缺少凭证]
+	UpdateImapMigrationFailedCode = ErrorCodeInfo{7092, http.StatusConflict, "Failed to update IMAP migration"}
+	//获取收信死信失败 [非域名管理员或数据库错误]
+	GetDeadLettersFailedCode = ErrorCodeInfo{7093, http.StatusInternalServerError, "Failed to retrieve dead letters"}
+	//重新处理收信死信失败 [非域名管理员或参数不合法]
+	ReprocessDeadLettersFailedCode = ErrorCodeInfo{7094, http.StatusBadRequest, "Failed to reprocess dead letters"}
+	//丢弃收信死信失败 [非域名管理员或参数不合法]
+	DiscardDeadLettersFailedCode = ErrorCodeInfo{7095, http.StatusBadRequest, "Failed to discard dead letters"}
+	//获取邮件事件失败 [数据库错误]
+	GetMailEventsFailedCode = ErrorCodeInfo{7096, http.StatusInternalServerError, "Failed to retrieve mail events"}
+	//创建 Webhook 失败 [地址或事件不合法、创建域名订阅但非域名管理员]
+	CreateWebhookFailedCode = ErrorCodeInfo{7097, http.StatusBadRequest, "Failed to create webhook"}
+	//获取 Webhook 失败 [数据库错误]
+	GetWebhookFailedCode = ErrorCodeInfo{7098, http.StatusInternalServerError, "Failed to get webhook"}
+	//Webhook 不存在 [订阅或推送记录不存在、不属于当前账户或域名]
+	WebhookNotFoundCode = ErrorCodeInfo{7099, http.StatusNotFound, "Webhook not found"}
+	//更新 Webhook 失败 [地址或事件不合法、数据库错误]
+	UpdateWebhookFailedCode = ErrorCodeInfo{7100, http.StatusBadRequest, "Failed to update webhook"}
+	//重新推送 Webhook 失败 [数据库错误]
+	RedeliverWebhookFailedCode = ErrorCodeInfo{7101, http.StatusInternalServerError, "Failed to redeliver webhook"}
+	//收件人位于禁止发送列表 [永久退信或投诉后自动加入，需管理员移除后才能发送]
+	RecipientSuppressedCode = ErrorCodeInfo{7102, http.StatusUnprocessableEntity, "Recipient is on the suppression list"}
+	//获取禁止发送列表失败 [非域名管理员、非全局列表管理员或数据库错误]
+	GetSuppressionsFailedCode = ErrorCodeInfo{7103, http.StatusBadRequest, "Failed to retrieve suppression list"}
+	//移除禁止发送列表记录失败 [非域名管理员、非全局列表管理员或参数不合法]
+	RemoveSuppressionsFailedCode = ErrorCodeInfo{7104, http.StatusBadRequest, "Failed to remove suppression list entries"}
+	//账户已锁定 [认证失败次数过多，锁定到期或管理员解除前无法登录]
+	AccountLockedCode = ErrorCodeInfo{7105, http.StatusTooManyRequests, "Too many failed login attempts, the account is temporarily locked"}
+	//获取锁定账户列表失败 [非域名管理员或数据库错误]
+	GetLockedAccountsFailedCode = ErrorCodeInfo{7106, http.StatusBadRequest, "Failed to retrieve locked accounts"}
+	//解除账户锁定失败 [非域名管理员、账户不属于该域名或账户未被锁定]
+	UnlockAccountFailedCode = ErrorCodeInfo{7107, http.StatusBadRequest, "Failed to unlock account"}
+	//获取应用专用密码失败 [数据库错误]
+	GetAppPasswordsFailedCode = ErrorCodeInfo{7108, http.StatusInternalServerError, "Failed to retrieve app passwords"}
+	//创建应用专用密码失败 [名称为空、数量超过上限或数据库错误]
+	CreateAppPasswordFailedCode = ErrorCodeInfo{7109, http.StatusBadRequest, "Failed to create app password"}
+	//撤销应用专用密码失败 [密码不存在或数据库错误]
+	DeleteAppPasswordFailedCode = ErrorCodeInfo{7110, http.StatusBadRequest, "Failed to revoke app password"}
+	//更新应用专用密码设置失败 [数据库错误]
+	UpdateAppPasswordSettingsFailedCode = ErrorCodeInfo{7111, http.StatusInternalServerError, "Failed to update app password settings"}
+	//两步验证失败 [临时令牌无效或已过期、验证码错误或已被使用]
+	TwoFactorLoginFailedCode = ErrorCodeInfo{7112, http.StatusUnauthorized, "Two-factor authentication failed"}
+	//获取两步验证状态失败 [数据库错误]
+	GetTwoFactorFailedCode = ErrorCodeInfo{7113, http.StatusInternalServerError, "Failed to retrieve two-factor authentication status"}
+	//设置两步验证失败 [已启用两步验证或临时令牌无效]
+	SetupTwoFactorFailedCode = ErrorCodeInfo{7114, http.StatusBadRequest, "Failed to set up two-factor authentication"}
+	//启用两步验证失败 [尚未设置、验证码错误或已启用]
+	EnableTwoFactorFailedCode = ErrorCodeInfo{7115, http.StatusBadRequest, "Failed to enable two-factor authentication"}
+	//关闭两步验证失败 [域名要求两步验证、密码或验证码错误]
+	DisableTwoFactorFailedCode = ErrorCodeInfo{7116, http.StatusBadRequest, "Failed to disable two-factor authentication"}
+	//重新生成恢复码失败 [验证码错误或未启用两步验证]
+	RegenerateRecoveryCodesFailedCode = ErrorCodeInfo{7117, http.StatusBadRequest, "Failed to regenerate recovery codes"}
+	//重置两步验证失败 [非域名管理员、账户不属于该域名或未设置两步验证]
+	ResetTwoFactorFailedCode = ErrorCodeInfo{7118, http.StatusBadRequest, "Failed to reset two-factor authentication"}
 )
 
 // Response 定义统一的响应结构
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"email/controller/response"
+	"email/models"
+	"email/service"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct{}
+
+// CreateWebhook 创建 Webhook 订阅
+func (WebhookController) CreateWebhook(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	webhook, err := service.CreateWebhookProcess(reqAccount, req)
+	if err != nil {
+		response.FailedReq(c, response.CreateWebhookFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, webhook)
+}
+
+// GetWebhooks 获取 Webhook 订阅列表
+func (WebhookController) GetWebhooks(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	list, err := service.GetWebhooksProcess(reqAccount)
+	if err != nil {
+		response.FailedReq(c, response.GetWebhookFailedCode, err.Error())
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// UpdateWebhook 更新 Webhook 订阅
+func (WebhookController) UpdateWebhook(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	webhook, err := service.UpdateWebhookProcess(reqAccount, req)
+	if err != nil {
+		failedWebhookReq(c, response.UpdateWebhookFailedCode, err)
+		return
+	}
+	response.SuccessReq(c, webhook)
+}
+
+// DeleteWebhook 删除 Webhook 订阅
+func (WebhookController) DeleteWebhook(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.DeleteWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	if err := service.DeleteWebhookProcess(reqAccount, req.ID); err != nil {
+		failedWebhookReq(c, response.UpdateWebhookFailedCode, err)
+		return
+	}
+	response.SuccessReq(c, nil)
+}
+
+// GetWebhookDeliveries 获取 Webhook 推送记录
+func (WebhookController) GetWebhookDeliveries(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	webhookID, err := strconv.ParseUint(c.Query("webhook_id"), 10, 64)
+	if err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, "webhook_id is required")
+		return
+	}
+	list, err := service.GetWebhookDeliveriesProcess(reqAccount, uint(webhookID), c.DefaultQuery("page", "1"))
+	if err != nil {
+		failedWebhookReq(c, response.GetWebhookFailedCode, err)
+		return
+	}
+	response.SuccessReq(c, list)
+}
+
+// RedeliverWebhook 重新推送
+func (WebhookController) RedeliverWebhook(c *gin.Context) {
+	// 获取并验证 token
+	reqAccount, err := getAccountDataByParseAccessToken(c)
+	if err != nil {
+		response.FailedReq(c, response.InvalidAccessTokenCode)
+		return
+	}
+	var req models.RedeliverWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailedReq(c, response.ParseDataFailedCode, err.Error())
+		return
+	}
+	delivery, err := service.RedeliverWebhookProcess(reqAccount, req.DeliveryID)
+	if err != nil {
+		failedWebhookReq(c, response.RedeliverWebhookFailedCode, err)
+		return
+	}
+	response.SuccessReq(c, delivery)
+}
+
+// failedWebhookReq 订阅不存在时返回 404，其余返回 code
+func failedWebhookReq(c *gin.Context, code response.ErrorCodeInfo, err error) {
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		response.FailedReq(c, response.WebhookNotFoundCode)
+		return
+	}
+	response.FailedReq(c, code, err.Error())
+}
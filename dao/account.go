@@ -94,6 +94,7 @@ func AddAccount(domainName, userName, accountPrefix, password string) error {
 					body_text       TEXT,
 					body_html       TEXT,
 					is_read         BOOLEAN      DEFAULT FALSE NOT NULL,
+					is_flagged      BOOLEAN      DEFAULT FALSE NOT NULL,
 					email_type      VARCHAR(255) NOT NULL,
 					received_at     TIMESTAMP    DEFAULT CURRENT_TIMESTAMP NOT NULL,
 					attachment_info JSONB        DEFAULT '{}'::JSONB NOT NULL,
@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+)
+
+// AddAppPassword 添加应用专用密码
+func AddAppPassword(p *models.AppPassword) error {
+	if err := global.PsqlDB.Create(p).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("账户 [ID: %d] 添加应用专用密码失败: ", p.EmailAccountID), err)
+		return err
+	}
+	return nil
+}
+
+// GetAppPasswords 获取账户的应用专用密码
+func GetAppPasswords(accountID uint) ([]models.AppPassword, error) {
+	list := []models.AppPassword{}
+	if err := global.PsqlDB.Where("email_account_id = ?", accountID).Order("id ASC").Find(&list).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 应用专用密码失败: ", accountID), err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// DeleteAppPassword 撤销账户的应用专用密码，返回是否存在
+func DeleteAppPassword(accountID, id uint) (bool, error) {
+	result := global.PsqlDB.Where("email_account_id = ? AND id = ?", accountID, id).Delete(&models.AppPassword{})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("撤销账户 [ID: %d] 应用专用密码 [ID: %d] 失败: ", accountID, id), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TouchAppPassword 记录应用专用密码最近一次使用的时间与来源
+func TouchAppPassword(id uint, ip string) {
+	err := global.PsqlDB.Model(&models.AppPassword{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": time.Now(), "last_used_ip": ip}).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("更新应用专用密码 [ID: %d] 使用时间失败: ", id), err)
+	}
+}
+
+// UpdateAppPasswordsOnly 设置账户的邮件客户端是否只能使用应用专用密码
+func UpdateAppPasswordsOnly(accountID uint, only bool) error {
+	err := global.PsqlDB.Model(&models.EmailAccount{}).Where("id = ?", accountID).Update("app_passwords_only", only).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("更新账户 [ID: %d] 应用专用密码设置失败: ", accountID), err)
+	}
+	return err
+}
@@ -3,7 +3,9 @@ package dao
 import (
 	"email/global"
 	"email/models"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -93,3 +95,228 @@ func GetAttachmentsDataByCodes(frAtts []models.FrontendAttachment) ([]models.Web
 
 	return result, dbAtts, nil
 }
+
+// GrantAttachmentAccess 按附件哈希为账户添加访问授权，已存在的授权会被跳过
+func GrantAttachmentAccess(accountID uint, source string, hashes ...string) error {
+	return grantAttachmentAccess(global.PsqlDB, accountID, source, hashes)
+}
+
+func grantAttachmentAccess(db *gorm.DB, accountID uint, source string, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	err := db.Exec(fmt.Sprintf(`
+		INSERT INTO %s (attachment_id, email_account_id, source, created_at)
+		SELECT id, ?, ?, NOW() FROM %s WHERE file_hash IN ?
+		ON CONFLICT (attachment_id, email_account_id) DO NOTHING`,
+		models.AttachmentGrant{}.TableName(), models.Attachment{}.TableName()),
+		accountID, source, hashes).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("添加账户 [ID: %d] 附件授权失败: ", accountID), err)
+	}
+	return err
+}
+
+// CanAccessAttachment 判断账户是否可以访问附件
+// 授权表上线前保存的邮件没有授权记录，此时回退到检查账户邮件是否引用了该附件，命中后补录授权
+func CanAccessAttachment(accountID uint, att *models.Attachment) (bool, error) {
+	var count int64
+	err := global.PsqlDB.Model(&models.AttachmentGrant{}).
+		Where("attachment_id = ? AND email_account_id = ?", att.ID, accountID).Count(&count).Error
+	if err != nil {
+		global.Log.Error("查询附件授权失败: ", err)
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	ref, _ := json.Marshal([]map[string]string{{"short_url_code": att.ShortUrlCode}})
+	err = global.PsqlDB.Table(fmt.Sprintf("user_%d_emails", accountID)).
+		Where("attachment_info @> ?::jsonb", string(ref)).Count(&count).Error
+	if err != nil {
+		global.Log.Error("查询邮件附件引用失败: ", err)
+		return false, err
+	}
+	if count == 0 {
+		return false, nil
+	}
+	_ = GrantAttachmentAccess(accountID, models.AttachmentGrantMessage, att.FileHash)
+	return true, nil
+}
+
+// AddAttachmentShareLink 新增附件分享链接
+func AddAttachmentShareLink(link *models.AttachmentShareLink) error {
+	if err := global.PsqlDB.Create(link).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("新增附件 [ID: %d] 分享链接失败: ", link.AttachmentID), err)
+		return err
+	}
+	return nil
+}
+
+// GetAttachmentShareLinks 获取账户创建的分享链接，attachmentID 为 0 时返回全部
+func GetAttachmentShareLinks(accountID, attachmentID uint) ([]models.AttachmentShareLink, error) {
+	var links []models.AttachmentShareLink
+	query := global.PsqlDB.Where("email_account_id = ?", accountID)
+	if attachmentID != 0 {
+		query = query.Where("attachment_id = ?", attachmentID)
+	}
+	err := query.Order("id DESC").Find(&links).Error
+	return links, err
+}
+
+// GetAttachmentShareLinkByToken 通过 token 查询分享链接
+func GetAttachmentShareLinkByToken(token string) (*models.AttachmentShareLink, error) {
+	var link models.AttachmentShareLink
+	if err := global.PsqlDB.Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// RevokeAttachmentShareLink 撤销账户创建的分享链接
+func RevokeAttachmentShareLink(accountID, linkID uint) error {
+	result := global.PsqlDB.Model(&models.AttachmentShareLink{}).
+		Where("id = ? AND email_account_id = ? AND revoked_at IS NULL", linkID, accountID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("撤销分享链接 [ID: %d] 失败: ", linkID), result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// IncrementShareLinkDownloads 累计分享链接下载次数
+func IncrementShareLinkDownloads(linkID uint) error {
+	return global.PsqlDB.Model(&models.AttachmentShareLink{}).Where("id = ?", linkID).
+		Update("download_count", gorm.Expr("download_count + 1")).Error
+}
+
+// GetAttachmentByID 通过 ID 查询附件
+func GetAttachmentByID(id uint) (*models.Attachment, error) {
+	var att models.Attachment
+	if err := global.PsqlDB.First(&att, id).Error; err != nil {
+		return nil, err
+	}
+	return &att, nil
+}
+
+// GetExpiredAttachments 按 ID 顺序获取短码已过期的附件，afterID 用于分批遍历
+func GetExpiredAttachments(now time.Time, afterID uint, limit int) ([]models.Attachment, error) {
+	var atts []models.Attachment
+	err := global.PsqlDB.Where("expire_time < ? AND id > ?", now, afterID).
+		Order("id ASC").Limit(limit).Find(&atts).Error
+	return atts, err
+}
+
+// HasAttachmentReference 附件是否有邮件授权记录或有效的分享链接，只查询授权与分享表，可在请求中使用
+// 邮件删除后授权记录仍会保留，因此结果可能偏宽，准确的引用检查由回收任务完成
+func HasAttachmentReference(attachmentID uint) (bool, error) {
+	var count int64
+	err := global.PsqlDB.Model(&models.AttachmentGrant{}).
+		Where("attachment_id = ? AND source = ?", attachmentID, models.AttachmentGrantMessage).Limit(1).Count(&count).Error
+	if err == nil && count == 0 {
+		err = global.PsqlDB.Model(&models.AttachmentShareLink{}).
+			Where("attachment_id = ? AND revoked_at IS NULL AND expires_at > ?", attachmentID, time.Now()).Limit(1).Count(&count).Error
+	}
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("查询附件 [ID: %d] 引用失败: ", attachmentID), err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetReferencedAttachmentHashes 返回仍被任意账户邮件引用、或有有效分享链接的附件哈希
+// 需要扫描所有账户的邮件表，只在回收任务中使用；任何一个账户邮件表查询失败都会返回错误，调用方不应在此时删除附件
+func GetReferencedAttachmentHashes(atts []models.Attachment) (map[string]bool, error) {
+	referenced := map[string]bool{}
+	if len(atts) == 0 {
+		return referenced, nil
+	}
+	hashes := make([]string, 0, len(atts))
+	ids := make([]uint, 0, len(atts))
+	hashByID := map[uint]string{}
+	for _, a := range atts {
+		hashes = append(hashes, a.FileHash)
+		ids = append(ids, a.ID)
+		hashByID[a.ID] = a.FileHash
+	}
+	var accountIDs []uint
+	if err := global.PsqlDB.Model(&models.EmailAccount{}).Pluck("id", &accountIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, accountID := range accountIDs {
+		var found []string
+		err := global.PsqlDB.Raw(fmt.Sprintf(`
+			SELECT DISTINCT e->>'file_hash'
+			FROM user_%d_emails
+			CROSS JOIN LATERAL jsonb_array_elements(
+				CASE WHEN jsonb_typeof(attachment_info) = 'array' THEN attachment_info ELSE '[]'::jsonb END) AS e
+			WHERE e->>'file_hash' IN ?`, accountID), hashes).Scan(&found).Error
+		if err != nil {
+			global.Log.Error(fmt.Sprintf("查询账户 [ID: %d] 邮件附件引用失败: ", accountID), err)
+			return nil, err
+		}
+		for _, h := range found {
+			referenced[h] = true
+		}
+	}
+	var shared []uint
+	err := global.PsqlDB.Model(&models.AttachmentShareLink{}).
+		Where("attachment_id IN ? AND revoked_at IS NULL AND expires_at > ?", ids, time.Now()).
+		Distinct().Pluck("attachment_id", &shared).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range shared {
+		referenced[hashByID[id]] = true
+	}
+	return referenced, nil
+}
+
+// GetAttachmentGrantDomains 获取附件授权账户所属的域名，按附件 ID 索引，上传者的域名排在最前
+func GetAttachmentGrantDomains(attachmentIDs []uint) (map[uint][]uint, error) {
+	type row struct {
+		AttachmentID uint
+		DomainID     uint
+	}
+	var rows []row
+	err := global.PsqlDB.Raw(fmt.Sprintf(`
+		SELECT g.attachment_id, a.domain_id
+		FROM %s g JOIN %s a ON a.id = g.email_account_id
+		WHERE g.attachment_id IN ?
+		ORDER BY g.attachment_id, CASE WHEN g.source = ? THEN 0 ELSE 1 END, g.id`,
+		models.AttachmentGrant{}.TableName(), models.EmailAccount{}.TableName()),
+		attachmentIDs, models.AttachmentGrantUpload).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	domains := map[uint][]uint{}
+	for _, r := range rows {
+		domains[r.AttachmentID] = append(domains[r.AttachmentID], r.DomainID)
+	}
+	return domains, nil
+}
+
+// ExtendAttachmentExpire 延长附件短码有效期
+func ExtendAttachmentExpire(ids []uint, expireTime time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return global.PsqlDB.Model(&models.Attachment{}).Where("id IN ?", ids).Update("expire_time", expireTime).Error
+}
+
+// DeleteAttachment 删除附件记录及其授权与分享链接
+func DeleteAttachment(id uint) error {
+	return global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("attachment_id = ?", id).Delete(&models.AttachmentGrant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("attachment_id = ?", id).Delete(&models.AttachmentShareLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Attachment{}, id).Error
+	})
+}
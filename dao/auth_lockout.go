@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetAuthLockout 获取账户或 IP 的认证失败记录，没有记录时返回 nil
+func GetAuthLockout(kind, subject string) (*models.AuthLockout, error) {
+	var l models.AuthLockout
+	result := global.PsqlDB.Where("kind = ? AND subject = ?", kind, subject).Limit(1).Find(&l)
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("获取 [ %s ] 的认证失败记录失败: ", subject), result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &l, nil
+}
+
+// RecordAuthFailure 认证失败次数加一，距上次失败超过 window 时重新计数，返回更新后的记录
+func RecordAuthFailure(kind, subject, ip string, window time.Duration) (*models.AuthLockout, error) {
+	var l models.AuthLockout
+	now := time.Now()
+	err := global.PsqlDB.Raw(strings.ReplaceAll(`INSERT INTO {t} (kind, subject, failures, lockouts, last_failure_at, last_failure_ip, created_at, updated_at)
+		VALUES (?, ?, 1, 0, ?, ?, ?, ?)
+		ON CONFLICT (kind, subject) DO UPDATE SET
+			failures = CASE WHEN {t}.last_failure_at < ? THEN 1 ELSE {t}.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at,
+			last_failure_ip = EXCLUDED.last_failure_ip,
+			updated_at = EXCLUDED.updated_at
+		RETURNING *`, "{t}", global.Config.DatabseTableNames.AuthLockouts),
+		kind, subject, now, ip, now, now, now.Add(-window)).Scan(&l).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("记录 [ %s ] 的认证失败失败: ", subject), err)
+		return nil, err
+	}
+	return &l, nil
+}
+
+// LockAuthSubject 锁定账户或 IP 并清零失败次数，已处于锁定中时不重复锁定，返回是否由本次调用锁定
+func LockAuthSubject(id uint, until time.Time) (bool, error) {
+	result := global.PsqlDB.Exec(fmt.Sprintf(`UPDATE %s SET locked_until = ?, lockouts = lockouts + 1, failures = 0, updated_at = ?
+		WHERE id = ? AND (locked_until IS NULL OR locked_until <= ?)`, global.Config.DatabseTableNames.AuthLockouts),
+		until, time.Now(), id, time.Now())
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("锁定认证记录 [ID: %d] 失败: ", id), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ClearAuthFailures 认证成功后清零失败与锁定次数
+func ClearAuthFailures(kind, subject string) error {
+	err := global.PsqlDB.Model(&models.AuthLockout{}).
+		Where("kind = ? AND subject = ? AND (failures > 0 OR lockouts > 0)", kind, subject).
+		Updates(map[string]interface{}{"failures": 0, "lockouts": 0}).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("清除 [ %s ] 的认证失败记录失败: ", subject), err)
+	}
+	return err
+}
+
+// UnlockAuthSubject 解除锁定并清零失败与锁定次数，返回是否存在该记录
+func UnlockAuthSubject(kind, subject string) (bool, error) {
+	result := global.PsqlDB.Model(&models.AuthLockout{}).
+		Where("kind = ? AND subject = ?", kind, subject).
+		Updates(map[string]interface{}{"locked_until": nil, "failures": 0, "lockouts": 0})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("解除 [ %s ] 的锁定失败: ", subject), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetLockedAccounts 获取域名下处于锁定中的账户
+func GetLockedAccounts(domainName string) ([]models.AuthLockout, error) {
+	list := []models.AuthLockout{}
+	err := global.PsqlDB.Where("kind = ? AND subject LIKE ? ESCAPE '!' AND locked_until > ?", models.AuthSubjectAccount, suffixPattern("@"+strings.ToLower(domainName)), time.Now()).
+		Order("locked_until DESC").Find(&list).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取域名 [ %s ] 下锁定的账户失败: ", domainName), err)
+		return nil, err
+	}
+	return list, nil
+}
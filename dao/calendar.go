@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetCalendarEvents 获取账户的日历事件，start/end 不为空时只返回与该时间段重叠的事件，重复事件始终返回
+func GetCalendarEvents(accountID uint, start, end *time.Time) ([]models.CalendarEvent, error) {
+	var events []models.CalendarEvent
+	query := global.PsqlDB.Where("email_account_id = ?", accountID)
+	if end != nil {
+		query = query.Where("recurring OR start_at IS NULL OR start_at < ?", *end)
+	}
+	if start != nil {
+		query = query.Where("recurring OR COALESCE(end_at, start_at) IS NULL OR COALESCE(end_at, start_at) >= ?", *start)
+	}
+	if err := query.Order("id ASC").Find(&events).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 日历事件失败: ", accountID), err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetCalendarEventByUID 按 UID 获取日历事件
+func GetCalendarEventByUID(accountID uint, uid string) (*models.CalendarEvent, error) {
+	var event models.CalendarEvent
+	err := global.PsqlDB.Where("email_account_id = ? AND uid = ?", accountID, uid).First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// SaveCalendarEvent 按 UID 新增或覆盖日历事件，覆盖时保留原事件的来源与组织者
+func SaveCalendarEvent(event *models.CalendarEvent) error {
+	err := global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email_account_id"}, {Name: "uid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"summary", "start_at", "end_at", "recurring", "data", "updated_at"}),
+	}).Create(event).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("保存日历事件 [UID: %s] 失败: ", event.UID), err)
+	}
+	return err
+}
+
+// SaveInvitedCalendarEvent 按 UID 保存收到的日历邀请，已有事件只有在同样来自该组织者的邀请时才覆盖，返回是否保存
+func SaveInvitedCalendarEvent(event *models.CalendarEvent) (bool, error) {
+	table := models.CalendarEvent{}.TableName()
+	event.Source = models.CalendarSourceInvite
+	result := global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email_account_id"}, {Name: "uid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"summary", "start_at", "end_at", "recurring", "data", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: table + ".source = ?", Vars: []interface{}{models.CalendarSourceInvite}},
+			clause.Expr{SQL: table + ".organizer = ?", Vars: []interface{}{event.Organizer}},
+		}},
+	}).Create(event)
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("保存日历邀请 [UID: %s] 失败: ", event.UID), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteInvitedCalendarEvent 按 UID 删除来自 organizer 邀请的日历事件，返回是否删除
+func DeleteInvitedCalendarEvent(accountID uint, uid, organizer string) (bool, error) {
+	result := global.PsqlDB.Where("email_account_id = ? AND uid = ? AND source = ? AND organizer = ?", accountID, uid, models.CalendarSourceInvite, organizer).
+		Delete(&models.CalendarEvent{})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("取消日历邀请 [UID: %s] 失败: ", uid), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteCalendarEventByUID 按 UID 删除日历事件
+func DeleteCalendarEventByUID(accountID uint, uid string) error {
+	result := global.PsqlDB.Where("email_account_id = ? AND uid = ?", accountID, uid).Delete(&models.CalendarEvent{})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("删除日历事件 [UID: %s] 失败: ", uid), result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
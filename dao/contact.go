@@ -0,0 +1,202 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetContacts 分页获取联系人，group 不为空时按分组过滤，recent 为 nil 时不区分是否自动收集
+func GetContacts(accountID uint, page, pageSize int, group string, recent *bool) ([]models.Contact, int64, error) {
+	var contacts []models.Contact
+	var total int64
+	query := global.PsqlDB.Model(&models.Contact{}).Where("email_account_id = ?", accountID)
+	if group != "" {
+		g, _ := json.Marshal([]string{group})
+		query = query.Where("groups @> ?::jsonb", string(g))
+	}
+	if recent != nil {
+		query = query.Where("is_recent = ?", *recent)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 联系人总数失败: ", accountID), err)
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []models.Contact{}, 0, nil
+	}
+	err := query.Order("name ASC, id ASC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&contacts).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 联系人列表失败: ", accountID), err)
+		return nil, 0, err
+	}
+	return contacts, total, nil
+}
+
+// GetAllContacts 获取账户的全部已保存联系人
+func GetAllContacts(accountID uint) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := global.PsqlDB.Where("email_account_id = ? AND is_recent = ?", accountID, false).
+		Order("name ASC, id ASC").Find(&contacts).Error
+	return contacts, err
+}
+
+// GetContactByID 获取账户的指定联系人
+func GetContactByID(accountID, contactID uint) (*models.Contact, error) {
+	var contact models.Contact
+	err := global.PsqlDB.Where("id = ? AND email_account_id = ?", contactID, accountID).First(&contact).Error
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// AddContact 新增联系人
+func AddContact(contact *models.Contact) error {
+	if contact.UID == "" {
+		contact.UID = uuid.New().String()
+	}
+	if err := global.PsqlDB.Create(contact).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("新增账户 [ID: %d] 联系人失败: ", contact.EmailAccountID), err)
+		return err
+	}
+	return nil
+}
+
+// UpdateContact 更新联系人，保存后不再视为自动收集的联系人
+func UpdateContact(contact *models.Contact) error {
+	contact.IsRecent = false
+	result := global.PsqlDB.Model(&models.Contact{}).
+		Where("id = ? AND email_account_id = ?", contact.ID, contact.EmailAccountID).
+		Select("name", "emails", "phone", "notes", "groups", "vcard", "is_recent", "updated_at").
+		Updates(contact)
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("更新联系人 [ID: %d] 失败: ", contact.ID), result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteContact 删除联系人
+func DeleteContact(accountID, contactID uint) error {
+	result := global.PsqlDB.Where("id = ? AND email_account_id = ?", contactID, accountID).Delete(&models.Contact{})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("删除联系人 [ID: %d] 失败: ", contactID), result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetContactByUID 按 UID 获取账户的已保存联系人
+func GetContactByUID(accountID uint, uid string) (*models.Contact, error) {
+	var contact models.Contact
+	err := global.PsqlDB.Where("email_account_id = ? AND uid = ? AND is_recent = ?", accountID, uid, false).First(&contact).Error
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// DeleteContactByUID 按 UID 删除联系人
+func DeleteContactByUID(accountID uint, uid string) error {
+	result := global.PsqlDB.Where("email_account_id = ? AND uid = ?", accountID, uid).Delete(&models.Contact{})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("删除联系人 [UID: %s] 失败: ", uid), result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpsertContactsByUID 按 UID 批量新增或覆盖联系人，用于 vCard 导入
+// 同一批中 UID 重复时只保留最后一个，否则 ON CONFLICT 会因同一行被更新两次而失败
+func UpsertContactsByUID(contacts []models.Contact) error {
+	index := make(map[string]int, len(contacts))
+	unique := make([]models.Contact, 0, len(contacts))
+	for _, c := range contacts {
+		key := fmt.Sprintf("%d:%s", c.EmailAccountID, c.UID)
+		if i, ok := index[key]; ok {
+			unique[i] = c
+			continue
+		}
+		index[key] = len(unique)
+		unique = append(unique, c)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	return global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email_account_id"}, {Name: "uid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "emails", "phone", "notes", "groups", "vcard", "is_recent", "updated_at"}),
+	}).Create(&unique).Error
+}
+
+// RecordContactUsage 记录一次发件，已有联系人累计使用次数，否则新增为最近联系人
+func RecordContactUsage(accountID uint, name, email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+	key, _ := json.Marshal([]string{email})
+	now := time.Now()
+	result := global.PsqlDB.Model(&models.Contact{}).
+		Where("email_account_id = ? AND emails @> ?::jsonb", accountID, string(key)).
+		Updates(map[string]interface{}{
+			"use_count":    gorm.Expr("use_count + 1"),
+			"last_used_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+	return AddContact(&models.Contact{
+		EmailAccountID: accountID,
+		Name:           name,
+		Emails:         key,
+		Groups:         []byte("[]"),
+		IsRecent:       true,
+		UseCount:       1,
+		LastUsedAt:     &now,
+	})
+}
+
+// SearchContactSuggestions 搜索名称或地址中包含关键字的联系人，按使用频率与最近使用时间排序
+// 评分 = (使用次数 + 1) / (1 + 距最后使用的天数 / 30)，已保存的联系人额外加 1
+func SearchContactSuggestions(accountID uint, keyword string, limit int) ([]models.ContactSuggestion, error) {
+	var rows []models.ContactSuggestion
+	like := containsPattern(strings.ToLower(keyword))
+	err := global.PsqlDB.Raw(fmt.Sprintf(`
+		SELECT c.id AS contact_id, c.name, e.email
+		FROM %s c, jsonb_array_elements_text(c.emails) AS e(email)
+		WHERE c.email_account_id = ? AND (LOWER(c.name) LIKE ? ESCAPE '!' OR e.email LIKE ? ESCAPE '!')
+		ORDER BY ((c.use_count + 1 + CASE WHEN c.is_recent THEN 0 ELSE 1 END)
+			/ (1 + EXTRACT(EPOCH FROM (NOW() - COALESCE(c.last_used_at, c.created_at))) / 86400.0 / 30)) DESC,
+			c.name ASC
+		LIMIT ?`, models.Contact{}.TableName()),
+		accountID, like, like, limit).Scan(&rows).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("搜索账户 [ID: %d] 联系人失败: ", accountID), err)
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,84 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// AddDeliveryStatuses 记录发出邮件各收件人的投递状态，邮件已发出，失败时只记录日志
+func AddDeliveryStatuses(sesMessageID string, accountID, emailID uint, recipients []string) {
+	if sesMessageID == "" || emailID == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	var list []models.DeliveryStatus
+	for _, r := range recipients {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" || seen[r] {
+			continue
+		}
+		seen[r] = true
+		list = append(list, models.DeliveryStatus{
+			SESMessageID:   sesMessageID,
+			EmailAccountID: accountID,
+			EmailID:        emailID,
+			Recipient:      r,
+			Status:         models.DeliverySent,
+		})
+	}
+	if len(list) == 0 {
+		return
+	}
+	if err := global.PsqlDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&list).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("记录邮件 [ %s ] 投递状态失败: ", sesMessageID), err)
+	}
+}
+
+// GetDeliveryStatuses 获取 SES 消息 ID 对应的各收件人投递状态
+func GetDeliveryStatuses(sesMessageID string) ([]models.DeliveryStatus, error) {
+	var list []models.DeliveryStatus
+	err := global.PsqlDB.Where("ses_message_id = ?", sesMessageID).Order("id ASC").Find(&list).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取邮件 [ %s ] 投递状态失败: ", sesMessageID), err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateDeliveryStatus 更新收件人的投递状态，只允许更新为更靠后的状态，返回状态是否发生变化
+func UpdateDeliveryStatus(sesMessageID, recipient, status, bounceType, detail string) (bool, error) {
+	result := global.PsqlDB.Model(&models.DeliveryStatus{}).
+		Where("ses_message_id = ? AND recipient = ? AND status IN ?", sesMessageID, strings.ToLower(recipient), models.DeliveryStatusesBefore(status)).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"bounce_type": bounceType,
+			"detail":      detail,
+		})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("更新邮件 [ %s ] 收件人 [ %s ] 投递状态失败: ", sesMessageID, recipient), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetEmailDeliveryStatuses 获取账户发出邮件的投递状态，按邮件 ID 分组
+func GetEmailDeliveryStatuses(accountID uint, emailIDs []uint) (map[uint][]models.DeliveryStatus, error) {
+	statuses := make(map[uint][]models.DeliveryStatus)
+	if len(emailIDs) == 0 {
+		return statuses, nil
+	}
+	var list []models.DeliveryStatus
+	err := global.PsqlDB.Where("email_account_id = ? AND email_id IN ?", accountID, emailIDs).Order("id ASC").Find(&list).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ %d ] 邮件投递状态失败: ", accountID), err)
+		return nil, err
+	}
+	for _, s := range list {
+		statuses[s.EmailID] = append(statuses[s.EmailID], s)
+	}
+	return statuses, nil
+}
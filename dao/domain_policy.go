@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetDomainPolicy 获取域名策略，不存在时返回 nil, nil
+func GetDomainPolicy(domainID uint) (*models.DomainPolicy, error) {
+	var p models.DomainPolicy
+	err := global.PsqlDB.Where("domain_id = ?", domainID).First(&p).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		global.Log.Error(fmt.Sprintf("查询域名 [ID: %d] 策略失败: ", domainID), err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetDomainPolicies 批量获取域名策略，按域名 ID 索引
+func GetDomainPolicies(domainIDs []uint) (map[uint]models.DomainPolicy, error) {
+	policies := map[uint]models.DomainPolicy{}
+	if len(domainIDs) == 0 {
+		return policies, nil
+	}
+	var list []models.DomainPolicy
+	if err := global.PsqlDB.Where("domain_id IN ?", domainIDs).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range list {
+		policies[p.DomainID] = p
+	}
+	return policies, nil
+}
+
+// SaveDomainPolicy 新增或更新域名策略
+func SaveDomainPolicy(p *models.DomainPolicy) error {
+	err := global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "domain_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"attachment_reaper_enabled", "orphan_grace_hours", "require_two_factor", "updated_at"}),
+	}).Create(p).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("保存域名 [ID: %d] 策略失败: ", p.DomainID), err)
+		return err
+	}
+	return nil
+}
+
+// AddAttachmentReaperReports 保存附件回收报告
+func AddAttachmentReaperReports(reports []models.AttachmentReaperReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	return global.PsqlDB.Create(&reports).Error
+}
+
+// GetAttachmentReaperReports 分页获取域名的附件回收报告
+func GetAttachmentReaperReports(domainID uint, page, pageSize int) ([]models.AttachmentReaperReport, int64, error) {
+	var reports []models.AttachmentReaperReport
+	var total int64
+	query := global.PsqlDB.Model(&models.AttachmentReaperReport{}).Where("domain_id = ?", domainID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&reports).Error
+	return reports, total, err
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -35,6 +36,16 @@ func AddNewEmailToDB(r *models.EmailDetails, d *models.EmailAccount, atts []mode
 			return err // 返回错误会触发事务回滚
 		}
 		emailID = r.ID // 获取插入后的ID
+		// 邮件引用的附件授权给该账户
+		hashes := make([]string, 0, len(atts))
+		for _, att := range atts {
+			if att.FileHash != "" {
+				hashes = append(hashes, att.FileHash)
+			}
+		}
+		if err := grantAttachmentAccess(gd, d.ID, models.AttachmentGrantMessage, hashes); err != nil {
+			return err
+		}
 		global.Log.Info(fmt.Sprintf("插入 [ %s ] 邮件成功", r.S3Key))
 		return nil // 返回 nil 提交事务
 	})
@@ -42,6 +53,10 @@ func AddNewEmailToDB(r *models.EmailDetails, d *models.EmailAccount, atts []mode
 		// 事务回滚时的错误处理
 		return 0, err
 	}
+	// 发出与导入的邮件不触发收信事件
+	if r.EmailType != global.EmailTypeSent && !strings.HasPrefix(r.S3Key, models.ImportedEmailKeyPrefix) {
+		PublishEmailWebhook(models.WebhookMessageReceived, r, d)
+	}
 	return emailID, nil
 }
 
@@ -72,7 +87,7 @@ func GetEmailList(accountID uint, page, pageSize int, emailType string) ([]model
 	}
 	// 查询邮件列表，只选择指定的字段
 	if err := global.PsqlDB.Table(tableName).
-		Select("id, email_account_id, email_address,recipient_email, sender_name, sender_email, subject, body_text, body_html, is_read, received_at").
+		Select("id, email_account_id, email_address,recipient_email, sender_name, sender_email, subject, body_text, body_html, is_read, is_flagged, received_at").
 		Where("email_type = ? ", emailType).
 		Order("received_at DESC").
 		Offset(offset).Limit(pageSize).
@@ -88,7 +103,7 @@ func GetEmailDetails(emailID int, accountID uint) (*models.EmailDetailsResponse,
 	var email models.EmailDetailsResponse
 	tableName := fmt.Sprintf("user_%d_emails", accountID)
 	result := global.PsqlDB.Table(tableName).
-		Select("id, recipient_email,sender_name, sender_email, subject, body_text, body_html, received_at").
+		Select("id, recipient_email,sender_name, sender_email, subject, body_text, body_html, attachment_info, received_at").
 		Where("id = ?", emailID).
 		First(&email)
 	if result.Error != nil {
@@ -183,7 +198,7 @@ func GetLatestInboxEmailList(accountID uint, lastEmailID int) ([]models.EmailDet
 		limit = int(total)
 	}
 	if err := global.PsqlDB.Table(tableName).
-		Select("id, email_account_id, email_address, sender_name, sender_email, subject, body_text, body_html, is_read, received_at").
+		Select("id, email_account_id, email_address, sender_name, sender_email, subject, body_text, body_html, is_read, is_flagged, received_at").
 		Where("email_type = ? AND id > ?", "inbox", lastEmailID).
 		Order("received_at DESC").
 		Limit(limit).
@@ -232,7 +247,7 @@ func GetEmailListByID(accountID uint, emailID int, emailType string) ([]models.E
 	//此api每次默认返回10条，不足30条则返回全部
 	// 查询邮件列表，只选择指定的字段
 	query := global.PsqlDB.Table(tableName).
-		Select("id, email_account_id, email_address, recipient_email, sender_name, sender_email, cc,subject, body_text, body_html, is_read, attachment_info,received_at").
+		Select("id, email_account_id, email_address, recipient_email, sender_name, sender_email, cc,subject, body_text, body_html, is_read, is_flagged, attachment_info,received_at").
 		Where("email_type = ?", emailType).
 		Order("received_at DESC").
 		Limit(10)
@@ -330,3 +345,12 @@ func GetEmailFileName(emailID int, accountID uint) (string, error) {
 	//邮件的is_read字段如果是false的，则更新为true
 	return email.FileName, nil
 }
+
+// GetEmailStatesByMessageID 获取账户中指定 Message-ID 的邮件分类与已读状态，用于发布变更事件
+func GetEmailStatesByMessageID(accountID uint, emailMessageID string) ([]models.EmailDetails, error) {
+	var emails []models.EmailDetails
+	err := global.PsqlDB.Table(fmt.Sprintf("user_%d_emails", accountID)).
+		Select("id", "email_message_id", "email_type", "is_read").
+		Where("email_message_id = ?", emailMessageID).Find(&emails).Error
+	return emails, err
+}
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GetFilterRules 获取账户的过滤规则，按优先级排序
+func GetFilterRules(accountID uint) ([]models.FilterRule, error) {
+	var rules []models.FilterRule
+	err := global.PsqlDB.Where("email_account_id = ?", accountID).
+		Order("priority ASC, id ASC").
+		Find(&rules).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("查询账户 [ID: %d] 过滤规则失败: ", accountID), err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetFilterRuleByID 获取账户的指定过滤规则
+func GetFilterRuleByID(accountID, ruleID uint) (*models.FilterRule, error) {
+	var rule models.FilterRule
+	err := global.PsqlDB.Where("id = ? AND email_account_id = ?", ruleID, accountID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// AddFilterRule 新增过滤规则
+func AddFilterRule(rule *models.FilterRule) error {
+	if err := global.PsqlDB.Create(rule).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("新增账户 [ID: %d] 过滤规则失败: ", rule.EmailAccountID), err)
+		return err
+	}
+	return nil
+}
+
+// UpdateFilterRule 更新过滤规则
+func UpdateFilterRule(rule *models.FilterRule) error {
+	result := global.PsqlDB.Model(&models.FilterRule{}).
+		Where("id = ? AND email_account_id = ?", rule.ID, rule.EmailAccountID).
+		Select("name", "priority", "enabled", "match_type", "conditions", "actions", "stop").
+		Updates(rule)
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("更新过滤规则 [ID: %d] 失败: ", rule.ID), result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteFilterRule 删除过滤规则
+func DeleteFilterRule(accountID, ruleID uint) error {
+	result := global.PsqlDB.Where("id = ? AND email_account_id = ?", ruleID, accountID).Delete(&models.FilterRule{})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("删除过滤规则 [ID: %d] 失败: ", ruleID), result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ImportFilterRules 批量导入过滤规则，replace 为 true 时先清空原有规则
+func ImportFilterRules(accountID uint, rules []models.FilterRule, replace bool) error {
+	return global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := tx.Where("email_account_id = ?", accountID).Delete(&models.FilterRule{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		for i := range rules {
+			rules[i].ID = 0
+			rules[i].EmailAccountID = accountID
+		}
+		return tx.Create(&rules).Error
+	})
+}
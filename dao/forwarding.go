@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetForwardingRule 获取账户的自动转发设置，不存在时返回 nil, nil
+func GetForwardingRule(accountID uint) (*models.ForwardingRule, error) {
+	var f models.ForwardingRule
+	err := global.PsqlDB.Where("email_account_id = ?", accountID).First(&f).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		global.Log.Error(fmt.Sprintf("查询账户 [ID: %d] 自动转发设置失败: ", accountID), err)
+		return nil, err
+	}
+	return &f, nil
+}
+
+// SaveForwardingRule 新增或更新账户的自动转发设置
+func SaveForwardingRule(f *models.ForwardingRule) error {
+	err := global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email_account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "forward_to", "keep_copy", "updated_at"}),
+	}).Create(f).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("保存账户 [ID: %d] 自动转发设置失败: ", f.EmailAccountID), err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// orderFoldersByID 预加载文件夹时按创建顺序排列
+func orderFoldersByID(db *gorm.DB) *gorm.DB {
+	return db.Order("id ASC")
+}
+
+// AddImapMigration 新增 IMAP 迁移任务
+func AddImapMigration(m *models.ImapMigration) error {
+	if err := global.PsqlDB.Create(m).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("新增账户 [ID: %d] IMAP 迁移任务失败: ", m.EmailAccountID), err)
+		return err
+	}
+	return nil
+}
+
+// GetImapMigration 获取账户的 IMAP 迁移任务及各文件夹进度
+func GetImapMigration(accountID, id uint) (*models.ImapMigration, error) {
+	var m models.ImapMigration
+	err := global.PsqlDB.Preload("Folders", orderFoldersByID).
+		Where("id = ? AND email_account_id = ?", id, accountID).First(&m).Error
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetImapMigrations 分页获取账户的 IMAP 迁移任务，最新的在前
+func GetImapMigrations(accountID uint, page, pageSize int) ([]models.ImapMigration, int64, error) {
+	var list []models.ImapMigration
+	var total int64
+	query := global.PsqlDB.Model(&models.ImapMigration{}).Where("email_account_id = ?", accountID)
+	if err := query.Count(&total).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] IMAP 迁移任务总数失败: ", accountID), err)
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []models.ImapMigration{}, 0, nil
+	}
+	err := query.Preload("Folders", orderFoldersByID).
+		Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] IMAP 迁移任务列表失败: ", accountID), err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// GetPendingImapMigrationIDs 获取等待执行的迁移任务
+func GetPendingImapMigrationIDs() ([]uint, error) {
+	var ids []uint
+	err := global.PsqlDB.Model(&models.ImapMigration{}).Where("status = ?", models.ImapMigrationPending).
+		Order("id ASC").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// RequeueRunningImapMigrations 服务重启时将执行中的迁移任务重新排队，之后从记录的进度继续
+func RequeueRunningImapMigrations() error {
+	return global.PsqlDB.Model(&models.ImapMigration{}).Where("status = ?", models.ImapMigrationRunning).
+		Update("status", models.ImapMigrationPending).Error
+}
+
+// ClaimImapMigration 将等待中的迁移任务标记为执行中，已被领取时返回 false
+func ClaimImapMigration(id uint) (*models.ImapMigration, bool, error) {
+	result := global.PsqlDB.Model(&models.ImapMigration{}).
+		Where("id = ? AND status = ?", id, models.ImapMigrationPending).
+		Updates(map[string]interface{}{"status": models.ImapMigrationRunning, "error": ""})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, false, result.Error
+	}
+	var m models.ImapMigration
+	err := global.PsqlDB.Preload("Folders", orderFoldersByID).First(&m, id).Error
+	if err != nil {
+		return nil, false, err
+	}
+	return &m, true, nil
+}
+
+// GetImapMigrationStatus 获取迁移任务的当前状态，用于执行中检查是否已被取消
+func GetImapMigrationStatus(id uint) (string, error) {
+	var status string
+	err := global.PsqlDB.Model(&models.ImapMigration{}).Where("id = ?", id).Pluck("status", &status).Error
+	return status, err
+}
+
+// RestartImapMigration 将失败或已取消的迁移任务重新排队，secret 不为空时更新凭证
+func RestartImapMigration(accountID, id uint, secret string) (bool, error) {
+	updates := map[string]interface{}{"status": models.ImapMigrationPending, "error": "", "finished_at": nil}
+	if secret != "" {
+		updates["secret"] = secret
+	}
+	result := global.PsqlDB.Model(&models.ImapMigration{}).
+		Where("id = ? AND email_account_id = ? AND status IN ?", id, accountID,
+			[]string{models.ImapMigrationFailed, models.ImapMigrationCancelled}).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CancelImapMigration 取消等待中或执行中的迁移任务，执行中的任务在处理完当前批次后停止
+func CancelImapMigration(accountID, id uint) (bool, error) {
+	result := global.PsqlDB.Model(&models.ImapMigration{}).
+		Where("id = ? AND email_account_id = ? AND status IN ?", id, accountID,
+			[]string{models.ImapMigrationPending, models.ImapMigrationRunning}).
+		Updates(map[string]interface{}{"status": models.ImapMigrationCancelled, "finished_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FinishImapMigration 结束迁移任务，完成时清空保存的凭证；任务已被取消时不覆盖状态
+func FinishImapMigration(m *models.ImapMigration) error {
+	now := time.Now()
+	m.FinishedAt = &now
+	updates := map[string]interface{}{"status": m.Status, "error": m.Error, "finished_at": now}
+	if m.Status == models.ImapMigrationCompleted {
+		updates["secret"] = ""
+	}
+	err := global.PsqlDB.Model(&models.ImapMigration{}).
+		Where("id = ? AND status = ?", m.ID, models.ImapMigrationRunning).Updates(updates).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("更新 IMAP 迁移任务 [ID: %d] 状态失败: ", m.ID), err)
+	}
+	return err
+}
+
+// AddImapMigrationFolders 保存迁移任务的远程文件夹列表
+func AddImapMigrationFolders(folders []models.ImapMigrationFolder) error {
+	if len(folders) == 0 {
+		return nil
+	}
+	return global.PsqlDB.Create(&folders).Error
+}
+
+// SaveImapMigrationFolder 保存文件夹进度
+func SaveImapMigrationFolder(f *models.ImapMigrationFolder) error {
+	return global.PsqlDB.Model(&models.ImapMigrationFolder{}).Where("id = ?", f.ID).
+		Updates(map[string]interface{}{
+			"target_folder": f.TargetFolder,
+			"uid_validity":  f.UIDValidity,
+			"last_uid":      f.LastUID,
+			"total":         f.Total,
+			"processed":     f.Processed,
+			"imported":      f.Imported,
+			"skipped":       f.Skipped,
+			"failed":        f.Failed,
+			"status":        f.Status,
+			"error":         f.Error,
+		}).Error
+}
+
+// IsEmailExistByMessageID 根据 Message-ID 判断账户中邮件是否存在
+func IsEmailExistByMessageID(accountID uint, messageID string) (bool, error) {
+	var count int64
+	err := global.PsqlDB.Table(fmt.Sprintf("user_%d_emails", accountID)).
+		Where("email_message_id = ?", messageID).Count(&count).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("查询 Message-ID [%s] 对应的邮件失败: ", messageID), err)
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,177 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// orderDeliveriesByID 预加载投递记录时按收件人顺序排列
+func orderDeliveriesByID(db *gorm.DB) *gorm.DB {
+	return db.Order("id ASC")
+}
+
+// GetOrCreateInboundMessage 按 S3Key 获取收信记录，不存在时创建
+func GetOrCreateInboundMessage(m *models.InboundMessage) (*models.InboundMessage, error) {
+	err := global.PsqlDB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "s3_key"}}, DoNothing: true}).Create(m).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("新增收信记录 [ %s ] 失败: ", m.S3Key), err)
+		return nil, err
+	}
+	var existing models.InboundMessage
+	err = global.PsqlDB.Preload("Deliveries", orderDeliveriesByID).Where("s3_key = ?", m.S3Key).First(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// GetInboundMessage 获取收信记录及各收件人的投递进度
+func GetInboundMessage(id uint) (*models.InboundMessage, error) {
+	var m models.InboundMessage
+	if err := global.PsqlDB.Preload("Deliveries", orderDeliveriesByID).First(&m, id).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SaveInboundNormalized 保存规范化后的 Message-ID 与哈希，并创建各收件人的投递记录
+// 投递记录已存在时保留原有进度
+func SaveInboundNormalized(m *models.InboundMessage, deliveries []models.InboundDelivery) error {
+	return global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.InboundMessage{}).Where("id = ?", m.ID).Updates(map[string]interface{}{
+			"message_id": m.MessageID,
+			"email_hash": m.EmailHash,
+			"stage":      models.IngestStageNormalized,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if len(deliveries) > 0 {
+			err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+			if err != nil {
+				return err
+			}
+		}
+		m.Stage = models.IngestStageNormalized
+		m.Deliveries = nil
+		return tx.Where("inbound_message_id = ?", m.ID).Order("id ASC").Find(&m.Deliveries).Error
+	})
+}
+
+// UpdateInboundDelivery 保存收件人的投递进度
+func UpdateInboundDelivery(d *models.InboundDelivery) error {
+	return global.PsqlDB.Model(&models.InboundDelivery{}).Where("id = ?", d.ID).Updates(map[string]interface{}{
+		"email_account_id": d.EmailAccountID,
+		"stage":            d.Stage,
+		"outcome":          d.Outcome,
+		"email_type":       d.EmailType,
+		"mark_read":        d.MarkRead,
+		"flagged":          d.Flagged,
+	}).Error
+}
+
+// CompleteInboundMessage 将收信记录标记为处理完成
+func CompleteInboundMessage(id uint) error {
+	now := time.Now()
+	return global.PsqlDB.Model(&models.InboundMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.InboundCompleted,
+		"stage":        models.IngestStageDone,
+		"completed_at": &now,
+	}).Error
+}
+
+// RecordInboundFailure 记录处理失败的阶段与原因，dead 为 true 时转入死信
+func RecordInboundFailure(m *models.InboundMessage, letter *models.InboundDeadLetter, dead bool) error {
+	return global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"attempts":    m.Attempts,
+			"last_step":   letter.Step,
+			"last_reason": letter.Reason,
+			"last_error":  letter.Error,
+		}
+		if dead {
+			updates["status"] = models.InboundDead
+		}
+		if err := tx.Model(&models.InboundMessage{}).Where("id = ?", m.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if !dead {
+			return nil
+		}
+		return tx.Create(letter).Error
+	})
+}
+
+// domainDeadLetters 域名管理员可见的死信：任一收件人属于该域名
+func domainDeadLetters(domain string) *gorm.DB {
+	return global.PsqlDB.Model(&models.InboundDeadLetter{}).Where("domains @> ?::jsonb", fmt.Sprintf("[%q]", domain))
+}
+
+// GetInboundDeadLetters 分页获取域名下的死信，status 为空时返回全部
+func GetInboundDeadLetters(domain, status string, page, pageSize int) ([]models.InboundDeadLetter, int64, error) {
+	var list []models.InboundDeadLetter
+	var total int64
+	query := domainDeadLetters(domain)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取域名 [ %s ] 死信总数失败: ", domain), err)
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []models.InboundDeadLetter{}, 0, nil
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取域名 [ %s ] 死信列表失败: ", domain), err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ReopenInboundDeadLetter 将未处理的死信标记为已重新处理，并把对应邮件恢复为待处理
+// 死信不存在、不属于该域名或已处理时返回 false
+func ReopenInboundDeadLetter(domain string, id uint, resolvedBy string) (uint, bool, error) {
+	var letter models.InboundDeadLetter
+	err := domainDeadLetters(domain).Where("id = ? AND status = ?", id, models.DeadLetterOpen).First(&letter).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	ok := false
+	err = global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.InboundDeadLetter{}).Where("id = ? AND status = ?", id, models.DeadLetterOpen).
+			Updates(map[string]interface{}{"status": models.DeadLetterReprocessed, "resolved_by": resolvedBy, "resolved_at": &now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		ok = true
+		return tx.Model(&models.InboundMessage{}).Where("id = ? AND status = ?", letter.InboundMessageID, models.InboundDead).
+			Updates(map[string]interface{}{"status": models.InboundPending, "attempts": 0}).Error
+	})
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("重新处理死信 [ID: %d] 失败: ", id), err)
+		return 0, false, err
+	}
+	return letter.InboundMessageID, ok, nil
+}
+
+// DiscardInboundDeadLetters 丢弃域名下未处理的死信，返回丢弃的数量
+func DiscardInboundDeadLetters(domain string, ids []uint, resolvedBy string) (int64, error) {
+	now := time.Now()
+	result := domainDeadLetters(domain).Where("id IN ? AND status = ?", ids, models.DeadLetterOpen).
+		Updates(map[string]interface{}{"status": models.DeadLetterDiscarded, "resolved_by": resolvedBy, "resolved_at": &now})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("丢弃域名 [ %s ] 死信失败: ", domain), result.Error)
+	}
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,111 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MailEventChannel 邮件事件的 Postgres NOTIFY 频道，负载为 "<账户ID>:<事件ID>"
+const MailEventChannel = "mail_events"
+
+// PublishMailEvent 保存邮件事件并通知所有实例，失败时只记录日志，不影响邮件操作
+func PublishMailEvent(e *models.MailEvent) {
+	err := global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		// NOTIFY 在事务提交后才会送达，监听方收到时事件已可查询
+		return tx.Exec("SELECT pg_notify(?, ?)", MailEventChannel, fmt.Sprintf("%d:%d", e.EmailAccountID, e.ID)).Error
+	})
+	if err != nil {
+		global.Log.Errorf("发布账户 [ID: %d] 邮件事件 [ %s ] 失败: %v", e.EmailAccountID, e.Type, err)
+	}
+}
+
+// PublishNewEmailEvent 发布新邮件事件
+func PublishNewEmailEvent(r *models.EmailDetails) {
+	read, flagged := r.IsRead, r.IsFlagged
+	sender := r.SenderEmail
+	if r.SenderName != "" {
+		sender = fmt.Sprintf("%s <%s>", r.SenderName, r.SenderEmail)
+	}
+	PublishMailEvent(&models.MailEvent{
+		EmailAccountID: r.EmailAccountID,
+		Type:           models.MailEventNewMessage,
+		EmailID:        r.ID,
+		MessageID:      r.EmailMessageID,
+		Folder:         r.EmailType,
+		IsRead:         &read,
+		IsFlagged:      &flagged,
+		Subject:        r.Subject,
+		Sender:         sender,
+	})
+}
+
+// PublishMoveEmailEvent 发布邮件移动事件
+func PublishMoveEmailEvent(accountID, emailID uint, messageID, fromFolder, folder string) {
+	PublishMailEvent(&models.MailEvent{
+		EmailAccountID: accountID,
+		Type:           models.MailEventMove,
+		EmailID:        emailID,
+		MessageID:      messageID,
+		Folder:         folder,
+		FromFolder:     fromFolder,
+	})
+}
+
+// PublishReadStatusEvent 发布已读状态变更事件
+func PublishReadStatusEvent(accountID, emailID uint, messageID, folder string, read bool) {
+	PublishMailEvent(&models.MailEvent{
+		EmailAccountID: accountID,
+		Type:           models.MailEventFlagChange,
+		EmailID:        emailID,
+		MessageID:      messageID,
+		Folder:         folder,
+		IsRead:         &read,
+	})
+}
+
+// PublishDeleteEmailEvent 发布邮件删除事件
+func PublishDeleteEmailEvent(accountID, emailID uint, messageID, folder string) {
+	PublishMailEvent(&models.MailEvent{
+		EmailAccountID: accountID,
+		Type:           models.MailEventDelete,
+		EmailID:        emailID,
+		MessageID:      messageID,
+		Folder:         folder,
+	})
+}
+
+// GetMailEventsAfter 获取账户在游标之后的事件，按 ID 升序
+func GetMailEventsAfter(accountID uint, cursor uint64, limit int) ([]models.MailEvent, error) {
+	var events []models.MailEvent
+	err := global.PsqlDB.Where("email_account_id = ? AND id > ?", accountID, cursor).
+		Order("id ASC").Limit(limit).Find(&events).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 邮件事件失败: ", accountID), err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetMailEventIDRange 获取当前保留的事件 ID 范围，没有事件时均为 0
+func GetMailEventIDRange() (uint64, uint64, error) {
+	var r struct {
+		MinID uint64
+		MaxID uint64
+	}
+	err := global.PsqlDB.Model(&models.MailEvent{}).
+		Select("COALESCE(MIN(id), 0) AS min_id, COALESCE(MAX(id), 0) AS max_id").Scan(&r).Error
+	return r.MinID, r.MaxID, err
+}
+
+// DeleteMailEventsBefore 删除早于指定时间的事件
+func DeleteMailEventsBefore(t time.Time) (int64, error) {
+	result := global.PsqlDB.Where("created_at < ?", t).Delete(&models.MailEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,135 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+)
+
+// AddMailboxJob 新增邮箱导入导出任务
+func AddMailboxJob(job *models.MailboxJob) error {
+	if err := global.PsqlDB.Create(job).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("新增账户 [ID: %d] 邮箱%s任务失败: ", job.EmailAccountID, job.Kind), err)
+		return err
+	}
+	return nil
+}
+
+// GetMailboxJob 获取账户的邮箱导入导出任务
+func GetMailboxJob(accountID, id uint) (*models.MailboxJob, error) {
+	var job models.MailboxJob
+	if err := global.PsqlDB.Where("id = ? AND email_account_id = ?", id, accountID).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetMailboxJobs 分页获取账户的邮箱导入导出任务，最新的在前
+func GetMailboxJobs(accountID uint, page, pageSize int) ([]models.MailboxJob, int64, error) {
+	var jobs []models.MailboxJob
+	var total int64
+	query := global.PsqlDB.Model(&models.MailboxJob{}).Where("email_account_id = ?", accountID)
+	if err := query.Count(&total).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 邮箱任务总数失败: ", accountID), err)
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []models.MailboxJob{}, 0, nil
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 邮箱任务列表失败: ", accountID), err)
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// GetPendingMailboxJobIDs 获取等待执行的任务
+func GetPendingMailboxJobIDs() ([]uint, error) {
+	var ids []uint
+	err := global.PsqlDB.Model(&models.MailboxJob{}).Where("status = ?", models.MailboxJobPending).
+		Order("id ASC").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ClaimMailboxJob 将等待中的任务标记为执行中，任务已被其他进程领取时返回 false
+func ClaimMailboxJob(id uint) (*models.MailboxJob, bool, error) {
+	result := global.PsqlDB.Model(&models.MailboxJob{}).
+		Where("id = ? AND status = ?", id, models.MailboxJobPending).
+		Update("status", models.MailboxJobRunning)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, false, result.Error
+	}
+	var job models.MailboxJob
+	if err := global.PsqlDB.First(&job, id).Error; err != nil {
+		return nil, false, err
+	}
+	return &job, true, nil
+}
+
+// UpdateMailboxJobProgress 更新任务进度
+func UpdateMailboxJobProgress(job *models.MailboxJob) error {
+	return global.PsqlDB.Model(&models.MailboxJob{}).Where("id = ?", job.ID).
+		Updates(map[string]interface{}{
+			"total":     job.Total,
+			"processed": job.Processed,
+			"succeeded": job.Succeeded,
+			"skipped":   job.Skipped,
+			"failed":    job.Failed,
+		}).Error
+}
+
+// FinishMailboxJob 结束任务并保存最终进度与结果
+func FinishMailboxJob(job *models.MailboxJob) error {
+	now := time.Now()
+	job.FinishedAt = &now
+	err := global.PsqlDB.Model(&models.MailboxJob{}).Where("id = ?", job.ID).
+		Updates(map[string]interface{}{
+			"status":      job.Status,
+			"total":       job.Total,
+			"processed":   job.Processed,
+			"succeeded":   job.Succeeded,
+			"skipped":     job.Skipped,
+			"failed":      job.Failed,
+			"error":       job.Error,
+			"result_key":  job.ResultKey,
+			"result_size": job.ResultSize,
+			"finished_at": now,
+		}).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("更新邮箱任务 [ID: %d] 状态失败: ", job.ID), err)
+	}
+	return err
+}
+
+// FailInterruptedMailboxJobs 服务重启时将仍在执行中的任务标记为失败
+func FailInterruptedMailboxJobs() error {
+	return global.PsqlDB.Model(&models.MailboxJob{}).Where("status = ?", models.MailboxJobRunning).
+		Updates(map[string]interface{}{
+			"status":      models.MailboxJobFailed,
+			"error":       "interrupted by server restart",
+			"finished_at": time.Now(),
+		}).Error
+}
+
+// CountEmailsInFolders 统计账户指定分类下的邮件数量
+func CountEmailsInFolders(accountID uint, folders []string) (int64, error) {
+	var total int64
+	err := global.PsqlDB.Table(fmt.Sprintf("user_%d_emails", accountID)).
+		Where("email_type IN ?", folders).Count(&total).Error
+	return total, err
+}
+
+// GetEmailsForExport 按 ID 顺序分批获取需要导出的邮件，不含正文
+func GetEmailsForExport(accountID uint, folder string, afterID uint, limit int) ([]models.EmailDetails, error) {
+	var emails []models.EmailDetails
+	err := global.PsqlDB.Table(fmt.Sprintf("user_%d_emails", accountID)).
+		Select("id", "s3_key", "email_type", "sender_email", "is_read", "is_flagged", "received_at").
+		Where("email_type = ? AND id > ?", folder, afterID).
+		Order("id ASC").Limit(limit).Find(&emails).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 待导出邮件失败: ", accountID), err)
+	}
+	return emails, err
+}
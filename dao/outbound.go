@@ -0,0 +1,45 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AddOutboundMessage 将邮件加入发信队列
+func AddOutboundMessage(m *models.OutboundMessage) error {
+	if err := global.PsqlDB.Create(m).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("邮件 [ %s ] 加入发信队列失败: ", m.S3Key), err)
+		return err
+	}
+	return nil
+}
+
+// ClaimDueOutboundMessages 领取到期需要重试的邮件，并将下次重试时间推迟 lease，避免多个实例重复发送
+func ClaimDueOutboundMessages(limit int, lease time.Duration) ([]models.OutboundMessage, error) {
+	var list []models.OutboundMessage
+	table := global.Config.DatabseTableNames.OutboundQueue
+	now := time.Now()
+	err := global.PsqlDB.Raw(strings.ReplaceAll(`UPDATE {t} SET next_attempt_at = ? WHERE id IN (
+		SELECT id FROM {t} WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at ASC LIMIT ? FOR UPDATE SKIP LOCKED) RETURNING *`, "{t}", table),
+		now.Add(lease), models.OutboundQueued, now, limit).Scan(&list).Error
+	return list, err
+}
+
+// SaveOutboundResult 保存一次重试的结果
+func SaveOutboundResult(m *models.OutboundMessage) error {
+	return global.PsqlDB.Model(&models.OutboundMessage{}).Where("id = ?", m.ID).Updates(map[string]interface{}{
+		"status":          m.Status,
+		"to":              m.To,
+		"cc":              m.Cc,
+		"bcc":             m.Bcc,
+		"attempts":        m.Attempts,
+		"delay_notices":   m.DelayNotices,
+		"last_error":      m.LastError,
+		"ses_message_id":  m.SESMessageID,
+		"next_attempt_at": m.NextAttemptAt,
+	}).Error
+}
@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSenderPreferences 获取账户的全部发件人偏好设置
+func GetSenderPreferences(accountID uint) ([]models.SenderPreference, error) {
+	var prefs []models.SenderPreference
+	err := global.PsqlDB.Where("email_account_id = ?", accountID).Order("sender").Find(&prefs).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("查询账户 [ID: %d] 发件人偏好设置失败: ", accountID), err)
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// SaveSenderPreference 新增或更新发件人偏好设置
+func SaveSenderPreference(p *models.SenderPreference) error {
+	return global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email_account_id"}, {Name: "sender"}},
+		DoUpdates: clause.AssignmentColumns([]string{"always_load_images", "updated_at"}),
+	}).Create(p).Error
+}
+
+// DeleteSenderPreference 删除发件人偏好设置
+func DeleteSenderPreference(accountID, id uint) error {
+	result := global.PsqlDB.Where("id = ? AND email_account_id = ?", id, accountID).Delete(&models.SenderPreference{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// IsSenderImagesAllowed 判断是否总是加载该发件人的远程图片，地址设置优先于域名设置
+func IsSenderImagesAllowed(accountID uint, senderEmail string) (bool, error) {
+	senderEmail = strings.ToLower(strings.TrimSpace(senderEmail))
+	at := strings.LastIndex(senderEmail, "@")
+	if at < 0 {
+		return false, nil
+	}
+	var prefs []models.SenderPreference
+	err := global.PsqlDB.Where("email_account_id = ? AND sender IN ?", accountID, []string{senderEmail, senderEmail[at:]}).
+		Find(&prefs).Error
+	if err != nil {
+		return false, err
+	}
+	allowed := false
+	for _, p := range prefs {
+		if p.Sender == senderEmail {
+			return p.AlwaysLoadImages, nil
+		}
+		allowed = p.AlwaysLoadImages
+	}
+	return allowed, nil
+}
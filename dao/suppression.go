@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// AddSuppression 将地址加入禁止发送列表，已存在时更新原因与时间
+func AddSuppression(s *models.Suppression) error {
+	s.Address = strings.ToLower(strings.TrimSpace(s.Address))
+	err := global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "domain_id"}, {Name: "address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "detail", "source", "updated_at"}),
+	}).Create(s).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("将 [ %s ] 加入禁止发送列表失败: ", s.Address), err)
+	}
+	return err
+}
+
+// GetSuppressedAddresses 返回收件人中位于全局或该域名禁止发送列表中的记录
+func GetSuppressedAddresses(domainID uint, addresses []string) ([]models.Suppression, error) {
+	var list []models.Suppression
+	if len(addresses) == 0 {
+		return list, nil
+	}
+	lower := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		lower = append(lower, strings.ToLower(strings.TrimSpace(a)))
+	}
+	err := global.PsqlDB.Where("domain_id IN ? AND address IN ?", []uint{0, domainID}, lower).Order("address ASC").Find(&list).Error
+	if err != nil {
+		global.Log.Error("查询禁止发送列表失败: ", err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetSuppressions 分页获取禁止发送列表，domainID 为 0 时为全局列表，address 不为空时按地址模糊查询
+func GetSuppressions(domainID uint, address string, page, pageSize int) ([]models.Suppression, int64, error) {
+	var list []models.Suppression
+	var total int64
+	query := global.PsqlDB.Model(&models.Suppression{}).Where("domain_id = ?", domainID)
+	if address != "" {
+		query = query.Where("address LIKE ? ESCAPE '!'", containsPattern(strings.ToLower(address)))
+	}
+	if err := query.Count(&total).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取域名 [ %d ] 禁止发送列表总数失败: ", domainID), err)
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []models.Suppression{}, 0, nil
+	}
+	err := query.Order("updated_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取域名 [ %d ] 禁止发送列表失败: ", domainID), err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// DeleteSuppressions 从禁止发送列表中移除记录，返回移除的数量
+func DeleteSuppressions(domainID uint, ids []uint) (int64, error) {
+	result := global.PsqlDB.Where("domain_id = ? AND id IN ?", domainID, ids).Delete(&models.Suppression{})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("移除域名 [ %d ] 禁止发送列表记录失败: ", domainID), result.Error)
+	}
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,131 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetTwoFactor 获取账户的两步验证设置，没有设置时返回 nil
+func GetTwoFactor(accountID uint) (*models.TwoFactor, error) {
+	var t models.TwoFactor
+	result := global.PsqlDB.Where("email_account_id = ?", accountID).Limit(1).Find(&t)
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 两步验证设置失败: ", accountID), result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+// SaveTwoFactorSecret 保存尚未确认的 TOTP 密钥，已启用的设置不会被覆盖，返回是否保存
+func SaveTwoFactorSecret(accountID uint, secret string) (bool, error) {
+	result := global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email_account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "last_used_step", "updated_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: global.Config.DatabseTableNames.TwoFactors + ".enabled = false"}}},
+	}).Create(&models.TwoFactor{EmailAccountID: accountID, Secret: secret})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("保存账户 [ID: %d] 两步验证密钥失败: ", accountID), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// EnableTwoFactor 启用两步验证并生成新的恢复码
+func EnableTwoFactor(accountID uint, step int64, codeHashes []string) error {
+	err := global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TwoFactor{}).Where("email_account_id = ? AND enabled = false", accountID).
+			Updates(map[string]interface{}{"enabled": true, "enabled_at": time.Now(), "last_used_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, accountID, codeHashes)
+	})
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("启用账户 [ID: %d] 两步验证失败: ", accountID), err)
+	}
+	return err
+}
+
+// UseTwoFactorStep 记录已使用的时间步，时间步不晚于上次使用的时间步时返回 false(验证码已被使用)
+func UseTwoFactorStep(accountID uint, step int64) (bool, error) {
+	result := global.PsqlDB.Model(&models.TwoFactor{}).Where("email_account_id = ? AND last_used_step < ?", accountID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("更新账户 [ID: %d] 两步验证时间步失败: ", accountID), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UseRecoveryCode 使用一个恢复码，恢复码不存在或已使用时返回 false
+func UseRecoveryCode(accountID uint, codeHash string) (bool, error) {
+	result := global.PsqlDB.Model(&models.TwoFactorRecoveryCode{}).
+		Where("email_account_id = ? AND code_hash = ? AND used_at IS NULL", accountID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("使用账户 [ID: %d] 恢复码失败: ", accountID), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodes 获取账户未使用的恢复码数量
+func CountUnusedRecoveryCodes(accountID uint) (int64, error) {
+	var count int64
+	err := global.PsqlDB.Model(&models.TwoFactorRecoveryCode{}).Where("email_account_id = ? AND used_at IS NULL", accountID).Count(&count).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] 恢复码数量失败: ", accountID), err)
+	}
+	return count, err
+}
+
+// ReplaceRecoveryCodes 作废原有恢复码并保存新的恢复码
+func ReplaceRecoveryCodes(accountID uint, codeHashes []string) error {
+	err := global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, accountID, codeHashes)
+	})
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("重新生成账户 [ID: %d] 恢复码失败: ", accountID), err)
+	}
+	return err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, accountID uint, codeHashes []string) error {
+	if err := tx.Where("email_account_id = ?", accountID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.TwoFactorRecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, models.TwoFactorRecoveryCode{EmailAccountID: accountID, CodeHash: h})
+	}
+	return tx.Create(&codes).Error
+}
+
+// DeleteTwoFactor 关闭两步验证并删除恢复码，返回是否存在设置
+func DeleteTwoFactor(accountID uint) (bool, error) {
+	var deleted int64
+	err := global.PsqlDB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("email_account_id = ?", accountID).Delete(&models.TwoFactor{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return tx.Where("email_account_id = ?", accountID).Delete(&models.TwoFactorRecoveryCode{}).Error
+	})
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("关闭账户 [ID: %d] 两步验证失败: ", accountID), err)
+		return false, err
+	}
+	return deleted > 0, nil
+}
@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AddUploadSession 新增分片上传会话
+func AddUploadSession(s *models.UploadSession) error {
+	if err := global.PsqlDB.Create(s).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("新增账户 [ID: %d] 上传会话失败: ", s.EmailAccountID), err)
+		return err
+	}
+	return nil
+}
+
+// GetUploadSession 获取账户的上传会话
+func GetUploadSession(accountID uint, id string) (*models.UploadSession, error) {
+	var s models.UploadSession
+	err := global.PsqlDB.Where("id = ? AND email_account_id = ?", id, accountID).First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// AdvanceUploadSession 记录新接收的分片，仅当已接收字节数仍为 from 时更新，避免并发写入同一偏移量
+func AdvanceUploadSession(id string, from, to int64, parts datatypes.JSON, hashState []byte) (bool, error) {
+	result := global.PsqlDB.Model(&models.UploadSession{}).
+		Where("id = ? AND received_bytes = ? AND status = ?", id, from, models.UploadStatusUploading).
+		Updates(map[string]interface{}{
+			"received_bytes": to,
+			"parts":          parts,
+			"hash_state":     hashState,
+		})
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("更新上传会话 [ %s ] 失败: ", id), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FinishUploadSession 结束上传会话
+func FinishUploadSession(id, status, attachmentCode string) error {
+	return global.PsqlDB.Model(&models.UploadSession{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "attachment_code": attachmentCode}).Error
+}
+
+// GetExpiredUploadSessions 获取已过期但仍未结束的上传会话
+func GetExpiredUploadSessions(now time.Time, limit int) ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	err := global.PsqlDB.Where("status = ? AND expires_at < ?", models.UploadStatusUploading, now).
+		Order("expires_at ASC").Limit(limit).Find(&sessions).Error
+	return sessions, err
+}
@@ -1,7 +1,73 @@
 package dao
 
-import "fmt"
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"strings"
+)
+
+// likeEscaper 以 ! 转义 LIKE 中的通配符，查询中需加上 ESCAPE '!'，不依赖数据库对反斜杠的处理
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// containsPattern 生成匹配包含 keyword 的 LIKE 模式
+func containsPattern(keyword string) string {
+	return "%" + likeEscaper.Replace(keyword) + "%"
+}
+
+// suffixPattern 生成匹配以 suffix 结尾的 LIKE 模式
+func suffixPattern(suffix string) string {
+	return "%" + likeEscaper.Replace(suffix)
+}
 
 func getEmailTableName(accountID uint) string {
 	return fmt.Sprintf("user_%d_emails", accountID)
 }
+
+// 账户邮件表后续新增的字段，新账户的建表语句中已包含
+var userEmailTableColumns = []string{
+	"is_flagged BOOLEAN DEFAULT FALSE NOT NULL",
+}
+
+// baseTableColumns 由部署脚本创建的基础表后续新增的字段，README 中的建表语句已包含
+func baseTableColumns() map[string][]string {
+	return map[string][]string{
+		global.Config.DatabseTableNames.Attachments: {
+			"scan_status VARCHAR(16) DEFAULT 'unscanned' NOT NULL",
+			"scan_signature VARCHAR(255)",
+		},
+		global.Config.DatabseTableNames.EmailAccounts: {
+			"app_passwords_only BOOLEAN DEFAULT FALSE NOT NULL",
+		},
+	}
+}
+
+// MigrateBaseTables 为已存在的基础表补充新增字段
+func MigrateBaseTables() error {
+	for table, columns := range baseTableColumns() {
+		for _, column := range columns {
+			sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", table, column)
+			if err := global.PsqlDB.Exec(sql).Error; err != nil {
+				return fmt.Errorf("迁移数据表 [ %s ] 失败: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MigrateUserEmailTables 为已存在的账户邮件表补充新增字段
+func MigrateUserEmailTables() error {
+	var ids []uint
+	if err := global.PsqlDB.Model(&models.EmailAccount{}).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		for _, column := range userEmailTableColumns {
+			sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", getEmailTableName(id), column)
+			if err := global.PsqlDB.Exec(sql).Error; err != nil {
+				return fmt.Errorf("迁移邮件表 [ %s ] 失败: %w", getEmailTableName(id), err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package dao
+
+import "testing"
+
+func TestContainsPattern(t *testing.T) {
+	tests := map[string]string{
+		"alice":      "%alice%",
+		"100%":       "%100!%%",
+		"first_last": "%first!_last%",
+		"wow!":       "%wow!!%",
+		`back\slash`: `%back\slash%`,
+		"":           "%%",
+		"%_!":        "%!%!_!!%",
+	}
+	for in, want := range tests {
+		if got := containsPattern(in); got != want {
+			t.Errorf("containsPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSuffixPattern(t *testing.T) {
+	tests := map[string]string{
+		"@example.com":   "%@example.com",
+		"@my_domain.com": "%@my!_domain.com",
+		"@%":             "%@!%",
+	}
+	for in, want := range tests {
+		if got := suffixPattern(in); got != want {
+			t.Errorf("suffixPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetVacationResponder 获取账户的自动回复设置，不存在时返回 nil, nil
+func GetVacationResponder(accountID uint) (*models.VacationResponder, error) {
+	var v models.VacationResponder
+	err := global.PsqlDB.Where("email_account_id = ?", accountID).First(&v).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		global.Log.Error(fmt.Sprintf("查询账户 [ID: %d] 自动回复设置失败: ", accountID), err)
+		return nil, err
+	}
+	return &v, nil
+}
+
+// SaveVacationResponder 新增或更新账户的自动回复设置
+func SaveVacationResponder(v *models.VacationResponder) error {
+	err := global.PsqlDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email_account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "start_at", "end_at", "subject", "body_text", "body_html", "resend_interval_days", "updated_at"}),
+	}).Create(v).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("保存账户 [ID: %d] 自动回复设置失败: ", v.EmailAccountID), err)
+		return err
+	}
+	return nil
+}
+
+// ClaimVacationReply 原子地占用一次对指定发件人的回复机会
+// 在间隔期内已回复过则返回 false，否则记录本次回复时间 now 并返回 true
+func ClaimVacationReply(accountID uint, senderEmail string, now time.Time, interval time.Duration) (bool, error) {
+	result := global.PsqlDB.Exec(fmt.Sprintf(`
+		INSERT INTO %s (email_account_id, sender_email, last_replied_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (email_account_id, sender_email)
+		DO UPDATE SET last_replied_at = EXCLUDED.last_replied_at
+		WHERE %s.last_replied_at < ?`,
+		models.VacationReplyLog{}.TableName(), models.VacationReplyLog{}.TableName()),
+		accountID, senderEmail, now, now.Add(-interval))
+	if result.Error != nil {
+		global.Log.Error(fmt.Sprintf("记录账户 [ID: %d] 自动回复 [%s] 失败: ", accountID, senderEmail), result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseVacationReply 发送失败时释放 ClaimVacationReply 占用的回复机会，只删除本次写入的记录
+func ReleaseVacationReply(accountID uint, senderEmail string, claimedAt time.Time) error {
+	err := global.PsqlDB.Where("email_account_id = ? AND sender_email = ? AND last_replied_at = ?", accountID, senderEmail, claimedAt).
+		Delete(&models.VacationReplyLog{}).Error
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("释放账户 [ID: %d] 自动回复 [%s] 记录失败: ", accountID, senderEmail), err)
+	}
+	return err
+}
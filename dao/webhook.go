@@ -0,0 +1,168 @@
+package dao
+
+import (
+	"email/global"
+	"email/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AddWebhook 新增 Webhook 订阅
+func AddWebhook(w *models.Webhook) error {
+	if err := global.PsqlDB.Create(w).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("新增 Webhook [ %s ] 失败: ", w.URL), err)
+		return err
+	}
+	return nil
+}
+
+// GetWebhook 获取 Webhook 订阅
+func GetWebhook(id uint) (*models.Webhook, error) {
+	var w models.Webhook
+	if err := global.PsqlDB.First(&w, id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// GetWebhooks 获取账户自己的订阅，domainID 不为 0 时同时返回该域名的订阅
+func GetWebhooks(accountID, domainID uint) ([]models.Webhook, error) {
+	list := []models.Webhook{}
+	query := global.PsqlDB.Where("scope = ? AND email_account_id = ?", models.WebhookScopeAccount, accountID)
+	if domainID != 0 {
+		query = query.Or("scope = ? AND domain_id = ?", models.WebhookScopeDomain, domainID)
+	}
+	if err := query.Order("id ASC").Find(&list).Error; err != nil {
+		global.Log.Error(fmt.Sprintf("获取账户 [ID: %d] Webhook 列表失败: ", accountID), err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// SaveWebhook 保存 Webhook 订阅的修改
+func SaveWebhook(w *models.Webhook) error {
+	return global.PsqlDB.Model(&models.Webhook{}).Where("id = ?", w.ID).Updates(map[string]interface{}{
+		"url":         w.URL,
+		"secret":      w.Secret,
+		"events":      w.Events,
+		"description": w.Description,
+		"enabled":     w.Enabled,
+	}).Error
+}
+
+// DeleteWebhook 删除 Webhook 订阅及其推送记录
+func DeleteWebhook(id uint) error {
+	if err := global.PsqlDB.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return global.PsqlDB.Delete(&models.Webhook{}, id).Error
+}
+
+// PublishWebhookEvent 为订阅了该事件的账户与域名 Webhook 生成推送记录，由推送任务异步发送
+// 失败时只记录日志，不影响邮件操作
+func PublishWebhookEvent(accountID, domainID uint, account, event string, data interface{}) {
+	var hooks []models.Webhook
+	err := global.PsqlDB.Where("enabled = ? AND events @> ?::jsonb", true, fmt.Sprintf("[%q]", event)).
+		Where(global.PsqlDB.Where("scope = ? AND email_account_id = ?", models.WebhookScopeAccount, accountID).
+			Or("scope = ? AND domain_id = ?", models.WebhookScopeDomain, domainID)).
+		Find(&hooks).Error
+	if err != nil {
+		global.Log.Errorf("查询账户 [ %s ] 的 Webhook 订阅失败: %v", account, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	eventID := uuid.New().String()
+	payload, err := json.Marshal(&models.WebhookPayload{
+		ID:        eventID,
+		Event:     event,
+		Account:   account,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		global.Log.Errorf("生成 Webhook 事件 [ %s ] 失败: %v", event, err)
+		return
+	}
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, 0, len(hooks))
+	for _, h := range hooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     h.ID,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+	if err := global.PsqlDB.Create(&deliveries).Error; err != nil {
+		global.Log.Errorf("保存 Webhook 事件 [ %s ] 推送记录失败: %v", event, err)
+	}
+}
+
+// PublishEmailWebhook 发布邮件相关的 Webhook 事件
+func PublishEmailWebhook(event string, r *models.EmailDetails, d *models.EmailAccount) {
+	PublishWebhookEvent(d.ID, d.DomainID, d.EmailAddress, event, models.NewWebhookMessageData(r))
+}
+
+// ClaimDueWebhookDeliveries 领取到期的推送记录，并将下次推送时间推迟 lease，避免多个实例重复推送
+// 进程在推送过程中退出时，租约到期后会被重新领取
+func ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var list []models.WebhookDelivery
+	table := global.Config.DatabseTableNames.WebhookDeliveries
+	now := time.Now()
+	err := global.PsqlDB.Raw(strings.ReplaceAll(`UPDATE {t} SET next_attempt_at = ? WHERE id IN (
+		SELECT id FROM {t} WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at ASC LIMIT ? FOR UPDATE SKIP LOCKED) RETURNING *`, "{t}", table),
+		now.Add(lease), models.WebhookDeliveryPending, now, limit).Scan(&list).Error
+	return list, err
+}
+
+// SaveWebhookDeliveryResult 保存一次推送的结果
+func SaveWebhookDeliveryResult(d *models.WebhookDelivery) error {
+	return global.PsqlDB.Model(&models.WebhookDelivery{}).Where("id = ?", d.ID).Updates(map[string]interface{}{
+		"status":          d.Status,
+		"attempts":        d.Attempts,
+		"response_code":   d.ResponseCode,
+		"response_body":   d.ResponseBody,
+		"error":           d.Error,
+		"duration_ms":     d.DurationMs,
+		"next_attempt_at": d.NextAttemptAt,
+		"delivered_at":    d.DeliveredAt,
+	}).Error
+}
+
+// GetWebhookDelivery 获取推送记录
+func GetWebhookDelivery(id uint) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	if err := global.PsqlDB.First(&d, id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetWebhookDeliveries 分页获取 Webhook 的推送记录，最新的在前
+func GetWebhookDeliveries(webhookID uint, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	var list []models.WebhookDelivery
+	var total int64
+	query := global.PsqlDB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []models.WebhookDelivery{}, 0, nil
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// AddWebhookDelivery 新增推送记录，用于手动重新推送
+func AddWebhookDelivery(d *models.WebhookDelivery) error {
+	return global.PsqlDB.Create(d).Error
+}
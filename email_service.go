@@ -17,6 +17,8 @@ func initEnv() {
 	Inits.InitLogger()
 	//初始化数据库链接
 	Inits.InitPsql()
+	//初始化功能数据表
+	Inits.InitTables()
 }
 
 func main() {
@@ -38,6 +40,12 @@ func main() {
 		go dovecot.DovecotStatusMonitorInit()
 		go service.SmtpServerInit()
 	}
+	go service.AttachmentReaperInit()
+	go service.MailboxJobInit()
+	go service.ImapMigrationInit()
+	go service.MailEventInit()
+	go service.WebhookInit()
+	go aws.OutboundQueueInit()
 	go router.InitRouter()
 	aws.ProcessSQSEmailMessages()
 
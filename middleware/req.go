@@ -4,7 +4,9 @@ import (
 	"email/controller/response"
 	"email/global"
 	"email/models"
+	"email/service"
 	"email/utils"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -186,3 +188,21 @@ func AIReplyMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// CardDAV/CalDAV 认证中间件，使用与 SMTP AUTH 相同的邮箱账户凭据 (HTTP Basic)
+func DavAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if ok {
+			account, err := service.AuthenticateMailAccount(username, password, c.ClientIP(), models.AuthFrontendDAV)
+			if err == nil {
+				c.Set("davAccount", account)
+				c.Next()
+				return
+			}
+			global.Log.Warnf("DAV 账户 [ %s ] 认证失败: %v", username, err)
+		}
+		c.Header("WWW-Authenticate", `Basic realm="Mail", charset="UTF-8"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
@@ -17,6 +17,8 @@ type EmailAccount struct {
 	Status       string    `gorm:"type:varchar(50);not null;default:'active'"`                                // 账号状态，默认 'active'
 	StorageUsed  int64     `gorm:"type:bigint;not null;default:0" json:"storage_used"`                        // 新增字段
 	CreatedAt    time.Time `gorm:"autoCreateTime;not null"`
+	// 为 true 时 SMTP/IMAP/CardDAV 只接受应用专用密码，登录密码只用于网页登录
+	AppPasswordsOnly bool `gorm:"not null;default:false"`
 }
 
 func (EmailAccount) TableName() string {
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// MaxAppPasswordsPerAccount 每个账户最多的应用专用密码数量，认证时需逐个比对
+const MaxAppPasswordsPerAccount = 20
+
+// AppPassword 应用专用密码，供 SMTP/IMAP/CardDAV 等客户端使用，只保存 bcrypt 哈希
+type AppPassword struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint       `gorm:"not null;index" json:"-"`
+	Name           string     `gorm:"type:varchar(64);not null" json:"name"` // 用户填写的名称，如 Thunderbird
+	PasswordHash   string     `gorm:"type:varchar(255);not null" json:"-"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP     string     `gorm:"type:varchar(64)" json:"last_used_ip,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AppPassword) TableName() string {
+	return global.Config.DatabseTableNames.AppPasswords
+}
+
+// AppPasswordCreated 新建的应用专用密码，明文只在创建时返回一次
+type AppPasswordCreated struct {
+	AppPassword
+	Password string `json:"password"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 附件访问授权来源
+const (
+	AttachmentGrantUpload  = "upload"  // 账户上传的附件
+	AttachmentGrantMessage = "message" // 账户邮件中引用的附件
+)
+
+// AttachmentGrant 账户对附件的访问授权，附件按哈希全局去重，因此需要单独记录哪些账户可以访问
+type AttachmentGrant struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AttachmentID   uint      `gorm:"uniqueIndex:idx_attachment_grant;not null" json:"attachment_id"`
+	EmailAccountID uint      `gorm:"uniqueIndex:idx_attachment_grant;index;not null" json:"email_account_id"`
+	Source         string    `gorm:"type:varchar(32);not null" json:"source"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AttachmentGrant) TableName() string {
+	return global.Config.DatabseTableNames.AttachmentGrants
+}
+
+// AttachmentShareLink 附件的公开分享链接，过期或撤销后失效
+type AttachmentShareLink struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Token          string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"token"`
+	AttachmentID   uint       `gorm:"index;not null" json:"attachment_id"`
+	EmailAccountID uint       `gorm:"index;not null" json:"email_account_id"` // 创建者
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	DownloadCount  int        `gorm:"not null;default:0" json:"download_count"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AttachmentShareLink) TableName() string {
+	return global.Config.DatabseTableNames.AttachmentShareLinks
+}
+
+// IsActive 分享链接是否仍然有效
+func (l *AttachmentShareLink) IsActive(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 认证失败的统计对象
+const (
+	AuthSubjectAccount = "account" // 按邮箱地址统计
+	AuthSubjectIP      = "ip"      // 按来源 IP 统计
+)
+
+// 认证入口，用于日志与通知
+const (
+	AuthFrontendHTTP = "http"
+	AuthFrontendSMTP = "smtp"
+	AuthFrontendDAV  = "dav"
+)
+
+// AuthLockout 账户或来源 IP 的认证失败次数与锁定状态，所有实例共用
+type AuthLockout struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	Kind          string     `gorm:"type:varchar(16);not null;uniqueIndex:idx_auth_lockout_subject" json:"kind"`
+	Subject       string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_auth_lockout_subject" json:"subject"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"` // 当前时间窗口内的连续失败次数
+	Lockouts      int        `gorm:"not null;default:0" json:"lockouts"` // 连续被锁定的次数，每次锁定时长加倍，认证成功后清零
+	LastFailureAt time.Time  `json:"last_failure_at"`                    // 最近一次失败时间
+	LastFailureIP string     `gorm:"type:varchar(64)" json:"last_failure_ip,omitempty"`
+	LockedUntil   *time.Time `gorm:"index" json:"locked_until,omitempty"` // 锁定到期时间
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (AuthLockout) TableName() string {
+	return global.Config.DatabseTableNames.AuthLockouts
+}
+
+// Locked 是否处于锁定中
+func (l *AuthLockout) Locked() bool {
+	return l.LockedUntil != nil && l.LockedUntil.After(time.Now())
+}
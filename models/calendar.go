@@ -0,0 +1,32 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 日历事件的来源
+const (
+	CalendarSourceDAV    = "dav"    // 通过 CalDAV 创建
+	CalendarSourceInvite = "invite" // 由收到的日历邀请创建，只能由同一组织者的邀请更新或取消
+)
+
+// CalendarEvent 表示 calendar_events 表的 GORM 模型，Data 保存完整的 iCalendar 文本
+type CalendarEvent struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint       `gorm:"uniqueIndex:idx_calendar_event_uid;not null" json:"email_account_id"`      // 关联的邮箱账号 ID
+	UID            string     `gorm:"type:varchar(255);uniqueIndex:idx_calendar_event_uid;not null" json:"uid"` // CalDAV 资源名，通常与事件 UID 相同
+	Summary        string     `gorm:"type:varchar(512)" json:"summary"`                                         // 事件标题
+	StartAt        *time.Time `gorm:"index" json:"start_at"`                                                    // 开始时间
+	EndAt          *time.Time `json:"end_at"`                                                                   // 结束时间
+	Recurring      bool       `gorm:"not null;default:false" json:"recurring"`                                  // 是否为重复事件
+	Data           string     `gorm:"type:text;not null" json:"data"`                                           // iCalendar 原文
+	Source         string     `gorm:"type:varchar(16);not null;default:'dav'" json:"source"`                    // 来源，创建后不再改变
+	Organizer      string     `gorm:"type:varchar(255)" json:"organizer"`                                       // 邀请的组织者，仅来源为 invite 时使用
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (CalendarEvent) TableName() string {
+	return global.Config.DatabseTableNames.CalendarEvents
+}
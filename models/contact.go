@@ -0,0 +1,37 @@
+package models
+
+import (
+	"email/global"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Contact 表示 contacts 表的 GORM 模型
+type Contact struct {
+	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint           `gorm:"uniqueIndex:idx_contact_uid;not null" json:"email_account_id"`      // 关联的邮箱账号 ID
+	UID            string         `gorm:"type:varchar(255);uniqueIndex:idx_contact_uid;not null" json:"uid"` // vCard UID
+	Name           string         `gorm:"type:varchar(255);not null" json:"name"`                            // 显示名称
+	Emails         datatypes.JSON `gorm:"type:jsonb;default:'[]';not null" json:"emails"`                    // 邮件地址列表，统一小写
+	Phone          string         `gorm:"type:varchar(64)" json:"phone"`                                     // 电话
+	Notes          string         `gorm:"type:text" json:"notes"`                                            // 备注
+	Groups         datatypes.JSON `gorm:"type:jsonb;default:'[]';not null" json:"groups"`                    // 分组列表
+	VCard          string         `gorm:"column:vcard;type:text" json:"-"`                                   // CardDAV 客户端上传的原始 vCard，接口修改后清空
+	IsRecent       bool           `gorm:"not null;default:false;index" json:"is_recent"`                     // 由发件自动收集，尚未被用户保存
+	UseCount       int            `gorm:"not null;default:0" json:"use_count"`                               // 作为收件人的次数
+	LastUsedAt     *time.Time     `json:"last_used_at"`                                                      // 最后一次作为收件人的时间
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Contact) TableName() string {
+	return global.Config.DatabseTableNames.Contacts
+}
+
+// ContactSuggestion 联系人自动补全结果
+type ContactSuggestion struct {
+	ContactID uint   `json:"contact_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 发出邮件对单个收件人的投递状态
+const (
+	DeliverySent       = "sent"       // 已提交至 SES，尚未收到通知
+	DeliveryDelayed    = "delayed"    // 收件服务器暂时拒收，SES 仍在重试
+	DeliveryDelivered  = "delivered"  // 收件服务器已接收
+	DeliveryBounced    = "bounced"    // 退信
+	DeliveryComplained = "complained" // 收件人投诉为垃圾邮件
+)
+
+// 投递状态的先后顺序，通知可能乱序到达，只允许更新为更靠后的状态
+var deliveryStatusRank = map[string]int{
+	DeliverySent:       0,
+	DeliveryDelayed:    1,
+	DeliveryDelivered:  2,
+	DeliveryBounced:    3,
+	DeliveryComplained: 3,
+}
+
+// DeliveryStatusesBefore 返回可以更新为 status 的原状态
+func DeliveryStatusesBefore(status string) []string {
+	var list []string
+	for s, rank := range deliveryStatusRank {
+		if rank < deliveryStatusRank[status] {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// SES 通知类型
+const (
+	SesNotificationReceived      = "Received"
+	SesNotificationBounce        = "Bounce"
+	SesNotificationComplaint     = "Complaint"
+	SesNotificationDelivery      = "Delivery"
+	SesNotificationDeliveryDelay = "DeliveryDelay"
+)
+
+// DeliveryStatus 发出邮件对单个收件人的投递状态，以 SES 消息 ID 与 SES 通知对应
+type DeliveryStatus struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	SESMessageID   string    `gorm:"column:ses_message_id;type:varchar(255);not null;uniqueIndex:idx_delivery_status_recipient" json:"-"`
+	EmailAccountID uint      `gorm:"not null;index:idx_delivery_status_email" json:"-"`
+	EmailID        uint      `gorm:"not null;index:idx_delivery_status_email" json:"-"` // 发件人邮件表中的邮件 ID
+	Recipient      string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_delivery_status_recipient" json:"recipient"`
+	Status         string    `gorm:"type:varchar(16);not null" json:"status"`
+	BounceType     string    `gorm:"type:varchar(32)" json:"bounce_type,omitempty"` // Permanent / Transient / Undetermined
+	Detail         string    `gorm:"type:text" json:"detail,omitempty"`             // 诊断信息、投诉类型或收件服务器的响应
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"-"`
+}
+
+func (DeliveryStatus) TableName() string {
+	return global.Config.DatabseTableNames.DeliveryStatuses
+}
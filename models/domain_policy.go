@@ -0,0 +1,38 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 未设置策略时，未被引用的附件过期后的保留时长
+const DefaultOrphanGraceHours = 72
+
+// DomainPolicy 域名级策略，由域名管理员设置
+type DomainPolicy struct {
+	ID                      uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DomainID                uint      `gorm:"uniqueIndex;not null" json:"domain_id"`
+	AttachmentReaperEnabled bool      `gorm:"not null;default:false" json:"attachment_reaper_enabled"` // 是否回收未被邮件引用的附件
+	OrphanGraceHours        int       `gorm:"not null;default:72" json:"orphan_grace_hours"`           // 附件短码过期后再保留的时长
+	RequireTwoFactor        bool      `gorm:"not null;default:false" json:"require_two_factor"`        // 是否要求域名下的账户网页登录时使用两步验证
+	UpdatedAt               time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt               time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (DomainPolicy) TableName() string {
+	return global.Config.DatabseTableNames.DomainPolicies
+}
+
+// AttachmentReaperReport 附件回收任务按域名统计的结果
+type AttachmentReaperReport struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RunID          string    `gorm:"type:varchar(36);index;not null" json:"run_id"`
+	DomainID       uint      `gorm:"index;not null" json:"domain_id"`
+	DeletedCount   int       `gorm:"not null" json:"deleted_count"`
+	ReclaimedBytes int64     `gorm:"not null" json:"reclaimed_bytes"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (AttachmentReaperReport) TableName() string {
+	return global.Config.DatabseTableNames.ReaperReports
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// RFC 3464 投递状态通知中收件人的动作
+const (
+	DSNActionFailed  = "failed"
+	DSNActionDelayed = "delayed"
+)
+
+// DeliveryReport 发给发件人的投递状态通知(RFC 3464)
+type DeliveryReport struct {
+	ReportingMTA      string // 生成通知的域名
+	To                string // 原邮件的发件人
+	Action            string
+	ArrivalDate       time.Time
+	WillRetryUntil    time.Time // 仅 delayed 时有效
+	OriginalMessageID string    // 通知以 In-Reply-To 与原邮件关联为同一会话
+	OriginalSubject   string
+	OriginalHeaders   string // 原邮件的邮件头，作为 text/rfc822-headers 附在通知中
+	Recipients        []DeliveryReportRecipient
+}
+
+// DeliveryReportRecipient 投递状态通知中的一个收件人
+type DeliveryReportRecipient struct {
+	Address        string
+	Status         string // 如 5.1.1、4.0.0
+	DiagnosticCode string // 如 "smtp; 550 5.1.1 user unknown"
+	LastAttempt    time.Time
+}
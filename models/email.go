@@ -22,12 +22,30 @@ type Attachment struct {
 	DownloadURL    string    `gorm:"type:varchar(1024)" json:"download_url"`
 	S3StoragePath  string    `gorm:"type:varchar(512);default:'N/A'" json:"s3_storage_path"`
 	ExpireTime     time.Time `json:"expire_time"`
+	ScanStatus     string    `gorm:"type:varchar(16);not null;default:'unscanned'" json:"scan_status"`
+	ScanSignature  string    `gorm:"type:varchar(255)" json:"scan_signature,omitempty"` // 检测到的病毒名称
+	ContentID      string    `gorm:"-" json:"content_id,omitempty"`                     // 内嵌图片的 Content-ID，只保存在邮件的 attachment_info 中
+	Inline         bool      `gorm:"-" json:"inline,omitempty"`                         // 是否为正文内嵌图片
 }
 
 func (Attachment) TableName() string {
 	return global.Config.DatabseTableNames.Attachments
 }
 
+// 附件扫描状态
+const (
+	AttachmentScanUnscanned = "unscanned" // 未配置扫描器或扫描前已存在的附件
+	AttachmentScanClean     = "clean"
+	AttachmentScanInfected  = "infected" // 已隔离
+	AttachmentScanFailed    = "failed"   // 扫描器出错
+)
+
+// IsBlocked 附件是否禁止下载与发送，扫描失败的附件在 fail_closed 时同样禁止
+func (a *Attachment) IsBlocked() bool {
+	return a.ScanStatus == AttachmentScanInfected ||
+		(a.ScanStatus == AttachmentScanFailed && global.Config.Scanner.FailClosed)
+}
+
 // EmailDetails 表示用户 emails 表的 GORM 模型
 type EmailDetails struct {
 	ID             uint           `gorm:"primaryKey;" json:"id"`
@@ -49,6 +67,7 @@ type EmailDetails struct {
 	BodyText       string         `gorm:"type:text" json:"body_text,omitempty"`
 	BodyHTML       string         `gorm:"type:text" json:"body_html,omitempty"`
 	IsRead         bool           `gorm:"not null;default:false" json:"is_read"`
+	IsFlagged      bool           `gorm:"not null;default:false" json:"is_flagged"`
 	EmailType      string         `gorm:"type:varchar(255);not null" json:"email_type,omitempty"` // 移除错误的 default:false
 	ReceivedAt     time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"received_at"`  // 修改时间默认值
 	AttachmentInfo datatypes.JSON `gorm:"type:jsonb;default:'[]';not null" json:"attachment_info,omitempty"`
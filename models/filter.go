@@ -0,0 +1,42 @@
+package models
+
+import (
+	"email/global"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// FilterRule 表示 filter_rules 表的 GORM 模型，按 Priority 升序依次匹配
+type FilterRule struct {
+	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint           `gorm:"index;not null" json:"email_account_id"`                    // 关联的邮箱账号 ID
+	Name           string         `gorm:"type:varchar(255);not null" json:"name"`                    // 规则名称
+	Priority       int            `gorm:"not null;default:0" json:"priority"`                        // 优先级，越小越先执行
+	Enabled        bool           `gorm:"not null" json:"enabled"`                                   // 是否启用
+	MatchType      string         `gorm:"type:varchar(16);not null;default:'all'" json:"match_type"` // all: 全部条件满足 any: 任一条件满足
+	Conditions     datatypes.JSON `gorm:"type:jsonb;default:'[]';not null" json:"conditions"`        // []FilterCondition
+	Actions        datatypes.JSON `gorm:"type:jsonb;default:'[]';not null" json:"actions"`           // []FilterAction
+	Stop           bool           `gorm:"not null;default:false" json:"stop"`                        // 匹配后不再执行后续规则
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (FilterRule) TableName() string {
+	return global.Config.DatabseTableNames.FilterRules
+}
+
+// FilterCondition 过滤条件
+type FilterCondition struct {
+	Field    string `json:"field"`            // header/from/to/cc/subject/size/has_attachment
+	Header   string `json:"header,omitempty"` // Field 为 header 时的头部名称
+	Operator string `json:"operator"`         // is/contains/matches/regex/exists/over/under
+	Value    string `json:"value,omitempty"`
+	Negate   bool   `json:"negate,omitempty"` // 条件取反
+}
+
+// FilterAction 过滤动作
+type FilterAction struct {
+	Type  string `json:"type"`            // move/mark_read/flag/forward/discard/reject
+	Value string `json:"value,omitempty"` // move 的目标分类、forward 的地址、reject 的原因
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// ForwardingRule 表示 forwarding_rules 表的 GORM 模型，每个账户最多一条
+type ForwardingRule struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint      `gorm:"uniqueIndex;not null" json:"email_account_id"` // 关联的邮箱账号 ID，唯一
+	Enabled        bool      `gorm:"not null;default:false" json:"enabled"`        // 是否启用自动转发
+	ForwardTo      string    `gorm:"type:varchar(255);not null" json:"forward_to"` // 转发目标地址
+	KeepCopy       bool      `gorm:"not null;default:false" json:"keep_copy"`      // 是否在本地保留副本
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (ForwardingRule) TableName() string {
+	return global.Config.DatabseTableNames.ForwardingRules
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"email/global"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// IMAP 迁移认证方式
+const (
+	ImapAuthPassword = "password"
+	ImapAuthXOAuth2  = "xoauth2"
+)
+
+// IMAP 迁移任务与文件夹状态，文件夹另有 skipped 状态
+const (
+	ImapMigrationPending   = "pending"
+	ImapMigrationRunning   = "running"
+	ImapMigrationCompleted = "completed"
+	ImapMigrationFailed    = "failed"
+	ImapMigrationCancelled = "cancelled"
+	ImapFolderSkipped      = "skipped"
+)
+
+// ImapMigration 从外部 IMAP 服务器迁移邮件的任务，中断后从各文件夹记录的 UID 继续
+type ImapMigration struct {
+	ID             uint                  `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint                  `gorm:"index;not null" json:"-"`
+	Host           string                `gorm:"type:varchar(255);not null" json:"host"`
+	Port           int                   `gorm:"not null" json:"port"`
+	StartTLS       bool                  `gorm:"not null" json:"starttls"`
+	Username       string                `gorm:"type:varchar(255);not null" json:"username"`
+	AuthType       string                `gorm:"type:varchar(16);not null" json:"auth_type"`
+	Secret         string                `gorm:"type:text" json:"-"`                     // 加密后的密码或 OAuth token，任务结束后清空
+	FolderMap      datatypes.JSON        `gorm:"type:jsonb" json:"folder_map,omitempty"` // 用户指定的文件夹对应关系 map[string]string
+	Status         string                `gorm:"type:varchar(16);not null;index" json:"status"`
+	Error          string                `gorm:"type:varchar(1024)" json:"error,omitempty"`
+	Folders        []ImapMigrationFolder `gorm:"foreignKey:MigrationID" json:"folders,omitempty"`
+	FinishedAt     *time.Time            `json:"finished_at,omitempty"`
+	UpdatedAt      time.Time             `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time             `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (ImapMigration) TableName() string {
+	return global.Config.DatabseTableNames.ImapMigrations
+}
+
+// ImapMigrationFolder 迁移任务中一个远程文件夹的进度
+type ImapMigrationFolder struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	MigrationID  uint   `gorm:"index;not null" json:"-"`
+	RemoteName   string `gorm:"type:varchar(512);not null" json:"remote_name"`
+	TargetFolder string `gorm:"type:varchar(32);not null" json:"target_folder"`
+	UIDValidity  uint32 `gorm:"not null;default:0" json:"-"`
+	LastUID      uint32 `gorm:"not null;default:0" json:"last_uid"` // 已处理到的 UID，恢复时从其后继续
+	Total        int    `gorm:"not null;default:0" json:"total"`
+	Processed    int    `gorm:"not null;default:0" json:"processed"`
+	Imported     int    `gorm:"not null;default:0" json:"imported"`
+	Skipped      int    `gorm:"not null;default:0" json:"skipped"` // Message-ID 或哈希已存在
+	Failed       int    `gorm:"not null;default:0" json:"failed"`
+	Status       string `gorm:"type:varchar(16);not null" json:"status"`
+	Error        string `gorm:"type:varchar(1024)" json:"error,omitempty"`
+}
+
+func (ImapMigrationFolder) TableName() string {
+	return global.Config.DatabseTableNames.ImapMigrationFolders
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"email/global"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 收信处理状态
+const (
+	InboundPending   = "pending"
+	InboundCompleted = "completed"
+	InboundDead      = "dead"
+)
+
+// 收信处理阶段，邮件与收件人各自记录已完成的阶段，重试时从下一阶段继续
+const (
+	IngestStageReceived   = "received"   // 已记录，尚未读取原文
+	IngestStageNormalized = "normalized" // 已读取原文、补全 Message-ID 并生成各收件人的投递记录
+	IngestStageRouting    = "routing"    // 已确认账户并记录过滤规则结果，即将执行转发与过滤动作
+	IngestStageRouted     = "routed"     // 已执行转发与过滤动作
+	IngestStageAppended   = "appended"   // 已保存至邮件服务器
+	IngestStageStored     = "stored"     // 已写入数据库
+	IngestStageDone       = "done"       // 已处理日历邀请与自动回复
+	IngestStageSkipped    = "skipped"    // 无需投递，原因见 Outcome
+)
+
+// 阶段失败时出错的步骤，用于死信记录
+const (
+	IngestStepFetch     = "fetch"
+	IngestStepNormalize = "normalize"
+	IngestStepRoute     = "route"
+	IngestStepAppend    = "imap_append"
+	IngestStepStore     = "db_insert"
+	IngestStepPost      = "post"
+)
+
+// 死信处理状态
+const (
+	DeadLetterOpen        = "open"
+	DeadLetterReprocessed = "reprocessed"
+	DeadLetterDiscarded   = "discarded"
+)
+
+// InboundMessage 一封收到的邮件的处理状态，以 S3Key 保证同一封邮件只处理一次
+type InboundMessage struct {
+	ID          uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	S3Key       string            `gorm:"type:varchar(512);not null;uniqueIndex" json:"s3_key"`
+	MessageID   string            `gorm:"type:varchar(512)" json:"message_id"` // 原邮件缺少时生成，重试时沿用
+	EmailHash   string            `gorm:"type:varchar(64)" json:"email_hash"`
+	Recipients  datatypes.JSON    `gorm:"type:jsonb" json:"recipients"`
+	Domains     datatypes.JSON    `gorm:"type:jsonb" json:"domains"` // 收件人所属域名，供域名管理员查看死信
+	Status      string            `gorm:"type:varchar(16);not null;index" json:"status"`
+	Stage       string            `gorm:"type:varchar(16);not null" json:"stage"`
+	Attempts    int               `gorm:"not null;default:0" json:"attempts"`
+	LastStep    string            `gorm:"type:varchar(16)" json:"last_step,omitempty"`
+	LastReason  string            `gorm:"type:varchar(64)" json:"last_reason,omitempty"`
+	LastError   string            `gorm:"type:text" json:"last_error,omitempty"`
+	Deliveries  []InboundDelivery `gorm:"foreignKey:InboundMessageID" json:"deliveries,omitempty"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	UpdatedAt   time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt   time.Time         `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (InboundMessage) TableName() string {
+	return global.Config.DatabseTableNames.InboundMessages
+}
+
+// InboundDelivery 邮件对单个收件人的投递进度，路由阶段的结果保存下来，重试时不再重复执行转发与过滤
+type InboundDelivery struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	InboundMessageID uint      `gorm:"not null;uniqueIndex:idx_inbound_delivery_recipient" json:"-"`
+	Recipient        string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_inbound_delivery_recipient" json:"recipient"`
+	EmailAccountID   uint      `gorm:"not null;default:0" json:"-"`
+	Stage            string    `gorm:"type:varchar(16);not null" json:"stage"`
+	Outcome          string    `gorm:"type:varchar(32)" json:"outcome,omitempty"` // delivered / no_account / duplicate / forwarded / filtered
+	EmailType        string    `gorm:"type:varchar(32)" json:"email_type,omitempty"`
+	MarkRead         bool      `gorm:"not null;default:false" json:"mark_read"`
+	Flagged          bool      `gorm:"not null;default:false" json:"flagged"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (InboundDelivery) TableName() string {
+	return global.Config.DatabseTableNames.InboundDeliveries
+}
+
+// InboundDeadLetter 超过重试次数仍处理失败的邮件，可由域名管理员重新处理或丢弃
+type InboundDeadLetter struct {
+	ID               uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	InboundMessageID uint           `gorm:"not null;index" json:"inbound_message_id"`
+	S3Key            string         `gorm:"type:varchar(512);not null" json:"s3_key"`
+	MessageID        string         `gorm:"type:varchar(512)" json:"message_id,omitempty"`
+	Domains          datatypes.JSON `gorm:"type:jsonb" json:"domains"`
+	Recipient        string         `gorm:"type:varchar(255)" json:"recipient,omitempty"` // 收件人阶段失败时的收件人
+	Step             string         `gorm:"type:varchar(16);not null" json:"step"`
+	Reason           string         `gorm:"type:varchar(64);not null" json:"reason"`
+	Error            string         `gorm:"type:text" json:"error"`
+	Attempts         int            `gorm:"not null" json:"attempts"`
+	Status           string         `gorm:"type:varchar(16);not null;index" json:"status"`
+	ResolvedBy       string         `gorm:"type:varchar(255)" json:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time     `json:"resolved_at,omitempty"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (InboundDeadLetter) TableName() string {
+	return global.Config.DatabseTableNames.InboundDeadLetters
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 邮件事件类型
+const (
+	MailEventNewMessage = "new_message"
+	MailEventFlagChange = "flag_change"
+	MailEventMove       = "move"
+	MailEventDelete     = "delete"
+	// MailEventReset 不写入数据库，客户端的游标已超出保留范围时推送，收到后需重新拉取列表
+	MailEventReset = "reset"
+)
+
+// MailEvent 推送给客户端的邮件变更事件，ID 全局递增，作为客户端断线重连的游标
+type MailEvent struct {
+	ID             uint64    `gorm:"primaryKey;autoIncrement;index:idx_mail_event_account,priority:2" json:"id"`
+	EmailAccountID uint      `gorm:"not null;index:idx_mail_event_account,priority:1" json:"-"`
+	Type           string    `gorm:"type:varchar(16);not null" json:"type"`
+	EmailID        uint      `gorm:"not null" json:"email_id"`
+	MessageID      string    `gorm:"type:varchar(512)" json:"message_id,omitempty"`
+	Folder         string    `gorm:"type:varchar(32)" json:"folder,omitempty"`      // 邮件当前所在分类，移动事件为目标分类
+	FromFolder     string    `gorm:"type:varchar(32)" json:"from_folder,omitempty"` // 移动事件的原分类
+	IsRead         *bool     `json:"is_read,omitempty"`
+	IsFlagged      *bool     `json:"is_flagged,omitempty"`
+	Subject        string    `gorm:"type:text" json:"subject,omitempty"`
+	Sender         string    `gorm:"type:varchar(255)" json:"sender,omitempty"`
+	CreatedAt      time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+func (MailEvent) TableName() string {
+	return global.Config.DatabseTableNames.MailEvents
+}
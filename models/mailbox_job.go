@@ -0,0 +1,56 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 邮箱导入导出任务类型
+const (
+	MailboxJobImport = "import"
+	MailboxJobExport = "export"
+)
+
+// 邮箱导入导出任务格式，导入支持 mbox / zip / eml，导出支持 mbox / maildir
+const (
+	MailboxFormatMbox    = "mbox"
+	MailboxFormatZip     = "zip" // Maildir 或 .eml 文件打包的 zip
+	MailboxFormatEML     = "eml"
+	MailboxFormatMaildir = "maildir"
+)
+
+// 邮箱导入导出任务状态
+const (
+	MailboxJobPending   = "pending"
+	MailboxJobRunning   = "running"
+	MailboxJobCompleted = "completed"
+	MailboxJobFailed    = "failed"
+)
+
+// MailboxJob 异步执行的邮箱导入或导出任务
+type MailboxJob struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint       `gorm:"index;not null" json:"-"`
+	Kind           string     `gorm:"type:varchar(16);not null" json:"kind"`
+	Format         string     `gorm:"type:varchar(16);not null" json:"format"`
+	Folders        string     `gorm:"type:varchar(255);not null" json:"folders"` // 导入时为默认分类，导出时为逗号分隔的分类
+	FileName       string     `gorm:"type:varchar(255)" json:"file_name,omitempty"`
+	SourceKey      string     `gorm:"type:varchar(512)" json:"-"` // 导入文件在 S3 中的对象键
+	ResultKey      string     `gorm:"type:varchar(512)" json:"-"` // 导出文件在 S3 中的对象键
+	ResultSize     int64      `gorm:"not null;default:0" json:"result_size,omitempty"`
+	Status         string     `gorm:"type:varchar(16);not null;index" json:"status"`
+	Total          int        `gorm:"not null;default:0" json:"total"` // 邮件总数，mbox 导入完成前为 0
+	Processed      int        `gorm:"not null;default:0" json:"processed"`
+	Succeeded      int        `gorm:"not null;default:0" json:"succeeded"`
+	Skipped        int        `gorm:"not null;default:0" json:"skipped"` // 导入时为重复邮件，导出时为没有原文的邮件
+	Failed         int        `gorm:"not null;default:0" json:"failed"`
+	Error          string     `gorm:"type:varchar(1024)" json:"error,omitempty"`
+	DownloadURL    string     `gorm:"-" json:"download_url,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (MailboxJob) TableName() string {
+	return global.Config.DatabseTableNames.MailboxJobs
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"email/global"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 发信队列状态
+const (
+	OutboundQueued = "queued" // 等待重试
+	OutboundSent   = "sent"
+	OutboundFailed = "failed" // 永久失败或超过最长重试时间，已向发件人发送退信
+)
+
+// OutboundMessage SMTP 发信时 SES 暂时无法发送的邮件，已作为发出邮件保存，由队列定时重试
+type OutboundMessage struct {
+	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID uint           `gorm:"not null;index" json:"-"`
+	EmailID        uint           `gorm:"not null" json:"email_id"` // 发件人邮件表中的发出邮件
+	From           string         `gorm:"type:varchar(255);not null" json:"from"`
+	To             datatypes.JSON `gorm:"type:jsonb" json:"to"`
+	Cc             datatypes.JSON `gorm:"type:jsonb" json:"cc"`
+	Bcc            datatypes.JSON `gorm:"type:jsonb" json:"bcc"`
+	S3Key          string         `gorm:"type:varchar(512);not null" json:"-"` // 邮件原文
+	Status         string         `gorm:"type:varchar(16);not null;index:idx_outbound_due" json:"status"`
+	Attempts       int            `gorm:"not null;default:0" json:"attempts"`
+	DelayNotices   int            `gorm:"not null;default:0" json:"delay_notices"` // 已发送的延迟提醒次数
+	LastError      string         `gorm:"type:text" json:"last_error,omitempty"`
+	SESMessageID   string         `gorm:"column:ses_message_id;type:varchar(255)" json:"-"`
+	NextAttemptAt  time.Time      `gorm:"not null;index:idx_outbound_due" json:"next_attempt_at"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (OutboundMessage) TableName() string {
+	return global.Config.DatabseTableNames.OutboundQueue
+}
@@ -85,8 +85,15 @@ type EmailDetailsResponse struct {
 	BodyText       string         `json:"body_text"`
 	BodyHTML       string         `json:"body_html"`
 	IsRead         bool           `json:"is_read"`
+	IsFlagged      bool           `json:"is_flagged"`
 	AttachmentInfo datatypes.JSON `json:"attachment_info"`
 	ReceivedAt     time.Time      `json:"received_at"`
+	Attachments    []Attachment   `gorm:"-" json:"attachments,omitempty"` // 邮件详情中的附件
+	Inlines        []Attachment   `gorm:"-" json:"inlines,omitempty"`     // 邮件详情中的内嵌图片
+	// 是否有远程图片被屏蔽，可带 load_images=true 重新获取详情或为发件人开启总是加载
+	RemoteImagesBlocked bool `gorm:"-" json:"remote_images_blocked"`
+	// 发出邮件各收件人的投递状态
+	DeliveryStatus []DeliveryStatus `gorm:"-" json:"delivery_status,omitempty"`
 }
 
 // 移动邮件结构体
@@ -110,6 +117,7 @@ type WebSendEmailRequest struct {
 type FrontendAttachment struct {
 	Code     string `json:"code"`
 	Filename string `json:"filename"`
+	Inline   bool   `json:"inline"` // 内嵌图片，正文中以 cid:<code> 引用
 }
 
 // Web send mail att generate
@@ -163,6 +171,7 @@ type ImapOperatorOptions struct {
 	TargetMailBox   string
 	MessageID       string
 	ReadStatus      bool
+	Flagged         bool
 	EmailRawMessage []byte
 }
 
@@ -172,3 +181,335 @@ type UpdateAccountPasswordRequest struct {
 	NewPassword     string `json:"newPassword"`
 	ConfirmPassword string `json:"confirmPassword"`
 }
+
+// 更新自动回复设置请求结构体
+type UpdateVacationRequest struct {
+	Enabled            bool      `json:"enabled"`
+	StartAt            time.Time `json:"start_at" binding:"required"`
+	EndAt              time.Time `json:"end_at"`
+	Subject            string    `json:"subject"`
+	BodyText           string    `json:"body_text"`
+	BodyHTML           string    `json:"body_html"`
+	ResendIntervalDays int       `json:"resend_interval_days"`
+}
+
+// 更新自动转发设置请求结构体
+type UpdateForwardingRequest struct {
+	Enabled   bool   `json:"enabled"`
+	ForwardTo string `json:"forward_to"`
+	KeepCopy  bool   `json:"keep_copy"`
+}
+
+// 过滤规则请求结构体，新增与更新共用
+type FilterRuleRequest struct {
+	ID         uint              `json:"id"`
+	Name       string            `json:"name" binding:"required"`
+	Priority   int               `json:"priority"`
+	Enabled    *bool             `json:"enabled"`
+	MatchType  string            `json:"match_type"`
+	Conditions []FilterCondition `json:"conditions"`
+	Actions    []FilterAction    `json:"actions" binding:"required"`
+	Stop       bool              `json:"stop"`
+}
+
+// 删除过滤规则请求结构体
+type DeleteFilterRuleRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// 导入 Sieve 脚本请求结构体
+type ImportSieveRequest struct {
+	Script  string `json:"script" binding:"required"`
+	Replace bool   `json:"replace"` // 是否替换现有规则
+}
+
+// 测试过滤规则请求结构体，RuleID 与 Rule 二选一
+type TestFilterRuleRequest struct {
+	EmailID int                `json:"email_id" binding:"required"`
+	RuleID  uint               `json:"rule_id"`
+	Rule    *FilterRuleRequest `json:"rule"`
+}
+
+// 联系人请求结构体，新增与更新共用
+type ContactRequest struct {
+	ID     uint     `json:"id"`
+	Name   string   `json:"name" binding:"required"`
+	Emails []string `json:"emails" binding:"required"`
+	Phone  string   `json:"phone"`
+	Notes  string   `json:"notes"`
+	Groups []string `json:"groups"`
+}
+
+// 删除联系人请求结构体
+type DeleteContactRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// 联系人列表
+type ContactList struct {
+	Contacts []Contact `json:"contacts"`
+	Total    int64     `json:"total"`
+	Page     int       `json:"page"`
+}
+
+// 创建附件分享链接请求
+type CreateShareLinkRequest struct {
+	Code           string `json:"code" binding:"required"`
+	ExpiresInHours int    `json:"expires_in_hours"` // 有效时长，默认 24 小时
+}
+
+// 撤销附件分享链接请求
+type RevokeShareLinkRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// 创建分片上传会话请求
+type CreateUploadSessionRequest struct {
+	FileName    string `json:"filename" binding:"required"`
+	Size        int64  `json:"size" binding:"required"`
+	ContentType string `json:"content_type"`
+}
+
+// 附件上传完成返回结构体
+type UploadedAttachment struct {
+	FileName string `json:"filename"`
+	Size     int64  `json:"size"`
+	Type     string `json:"type"`
+	Code     string `json:"code"`
+}
+
+// 附件分享链接返回结构体
+type ShareLinkResponse struct {
+	AttachmentShareLink
+	URL string `json:"url"`
+}
+
+// 更新域名策略请求
+type UpdateDomainPolicyRequest struct {
+	AttachmentReaperEnabled *bool `json:"attachment_reaper_enabled" binding:"required"`
+	OrphanGraceHours        *int  `json:"orphan_grace_hours"` // 为空时保持原值
+	RequireTwoFactor        *bool `json:"require_two_factor"` // 为空时保持原值
+}
+
+// 附件回收报告列表
+type AttachmentReaperReportList struct {
+	Reports []AttachmentReaperReport `json:"reports"`
+	Total   int64                    `json:"total"`
+	Page    int                      `json:"page"`
+}
+
+// 保存发件人偏好设置请求
+type SenderPreferenceRequest struct {
+	Sender           string `json:"sender" binding:"required"` // 邮件地址或 @域名
+	AlwaysLoadImages bool   `json:"always_load_images"`
+}
+
+// 删除发件人偏好设置请求
+type DeleteSenderPreferenceRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// 邮件头部，按原始顺序返回
+type ParsedEmailHeader struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`   // 原始值，折行已合并
+	Decoded string `json:"decoded"` // RFC 2047 解码后的值
+}
+
+// 创建邮箱导出任务请求
+type CreateExportJobRequest struct {
+	Folders []string `json:"folders" binding:"required"`
+	Format  string   `json:"format"` // mbox / maildir，默认 mbox
+}
+
+// 邮箱导入导出任务列表
+type MailboxJobList struct {
+	Jobs  []MailboxJob `json:"jobs"`
+	Total int64        `json:"total"`
+	Page  int          `json:"page"`
+}
+
+// 创建 IMAP 迁移任务请求
+type CreateImapMigrationRequest struct {
+	Host     string `json:"host" binding:"required"`
+	Port     int    `json:"port"`     // 默认 993，STARTTLS 时默认 143
+	StartTLS bool   `json:"starttls"` // 以明文连接后升级为 TLS
+	Username string `json:"username" binding:"required"`
+	AuthType string `json:"auth_type"` // password / xoauth2，默认 password
+	Password string `json:"password"`
+	Token    string `json:"token"` // XOAUTH2 的 access token
+	// 远程文件夹与分类的对应关系，值为 skip 时不迁移该文件夹，未指定的文件夹自动识别
+	FolderMap map[string]string `json:"folder_map"`
+}
+
+// 恢复或取消 IMAP 迁移任务请求，恢复时可更新凭证
+type ImapMigrationActionRequest struct {
+	ID       uint   `json:"id" binding:"required"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// IMAP 迁移任务列表
+type ImapMigrationList struct {
+	Migrations []ImapMigration `json:"migrations"`
+	Total      int64           `json:"total"`
+	Page       int             `json:"page"`
+}
+
+// 收信死信列表
+type InboundDeadLetterList struct {
+	DeadLetters []InboundDeadLetter `json:"dead_letters"`
+	Total       int64               `json:"total"`
+	Page        int                 `json:"page"`
+}
+
+// 重新处理或丢弃收信死信请求
+type DeadLetterIDsRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// 重新处理或丢弃收信死信的结果
+type DeadLetterActionResult struct {
+	Affected int64 `json:"affected"`
+}
+
+// 创建 Webhook 请求
+type CreateWebhookRequest struct {
+	Scope       string   `json:"scope"` // account / domain，默认 account，domain 需为域名管理员
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description string   `json:"description"`
+}
+
+// 更新 Webhook 请求，未传入的字段保持不变
+type UpdateWebhookRequest struct {
+	ID           uint     `json:"id" binding:"required"`
+	URL          *string  `json:"url"`
+	Events       []string `json:"events"`
+	Description  *string  `json:"description"`
+	Enabled      *bool    `json:"enabled"`
+	RotateSecret bool     `json:"rotate_secret"` // 重新生成签名密钥
+}
+
+// 删除 Webhook 请求
+type DeleteWebhookRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// 重新推送 Webhook 请求
+type RedeliverWebhookRequest struct {
+	DeliveryID uint `json:"delivery_id" binding:"required"`
+}
+
+// Webhook 详情，创建或重新生成密钥时返回签名密钥
+type WebhookDetails struct {
+	Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// Webhook 推送记录列表
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+}
+
+// 禁止发送列表
+type SuppressionList struct {
+	Scope        string        `json:"scope"`
+	Suppressions []Suppression `json:"suppressions"`
+	Total        int64         `json:"total"`
+	Page         int           `json:"page"`
+}
+
+// 移除禁止发送列表记录请求，scope 为空时为域名列表
+type RemoveSuppressionsRequest struct {
+	Scope string `json:"scope"`
+	IDs   []uint `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// 移除禁止发送列表记录的结果
+type RemoveSuppressionsResult struct {
+	Removed int64 `json:"removed"`
+}
+
+// 解除账户锁定的请求
+type UnlockAccountRequest struct {
+	EmailAddress string `json:"email_address" binding:"required,email"`
+}
+
+// 创建应用专用密码的请求
+type CreateAppPasswordRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+// 删除应用专用密码的请求
+type DeleteAppPasswordRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// 应用专用密码设置
+type AppPasswordSettings struct {
+	AppPasswordsOnly bool `json:"app_passwords_only"`
+}
+
+// 应用专用密码列表
+type AppPasswordList struct {
+	AppPasswords     []AppPassword `json:"app_passwords"`
+	AppPasswordsOnly bool          `json:"app_passwords_only"`
+}
+
+// 两步验证登录的第二步，code 为验证码或恢复码
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	Code           string `json:"code"`
+}
+
+// 输入验证码的请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// 关闭两步验证的请求，需同时验证密码与验证码
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// 域名管理员重置账户两步验证的请求
+type ResetTwoFactorRequest struct {
+	EmailAddress string `json:"email_address" binding:"required,email"`
+}
+
+// 密码验证通过但需要两步验证时的登录结果
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	SetupRequired     bool   `json:"setup_required"` // 域名要求两步验证但账户尚未设置，令牌只能用于设置
+	TwoFactorToken    string `json:"two_factor_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// 两步验证设置信息，密钥只在设置时返回
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// 地址，前端转为二维码
+}
+
+// 两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+	RequiredByDomain       bool       `json:"required_by_domain"`
+}
+
+// 新生成的恢复码，只返回一次
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// 登录过程中完成两步验证设置的结果
+type TwoFactorSetupLogin struct {
+	AccessToken
+	RecoveryCodes []string `json:"recovery_codes"`
+}
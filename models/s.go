@@ -47,10 +47,18 @@ type EmailContent struct {
 	TextBody string
 	HtmlBody string
 	MailDir  string
+	Headers  []EmailHeader
+}
+
+// EmailHeader 表示一个附加的邮件头
+type EmailHeader struct {
+	Name  string
+	Value string
 }
 
 type AwsSnsEvent struct {
 	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"` // 通过配置集事件发布时的通知类型
 	Mail             struct {
 		Timestamp        time.Time `json:"timestamp"`
 		Source           string    `json:"source"`
@@ -98,6 +106,48 @@ type AwsSnsEvent struct {
 			ObjectKey       string `json:"objectKey"`
 		} `json:"action"`
 	} `json:"receipt"`
+	Bounce struct {
+		FeedbackId        string    `json:"feedbackId"`
+		BounceType        string    `json:"bounceType"`
+		BounceSubType     string    `json:"bounceSubType"`
+		Timestamp         time.Time `json:"timestamp"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			Action         string `json:"action"`
+			Status         string `json:"status"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		FeedbackId            string    `json:"feedbackId"`
+		ComplaintFeedbackType string    `json:"complaintFeedbackType"`
+		Timestamp             time.Time `json:"timestamp"`
+		ComplainedRecipients  []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+	Delivery struct {
+		Timestamp    time.Time `json:"timestamp"`
+		Recipients   []string  `json:"recipients"`
+		SmtpResponse string    `json:"smtpResponse"`
+	} `json:"delivery"`
+	DeliveryDelay struct {
+		DelayType         string    `json:"delayType"`
+		Timestamp         time.Time `json:"timestamp"`
+		DelayedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			Status         string `json:"status"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"delayedRecipients"`
+	} `json:"deliveryDelay"`
+}
+
+// Type 通知类型，收信与退信通知使用 notificationType，配置集事件发布使用 eventType
+func (e *AwsSnsEvent) Type() string {
+	if e.NotificationType != "" {
+		return e.NotificationType
+	}
+	return e.EventType
 }
 
 type Recipients struct {
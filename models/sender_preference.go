@@ -0,0 +1,20 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// SenderPreference 账户对发件人的偏好设置，Sender 为小写的邮件地址或以 @ 开头的域名
+type SenderPreference struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID   uint      `gorm:"uniqueIndex:idx_sender_preference;not null" json:"-"`
+	Sender           string    `gorm:"type:varchar(255);uniqueIndex:idx_sender_preference;not null" json:"sender"`
+	AlwaysLoadImages bool      `gorm:"not null" json:"always_load_images"` // 总是加载该发件人邮件中的远程图片
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (SenderPreference) TableName() string {
+	return global.Config.DatabseTableNames.SenderPreferences
+}
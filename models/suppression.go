@@ -0,0 +1,35 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 禁止发送列表的范围
+const (
+	SuppressionScopeDomain = "domain" // 只对该域名的账户生效
+	SuppressionScopeGlobal = "global" // 对所有域名生效
+)
+
+// 加入禁止发送列表的原因
+const (
+	SuppressionHardBounce = "hard_bounce" // 永久退信，加入全局列表
+	SuppressionComplaint  = "complaint"   // 投诉，加入发件人所属域名的列表
+)
+
+// Suppression 禁止发送的收件人地址，DomainID 为 0 时为全局列表
+type Suppression struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DomainID  uint      `gorm:"not null;default:0;uniqueIndex:idx_suppression_address" json:"-"`
+	Scope     string    `gorm:"type:varchar(16);not null" json:"scope"`
+	Address   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_suppression_address" json:"address"`
+	Reason    string    `gorm:"type:varchar(32);not null" json:"reason"`
+	Detail    string    `gorm:"type:text" json:"detail,omitempty"`         // 退信诊断信息或投诉类型
+	Source    string    `gorm:"type:varchar(255)" json:"source,omitempty"` // 退信或被投诉邮件的发件人
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"` // 再次退信或投诉时更新
+}
+
+func (Suppression) TableName() string {
+	return global.Config.DatabseTableNames.Suppressions
+}
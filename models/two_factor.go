@@ -0,0 +1,44 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// 两步验证临时令牌的用途
+const (
+	TwoFactorTokenLogin = "2fa"       // 密码正确，等待输入验证码
+	TwoFactorTokenSetup = "2fa-setup" // 域名要求两步验证但账户尚未设置，只能用于设置
+)
+
+// TwoFactorRecoveryCodeCount 每次生成的恢复码数量
+const TwoFactorRecoveryCodeCount = 10
+
+// TwoFactor 账户的 TOTP 两步验证设置，Enabled 为 false 时为尚未确认的设置
+type TwoFactor struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	EmailAccountID uint   `gorm:"uniqueIndex;not null"`
+	Secret         string `gorm:"type:text;not null"` // 加密保存的 TOTP 密钥
+	Enabled        bool   `gorm:"not null;default:false"`
+	EnabledAt      *time.Time
+	LastUsedStep   int64     `gorm:"not null;default:0"` // 最近一次使用的时间步，同一验证码不能重复使用
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (TwoFactor) TableName() string {
+	return global.Config.DatabseTableNames.TwoFactors
+}
+
+// TwoFactorRecoveryCode 一次性恢复码，只保存哈希
+type TwoFactorRecoveryCode struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	EmailAccountID uint   `gorm:"not null;index"`
+	CodeHash       string `gorm:"type:varchar(64);not null;index"`
+	UsedAt         *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (TwoFactorRecoveryCode) TableName() string {
+	return global.Config.DatabseTableNames.TwoFactorRecoveryCodes
+}
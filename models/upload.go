@@ -0,0 +1,45 @@
+package models
+
+import (
+	"email/global"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 上传会话状态
+const (
+	UploadStatusUploading = "uploading"
+	UploadStatusCompleted = "completed"
+	UploadStatusAborted   = "aborted"
+)
+
+// UploadSession 可断点续传的分片上传会话，分片直接对应 S3 分片上传的 part
+type UploadSession struct {
+	ID             string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	EmailAccountID uint           `gorm:"index;not null" json:"-"`
+	FileName       string         `gorm:"type:varchar(255);not null" json:"filename"`
+	ContentType    string         `gorm:"type:varchar(255)" json:"content_type"`
+	Size           int64          `gorm:"not null" json:"size"`                     // 文件总大小
+	ChunkSize      int64          `gorm:"not null" json:"chunk_size"`               // 除最后一片外每片的大小
+	ReceivedBytes  int64          `gorm:"not null;default:0" json:"received_bytes"` // 已接收字节数，即下一片的偏移量
+	S3Key          string         `gorm:"type:varchar(512);not null" json:"-"`
+	S3UploadID     string         `gorm:"type:varchar(1024);not null" json:"-"`
+	Parts          datatypes.JSON `gorm:"type:jsonb;default:'[]';not null" json:"-"` // 已上传的分片 []UploadPart
+	HashState      []byte         `gorm:"type:bytea" json:"-"`                       // SHA-256 中间状态，用于跨请求流式计算哈希
+	Status         string         `gorm:"type:varchar(16);not null;index" json:"status"`
+	AttachmentCode string         `gorm:"type:varchar(255)" json:"attachment_code,omitempty"` // 完成后的附件短码
+	ExpiresAt      time.Time      `gorm:"not null;index" json:"expires_at"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (UploadSession) TableName() string {
+	return global.Config.DatabseTableNames.UploadSessions
+}
+
+// UploadPart S3 分片上传中已完成的分片
+type UploadPart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"email/global"
+	"time"
+)
+
+// VacationResponder 表示 vacation_responders 表的 GORM 模型，每个账户最多一条
+type VacationResponder struct {
+	ID                 uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	EmailAccountID     uint      `gorm:"uniqueIndex;not null" json:"email_account_id"`   // 关联的邮箱账号 ID，唯一
+	Enabled            bool      `gorm:"not null;default:false" json:"enabled"`          // 是否启用自动回复
+	StartAt            time.Time `gorm:"not null" json:"start_at"`                       // 开始时间
+	EndAt              time.Time `json:"end_at"`                                         // 结束时间，零值表示不限
+	Subject            string    `gorm:"type:varchar(255);not null" json:"subject"`      // 回复主题
+	BodyText           string    `gorm:"type:text" json:"body_text"`                     // 回复正文（纯文本）
+	BodyHTML           string    `gorm:"type:text" json:"body_html"`                     // 回复正文（HTML）
+	ResendIntervalDays int       `gorm:"not null;default:7" json:"resend_interval_days"` // 同一发件人重复回复的最小间隔(天)
+	UpdatedAt          time.Time `gorm:"autoUpdateTime" json:"updated_at"`               // 更新时间
+	CreatedAt          time.Time `gorm:"autoCreateTime" json:"created_at"`               // 创建时间
+}
+
+func (VacationResponder) TableName() string {
+	return global.Config.DatabseTableNames.VacationResponders
+}
+
+// IsActive 判断自动回复在指定时间是否生效
+func (v *VacationResponder) IsActive(t time.Time) bool {
+	if !v.Enabled || t.Before(v.StartAt) {
+		return false
+	}
+	if !v.EndAt.IsZero() && t.After(v.EndAt) {
+		return false
+	}
+	return true
+}
+
+// VacationReplyLog 记录对每个发件人最后一次自动回复的时间，用于控制重复回复间隔
+type VacationReplyLog struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	EmailAccountID uint      `gorm:"uniqueIndex:idx_vacation_reply_sender;not null"`                   // 关联的邮箱账号 ID
+	SenderEmail    string    `gorm:"type:varchar(255);uniqueIndex:idx_vacation_reply_sender;not null"` // 已回复的发件人
+	LastRepliedAt  time.Time `gorm:"not null"`                                                         // 最后回复时间
+}
+
+func (VacationReplyLog) TableName() string {
+	return global.Config.DatabseTableNames.VacationReplyLogs
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"email/global"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Webhook 事件类型
+const (
+	WebhookMessageReceived = "message.received"
+	WebhookMessageSent     = "message.sent"
+	WebhookMessageBounced  = "message.bounced"
+	WebhookMessageMoved    = "message.moved"
+	WebhookAccountCreated  = "account.created"
+	WebhookAccountLocked   = "account.locked"
+)
+
+// WebhookEvents 支持订阅的全部事件
+var WebhookEvents = []string{WebhookMessageReceived, WebhookMessageSent, WebhookMessageBounced, WebhookMessageMoved, WebhookAccountCreated, WebhookAccountLocked}
+
+// Webhook 订阅范围
+const (
+	WebhookScopeAccount = "account"
+	WebhookScopeDomain  = "domain"
+)
+
+// Webhook 推送状态
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// ImportedEmailKeyPrefix 导入邮件原文在 S3 中的存放前缀，导入的邮件不触发收信事件
+const ImportedEmailKeyPrefix = "imports/"
+
+// Webhook 账户或域名的事件订阅，域名订阅由域名管理员创建，接收域名下所有账户的事件
+type Webhook struct {
+	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope          string         `gorm:"type:varchar(16);not null" json:"scope"`
+	EmailAccountID uint           `gorm:"not null;default:0;index" json:"-"` // 账户订阅的账户
+	DomainID       uint           `gorm:"not null;default:0;index" json:"-"` // 域名订阅的域名
+	URL            string         `gorm:"type:varchar(2048);not null" json:"url"`
+	Secret         string         `gorm:"type:text;not null" json:"-"` // 加密保存的签名密钥
+	Events         datatypes.JSON `gorm:"type:jsonb;not null" json:"events"`
+	Description    string         `gorm:"type:varchar(255)" json:"description,omitempty"`
+	Enabled        bool           `gorm:"not null;default:true" json:"enabled"`
+	CreatedBy      string         `gorm:"type:varchar(255)" json:"created_by"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Webhook) TableName() string {
+	return global.Config.DatabseTableNames.Webhooks
+}
+
+// WebhookDelivery 一次事件推送及其最近一次请求的结果
+type WebhookDelivery struct {
+	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	WebhookID     uint           `gorm:"not null;index" json:"webhook_id"`
+	EventID       string         `gorm:"type:varchar(64);not null;index" json:"event_id"` // 重新推送时沿用，接收方可据此去重
+	Event         string         `gorm:"type:varchar(32);not null" json:"event"`
+	Payload       datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+	Status        string         `gorm:"type:varchar(16);not null;index:idx_webhook_delivery_due,priority:1" json:"status"`
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`
+	ResponseCode  int            `gorm:"not null;default:0" json:"response_code"`
+	ResponseBody  string         `gorm:"type:text" json:"response_body,omitempty"`
+	Error         string         `gorm:"type:text" json:"error,omitempty"`
+	DurationMs    int64          `gorm:"not null;default:0" json:"duration_ms"`
+	NextAttemptAt *time.Time     `gorm:"index:idx_webhook_delivery_due,priority:2" json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return global.Config.DatabseTableNames.WebhookDeliveries
+}
+
+// WebhookPayload 推送的请求体
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	Account   string      `json:"account,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookMessageData 邮件事件的数据
+type WebhookMessageData struct {
+	EmailID    uint      `json:"email_id"`
+	MessageID  string    `json:"message_id"`
+	Folder     string    `json:"folder,omitempty"`
+	FromFolder string    `json:"from_folder,omitempty"`
+	Subject    string    `json:"subject,omitempty"`
+	From       string    `json:"from,omitempty"`
+	To         string    `json:"to,omitempty"`
+	Cc         string    `json:"cc,omitempty"`
+	ReceivedAt time.Time `json:"received_at,omitempty"`
+	// 退信事件的失败收件人与原因
+	BouncedRecipients []string `json:"bounced_recipients,omitempty"`
+	BounceReason      string   `json:"bounce_reason,omitempty"`
+}
+
+// NewWebhookMessageData 由邮件记录生成事件数据
+func NewWebhookMessageData(r *EmailDetails) *WebhookMessageData {
+	return &WebhookMessageData{
+		EmailID:    r.ID,
+		MessageID:  r.EmailMessageID,
+		Folder:     r.EmailType,
+		Subject:    r.Subject,
+		From:       r.SenderEmail,
+		To:         r.RecipientEmail,
+		Cc:         r.Cc,
+		ReceivedAt: r.ReceivedAt,
+	}
+}
+
+// WebhookAccountData 账户事件的数据
+type WebhookAccountData struct {
+	EmailAddress string `json:"email_address"`
+	UserName     string `json:"user_name"`
+	Domain       string `json:"domain"`
+}
+
+// WebhookAccountLockedData 账户因认证失败过多被锁定的事件数据
+type WebhookAccountLockedData struct {
+	EmailAddress string    `json:"email_address"`
+	Frontend     string    `json:"frontend"` // 触发锁定的登录入口：http / smtp / dav
+	IP           string    `json:"ip"`       // 最后一次失败的来源 IP
+	LockedUntil  time.Time `json:"locked_until"`
+}
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"email/controller"
+	"email/middleware"
+	"email/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CardDAV / CalDAV 路由初始化，挂载在根路径下，供手机与桌面客户端同步
+func DavRouterInit(r *gin.Engine) {
+	var DavController controller.DavController
+	// * 服务发现
+	for _, p := range []string{"/.well-known/carddav", "/.well-known/caldav"} {
+		r.GET(p, DavController.WellKnown)
+		r.Handle("PROPFIND", p, DavController.WellKnown)
+	}
+	dav := r.Group(service.DavPrefix)
+	dav.OPTIONS("/*path", DavController.Options)
+	dav.Use(middleware.DavAuthMiddleware())
+	{
+		dav.Handle("PROPFIND", "/*path", DavController.Propfind)
+		dav.Handle("PROPPATCH", "/*path", DavController.Proppatch)
+		dav.Handle("REPORT", "/*path", DavController.Report)
+		dav.GET("/*path", DavController.Get)
+		dav.HEAD("/*path", DavController.Get)
+		dav.PUT("/*path", DavController.Put)
+		dav.DELETE("/*path", DavController.Delete)
+	}
+}
@@ -31,8 +31,8 @@ func InitRouter() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173", "https://mail.web.mail.mountex.net", "https://webmail.mountex.net"}, // 允许的前端地址
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Range", "Upload-Offset"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Range", "Accept-Ranges", "Content-Disposition", "Upload-Offset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -41,6 +41,8 @@ func InitRouter() {
 
 	// 初始化 v1 版本的路由
 	v1.V1RouterInit(r)
+	// 初始化 CardDAV / CalDAV 路由
+	DavRouterInit(r)
 
 	r.Run(":8080")
 }
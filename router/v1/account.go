@@ -16,6 +16,23 @@ func AccountRouterInit(r *gin.RouterGroup) {
 		{
 			update.POST("/password", AccountController.UpdateAccountPassword)
 		}
+		// * 自动回复设置
+		account.GET("/vacation", AccountController.GetVacation)
+		account.POST("/vacation", AccountController.UpdateVacation)
+		// * 自动转发设置
+		account.GET("/forwarding", AccountController.GetForwarding)
+		account.POST("/forwarding", AccountController.UpdateForwarding)
+		// * 应用专用密码
+		account.GET("/app-passwords", AccountController.GetAppPasswords)
+		account.POST("/app-passwords/create", AccountController.CreateAppPassword)
+		account.POST("/app-passwords/delete", AccountController.DeleteAppPassword)
+		account.POST("/app-passwords/settings", AccountController.UpdateAppPasswordSettings)
+		// * 两步验证
+		account.GET("/2fa", AccountController.GetTwoFactor)
+		account.POST("/2fa/setup", AccountController.SetupTwoFactor)
+		account.POST("/2fa/enable", AccountController.EnableTwoFactor)
+		account.POST("/2fa/disable", AccountController.DisableTwoFactor)
+		account.POST("/2fa/recovery-codes", AccountController.RegenerateRecoveryCodes)
 
 		//獲取郵箱信息
 	}
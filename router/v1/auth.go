@@ -12,6 +12,10 @@ func AuthRouterInit(r *gin.RouterGroup) {
 
 	// 公共路由
 	r.POST("/auth/login", middleware.LoginMiddleware(), AuthController.Login)
+	// 两步验证，使用登录时返回的临时令牌
+	r.POST("/auth/login/2fa", AuthController.LoginTwoFactor)
+	r.POST("/auth/login/2fa/setup", AuthController.LoginTwoFactorSetup)
+	r.POST("/auth/login/2fa/enable", AuthController.LoginTwoFactorEnable)
 
 	// 受保护的路由
 	auth := r.Group("/auth")
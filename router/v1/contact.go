@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"email/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// v1 下 关于联系人的Api初始化
+func ContactRouterInit(r *gin.RouterGroup) {
+	var ContactController controller.ContactController
+	contact := r.Group("/contact")
+	{
+		// * 联系人增删改查
+		contact.GET("/list", ContactController.GetContactList)
+		contact.GET("/details", ContactController.GetContactDetails)
+		contact.POST("/add", ContactController.AddContact)
+		contact.POST("/update", ContactController.UpdateContact)
+		contact.POST("/delete", ContactController.DeleteContact)
+		// * 收件人自动补全
+		contact.GET("/autocomplete", ContactController.AutocompleteContacts)
+		// * vCard 导入导出
+		contact.POST("/import", ContactController.ImportVCard)
+		contact.GET("/export", ContactController.ExportVCard)
+	}
+}
@@ -31,5 +31,32 @@ func DomainRouterInit(r *gin.RouterGroup) {
 		}
 
 		domain.GET("/list", DomainController.GetDomainEmailList)
+
+		// * 域名策略与附件回收
+		{
+			domain.GET("/policy", DomainController.GetDomainPolicy)
+			domain.POST("/policy", DomainController.UpdateDomainPolicy)
+			domain.GET("/reaper-reports", DomainController.GetReaperReports)
+		}
+
+		// * 收信死信
+		{
+			domain.GET("/dead-letters", DomainController.GetDeadLetters)
+			domain.POST("/dead-letters/reprocess", DomainController.ReprocessDeadLetters)
+			domain.POST("/dead-letters/discard", DomainController.DiscardDeadLetters)
+		}
+
+		// * 禁止发送列表
+		{
+			domain.GET("/suppressions", DomainController.GetSuppressions)
+			domain.POST("/suppressions/remove", DomainController.RemoveSuppressions)
+		}
+
+		// * 登录失败锁定与两步验证
+		{
+			domain.GET("/lockouts", DomainController.GetLockedAccounts)
+			domain.POST("/lockouts/unlock", DomainController.UnlockAccount)
+			domain.POST("/2fa/reset", DomainController.ResetTwoFactor)
+		}
 	}
 }
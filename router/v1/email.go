@@ -20,6 +20,7 @@ TODO: 与前端对接采用何种形式
 // @BasePath /api/v1
 func EmailRouterInit(r *gin.RouterGroup) {
 	var EmailController controller.EmailController
+	var MailboxJobController controller.MailboxJobController
 	email := r.Group("/email")
 	{
 		// * 对域名下的 account 进行操作
@@ -36,6 +37,10 @@ func EmailRouterInit(r *gin.RouterGroup) {
 
 			//获取邮件详情
 			email.GET("/details", EmailController.GetEmailDetails)
+			// * 下载邮件原文(.eml)
+			email.GET("/raw", EmailController.GetRawEmail)
+			// * 获取邮件头部
+			email.GET("/headers", EmailController.GetEmailHeaders)
 
 			// * 获取邮件列表
 			email.GET("/inbox/uid", func(c *gin.Context) { EmailController.GetEmailListByEmailId(c, "inbox") })
@@ -61,6 +66,24 @@ func EmailRouterInit(r *gin.RouterGroup) {
 
 			// * 获取最新的收件箱邮件
 			email.GET("/latest/inbox", EmailController.GetLatestInboxEmailList)
+			// * 发件人偏好设置(总是加载远程图片)
+			email.GET("/sender-preference/list", EmailController.GetSenderPreferences)
+			email.POST("/sender-preference/save", EmailController.SaveSenderPreference)
+			email.POST("/sender-preference/delete", EmailController.DeleteSenderPreference)
+
+			// * 邮箱导入导出(mbox / Maildir zip / EML)，异步执行
+			email.POST("/import", MailboxJobController.CreateImportJob)
+			email.POST("/export", MailboxJobController.CreateExportJob)
+			email.GET("/transfer/jobs", MailboxJobController.GetMailboxJobs)
+			email.GET("/transfer/job", MailboxJobController.GetMailboxJob)
+			email.GET("/transfer/download", MailboxJobController.DownloadMailboxExport)
+
+			// * 从外部 IMAP 服务器迁移邮件
+			email.POST("/migration/create", MailboxJobController.CreateImapMigration)
+			email.GET("/migration/list", MailboxJobController.GetImapMigrations)
+			email.GET("/migration/details", MailboxJobController.GetImapMigration)
+			email.POST("/migration/resume", MailboxJobController.ResumeImapMigration)
+			email.POST("/migration/cancel", MailboxJobController.CancelImapMigration)
 		}
 	}
 
@@ -71,3 +94,16 @@ func EmailRouterInit(r *gin.RouterGroup) {
 	//---------------------------------------------------------------------------------------------------------------------------
 
 }
+
+// ImageProxyRouterInit 图片代理，使用签名校验，无需登录
+func ImageProxyRouterInit(r *gin.RouterGroup) {
+	var EmailController controller.EmailController
+	r.GET("/image-proxy", EmailController.ImageProxy)
+}
+
+// MailEventRouterInit 邮件事件推送(SSE)，代替轮询 /latest/inbox
+// 浏览器的 EventSource 无法设置 Authorization 请求头，因此不经过 AuthMiddleware，由控制器校验请求头或 access_token 参数中的 token
+func MailEventRouterInit(r *gin.RouterGroup) {
+	var EmailController controller.EmailController
+	r.GET("/email/events", EmailController.EventStream)
+}
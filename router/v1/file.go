@@ -11,7 +11,25 @@ func FileManageRouterInit(r *gin.RouterGroup) {
 	file := r.Group("/file")
 	Upload := file.Group("/upload")
 	Download := file.Group("/download")
+	Share := file.Group("/share")
 
 	Upload.POST("/", FileManageController.UploadFile)
+	// * 断点续传
+	Upload.POST("/session", FileManageController.CreateUploadSession)
+	Upload.GET("/session/:id", FileManageController.GetUploadSession)
+	Upload.PUT("/session/:id", FileManageController.UploadChunk)
+	Upload.DELETE("/session/:id", FileManageController.AbortUploadSession)
 	Download.GET("/:code", FileManageController.DownloadFile)
+	// * 服务端代理下载，支持 Range
+	file.GET("/stream/:code", FileManageController.StreamFile)
+	// * 附件分享链接
+	Share.POST("/create", FileManageController.CreateShareLink)
+	Share.GET("/list", FileManageController.GetShareLinks)
+	Share.POST("/revoke", FileManageController.RevokeShareLink)
+}
+
+// 公开的附件分享链接，无需登录
+func FileShareRouterInit(r *gin.RouterGroup) {
+	var FileManageController controller.FileManageController
+	r.GET("/share/:token", FileManageController.OpenShareLink)
 }
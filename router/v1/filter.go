@@ -0,0 +1,25 @@
+package v1
+
+import (
+	"email/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// v1 下 关于过滤规则的Api初始化
+func FilterRouterInit(r *gin.RouterGroup) {
+	var FilterController controller.FilterController
+	filter := r.Group("/filter")
+	{
+		// * 规则增删改查
+		filter.GET("/list", FilterController.GetFilterRules)
+		filter.POST("/add", FilterController.AddFilterRule)
+		filter.POST("/update", FilterController.UpdateFilterRule)
+		filter.POST("/delete", FilterController.DeleteFilterRule)
+		// * Sieve 脚本导入导出
+		filter.POST("/import", FilterController.ImportSieve)
+		filter.GET("/export", FilterController.ExportSieve)
+		// * 使用指定邮件试运行规则
+		filter.POST("/test", FilterController.TestFilterRule)
+	}
+}
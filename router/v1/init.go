@@ -13,6 +13,9 @@ func V1RouterInit(r *gin.Engine) {
 	publicRoutes := v1.Group("/")
 	{
 		AuthRouterInit(publicRoutes)
+		FileShareRouterInit(publicRoutes)
+		ImageProxyRouterInit(publicRoutes)
+		MailEventRouterInit(publicRoutes)
 	}
 	// 需要认证的路由组
 	protectedRoutes := v1.Group("/")
@@ -22,6 +25,9 @@ func V1RouterInit(r *gin.Engine) {
 		DomainRouterInit(protectedRoutes)
 		AccountRouterInit(protectedRoutes)
 		EmailRouterInit(protectedRoutes)
+		FilterRouterInit(protectedRoutes)
+		ContactRouterInit(protectedRoutes)
+		WebhookRouterInit(protectedRoutes)
 		// 在这里添加其他需要认证的路由初始化
 	}
 }
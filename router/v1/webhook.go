@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"email/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRouterInit 账户与域名的 Webhook 订阅管理
+func WebhookRouterInit(r *gin.RouterGroup) {
+	var WebhookController controller.WebhookController
+	webhook := r.Group("/webhook")
+	{
+		webhook.POST("/create", WebhookController.CreateWebhook)
+		webhook.GET("/list", WebhookController.GetWebhooks)
+		webhook.POST("/update", WebhookController.UpdateWebhook)
+		webhook.POST("/delete", WebhookController.DeleteWebhook)
+		webhook.GET("/deliveries", WebhookController.GetWebhookDeliveries)
+		webhook.POST("/redeliver", WebhookController.RedeliverWebhook)
+	}
+}
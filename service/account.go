@@ -1,33 +1,58 @@
 package service
 
 import (
-	"email/controller/response"
 	"email/dao"
-	"email/global"
 	"email/models"
 	"email/utils"
 	"errors"
-	"time"
+	"strings"
 )
 
-// LoginProcess 登录
-func LoginProcess(l models.LoginRequest) (*models.AccessToken, error) {
+// LoginProcess 登录，ip 为请求来源，用于统计认证失败
+// 账户启用了两步验证或域名要求两步验证时不返回 JWT，而是返回两步验证的临时令牌
+func LoginProcess(l models.LoginRequest, ip string) (*models.AccessToken, *models.TwoFactorChallenge, error) {
 	// 验证密码
-	s, e := dao.ValidateAccount(l.Email, l.Password)
+	s, e := authenticateWithLockout(l.Email, ip, models.AuthFrontendHTTP, func() (*models.EmailAccount, error) {
+		return dao.ValidateAccount(l.Email, l.Password)
+	})
 	if e != nil {
-		return nil, e
+		return nil, nil, e
 	}
-	expirationTime := time.Now().Add(time.Duration(global.Config.Jwt.ExpiredTime) * time.Hour * 24)
-	token, err := utils.GenerateJwtToken(s, expirationTime)
+	challenge, err := twoFactorChallenge(s)
 	if err != nil {
-		//返回错误
-		return nil, errors.New(response.GenerateJwtTokenFailedCode.ErrMessage)
+		return nil, nil, err
 	}
-	//返回成功
-	return &models.AccessToken{
-			AccessToken: token,
-			TokenType:   "Bearer",
-			ExpiresIn:   expirationTime.Unix()},
-		nil
+	if challenge != nil {
+		return nil, challenge, nil
+	}
+	token, err := issueAccessToken(s)
+	return token, nil, err
+}
 
+// AuthenticateMailAccount 使用邮箱地址与密码验证账户，SMTP AUTH 与 CardDAV/CalDAV 共用
+// ip 与 frontend 为来源与登录入口，失败次数过多时锁定
+func AuthenticateMailAccount(username, password, ip, frontend string) (*models.EmailAccount, error) {
+	return authenticateWithLockout(username, ip, frontend, func() (*models.EmailAccount, error) {
+		return verifyMailAccountPassword(username, password, ip)
+	})
+}
+
+// verifyMailAccountPassword 验证邮箱地址与密码，接受登录密码(账户未设置只允许应用专用密码时)或应用专用密码
+func verifyMailAccountPassword(username, password, ip string) (*models.EmailAccount, error) {
+	// 判断邮箱地址是否有效
+	if !utils.IsValidEmail(username) {
+		return nil, errors.New("invalid email address")
+	}
+	ad, err := dao.IsAccountExist(username, strings.Split(username, "@")[1])
+	if err != nil {
+		return nil, err
+	}
+	// 验证密码
+	if !ad.AppPasswordsOnly && utils.CheckPasswordHash(password, ad.PasswordHash) {
+		return ad, nil
+	}
+	if matchAppPassword(ad.ID, password, ip) {
+		return ad, nil
+	}
+	return nil, errors.New("invalid password")
 }
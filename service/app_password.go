@@ -0,0 +1,87 @@
+package service
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// matchAppPassword 逐个比对账户的应用专用密码，匹配时记录使用时间与来源
+func matchAppPassword(accountID uint, password, ip string) bool {
+	password = utils.NormalizeAppPassword(password)
+	if len(password) != 16 {
+		return false
+	}
+	list, err := dao.GetAppPasswords(accountID)
+	if err != nil {
+		return false
+	}
+	for _, p := range list {
+		if utils.CheckPasswordHash(password, p.PasswordHash) {
+			dao.TouchAppPassword(p.ID, ip)
+			return true
+		}
+	}
+	return false
+}
+
+// GetAppPasswordsProcess 获取账户的应用专用密码(不含密码本身)与设置
+func GetAppPasswordsProcess(claims *models.CustomJwtClaims) (*models.AppPasswordList, error) {
+	account, err := dao.GetAccountByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := dao.GetAppPasswords(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AppPasswordList{AppPasswords: list, AppPasswordsOnly: account.AppPasswordsOnly}, nil
+}
+
+// CreateAppPasswordProcess 生成应用专用密码，明文只在此时返回一次
+func CreateAppPasswordProcess(claims *models.CustomJwtClaims, req models.CreateAppPasswordRequest) (*models.AppPasswordCreated, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	list, err := dao.GetAppPasswords(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) >= models.MaxAppPasswordsPerAccount {
+		return nil, fmt.Errorf("an account can have at most %d app passwords", models.MaxAppPasswordsPerAccount)
+	}
+	password := utils.GenerateAppPassword()
+	hash, err := utils.HashPassword(utils.NormalizeAppPassword(password))
+	if err != nil {
+		return nil, err
+	}
+	p := &models.AppPassword{EmailAccountID: claims.UserID, Name: name, PasswordHash: hash}
+	if err := dao.AddAppPassword(p); err != nil {
+		return nil, err
+	}
+	global.Log.Infof("[ %s ] 创建了应用专用密码 [ %s ]", claims.EmailAddress, name)
+	return &models.AppPasswordCreated{AppPassword: *p, Password: password}, nil
+}
+
+// DeleteAppPasswordProcess 撤销应用专用密码，之后使用该密码的客户端无法再认证
+func DeleteAppPasswordProcess(claims *models.CustomJwtClaims, req models.DeleteAppPasswordRequest) error {
+	found, err := dao.DeleteAppPassword(claims.UserID, req.ID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("app password not found")
+	}
+	global.Log.Infof("[ %s ] 撤销了应用专用密码 [ID: %d]", claims.EmailAddress, req.ID)
+	return nil
+}
+
+// UpdateAppPasswordSettingsProcess 设置邮件客户端是否只能使用应用专用密码
+func UpdateAppPasswordSettingsProcess(claims *models.CustomJwtClaims, req models.AppPasswordSettings) error {
+	return dao.UpdateAppPasswordsOnly(claims.UserID, req.AppPasswordsOnly)
+}
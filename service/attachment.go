@@ -0,0 +1,190 @@
+package service
+
+import (
+	"crypto/rand"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"email/utils"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 分享链接默认与最大有效时长
+const (
+	defaultShareLinkHours = 24
+	maxShareLinkHours     = 30 * 24
+)
+
+// SharePathPrefix 公开分享链接的访问路径
+const SharePathPrefix = "/api/v1/share/"
+
+// ErrShareLinkInactive 分享链接已过期或已撤销
+var ErrShareLinkInactive = errors.New("share link has expired or been revoked")
+
+// ErrAttachmentExpired 附件短码已过期且不再被任何邮件引用
+var ErrAttachmentExpired = errors.New("attachment code has expired")
+
+// ErrAttachmentInfected 附件未通过病毒扫描，已被隔离
+var ErrAttachmentInfected = errors.New("attachment failed virus scanning and has been quarantined")
+
+// GetAttachmentDownloadURLProcess 校验访问权限后生成附件下载链接，无权访问时与附件不存在一样返回 gorm.ErrRecordNotFound
+func GetAttachmentDownloadURLProcess(userID uint, code string) (string, error) {
+	att, err := getAccessibleAttachment(userID, code)
+	if err != nil {
+		return "", err
+	}
+	return aws.GeneratePresignedURL(att.S3StoragePath)
+}
+
+// CreateShareLinkProcess 为可访问的附件创建公开分享链接
+func CreateShareLinkProcess(userID uint, req models.CreateShareLinkRequest) (*models.ShareLinkResponse, error) {
+	att, err := getAccessibleAttachment(userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = defaultShareLinkHours
+	}
+	if hours < 0 || hours > maxShareLinkHours {
+		return nil, errors.New("expires_in_hours must be between 1 and 720")
+	}
+	token, err := generateShareToken()
+	if err != nil {
+		return nil, err
+	}
+	link := &models.AttachmentShareLink{
+		Token:          token,
+		AttachmentID:   att.ID,
+		EmailAccountID: userID,
+		ExpiresAt:      time.Now().Add(time.Duration(hours) * time.Hour),
+	}
+	if err := dao.AddAttachmentShareLink(link); err != nil {
+		return nil, err
+	}
+	return &models.ShareLinkResponse{AttachmentShareLink: *link, URL: SharePathPrefix + link.Token}, nil
+}
+
+// GetShareLinksProcess 获取账户创建的分享链接，code 不为空时只返回该附件的链接
+func GetShareLinksProcess(userID uint, code string) ([]models.ShareLinkResponse, error) {
+	var attachmentID uint
+	if code != "" {
+		att, err := getAccessibleAttachment(userID, code)
+		if err != nil {
+			return nil, err
+		}
+		attachmentID = att.ID
+	}
+	links, err := dao.GetAttachmentShareLinks(userID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]models.ShareLinkResponse, 0, len(links))
+	for _, l := range links {
+		list = append(list, models.ShareLinkResponse{AttachmentShareLink: l, URL: SharePathPrefix + l.Token})
+	}
+	return list, nil
+}
+
+// RevokeShareLinkProcess 撤销分享链接
+func RevokeShareLinkProcess(userID, linkID uint) error {
+	return dao.RevokeAttachmentShareLink(userID, linkID)
+}
+
+// OpenShareLinkProcess 通过分享链接获取附件下载地址
+func OpenShareLinkProcess(token string) (string, error) {
+	link, err := dao.GetAttachmentShareLinkByToken(token)
+	if err != nil {
+		return "", err
+	}
+	if !link.IsActive(time.Now()) {
+		return "", ErrShareLinkInactive
+	}
+	att, err := dao.GetAttachmentByID(link.AttachmentID)
+	if err != nil {
+		return "", err
+	}
+	if att.IsBlocked() {
+		return "", attachmentBlockedError(att)
+	}
+	url, err := aws.GeneratePresignedURL(att.S3StoragePath)
+	if err != nil {
+		return "", err
+	}
+	if err := dao.IncrementShareLinkDownloads(link.ID); err != nil {
+		global.Log.Warnf("更新分享链接 [ID: %d] 下载次数失败: %v", link.ID, err)
+	}
+	return url, nil
+}
+
+// authorizeAttachmentCodes 校验发信时引用的附件都可被当前账户访问
+func authorizeAttachmentCodes(userID uint, atts []models.FrontendAttachment) error {
+	for _, a := range atts {
+		if _, err := getAccessibleAttachment(userID, a.Code); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("attachment not found: " + a.Code)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// getAccessibleAttachment 获取账户有权访问的附件
+func getAccessibleAttachment(userID uint, code string) (*models.Attachment, error) {
+	att, err := dao.GetAttachmentByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if att == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	ok, err := dao.CanAccessAttachment(userID, att)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		global.Log.Warnf("账户 [ID: %d] 无权访问附件 [ %s ]", userID, code)
+		return nil, gorm.ErrRecordNotFound
+	}
+	if att.IsBlocked() {
+		return nil, attachmentBlockedError(att)
+	}
+	if att.ExpireTime.Before(time.Now()) {
+		// 仍被邮件或分享链接引用的附件自动延期，否则短码失效，等待回收任务清理
+		referenced, err := dao.HasAttachmentReference(att.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !referenced {
+			return nil, ErrAttachmentExpired
+		}
+		att.ExpireTime = utils.GetAttachmentExpireTime()
+		if err := dao.ExtendAttachmentExpire([]uint{att.ID}, att.ExpireTime); err != nil {
+			global.Log.Warnf("附件 [ %s ] 延期失败: %v", code, err)
+		}
+	}
+	return att, nil
+}
+
+// attachmentBlockedError 返回带病毒名称的隔离错误
+func attachmentBlockedError(att *models.Attachment) error {
+	if att.ScanSignature == "" {
+		return fmt.Errorf("%w [ %s ]", ErrAttachmentInfected, att.FileName)
+	}
+	return fmt.Errorf("%w [ %s: %s ]", ErrAttachmentInfected, att.FileName, att.ScanSignature)
+}
+
+func generateShareToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrAuthLocked 账户或来源 IP 因认证失败次数过多被暂时锁定
+var ErrAuthLocked = errors.New("too many failed login attempts, try again later")
+
+// authenticateWithLockout 所有登录入口(HTTP、SMTP AUTH、CardDAV/CalDAV 及之后的 IMAP/POP)共用的失败统计与锁定
+// 锁定中时不验证密码，失败后按失败次数延迟返回，连续失败达到上限时锁定账户或 IP
+func authenticateWithLockout(address, ip, frontend string, verify func() (*models.EmailAccount, error)) (*models.EmailAccount, error) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if err := checkAuthLockout(address, ip); err != nil {
+		global.Log.Warnf("[ %s ] 登录被拒绝 (%s, %s): %v", address, frontend, ip, err)
+		return nil, err
+	}
+	account, err := verify()
+	if err != nil {
+		recordAuthFailureAttempt(address, ip, frontend)
+		return nil, err
+	}
+	dao.ClearAuthFailures(models.AuthSubjectAccount, address)
+	return account, nil
+}
+
+// checkAuthLockout 账户或 IP 处于锁定中时返回 ErrAuthLocked，查询失败时不阻止登录
+func checkAuthLockout(address, ip string) error {
+	for _, s := range [][2]string{{models.AuthSubjectAccount, address}, {models.AuthSubjectIP, ip}} {
+		if s[1] == "" {
+			continue
+		}
+		l, err := dao.GetAuthLockout(s[0], s[1])
+		if err != nil || l == nil {
+			continue
+		}
+		if l.Locked() {
+			return fmt.Errorf("%w [ locked until %s ]", ErrAuthLocked, l.LockedUntil.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// recordAuthFailureAttempt 记录账户与 IP 的失败次数，达到上限时锁定，并按失败次数延迟返回
+func recordAuthFailureAttempt(address, ip, frontend string) {
+	c := global.Config.AuthLockout
+	window := time.Duration(c.FailureWindowMinutes) * time.Minute
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	failures := 0
+	for _, s := range []struct {
+		kind, subject string
+		max           int
+	}{
+		{models.AuthSubjectAccount, address, c.AccountMaxFailures},
+		{models.AuthSubjectIP, ip, c.IPMaxFailures},
+	} {
+		if s.subject == "" {
+			continue
+		}
+		l, err := dao.RecordAuthFailure(s.kind, s.subject, ip, window)
+		if err != nil {
+			continue
+		}
+		if l.Failures > failures {
+			failures = l.Failures
+		}
+		if s.max <= 0 || l.Failures < s.max {
+			continue
+		}
+		until := time.Now().Add(lockoutDuration(l.Lockouts))
+		locked, err := dao.LockAuthSubject(l.ID, until)
+		if err != nil || !locked {
+			continue
+		}
+		global.Log.Warnf("%s [ %s ] 连续认证失败 %d 次，锁定至 %s (%s, 最后来源 %s)", s.kind, s.subject, l.Failures, until.Format(time.RFC3339), frontend, ip)
+		if s.kind == models.AuthSubjectAccount {
+			go notifyAccountLocked(address, ip, frontend, until)
+		}
+	}
+	time.Sleep(authFailureDelay(failures))
+}
+
+// lockoutDuration 第 lockouts+1 次锁定的时长，每次加倍，不超过最长锁定时长
+func lockoutDuration(lockouts int) time.Duration {
+	c := global.Config.AuthLockout
+	d := time.Duration(c.LockoutMinutes) * time.Minute
+	if d <= 0 {
+		d = 15 * time.Minute
+	}
+	max := time.Duration(c.MaxLockoutMinutes) * time.Minute
+	for i := 0; i < lockouts && (max <= 0 || d < max); i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+// authFailureDelay 第 failures 次失败后的响应延迟，每次加倍
+func authFailureDelay(failures int) time.Duration {
+	c := global.Config.AuthLockout
+	d := time.Duration(c.DelayMilliseconds) * time.Millisecond
+	if d <= 0 || failures <= 0 {
+		return 0
+	}
+	max := time.Duration(c.MaxDelayMilliseconds) * time.Millisecond
+	for i := 1; i < failures && (max <= 0 || d < max); i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+// notifyAccountLocked 在账户的收件箱中保存锁定提醒并推送 account.locked 事件，不存在的账户不通知
+func notifyAccountLocked(address, ip, frontend string, until time.Time) {
+	i := strings.LastIndex(address, "@")
+	if i < 0 {
+		return
+	}
+	account, err := dao.IsAccountExist(address, address[i+1:])
+	if err != nil {
+		return
+	}
+	text := fmt.Sprintf("Your account %s has been temporarily locked after too many failed sign-in attempts.\n\n"+
+		"Last attempt: %s via %s\nLocked until: %s\n\n"+
+		"If these attempts were not made by you, change your password after the lock expires or ask your domain administrator to unlock the account.\n",
+		account.EmailAddress, ip, frontend, until.Format(time.RFC1123Z))
+	if err := aws.StoreNoticeEmail(account, "Your account has been temporarily locked", text); err != nil {
+		global.Log.Errorf("保存账户 [ %s ] 的锁定提醒失败: %v", account.EmailAddress, err)
+	}
+	dao.PublishWebhookEvent(account.ID, account.DomainID, account.EmailAddress, models.WebhookAccountLocked, &models.WebhookAccountLockedData{
+		EmailAddress: account.EmailAddress,
+		Frontend:     frontend,
+		IP:           ip,
+		LockedUntil:  until,
+	})
+}
+
+// GetLockedAccountsProcess 域名管理员获取域名下处于锁定中的账户
+func GetLockedAccountsProcess(emailAddress string) ([]models.AuthLockout, error) {
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	return dao.GetLockedAccounts(domainDetails.DomainName)
+}
+
+// UnlockAccountProcess 域名管理员解除域名下账户的锁定
+func UnlockAccountProcess(emailAddress string, req models.UnlockAccountRequest) error {
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return err
+	}
+	target := strings.ToLower(strings.TrimSpace(req.EmailAddress))
+	if !strings.HasSuffix(target, "@"+strings.ToLower(domainDetails.DomainName)) {
+		return errors.New("The account does not belong to your domain.")
+	}
+	found, err := dao.UnlockAuthSubject(models.AuthSubjectAccount, target)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("The account has no failed login attempts.")
+	}
+	global.Log.Infof("[ %s ] 解除了账户 [ %s ] 的锁定", emailAddress, target)
+	return nil
+}
@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"bufio"
+	"mime"
+	"net/textproto"
+	"strings"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// parseBounceReport 解析 RFC 3464 退信(multipart/report; report-type=delivery-status)中投递失败的收件人与原因
+// 不是退信或只有延迟通知(Action: delayed)时返回 false
+func parseBounceReport(env *enmime.Envelope) ([]string, string, bool) {
+	mediaType, params, err := mime.ParseMediaType(env.GetHeader("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "delivery-status") {
+		return nil, "", false
+	}
+	status := findPart(env.Root, func(p *enmime.Part) bool {
+		return p.ContentType == "message/delivery-status" || p.ContentType == "message/global-delivery-status"
+	})
+	if status == nil {
+		return nil, "", true
+	}
+	var recipients []string
+	var reason string
+	// 第一段为报告本身的字段，之后每段对应一个收件人
+	content := strings.ReplaceAll(string(status.Content), "\r\n", "\n")
+	for _, block := range strings.Split(content, "\n\n") {
+		fields, err := textproto.NewReader(bufio.NewReader(strings.NewReader(strings.TrimSpace(block) + "\n\n"))).ReadMIMEHeader()
+		if err != nil && len(fields) == 0 {
+			continue
+		}
+		if !strings.EqualFold(strings.TrimSpace(fields.Get("Action")), "failed") {
+			continue
+		}
+		recipient := fields.Get("Final-Recipient")
+		if recipient == "" {
+			recipient = fields.Get("Original-Recipient")
+		}
+		// 格式为 "rfc822; user@example.com"
+		if i := strings.IndexByte(recipient, ';'); i >= 0 {
+			recipient = recipient[i+1:]
+		}
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+		if reason == "" {
+			reason = dsnFieldValue(fields.Get("Diagnostic-Code"))
+			if reason == "" {
+				reason = strings.TrimSpace(fields.Get("Status"))
+			}
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, "", false
+	}
+	return recipients, reason, true
+}
+
+// dsnFieldValue 去掉 "smtp; 550 ..." 中的类型前缀
+func dsnFieldValue(v string) string {
+	if i := strings.IndexByte(v, ';'); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// findPart 深度优先查找满足条件的部分
+func findPart(p *enmime.Part, match func(*enmime.Part) bool) *enmime.Part {
+	for ; p != nil; p = p.NextSibling {
+		if match(p) {
+			return p
+		}
+		if found := findPart(p.FirstChild, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// dsnMessage 构造 multipart/report 退信，status 为 message/delivery-status 部分的内容
+func dsnMessage(status string) string {
+	return "From: MAILER-DAEMON@mx.example.com\r\n" +
+		"To: user@mail.example\r\n" +
+		"Subject: Delivery Status Notification\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Your message could not be delivered.\r\n" +
+		"--b1\r\n" +
+		"Content-Type: message/delivery-status\r\n" +
+		"\r\n" +
+		strings.ReplaceAll(status, "\n", "\r\n") +
+		"--b1\r\n" +
+		"Content-Type: text/rfc822-headers\r\n" +
+		"\r\n" +
+		"From: user@mail.example\r\n" +
+		"Subject: hello\r\n" +
+		"--b1--\r\n"
+}
+
+func TestParseBounceReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		want   []string
+		reason string
+		ok     bool
+	}{
+		{
+			name: "permanent failure",
+			raw: dsnMessage("Reporting-MTA: dns; mx.example.com\n\n" +
+				"Final-Recipient: rfc822; bob@example.com\nAction: failed\nStatus: 5.1.1\n" +
+				"Diagnostic-Code: smtp; 550 5.1.1 <bob@example.com>: user unknown\n\n" +
+				"Original-Recipient: rfc822;carol@example.com\nAction: failed\nStatus: 5.2.2\n\n"),
+			want:   []string{"bob@example.com", "carol@example.com"},
+			reason: "550 5.1.1 <bob@example.com>: user unknown",
+			ok:     true,
+		},
+		{
+			name: "failure without diagnostic code",
+			raw: dsnMessage("Reporting-MTA: dns; mx.example.com\n\n" +
+				"Final-Recipient: rfc822; bob@example.com\nAction: Failed\nStatus: 5.7.1\n"),
+			want:   []string{"bob@example.com"},
+			reason: "5.7.1",
+			ok:     true,
+		},
+		{
+			name: "transient delay",
+			raw: dsnMessage("Reporting-MTA: dns; mx.example.com\n\n" +
+				"Final-Recipient: rfc822; bob@example.com\nAction: delayed\nStatus: 4.4.1\n" +
+				"Diagnostic-Code: smtp; 421 4.4.1 connection timed out\n" +
+				"Will-Retry-Until: Tue, 02 Jan 2024 03:04:05 +0000\n"),
+			ok: false,
+		},
+		{
+			name: "mixed delay and failure",
+			raw: dsnMessage("Reporting-MTA: dns; mx.example.com\n\n" +
+				"Final-Recipient: rfc822; slow@example.com\nAction: delayed\nStatus: 4.4.7\n\n" +
+				"Final-Recipient: rfc822; gone@example.com\nAction: failed\nStatus: 5.1.1\n" +
+				"Diagnostic-Code: smtp; 550 no such user\n"),
+			want:   []string{"gone@example.com"},
+			reason: "550 no such user",
+			ok:     true,
+		},
+		{
+			name: "not a report",
+			raw:  "From: alice@example.com\r\nTo: user@mail.example\r\nSubject: hi\r\nContent-Type: text/plain\r\n\r\nAction: failed\r\nStatus: 5.1.1\r\n",
+			ok:   false,
+		},
+		{
+			name: "other report type",
+			raw: "From: alice@example.com\r\nMIME-Version: 1.0\r\n" +
+				"Content-Type: multipart/report; report-type=disposition-notification; boundary=\"b1\"\r\n\r\n" +
+				"--b1\r\nContent-Type: text/plain\r\n\r\nread\r\n--b1--\r\n",
+			ok: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := enmime.ReadEnvelope(strings.NewReader(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, reason, ok := parseBounceReport(env)
+			if ok != tt.ok || reason != tt.reason || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseBounceReport() = %v, %q, %v, want %v, %q, %v", got, reason, ok, tt.want, tt.reason, tt.ok)
+			}
+		})
+	}
+}
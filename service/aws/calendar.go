@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"strings"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// CalendarInviteProcessor 将收到的日历邀请 (text/calendar) 同步到账户日历，CANCEL 会删除对应事件
+// 发件人必须是事件的 ORGANIZER，且只能更新或取消由同一组织者的邀请创建的事件，CalDAV 创建的事件不受邮件影响
+func CalendarInviteProcessor(accountData *models.EmailAccount, env *enmime.Envelope) {
+	if env.Root == nil {
+		return
+	}
+	part := env.Root.BreadthMatchFirst(func(p *enmime.Part) bool {
+		return strings.EqualFold(p.ContentType, "text/calendar")
+	})
+	if part == nil {
+		return
+	}
+	data := stripICalMethod(string(part.Content))
+	ev, err := utils.ParseICalendar(string(part.Content))
+	if err != nil {
+		global.Log.Warnf("账户 [ %s ] 日历邀请解析失败: %v", accountData.EmailAddress, err)
+		return
+	}
+	sender := strings.ToLower(strings.TrimSpace(utils.ParseFromEmailAddress(env.GetHeader("From")).Address))
+	if ev.Organizer == "" || ev.Organizer != sender {
+		global.Log.Warnf("账户 [ %s ] 日历邀请 [ %s ] 的组织者 [ %s ] 与发件人 [ %s ] 不一致，已忽略", accountData.EmailAddress, ev.UID, ev.Organizer, sender)
+		return
+	}
+	// 资源名不能包含路径分隔符
+	uid := strings.ReplaceAll(ev.UID, "/", "_")
+	applied := false
+	switch ev.Method {
+	case "CANCEL":
+		applied, err = dao.DeleteInvitedCalendarEvent(accountData.ID, uid, ev.Organizer)
+	case "REQUEST", "PUBLISH", "":
+		applied, err = dao.SaveInvitedCalendarEvent(&models.CalendarEvent{
+			EmailAccountID: accountData.ID,
+			UID:            uid,
+			Summary:        ev.Summary,
+			StartAt:        ev.Start,
+			EndAt:          ev.End,
+			Recurring:      ev.Recurring,
+			Data:           data,
+			Organizer:      ev.Organizer,
+		})
+	default:
+		// REPLY、COUNTER 等不改变本账户的日历
+		return
+	}
+	if err != nil {
+		global.Log.Errorf("账户 [ %s ] 同步日历邀请 [ %s ] 失败: %v", accountData.EmailAddress, ev.UID, err)
+		return
+	}
+	if !applied {
+		global.Log.Warnf("账户 [ %s ] 日历邀请 [ %s ] (%s) 对应的事件不存在或不是来自 [ %s ] 的邀请，已忽略", accountData.EmailAddress, ev.UID, ev.Method, ev.Organizer)
+		return
+	}
+	global.Log.Infof("账户 [ %s ] 已同步日历邀请 [ %s ] (%s)", accountData.EmailAddress, ev.UID, ev.Method)
+}
+
+// stripICalMethod 去掉 METHOD 属性，CalDAV 中保存的日历对象不能包含 METHOD (RFC 4791 4.1)
+func stripICalMethod(data string) string {
+	lines := strings.SplitAfter(data, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if strings.HasPrefix(strings.ToUpper(l), "METHOD:") {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return strings.Join(kept, "")
+}
@@ -0,0 +1,78 @@
+package aws
+
+import (
+	"bytes"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// storeDeliveryReport 在发件人的收件箱中保存投递状态通知
+func storeDeliveryReport(account *models.EmailAccount, report *models.DeliveryReport) error {
+	report.ReportingMTA, report.To = account.DomainName, account.EmailAddress
+	raw, _ := utils.GenerateDeliveryReport(report)
+	return storeNoticeEmail(account, []byte(raw))
+}
+
+// StoreNoticeEmail 以系统名义在账户的收件箱中保存一封通知邮件(如账户锁定提醒)
+func StoreNoticeEmail(account *models.EmailAccount, subject, text string) error {
+	raw, _ := utils.GenerateEmailRawMessage(&models.EmailContent{
+		From:     fmt.Sprintf("Mail System <MAILER-DAEMON@%s>", account.DomainName),
+		To:       account.EmailAddress,
+		Subject:  subject,
+		TextBody: text,
+		HtmlBody: "<pre>" + html.EscapeString(text) + "</pre>",
+		Headers:  []models.EmailHeader{{Name: "Auto-Submitted", Value: "auto-generated"}},
+	})
+	return storeNoticeEmail(account, []byte(raw))
+}
+
+// storeNoticeEmail 保存原文并投递到账户的收件箱
+func storeNoticeEmail(account *models.EmailAccount, rawMessage []byte) error {
+	env, err := enmime.ReadEnvelope(bytes.NewReader(rawMessage))
+	if err != nil {
+		return err
+	}
+	key, err := saveRawEmail(NoticeEmailKeyPrefix, rawMessage)
+	if err != nil {
+		return err
+	}
+	opts := storeOptions{EmailType: global.EmailTypeInbox, ReceivedAt: time.Now()}
+	if err := appendEmailToServer(account, rawMessage, env, opts); err != nil {
+		return err
+	}
+	r, err := insertEmailRecord(account, key, global.Config.AWS.S3Bucket, GetEmailFileHash(env), env, account.EmailAddress, &models.Recipients{To: []string{account.EmailAddress}}, opts)
+	if err != nil {
+		return err
+	}
+	dao.PublishNewEmailEvent(r)
+	return nil
+}
+
+// rawHeaderBlock 返回原始邮件的邮件头部分
+func rawHeaderBlock(raw []byte) string {
+	raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		raw = raw[:i]
+	}
+	return string(raw)
+}
+
+// sentEmailHeaders 根据已保存的发出邮件生成原邮件的主要邮件头，用于没有原文的情况
+func sentEmailHeaders(sent *models.EmailDetails) string {
+	headers := fmt.Sprintf("From: %s\nTo: %s\n", sent.SenderEmail, sent.RecipientEmail)
+	if sent.Cc != "" {
+		headers += fmt.Sprintf("Cc: %s\n", sent.Cc)
+	}
+	headers += fmt.Sprintf("Subject: %s\nDate: %s\n", sent.Subject, sent.ReceivedAt.Format(time.RFC1123Z))
+	if sent.EmailMessageID != "" {
+		headers += fmt.Sprintf("Message-ID: %s\n", sent.EmailMessageID)
+	}
+	return headers
+}
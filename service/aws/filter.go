@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/filter"
+	"email/utils"
+	"fmt"
+	"strings"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// EvaluateFilterRules 执行账户的过滤规则，只计算结果，不执行转发与拒收动作
+// 规则读取失败时按默认方式投递，避免丢信
+func EvaluateFilterRules(accountData *models.EmailAccount, env *enmime.Envelope, rawMessage []byte) *filter.Result {
+	rules, err := dao.GetFilterRules(accountData.ID)
+	if err != nil || len(rules) == 0 {
+		return &filter.Result{}
+	}
+	res := filter.Evaluate(rules, filter.NewMessageFromEnvelope(env, len(rawMessage)))
+	if len(res.Matched) > 0 {
+		global.Log.Infof("邮件 [ %s ] 命中账户 [ %s ] 的过滤规则 %v", env.GetHeader("Message-Id"), accountData.EmailAddress, res.Matched)
+	}
+	return res
+}
+
+// FilterActionProcessor 执行过滤规则结果中的转发与拒收动作
+func FilterActionProcessor(accountData *models.EmailAccount, env *enmime.Envelope, rawMessage []byte, res *filter.Result) {
+	for _, to := range res.Forward {
+		go ForwardRawEmail(accountData, env, rawMessage, to)
+	}
+	if res.Reject != "" {
+		go sendFilterRejection(accountData, env, res.Reject)
+	}
+}
+
+// sendFilterRejection 向发件人发送拒收通知，自动发送的邮件不回复以免形成循环
+func sendFilterRejection(accountData *models.EmailAccount, env *enmime.Envelope, reason string) {
+	if r := utils.IsAutoReplySuppressed(env.GetHeader); r != "" {
+		global.Log.Infof("邮件 [ %s ] 不发送拒收通知: %s", env.GetHeader("Message-Id"), r)
+		return
+	}
+	to := strings.TrimSpace(utils.ParseFromEmailAddress(env.GetHeader("Return-Path")).Address)
+	if to == "" {
+		to = strings.TrimSpace(utils.ParseFromEmailAddress(env.GetHeader("From")).Address)
+	}
+	if to == "" || strings.EqualFold(to, accountData.EmailAddress) || utils.IsAutoReplyIgnoredSender(to) {
+		return
+	}
+	if err := CheckSuppressedRecipients(accountData.DomainID, []string{to}); err != nil {
+		global.Log.Infof("账户 [ %s ] 不发送拒收通知: %v", accountData.EmailAddress, err)
+		return
+	}
+	headers := []models.EmailHeader{{Name: "Auto-Submitted", Value: "auto-replied"}}
+	if msgID := env.GetHeader("Message-Id"); msgID != "" {
+		headers = append(headers, models.EmailHeader{Name: "In-Reply-To", Value: msgID})
+	}
+	body := fmt.Sprintf("Your message to %s was rejected.\n\nReason: %s\n\nOriginal subject: %s\n",
+		accountData.EmailAddress, reason, env.GetHeader("Subject"))
+	raw, _ := utils.GenerateEmailRawMessage(&models.EmailContent{
+		From:     fmt.Sprintf("%s <%s>", accountData.UserName, accountData.EmailAddress),
+		To:       to,
+		Subject:  "Rejected: " + env.GetHeader("Subject"),
+		TextBody: body,
+		HtmlBody: strings.ReplaceAll(body, "\n", "<br>"),
+		Headers:  headers,
+	})
+	if _, err := SendEmailByAwsSesWithRawMessage([]byte(raw), accountData.EmailAddress, []string{to}, nil, nil); err != nil {
+		global.Log.Errorf("账户 [ %s ] 发送拒收通知至 [ %s ] 失败: %v", accountData.EmailAddress, to, err)
+	}
+}
@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// ErrForwardingLoop 邮件已经过本账户转发
+var ErrForwardingLoop = errors.New("forwarding loop detected")
+
+// 转发时需移除的头部，原签名与退信地址在改写发件人后不再有效
+var forwardDropHeaders = []string{"Return-Path", "DKIM-Signature", "Sender", "From", "Reply-To"}
+
+// ForwardingProcessor 按账户的自动转发设置转发邮件，返回是否仍需在本地保存
+// 转发失败或检测到环路时始终保留本地副本，避免丢信
+func ForwardingProcessor(accountData *models.EmailAccount, env *enmime.Envelope, rawMessage []byte) bool {
+	rule, err := dao.GetForwardingRule(accountData.ID)
+	if err != nil || rule == nil || !rule.Enabled || rule.ForwardTo == "" {
+		return true
+	}
+	if err := ForwardRawEmail(accountData, env, rawMessage, rule.ForwardTo); err != nil {
+		return true
+	}
+	return rule.KeepCopy
+}
+
+// ForwardRawEmail 以账户身份转发原始邮件，原发件人写入 Reply-To，退信与投诉通知发送至账户本身
+// SES 要求 From 为已验证身份且不支持指定信封发件人，因此不进行 SRS 改写，SPF 按 SES 或自定义 MAIL FROM 域名校验
+func ForwardRawEmail(accountData *models.EmailAccount, env *enmime.Envelope, rawMessage []byte, to string) error {
+	if IsForwardingLoop(accountData.EmailAddress, env) {
+		global.Log.Warnf("账户 [ %s ] 转发邮件 [ %s ] 时检测到环路，已停止转发", accountData.EmailAddress, env.GetHeader("Message-Id"))
+		return ErrForwardingLoop
+	}
+	if err := CheckSuppressedRecipients(accountData.DomainID, []string{to}); err != nil {
+		global.Log.Warnf("账户 [ %s ] 不转发邮件至 [ %s ]: %v", accountData.EmailAddress, to, err)
+		return err
+	}
+	from := utils.ParseFromEmailAddress(env.GetHeader("From"))
+	displayName := strings.Trim(strings.TrimSpace(from.DisplayName), `"`)
+	if displayName == "" {
+		displayName = from.Address
+	}
+	replyTo := from
+	if env.GetHeader("Reply-To") != "" {
+		replyTo = utils.ParseFromEmailAddress(env.GetHeader("Reply-To"))
+	}
+	// mail.Address 会对非 ASCII 名称进行 RFC 2047 编码
+	headers := []models.EmailHeader{
+		{Name: "Delivered-To", Value: accountData.EmailAddress},
+		{Name: "From", Value: (&mail.Address{Name: displayName + " via " + accountData.EmailAddress, Address: accountData.EmailAddress}).String()},
+		{Name: "Reply-To", Value: (&mail.Address{Name: strings.Trim(strings.TrimSpace(replyTo.DisplayName), `"`), Address: strings.TrimSpace(replyTo.Address)}).String()},
+		{Name: "X-Forwarded-For", Value: accountData.EmailAddress + " " + to},
+	}
+	raw := utils.RewriteHeaders(rawMessage, forwardDropHeaders, headers)
+	_, err := SendEmailByAwsSesWithRawMessage(raw, accountData.EmailAddress, []string{to}, nil, nil)
+	if err != nil {
+		global.Log.Errorf("账户 [ %s ] 转发邮件至 [ %s ] 失败: %v", accountData.EmailAddress, to, err)
+		return err
+	}
+	global.Log.Infof("账户 [ %s ] 已转发邮件至 [ %s ]", accountData.EmailAddress, to)
+	return nil
+}
+
+// IsForwardingLoop 邮件的 Delivered-To 中已包含本账户，说明已经转发过一次
+func IsForwardingLoop(emailAddress string, env *enmime.Envelope) bool {
+	for _, v := range env.GetHeaderValues("Delivered-To") {
+		if strings.EqualFold(strings.Trim(strings.TrimSpace(v), "<>"), emailAddress) {
+			return true
+		}
+	}
+	return false
+}
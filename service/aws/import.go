@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"bytes"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhillyerd/enmime"
+)
+
+// ImportOptions 导入邮件时的分类与状态
+type ImportOptions struct {
+	EmailType string
+	Read      bool
+	Flagged   bool
+	Date      time.Time // Date 头部无法解析时使用的时间，为零值时使用当前时间
+}
+
+// ImportRawEmail 将一封原始邮件导入账户，复用收信的保存流程但不执行过滤、转发与自动回复
+// 按邮件哈希去重，已存在时返回 false
+func ImportRawEmail(accountData *models.EmailAccount, raw []byte, opts ImportOptions) (bool, error) {
+	env, err := enmime.ReadEnvelope(bytes.NewReader(raw))
+	if err != nil {
+		return false, fmt.Errorf("解析邮件失败: %w", err)
+	}
+	if env.GetHeader("Message-ID") == "" {
+		messageID := fmt.Sprintf("<%s@%s>", uuid.New().String(), utils.MailHostname())
+		raw = utils.RewriteHeaders(raw, nil, []models.EmailHeader{{Name: "Message-ID", Value: messageID}})
+		if err := env.AddHeader("Message-ID", messageID); err != nil {
+			return false, err
+		}
+	}
+	hash := GetEmailFileHash(env)
+	exist, err := dao.IsEmailExistByHash(accountData.ID, hash)
+	if err != nil {
+		return false, err
+	}
+	if exist {
+		return false, nil
+	}
+	receivedAt, err := utils.ParseTime(decodeText(env.GetHeader("Date")))
+	if err != nil {
+		if receivedAt = opts.Date; receivedAt.IsZero() {
+			receivedAt = time.Now()
+		}
+	}
+	key, err := saveRawEmail(ImportedEmailKeyPrefix, raw)
+	if err != nil {
+		return false, err
+	}
+	recipients := &models.Recipients{
+		To: utils.ParseAddressList(env.GetHeaderValues("To")),
+		Cc: utils.ParseAddressList(env.GetHeaderValues("Cc")),
+	}
+	err = storeEmail(accountData, key, global.Config.AWS.S3Bucket, &raw, hash, env, accountData.EmailAddress, recipients, storeOptions{
+		EmailType:  opts.EmailType,
+		Read:       opts.Read,
+		Flagged:    opts.Flagged,
+		ReceivedAt: receivedAt,
+	})
+	if err != nil {
+		if derr := DeleteS3Object(key); derr != nil {
+			global.Log.Warnf("删除导入失败的邮件原文 [ %s ] 失败: %v", key, derr)
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,358 @@
+package aws
+
+import (
+	"bytes"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhillyerd/enmime"
+	"gorm.io/gorm"
+)
+
+// 未配置时的最大尝试次数
+const defaultIngestMaxAttempts = 5
+
+// ingestError 收信处理失败的阶段与原因，写入死信记录
+type ingestError struct {
+	Step      string
+	Reason    string
+	Recipient string
+	Err       error
+}
+
+func (e *ingestError) Error() string {
+	if e.Recipient != "" {
+		return fmt.Sprintf("%s/%s [ %s ]: %v", e.Step, e.Reason, e.Recipient, e.Err)
+	}
+	return fmt.Sprintf("%s/%s: %v", e.Step, e.Reason, e.Err)
+}
+
+func (e *ingestError) Unwrap() error {
+	return e.Err
+}
+
+func maxIngestAttempts() int {
+	if global.Config.Ingest.MaxAttempts > 0 {
+		return global.Config.Ingest.MaxAttempts
+	}
+	return defaultIngestMaxAttempts
+}
+
+// IngestS3Email 处理 SES 保存在 S3 中的邮件，返回错误时由 SQS 重新投递
+// 已处理完成或已转入死信的邮件直接返回 nil
+func IngestS3Email(key string, recipients *models.Recipients) error {
+	recipientsJSON, _ := json.Marshal(recipients)
+	domainsJSON, _ := json.Marshal(recipientDomains(recipients.Recipients))
+	m, err := dao.GetOrCreateInboundMessage(&models.InboundMessage{
+		S3Key:      key,
+		Recipients: recipientsJSON,
+		Domains:    domainsJSON,
+		Status:     models.InboundPending,
+		Stage:      models.IngestStageReceived,
+	})
+	if err != nil {
+		return err
+	}
+	if m.Status != models.InboundPending {
+		global.Log.Infof("邮件 [ %s ] 已处理(%s)，跳过", key, m.Status)
+		return nil
+	}
+	return runIngest(m, true)
+}
+
+// ReprocessInboundMessage 重新处理死信对应的邮件，从各收件人记录的阶段继续，再次失败时直接转入死信
+func ReprocessInboundMessage(id uint) error {
+	m, err := dao.GetInboundMessage(id)
+	if err != nil {
+		return err
+	}
+	if m.Status != models.InboundPending {
+		return nil
+	}
+	return runIngest(m, false)
+}
+
+// runIngest 执行处理流程并记录结果，retryable 为 false 或超过最大尝试次数时转入死信
+func runIngest(m *models.InboundMessage, retryable bool) error {
+	err := ingestMessage(m)
+	if err == nil {
+		if err := dao.CompleteInboundMessage(m.ID); err != nil {
+			global.Log.Errorf("更新邮件 [ %s ] 处理状态失败: %v", m.S3Key, err)
+			return err
+		}
+		global.Log.Infof("邮件 [ %s ] 处理完成", m.S3Key)
+		return nil
+	}
+	var ie *ingestError
+	if !errors.As(err, &ie) {
+		ie = &ingestError{Step: m.Stage, Reason: "internal_error", Err: err}
+	}
+	m.Attempts++
+	dead := !retryable || m.Attempts >= maxIngestAttempts()
+	letter := &models.InboundDeadLetter{
+		InboundMessageID: m.ID,
+		S3Key:            m.S3Key,
+		MessageID:        m.MessageID,
+		Domains:          m.Domains,
+		Recipient:        ie.Recipient,
+		Step:             ie.Step,
+		Reason:           ie.Reason,
+		Error:            ie.Err.Error(),
+		Attempts:         m.Attempts,
+		Status:           models.DeadLetterOpen,
+	}
+	if rerr := dao.RecordInboundFailure(m, letter, dead); rerr != nil {
+		global.Log.Errorf("记录邮件 [ %s ] 处理失败原因失败: %v", m.S3Key, rerr)
+		return err
+	}
+	if dead {
+		global.Log.Errorf("邮件 [ %s ] 第 %d 次处理失败，已转入死信: %v", m.S3Key, m.Attempts, err)
+		return nil
+	}
+	global.Log.Warnf("邮件 [ %s ] 第 %d 次处理失败，等待重试: %v", m.S3Key, m.Attempts, err)
+	return err
+}
+
+// ingestMessage 读取并规范化邮件，然后依次处理各收件人
+func ingestMessage(m *models.InboundMessage) error {
+	raw, err := GetS3ObjectBytes(m.S3Key)
+	if err != nil {
+		return &ingestError{Step: models.IngestStepFetch, Reason: "s3_fetch_failed", Err: err}
+	}
+	env, raw, err := normalizeInbound(m, raw)
+	if err != nil {
+		return &ingestError{Step: models.IngestStepNormalize, Reason: "parse_failed", Err: err}
+	}
+	var recipients models.Recipients
+	if err := json.Unmarshal(m.Recipients, &recipients); err != nil {
+		return &ingestError{Step: models.IngestStepNormalize, Reason: "parse_failed", Err: err}
+	}
+	if m.Stage == models.IngestStageReceived {
+		if err := dao.SaveInboundNormalized(m, newInboundDeliveries(m.ID, recipients.Recipients)); err != nil {
+			return &ingestError{Step: models.IngestStepNormalize, Reason: "state_save_failed", Err: err}
+		}
+	}
+	for i := range m.Deliveries {
+		d := &m.Deliveries[i]
+		if d.Stage == models.IngestStageDone || d.Stage == models.IngestStageSkipped {
+			continue
+		}
+		if err := deliverInbound(m, d, env, raw, &recipients); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeInbound 解析邮件并补全 Message-ID，生成的 Message-ID 保存在处理记录中，重试时保持一致
+func normalizeInbound(m *models.InboundMessage, raw []byte) (*enmime.Envelope, []byte, error) {
+	env, err := enmime.ReadEnvelope(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	if env.GetHeader("Message-ID") == "" {
+		if m.MessageID == "" {
+			m.MessageID = fmt.Sprintf("<%s@%s>", uuid.New().String(), utils.MailHostname())
+		}
+		raw = utils.RewriteHeaders(raw, nil, []models.EmailHeader{{Name: "Message-ID", Value: m.MessageID}})
+		if err := env.AddHeader("Message-ID", m.MessageID); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		m.MessageID = env.GetHeader("Message-ID")
+	}
+	m.EmailHash = GetEmailFileHash(env)
+	return env, raw, nil
+}
+
+// newInboundDeliveries 为每个收件人生成投递记录，重复的地址只投递一次
+func newInboundDeliveries(messageID uint, addresses []string) []models.InboundDelivery {
+	seen := make(map[string]bool)
+	var deliveries []models.InboundDelivery
+	for _, address := range addresses {
+		if address == "" || seen[address] {
+			continue
+		}
+		seen[address] = true
+		deliveries = append(deliveries, models.InboundDelivery{
+			InboundMessageID: messageID,
+			Recipient:        address,
+			Stage:            models.IngestStageReceived,
+		})
+	}
+	return deliveries
+}
+
+// deliverInbound 从收件人记录的阶段继续投递，每完成一个阶段即保存进度
+func deliverInbound(m *models.InboundMessage, d *models.InboundDelivery, env *enmime.Envelope, raw []byte, recipients *models.Recipients) error {
+	var accountData *models.EmailAccount
+	var err error
+	if d.Stage == models.IngestStageReceived {
+		if accountData, err = routeInbound(m, d, env, raw); err != nil {
+			return err
+		}
+		if err := saveInboundDelivery(d, models.IngestStepRoute); err != nil {
+			return err
+		}
+		if d.Stage == models.IngestStageSkipped {
+			return nil
+		}
+	}
+	if d.Stage == models.IngestStageRouting {
+		// 转发与过滤规则的动作可能已执行，不再重复；结果未知，按规则结果保存本地副本以免丢信
+		global.Log.Warnf("邮件 [ %s ] 上次路由至 [ %s ] 时中断，不再执行转发与过滤动作，保存本地副本", m.S3Key, d.Recipient)
+		d.Stage = models.IngestStageRouted
+		if err := saveInboundDelivery(d, models.IngestStepRoute); err != nil {
+			return err
+		}
+	}
+	if accountData == nil {
+		if accountData, err = dao.GetAccountByID(d.EmailAccountID); err != nil {
+			return &ingestError{Step: models.IngestStepRoute, Reason: "account_lookup_failed", Recipient: d.Recipient, Err: err}
+		}
+	}
+	opts := storeOptions{
+		EmailType:  d.EmailType,
+		Read:       d.MarkRead,
+		Flagged:    d.Flagged,
+		ReceivedAt: inboundReceivedAt(env),
+	}
+	if d.Stage == models.IngestStageRouted {
+		if err := appendEmailToServer(accountData, raw, env, opts); err != nil {
+			return &ingestError{Step: models.IngestStepAppend, Reason: "imap_append_failed", Recipient: d.Recipient, Err: err}
+		}
+		d.Stage = models.IngestStageAppended
+		if err := saveInboundDelivery(d, models.IngestStepAppend); err != nil {
+			return err
+		}
+	}
+	if d.Stage == models.IngestStageAppended {
+		// 上次写入成功但未能保存进度时，数据库中已有该邮件
+		exist, err := dao.IsEmailExistByHash(accountData.ID, m.EmailHash)
+		if err != nil {
+			return &ingestError{Step: models.IngestStepStore, Reason: "db_query_failed", Recipient: d.Recipient, Err: err}
+		}
+		if !exist {
+			r, err := insertEmailRecord(accountData, m.S3Key, global.Config.AWS.S3Bucket, m.EmailHash, env, d.Recipient, recipients, opts)
+			if err != nil {
+				return &ingestError{Step: models.IngestStepStore, Reason: "db_insert_failed", Recipient: d.Recipient, Err: err}
+			}
+			dao.PublishNewEmailEvent(r)
+			if bounced, reason, ok := parseBounceReport(env); ok {
+				data := models.NewWebhookMessageData(r)
+				data.BouncedRecipients, data.BounceReason = bounced, reason
+				dao.PublishWebhookEvent(accountData.ID, accountData.DomainID, accountData.EmailAddress, models.WebhookMessageBounced, data)
+			}
+		}
+		d.Stage = models.IngestStageStored
+		if err := saveInboundDelivery(d, models.IngestStepStore); err != nil {
+			return err
+		}
+	}
+	if d.Stage == models.IngestStageStored {
+		// 同步日历邀请
+		CalendarInviteProcessor(accountData, env)
+		//自动回复，被规则移出收件箱的邮件不回复
+		if d.EmailType == global.EmailTypeInbox {
+			go VacationAutoReplyProcessor(accountData, env, recipients)
+		}
+		d.Stage, d.Outcome = models.IngestStageDone, "delivered"
+		if err := saveInboundDelivery(d, models.IngestStepPost); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeInbound 确认收件账户并执行转发与过滤规则，结果写入投递记录
+// 转发与过滤规则的动作会对外发信，执行前先保存 routing 阶段与规则结果，重试时不再重复执行
+// 无需投递时将阶段设为 skipped 并记录原因
+func routeInbound(m *models.InboundMessage, d *models.InboundDelivery, env *enmime.Envelope, raw []byte) (*models.EmailAccount, error) {
+	address := d.Recipient
+	skip := func(outcome string) (*models.EmailAccount, error) {
+		d.Stage, d.Outcome = models.IngestStageSkipped, outcome
+		return nil, nil
+	}
+	accountData, err := dao.IsAccountExist(address, address[strings.LastIndex(address, "@")+1:])
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		global.Log.Warnf("账户 [ %s ] 不存在，将从收件人列表中移除", address)
+		return skip("no_account")
+	}
+	if err != nil {
+		return nil, &ingestError{Step: models.IngestStepRoute, Reason: "account_lookup_failed", Recipient: address, Err: err}
+	}
+	emailExist, err := dao.IsEmailExistByHash(accountData.ID, m.EmailHash)
+	if err != nil {
+		return nil, &ingestError{Step: models.IngestStepRoute, Reason: "db_query_failed", Recipient: address, Err: err}
+	}
+	if emailExist {
+		global.Log.Warnf("[%s]邮件已存在，将从收件人列表中移除", address)
+		return skip("duplicate")
+	}
+	//执行过滤规则
+	filterResult := EvaluateFilterRules(accountData, env, raw)
+	d.EmailAccountID = accountData.ID
+	d.EmailType = filterResult.TargetFolder()
+	d.MarkRead = filterResult.MarkRead
+	d.Flagged = filterResult.Flag
+	d.Stage = models.IngestStageRouting
+	if err := saveInboundDelivery(d, models.IngestStepRoute); err != nil {
+		return nil, err
+	}
+	//自动转发，不保留副本时不再保存到本地
+	if !ForwardingProcessor(accountData, env, raw) {
+		global.Log.Infof("账户 [ %s ] 的邮件已转发且不保留副本", address)
+		return skip("forwarded")
+	}
+	FilterActionProcessor(accountData, env, raw, filterResult)
+	if !filterResult.Store() {
+		global.Log.Infof("邮件 [ %s ] 已被账户 [ %s ] 的过滤规则丢弃或拒收", env.GetHeader("Message-Id"), address)
+		return skip("filtered")
+	}
+	d.Stage = models.IngestStageRouted
+	return accountData, nil
+}
+
+// saveInboundDelivery 保存收件人的投递进度
+func saveInboundDelivery(d *models.InboundDelivery, step string) error {
+	if err := dao.UpdateInboundDelivery(d); err != nil {
+		return &ingestError{Step: step, Reason: "state_save_failed", Recipient: d.Recipient, Err: err}
+	}
+	return nil
+}
+
+// inboundReceivedAt 解析邮件的 Date 头部，无法解析时使用当前时间
+func inboundReceivedAt(env *enmime.Envelope) time.Time {
+	p, err := utils.ParseTime(decodeText(env.GetHeader("Date")))
+	if err != nil {
+		global.Log.Warnf("解析邮件 [ %s ] 时间失败，使用当前时间: %v", env.GetHeader("Message-Id"), err)
+		return time.Now()
+	}
+	return p
+}
+
+// recipientDomains 收件人地址所属的域名(小写、去重)
+func recipientDomains(addresses []string) []string {
+	seen := make(map[string]bool)
+	domains := []string{}
+	for _, address := range addresses {
+		i := strings.LastIndex(address, "@")
+		if i < 0 {
+			continue
+		}
+		domain := strings.ToLower(address[i+1:])
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
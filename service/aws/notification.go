@@ -0,0 +1,155 @@
+package aws
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"strings"
+)
+
+// bouncedRecipient 退信通知中的一个收件人
+type bouncedRecipient struct {
+	Address        string
+	Status         string
+	DiagnosticCode string
+	Diagnostic     string // 去掉类型前缀的诊断信息
+}
+
+// ProcessSESNotification 处理 SES 的退信、投诉、投递与延迟通知，按 SES 消息 ID 更新发出邮件各收件人的投递状态
+// 未记录投递状态的邮件(转发、自动回复等)直接忽略
+func ProcessSESNotification(event *models.AwsSnsEvent) error {
+	sesMessageID := event.Mail.MessageId
+	statuses, err := dao.GetDeliveryStatuses(sesMessageID)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		global.Log.Infof("SES 消息 [ %s ] 没有对应的发出邮件，忽略 %s 通知", sesMessageID, event.Type())
+		return nil
+	}
+	switch event.Type() {
+	case models.SesNotificationBounce:
+		var bounced []bouncedRecipient
+		for _, r := range event.Bounce.BouncedRecipients {
+			detail := dsnFieldValue(r.DiagnosticCode)
+			if detail == "" {
+				detail = r.Status
+			}
+			changed, err := dao.UpdateDeliveryStatus(sesMessageID, r.EmailAddress, models.DeliveryBounced, event.Bounce.BounceType, detail)
+			if err != nil {
+				return err
+			}
+			// 永久退信说明地址不存在，对所有域名停止发送
+			if event.Bounce.BounceType == "Permanent" {
+				err = dao.AddSuppression(&models.Suppression{
+					Scope:   models.SuppressionScopeGlobal,
+					Address: r.EmailAddress,
+					Reason:  models.SuppressionHardBounce,
+					Detail:  detail,
+					Source:  event.Mail.Source,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			// 重复投递的通知不再重复提醒
+			if changed {
+				bounced = append(bounced, bouncedRecipient{Address: r.EmailAddress, Status: r.Status, DiagnosticCode: r.DiagnosticCode, Diagnostic: detail})
+			}
+		}
+		if len(bounced) > 0 {
+			notifyBounce(&statuses[0], event, bounced)
+		}
+	case models.SesNotificationComplaint:
+		// 投诉只针对发件人所属的域名
+		account, err := dao.GetAccountByID(statuses[0].EmailAccountID)
+		if err != nil {
+			return err
+		}
+		for _, r := range event.Complaint.ComplainedRecipients {
+			if _, err := dao.UpdateDeliveryStatus(sesMessageID, r.EmailAddress, models.DeliveryComplained, "", event.Complaint.ComplaintFeedbackType); err != nil {
+				return err
+			}
+			err = dao.AddSuppression(&models.Suppression{
+				DomainID: account.DomainID,
+				Scope:    models.SuppressionScopeDomain,
+				Address:  r.EmailAddress,
+				Reason:   models.SuppressionComplaint,
+				Detail:   event.Complaint.ComplaintFeedbackType,
+				Source:   account.EmailAddress,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	case models.SesNotificationDelivery:
+		for _, r := range event.Delivery.Recipients {
+			if _, err := dao.UpdateDeliveryStatus(sesMessageID, r, models.DeliveryDelivered, "", event.Delivery.SmtpResponse); err != nil {
+				return err
+			}
+		}
+	case models.SesNotificationDeliveryDelay:
+		for _, r := range event.DeliveryDelay.DelayedRecipients {
+			detail := dsnFieldValue(r.DiagnosticCode)
+			if detail == "" {
+				detail = event.DeliveryDelay.DelayType
+			}
+			if _, err := dao.UpdateDeliveryStatus(sesMessageID, r.EmailAddress, models.DeliveryDelayed, "", detail); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// notifyBounce 推送退信事件，并在发件人的收件箱中保存 RFC 3464 退信通知，失败时只记录日志
+func notifyBounce(s *models.DeliveryStatus, event *models.AwsSnsEvent, bounced []bouncedRecipient) {
+	account, err := dao.GetAccountByID(s.EmailAccountID)
+	if err != nil {
+		global.Log.Errorf("获取退信邮件 [ %s ] 的发件账户失败: %v", s.SESMessageID, err)
+		return
+	}
+	sent, err := dao.GetEmailDetailFullFileds(int(s.EmailID), account.ID)
+	if err != nil {
+		// 发出的邮件已被删除，使用通知中的信息
+		sent = &models.EmailDetails{
+			ID:             s.EmailID,
+			EmailMessageID: event.Mail.CommonHeaders.MessageId,
+			EmailType:      global.EmailTypeSent,
+			Subject:        event.Mail.CommonHeaders.Subject,
+			SenderEmail:    account.EmailAddress,
+			RecipientEmail: strings.Join(event.Mail.Destination, ","),
+			ReceivedAt:     event.Mail.Timestamp,
+		}
+	}
+	addresses := make([]string, 0, len(bounced))
+	for _, r := range bounced {
+		addresses = append(addresses, r.Address)
+	}
+	data := models.NewWebhookMessageData(sent)
+	data.BouncedRecipients, data.BounceReason = addresses, bounced[0].Diagnostic
+	dao.PublishWebhookEvent(account.ID, account.DomainID, account.EmailAddress, models.WebhookMessageBounced, data)
+
+	report := &models.DeliveryReport{
+		Action:            models.DSNActionFailed,
+		ArrivalDate:       event.Mail.Timestamp,
+		OriginalMessageID: sent.EmailMessageID,
+		OriginalSubject:   sent.Subject,
+		OriginalHeaders:   sentEmailHeaders(sent),
+	}
+	for _, r := range bounced {
+		status := r.Status
+		if status == "" {
+			status = "5.0.0"
+		}
+		report.Recipients = append(report.Recipients, models.DeliveryReportRecipient{
+			Address:        r.Address,
+			Status:         status,
+			DiagnosticCode: r.DiagnosticCode,
+			LastAttempt:    event.Bounce.Timestamp,
+		})
+	}
+	if err := storeDeliveryReport(account, report); err != nil {
+		global.Log.Errorf("保存退信通知至 [ %s ] 失败: %v", account.EmailAddress, err)
+	}
+}
@@ -0,0 +1,227 @@
+package aws
+
+import (
+	"bytes"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"encoding/json"
+	"net/mail"
+	"sort"
+	"time"
+)
+
+const (
+	outboundRetryInitial  = time.Minute
+	outboundRetryMax      = time.Hour
+	outboundBatchSize     = 20
+	defaultMaxQueueHours  = 72
+	outboundClaimLease    = 10 * time.Minute
+	outboundIdleInterval  = 30 * time.Second
+	outboundFailureStatus = "5.0.0"
+	outboundDelayStatus   = "4.0.0"
+)
+
+// QueueOutboundEmail 将 SES 暂时无法发送的邮件加入发信队列，邮件已作为发出邮件保存
+// 之后的失败无法再告知 SMTP 客户端，改为向发件人的收件箱发送延迟提醒或退信
+func QueueOutboundEmail(sent *models.EmailDetails, from string, to, cc, bcc []string, sendErr error) error {
+	toJSON, _ := json.Marshal(to)
+	ccJSON, _ := json.Marshal(cc)
+	bccJSON, _ := json.Marshal(bcc)
+	m := &models.OutboundMessage{
+		EmailAccountID: sent.EmailAccountID,
+		EmailID:        sent.ID,
+		From:           from,
+		To:             toJSON,
+		Cc:             ccJSON,
+		Bcc:            bccJSON,
+		S3Key:          sent.S3Key,
+		Status:         models.OutboundQueued,
+		Attempts:       1,
+		LastError:      sendErr.Error(),
+		NextAttemptAt:  time.Now().Add(outboundRetryDelay(1)),
+	}
+	if err := dao.AddOutboundMessage(m); err != nil {
+		return err
+	}
+	global.Log.Warnf("邮件 [ %s ] 暂时无法发送，已加入发信队列: %v", sent.EmailMessageID, sendErr)
+	return nil
+}
+
+// OutboundQueueInit 定时重试发信队列中到期的邮件
+func OutboundQueueInit() {
+	for {
+		list, err := dao.ClaimDueOutboundMessages(outboundBatchSize, outboundClaimLease)
+		if err != nil {
+			global.Log.Errorf("领取发信队列失败: %v", err)
+		}
+		if len(list) == 0 {
+			time.Sleep(outboundIdleInterval)
+			continue
+		}
+		for i := range list {
+			retryOutboundMessage(&list[i])
+		}
+	}
+}
+
+// outboundRetryDelay 第 attempts 次失败后的等待时间，1 分钟起按指数退避，最长 1 小时
+func outboundRetryDelay(attempts int) time.Duration {
+	delay := outboundRetryInitial
+	for i := 1; i < attempts && delay < outboundRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > outboundRetryMax {
+		delay = outboundRetryMax
+	}
+	return delay
+}
+
+// maxQueueAge 邮件在队列中的最长重试时间
+func maxQueueAge() time.Duration {
+	hours := global.Config.OutboundQueue.MaxQueueHours
+	if hours <= 0 {
+		hours = defaultMaxQueueHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// retryOutboundMessage 重新发送队列中的邮件
+// 永久失败或超过最长重试时间时放弃并发送退信，停留时长达到配置的提醒时间时发送延迟提醒
+func retryOutboundMessage(m *models.OutboundMessage) {
+	var to, cc, bcc []string
+	_ = json.Unmarshal(m.To, &to)
+	_ = json.Unmarshal(m.Cc, &cc)
+	_ = json.Unmarshal(m.Bcc, &bcc)
+	recipients := append(append(append([]string{}, to...), cc...), bcc...)
+
+	m.Attempts++
+	raw, err := GetS3ObjectBytes(m.S3Key)
+	if err == nil {
+		to, cc, bcc, err = removeSuppressedRecipients(m, raw, to, cc, bcc)
+		recipients = append(append(append([]string{}, to...), cc...), bcc...)
+	}
+	if err == nil && len(recipients) == 0 {
+		m.Status = models.OutboundFailed
+		saveOutboundResult(m)
+		global.Log.Warnf("发信队列中的邮件 [ID: %d] 的收件人均位于禁止发送列表中，不再发送", m.ID)
+		return
+	}
+	if err == nil {
+		m.SESMessageID, err = rawEmailSender(raw, m.From, to, cc, bcc)
+	}
+	if err == nil {
+		m.Status, m.LastError = models.OutboundSent, ""
+		saveOutboundResult(m)
+		dao.AddDeliveryStatuses(m.SESMessageID, m.EmailAccountID, m.EmailID, recipients)
+		global.Log.Infof("发信队列中的邮件 [ID: %d] 第 %d 次尝试发送成功", m.ID, m.Attempts)
+		return
+	}
+	m.LastError = err.Error()
+	age := time.Since(m.CreatedAt)
+	if IsPermanentSendError(err) || age >= maxQueueAge() {
+		m.Status = models.OutboundFailed
+		saveOutboundResult(m)
+		global.Log.Errorf("发信队列中的邮件 [ID: %d] 发送失败，不再重试: %v", m.ID, err)
+		reportOutboundMessage(m, raw, models.DSNActionFailed, recipients)
+		return
+	}
+	hours := append([]int{}, global.Config.OutboundQueue.DelayWarningHours...)
+	sort.Ints(hours)
+	warn := false
+	for m.DelayNotices < len(hours) && age >= time.Duration(hours[m.DelayNotices])*time.Hour {
+		m.DelayNotices++
+		warn = true
+	}
+	m.NextAttemptAt = time.Now().Add(outboundRetryDelay(m.Attempts))
+	saveOutboundResult(m)
+	if warn {
+		reportOutboundMessage(m, raw, models.DSNActionDelayed, recipients)
+	}
+}
+
+// removeSuppressedRecipients 排队期间收件人可能因退信或投诉被加入禁止发送列表，移除这些收件人并向发件人发送退信
+func removeSuppressedRecipients(m *models.OutboundMessage, raw []byte, to, cc, bcc []string) ([]string, []string, []string, error) {
+	account, err := dao.GetAccountByID(m.EmailAccountID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	all := append(append(append([]string{}, to...), cc...), bcc...)
+	allowed, suppressed, err := splitSuppressedRecipients(account.DomainID, all)
+	if err != nil || len(suppressed) == 0 {
+		return to, cc, bcc, err
+	}
+	keep := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		keep[a] = true
+	}
+	filter := func(list []string) []string {
+		var kept []string
+		for _, a := range list {
+			if keep[a] {
+				kept = append(kept, a)
+			}
+		}
+		return kept
+	}
+	to, cc, bcc = filter(to), filter(cc), filter(bcc)
+	m.To, _ = json.Marshal(to)
+	m.Cc, _ = json.Marshal(cc)
+	m.Bcc, _ = json.Marshal(bcc)
+
+	addresses := make([]string, 0, len(suppressed))
+	for _, s := range suppressed {
+		addresses = append(addresses, s.Address)
+	}
+	m.LastError = ErrRecipientSuppressed.Error()
+	global.Log.Warnf("发信队列中的邮件 [ID: %d] 的收件人 %v 位于禁止发送列表中，已移除", m.ID, addresses)
+	reportOutboundMessage(m, raw, models.DSNActionFailed, addresses)
+	return to, cc, bcc, nil
+}
+
+func saveOutboundResult(m *models.OutboundMessage) {
+	if err := dao.SaveOutboundResult(m); err != nil {
+		global.Log.Errorf("保存发信队列 [ID: %d] 结果失败: %v", m.ID, err)
+	}
+}
+
+// reportOutboundMessage 向发件人的收件箱发送退信或延迟提醒，与原邮件关联为同一会话，失败时只记录日志
+func reportOutboundMessage(m *models.OutboundMessage, raw []byte, action string, recipients []string) {
+	account, err := dao.GetAccountByID(m.EmailAccountID)
+	if err != nil {
+		global.Log.Errorf("获取发信队列 [ID: %d] 的发件账户失败: %v", m.ID, err)
+		return
+	}
+	report := &models.DeliveryReport{
+		Action:         action,
+		ArrivalDate:    m.CreatedAt,
+		WillRetryUntil: m.CreatedAt.Add(maxQueueAge()),
+	}
+	if sent, err := dao.GetEmailDetailFullFileds(int(m.EmailID), account.ID); err == nil {
+		report.OriginalMessageID, report.OriginalSubject = sent.EmailMessageID, sent.Subject
+		report.OriginalHeaders = sentEmailHeaders(sent)
+	}
+	// 优先使用原文中的邮件头
+	if len(raw) > 0 {
+		report.OriginalHeaders = rawHeaderBlock(raw)
+		if msg, err := mail.ReadMessage(bytes.NewReader(raw)); err == nil {
+			report.OriginalMessageID = msg.Header.Get("Message-ID")
+			report.OriginalSubject = decodeText(msg.Header.Get("Subject"))
+		}
+	}
+	status := outboundFailureStatus
+	if action == models.DSNActionDelayed {
+		status = outboundDelayStatus
+	}
+	for _, r := range recipients {
+		report.Recipients = append(report.Recipients, models.DeliveryReportRecipient{
+			Address:        r,
+			Status:         status,
+			DiagnosticCode: "x-ses; " + m.LastError,
+			LastAttempt:    time.Now(),
+		})
+	}
+	if err := storeDeliveryReport(account, report); err != nil {
+		global.Log.Errorf("保存发信队列 [ID: %d] 的投递状态通知失败: %v", m.ID, err)
+	}
+}
@@ -0,0 +1,35 @@
+package aws
+
+import (
+	"email/global"
+	"email/models"
+	"email/utils"
+)
+
+// SentEmailKeyPrefix 发出邮件原文在 S3 中的存放前缀
+const SentEmailKeyPrefix = "sent/"
+
+// ImportedEmailKeyPrefix 导入邮件原文在 S3 中的存放前缀
+const ImportedEmailKeyPrefix = models.ImportedEmailKeyPrefix
+
+// NoticeEmailKeyPrefix 退信通知等系统生成邮件的原文在 S3 中的存放前缀
+const NoticeEmailKeyPrefix = "notice/"
+
+// SaveSentRawEmail 保存发出邮件的原文，返回 S3 对象键
+func SaveSentRawEmail(rawMessage []byte) (string, error) {
+	return saveRawEmail(SentEmailKeyPrefix, rawMessage)
+}
+
+// saveRawEmail 以 prefix 为前缀保存邮件原文，返回 S3 对象键
+func saveRawEmail(prefix string, rawMessage []byte) (string, error) {
+	id, err := utils.GenerateSendID()
+	if err != nil {
+		return "", err
+	}
+	key := prefix + id + ".eml"
+	if err := SaveAttachmentToS3(key, rawMessage, "message/rfc822"); err != nil {
+		global.Log.Errorf("保存邮件原文 [ %s ] 失败: %v", key, err)
+		return "", err
+	}
+	return key, nil
+}
@@ -7,6 +7,7 @@ import (
 	"email/global"
 	"email/models"
 	"email/service/dovecot"
+	"email/service/filter"
 	"email/service/shortlink"
 	"email/utils"
 	"errors"
@@ -21,7 +22,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/google/uuid"
 	"github.com/jhillyerd/enmime"
 )
 
@@ -40,109 +40,35 @@ func CreateS3Client() (*s3.Client, error) {
 	return S3Client, nil
 }
 
-// 获取s3中邮件的数据
-func S3EmailDataProcessor(key string, recipients *models.Recipients) error {
-	ctx := context.TODO()
-	cfg, err := config.LoadDefaultConfig(
-		ctx,
-		config.WithRegion(global.Config.AWS.ConfigRegion),
-		config.WithSharedConfigProfile(global.Config.AWS.ConfigProfile),
-	)
-	if err != nil {
-		global.Log.Errorf("无法加载 AWS 配置: %v", err)
-		return err
-	}
-	client := s3.NewFromConfig(cfg)
-	bucket := global.Config.AWS.S3Bucket
-	input := &s3.GetObjectInput{
-		Bucket: &bucket,
-		Key:    &key,
-	}
-	// 获取详细数据
-	result, err := client.GetObject(ctx, input)
-	if err != nil {
-		global.Log.Errorf("无法获取对象: %v", err)
-		return err
-	}
-	defer result.Body.Close()
-	// 读取数据
-	body, err := io.ReadAll(result.Body)
-	if err != nil {
-		global.Log.Errorf("无法读取对象内容: %v", err)
-		return err
-	}
-	// 转换数据
-	emailStr := string(body)
-	// 解析数据
-	env, err := enmime.ReadEnvelope(strings.NewReader(emailStr))
-	if err != nil {
-		global.Log.Errorf("无法解析邮件内容: %v", err)
-		return err
-	}
-
-	if env.GetHeader("Message-ID") == "" {
-		newMessageID := fmt.Sprintf("<%s@%s>", uuid.New().String(), "mountex.net")
-		err = env.AddHeader("Message-ID", newMessageID)
-		if err != nil {
-			return err
-		}
-		dateIndex := strings.Index(emailStr, "Date:")
-		if dateIndex != -1 {
-			dateEnd := strings.Index(emailStr[dateIndex:], "\n")
-			if dateEnd != -1 {
-				insertPos := dateIndex + dateEnd + 1
-				body = []byte(emailStr[:insertPos] + fmt.Sprintf("Message-ID: %s\n", newMessageID) + emailStr[insertPos:])
-			} else {
-				return errors.New("can't find the end of date header")
-			}
-		} else {
-			return errors.New("can't find the start of date header")
-		}
-	}
-	//获取邮件内容hash
-	hashConent := GetEmailFileHash(env)
-	//判断邮件地址是否存在
-	//解析收件人
-
-	for _, address := range recipients.Recipients {
-		accountData, err := dao.IsAccountExist(address, strings.Split(address, "@")[1])
-		if err != nil {
-			global.Log.Warnf("账户 [ %s ] 不存在，将从收件人列表中移除", address)
-			continue
-		}
-		emailExist, err := dao.IsEmailExistByHash(accountData.ID, hashConent)
-		if err != nil {
-			return err
-		}
-		if emailExist {
-			global.Log.Warnf("[%s]邮件已存在，将从收件人列表中移除", address)
-			continue
-		}
-		err = SaveEmailToServerWithDB(accountData, key, bucket, &body, &hashConent, env, address, recipients)
+// storeOptions 保存邮件时的分类与状态
+type storeOptions struct {
+	EmailType  string
+	Read       bool
+	Flagged    bool
+	ReceivedAt time.Time
+}
 
-		if err != nil {
-			return err
-		}
+// storeEmail 将邮件保存至邮件服务器，处理附件后写入数据库
+func storeEmail(accountData *models.EmailAccount, key, bucket string, emailRawMessage *[]byte, hashContent string, env *enmime.Envelope, deliver string, recipients *models.Recipients, opts storeOptions) error {
+	if err := appendEmailToServer(accountData, *emailRawMessage, env, opts); err != nil {
+		return err
 	}
-
-	return nil
+	_, err := insertEmailRecord(accountData, key, bucket, hashContent, env, deliver, recipients, opts)
+	return err
 }
 
-// 保存邮件到服务器与数据库
-func SaveEmailToServerWithDB(accountData *models.EmailAccount, key, bucket string, emailRawMessage *[]byte, hashContent *string, env *enmime.Envelope, deliver string, recipients *models.Recipients) error {
-	// 记录日志
-	global.Log.Info(fmt.Sprintf("查询账户 [ %s ] 成功，即将开始保存邮件至邮件服务器！", deliver))
-
-	//imap通信保存至指定文件夹
+// appendEmailToServer 通过 imap 将邮件保存至邮件服务器的指定文件夹，邮件已存在时不重复保存
+func appendEmailToServer(accountData *models.EmailAccount, emailRawMessage []byte, env *enmime.Envelope, opts storeOptions) error {
 	if global.Config.System.Env {
 
 		options := models.ImapOperatorOptions{
 			UserName:        accountData.EmailAddress,
 			MoveType:        global.AppendEmail,
-			TargetMailBox:   "Inbox",
+			TargetMailBox:   filter.MailboxName(opts.EmailType),
 			MessageID:       env.GetHeader("Message-Id"),
-			ReadStatus:      false,
-			EmailRawMessage: *emailRawMessage,
+			ReadStatus:      opts.Read,
+			Flagged:         opts.Flagged,
+			EmailRawMessage: emailRawMessage,
 		}
 
 		err := dovecot.ImapOperatorEmail(options)
@@ -150,13 +76,11 @@ func SaveEmailToServerWithDB(accountData *models.EmailAccount, key, bucket strin
 			return errors.New(err.Error() + "保存邮件至邮件服务器失败")
 		}
 	}
+	return nil
+}
 
-	//解析时间
-	p, err := utils.ParseTime(decodeText(env.GetHeader("Date")))
-	if err != nil {
-		global.Log.Errorf("解析时间失败: %s", err)
-		return err
-	}
+// insertEmailRecord 处理附件后将邮件写入数据库，返回写入的邮件
+func insertEmailRecord(accountData *models.EmailAccount, key, bucket string, hashContent string, env *enmime.Envelope, deliver string, recipients *models.Recipients, opts storeOptions) (*models.EmailDetails, error) {
 	//解析流程结束
 	global.Log.Info("解析完毕，开始处理邮件数据...")
 
@@ -166,7 +90,7 @@ func SaveEmailToServerWithDB(accountData *models.EmailAccount, key, bucket strin
 		EmailMessageID: env.GetHeader("Message-Id"),
 		FileName:       "",
 		DomainName:     strings.Split(deliver, "@")[1],
-		EmailHash:      *hashContent,
+		EmailHash:      hashContent,
 		EmailAddress:   deliver,
 		S3Key:          key,
 		RecipientEmail: strings.Join(recipients.To, ","),
@@ -177,26 +101,26 @@ func SaveEmailToServerWithDB(accountData *models.EmailAccount, key, bucket strin
 		Subject:        decodeText(env.GetHeader("Subject")),
 		BodyText:       env.Text,
 		BodyHTML:       env.HTML,
-		IsRead:         false,
-		EmailType:      global.EmailTypeInbox,
-		ReceivedAt:     p,
+		IsRead:         opts.Read,
+		IsFlagged:      opts.Flagged,
+		EmailType:      opts.EmailType,
+		ReceivedAt:     opts.ReceivedAt,
 	}
 	//判断是否有附件
 	var atts []models.Attachment
-	if len(env.Attachments) > 0 || len(env.Inlines) > 0 {
+	if len(env.Attachments) > 0 || len(InlineParts(env)) > 0 {
 		client, err := CreateS3Client()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		newparts := append(env.Attachments, env.Inlines...)
+		newparts := append(env.Attachments, InlineParts(env)...)
 		atts = EmailAttachmentProcessor(client, bucket, key, newparts)
 		global.Log.Info("附件处理完毕，开始保存邮件数据...")
 	}
-	_, err = dao.AddNewEmailToDB(&r, accountData, atts)
-	if err != nil {
-		return err
+	if _, err := dao.AddNewEmailToDB(&r, accountData, atts); err != nil {
+		return nil, err
 	}
-	return nil
+	return &r, nil
 }
 
 // 处理附件
@@ -290,9 +214,14 @@ func EmailAttachmentProcessor(client *s3.Client, bucket, key string, parts []*en
 		return attachments
 	}
 
-	for _, part := range parts {
+	for i, part := range parts {
+		// 带 Content-ID 且不是附件的部分为正文内嵌图片
+		inline := part.ContentID != "" && part.Disposition != "attachment"
 		// 解码文件名
 		decodedFileName := decodeText(part.FileName)
+		if decodedFileName == "" && inline {
+			decodedFileName = inlineFileName(i, part.ContentType)
+		}
 		if decodedFileName == "" {
 			global.Log.Warn("附件文件名为空，跳过该附件")
 			continue
@@ -313,6 +242,7 @@ func EmailAttachmentProcessor(client *s3.Client, bucket, key string, parts []*en
 			return attachments
 		}
 		if attachment != nil {
+			attachment.ContentID, attachment.Inline = part.ContentID, inline
 			attachments = append(attachments, *attachment)
 			global.Log.Warnf("附件 %s 已存在，跳过该附件", decodedFileName)
 			continue
@@ -320,6 +250,11 @@ func EmailAttachmentProcessor(client *s3.Client, bucket, key string, parts []*en
 		// 生成 S3 对象键
 		sanitizedKey := strings.TrimPrefix(key, "email/")
 		objectKey := fmt.Sprintf("attachment/%s/%s", sanitizedKey, decodedFileName)
+		// 入库前扫描，感染的附件直接存入隔离区
+		scanStatus, signature := ScanAttachmentContent(decodedFileName, part.Content)
+		if scanStatus == models.AttachmentScanInfected {
+			objectKey = QuarantinePrefix + objectKey
+		}
 
 		// 将附件保存到 S3
 		err = SaveAttachmentToS3(objectKey, part.Content, contentType)
@@ -328,11 +263,14 @@ func EmailAttachmentProcessor(client *s3.Client, bucket, key string, parts []*en
 			return attachments
 		}
 
-		// 生成预签名 URL
-		presignedURL, err := GeneratePresignedURL(objectKey)
-		if err != nil {
-			global.Log.Errorf("生成预签名 URL 失败: %v", err)
-			return attachments
+		// 生成预签名 URL，隔离的附件不生成
+		var presignedURL string
+		if scanStatus != models.AttachmentScanInfected {
+			presignedURL, err = GeneratePresignedURL(objectKey)
+			if err != nil {
+				global.Log.Errorf("生成预签名 URL 失败: %v", err)
+				return attachments
+			}
 		}
 		global.Log.Infof("附件 %s 已保存到 S3，对象键：%s", decodedFileName, objectKey)
 		global.Log.Info("生成短链接Code = > " + shortlink.CreateShortLinkCode(objectKey))
@@ -346,7 +284,11 @@ func EmailAttachmentProcessor(client *s3.Client, bucket, key string, parts []*en
 			ShortUrlCode:   shortlink.CreateShortLinkCode(objectKey),
 			DownloadURL:    presignedURL,
 			S3StoragePath:  objectKey,
-			ExpireTime:     time.Now().Add(time.Hour * time.Duration(global.Config.AWS.FileExpireTime)),
+			ExpireTime:     utils.GetAttachmentExpireTime(),
+			ScanStatus:     scanStatus,
+			ScanSignature:  signature,
+			ContentID:      part.ContentID,
+			Inline:         inline,
 		}
 		attachments = append(attachments, att)
 	}
@@ -359,6 +301,26 @@ func EmailAttachmentProcessor(client *s3.Client, bucket, key string, parts []*en
 	return attachments
 }
 
+// InlineParts 返回正文内嵌部分，multipart/related 中未声明 Content-Disposition 的图片也算在内
+func InlineParts(env *enmime.Envelope) []*enmime.Part {
+	parts := append([]*enmime.Part{}, env.Inlines...)
+	for _, p := range env.OtherParts {
+		if p.ContentID != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// inlineFileName 为没有文件名的内嵌图片生成文件名
+func inlineFileName(index int, contentType string) string {
+	ext := ".bin"
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		ext = exts[0]
+	}
+	return fmt.Sprintf("inline-%d%s", index+1, ext)
+}
+
 // 生成s3对象键
 func GenerateS3ObjectKey(fileName string) string {
 	// 获取文件扩展名
@@ -412,6 +374,23 @@ func DownloadAttachmentFromS3(objectKey string) (string, error) {
 	return tempFile.Name(), nil
 }
 
+// 获取S3对象内容
+func GetS3ObjectBytes(objectKey string) ([]byte, error) {
+	client, err := CreateS3Client()
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(global.Config.AWS.S3Bucket),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get s3 object failed: %w", err)
+	}
+	defer result.Body.Close()
+	return io.ReadAll(result.Body)
+}
+
 // 保存附件到S3
 func SaveAttachmentToS3(objectKey string, content []byte, contentType string) error {
 	ctx := context.TODO()
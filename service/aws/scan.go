@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"email/global"
+	"email/models"
+	"email/service/scanner"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// QuarantinePrefix 感染附件在 S3 中的存放前缀
+const QuarantinePrefix = "quarantine/"
+
+// ScanAttachmentContent 扫描附件内容，返回扫描状态与病毒名称
+func ScanAttachmentContent(fileName string, content []byte) (string, string) {
+	return scanStatus(fileName, bytes.NewReader(content))
+}
+
+// ScanS3Object 从 S3 读取对象并扫描，返回扫描状态与病毒名称
+func ScanS3Object(objectKey string) (string, string) {
+	if scanner.Default() == nil {
+		return models.AttachmentScanUnscanned, ""
+	}
+	out, err := GetS3ObjectRange(objectKey, "")
+	if err != nil {
+		global.Log.Errorf("读取待扫描附件 [ %s ] 失败: %v", objectKey, err)
+		return models.AttachmentScanFailed, ""
+	}
+	defer out.Body.Close()
+	return scanStatus(objectKey, out.Body)
+}
+
+func scanStatus(name string, r io.Reader) (string, string) {
+	res, err := scanner.Scan(r)
+	switch {
+	case err != nil:
+		global.Log.Errorf("扫描附件 [ %s ] 失败: %v", name, err)
+		return models.AttachmentScanFailed, ""
+	case res == nil:
+		return models.AttachmentScanUnscanned, ""
+	case res.Infected:
+		global.Log.Warnf("附件 [ %s ] 检测到病毒: %s", name, res.Signature)
+		return models.AttachmentScanInfected, res.Signature
+	default:
+		return models.AttachmentScanClean, ""
+	}
+}
+
+// QuarantineS3Object 将对象移动到隔离区，返回新的对象键
+func QuarantineS3Object(objectKey string) (string, error) {
+	client, err := CreateS3Client()
+	if err != nil {
+		return "", err
+	}
+	bucket := global.Config.AWS.S3Bucket
+	newKey := QuarantinePrefix + objectKey
+	source := (&url.URL{Path: bucket + "/" + objectKey}).EscapedPath()
+	_, err = client.CopyObject(context.TODO(), &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(newKey),
+		CopySource: aws.String(source),
+	})
+	if err != nil {
+		return "", fmt.Errorf("copy s3 object to quarantine failed: %w", err)
+	}
+	if err := DeleteS3Object(objectKey); err != nil {
+		return "", err
+	}
+	return newKey, nil
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"email/global"
 	"email/models"
-	"encoding/json"
+	"email/utils"
+	"errors"
 	"fmt"
 	ses "github.com/aws/aws-sdk-go-v2/service/sesv2"
 	"log"
@@ -14,113 +15,49 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
 )
 
-// 调用SES发送新的邮件
-func SendNewEmailByAwsSes(e *models.SendNewEmailRequest, senderEmailAddress, senderName string) error {
-	ctx := context.TODO()
-	cfg, err := config.LoadDefaultConfig(
-		ctx,
-		config.WithRegion(global.Config.AWS.ConfigRegion),
-		config.WithSharedConfigProfile(global.Config.AWS.ConfigProfile),
-	)
+// 调用SES发送新的邮件，以原始邮件发送以便保存发出的原文，返回原始邮件、Message-ID 与 SES 消息 ID
+func SendNewEmailByAwsSes(e *models.SendNewEmailRequest, senderEmailAddress, senderName string) (string, string, string, error) {
+	rawMessage, messageID := utils.GenerateEmailRawMessage(&models.EmailContent{
+		From:     fmt.Sprintf("%s <%s>", senderName, senderEmailAddress),
+		To:       e.To,
+		Subject:  e.Subject,
+		TextBody: e.TextBody,
+		HtmlBody: e.HtmlBody,
+	})
+	sesMessageID, err := sendRawEmail([]byte(rawMessage), senderEmailAddress, []string{e.To}, nil, nil)
 	if err != nil {
-		log.Fatalf("无法加载AWS配置: %v", err)
+		return "", "", "", err
 	}
-	ses.NewDefaultEndpointResolverV2()
-	sesClient := ses.NewFromConfig(cfg)
-	sender := fmt.Sprintf("%s <%s>", senderName, senderEmailAddress)
-	recipient := e.To
-	subject := e.Subject
-
-	input := &ses.SendEmailInput{
-		Destination: &types.Destination{
-			ToAddresses: []string{
-				recipient,
-			},
-		},
-		Content: &types.EmailContent{
-			Simple: &types.Message{
-				Body: &types.Body{
-					Html: &types.Content{
-						Charset: aws.String("UTF-8"),
-						Data:    aws.String(string(e.HtmlBody)),
-					},
-					Text: &types.Content{
-						Charset: aws.String("UTF-8"),
-						Data:    aws.String(e.TextBody),
-					},
-				},
-				Subject: &types.Content{
-					Charset: aws.String("UTF-8"),
-					Data:    aws.String(subject),
-				},
-			},
-		},
-		FromEmailAddress: aws.String(sender),
-	}
-	result, err := sesClient.SendEmail(context.TODO(), input)
-	if err != nil {
-		return err
-	}
-	marshal, err := json.Marshal(result)
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(marshal))
-	return nil
+	return rawMessage, messageID, sesMessageID, nil
 }
 
-// 调用SES回复邮件
-func ReplyEmailByAwsSes(e *models.ReplyEmailRequest, senderEmailAddress, senderName string) error {
-	ctx := context.TODO()
-	cfg, err := config.LoadDefaultConfig(
-		ctx,
-		config.WithRegion(global.Config.AWS.ConfigRegion),
-		config.WithSharedConfigProfile(global.Config.AWS.ConfigProfile),
-	)
-	if err != nil {
-		log.Fatalf("无法加载AWS配置: %v", err)
-	}
-	sesClient := ses.NewFromConfig(cfg)
-	sender := fmt.Sprintf("%s <%s>", senderName, senderEmailAddress)
-	recipient := e.To
-	subject := e.Subject
-
-	input := &ses.SendEmailInput{
-		Destination: &types.Destination{
-			ToAddresses: []string{
-				recipient,
-			},
-		},
-		Content: &types.EmailContent{
-			Simple: &types.Message{
-				Body: &types.Body{
-					Html: &types.Content{
-						Charset: aws.String("UTF-8"),
-						Data:    aws.String(string(e.HtmlBody)),
-					},
-					Text: &types.Content{
-						Charset: aws.String("UTF-8"),
-						Data:    aws.String(e.TextBody),
-					},
-				},
-				Subject: &types.Content{
-					Charset: aws.String("UTF-8"),
-					Data:    aws.String(subject),
-				},
-			},
-		},
-		FromEmailAddress: aws.String(sender),
-	}
-	result, err := sesClient.SendEmail(context.TODO(), input)
+// 调用SES回复邮件，返回原始邮件、Message-ID 与 SES 消息 ID
+func ReplyEmailByAwsSes(e *models.ReplyEmailRequest, senderEmailAddress, senderName string) (string, string, string, error) {
+	rawMessage, messageID := utils.GenerateEmailRawMessage(&models.EmailContent{
+		From:     fmt.Sprintf("%s <%s>", senderName, senderEmailAddress),
+		To:       e.To,
+		Subject:  e.Subject,
+		TextBody: e.TextBody,
+		HtmlBody: e.HtmlBody,
+	})
+	sesMessageID, err := sendRawEmail([]byte(rawMessage), senderEmailAddress, []string{e.To}, nil, nil)
 	if err != nil {
-		return fmt.Errorf("发送邮件失败: %v", err)
+		return "", "", "", err
 	}
+	global.Log.Infof("邮件发送成功，Message-ID: %s", messageID)
+	return rawMessage, messageID, sesMessageID, nil
+}
 
-	log.Printf("邮件发送成功，消息ID: %s", *result.MessageId)
-	return nil
+// SendEmailByAwsSesWithRawMessage 发送原始邮件，返回 SES 消息 ID
+func SendEmailByAwsSesWithRawMessage(rawMessage []byte, from string, to, cc, bcc []string) (string, error) {
+	return rawEmailSender(rawMessage, from, to, cc, bcc)
 }
 
-func SendEmailByAwsSesWithRawMessage(rawMessage []byte, from string, to, cc, bcc []string) error {
+// rawEmailSender 发送原始邮件，测试时替换
+var rawEmailSender = sendRawEmail
+
+// sendRawEmail 调用 SES 发送原始邮件，返回 SES 消息 ID，退信等通知以该 ID 对应发出的邮件
+func sendRawEmail(rawMessage []byte, from string, to, cc, bcc []string) (string, error) {
 	ctx := context.TODO()
 	cfg, err := config.LoadDefaultConfig(
 		ctx,
@@ -128,7 +65,7 @@ func SendEmailByAwsSesWithRawMessage(rawMessage []byte, from string, to, cc, bcc
 		config.WithSharedConfigProfile(global.Config.AWS.ConfigProfile),
 	)
 	if err != nil {
-		return fmt.Errorf("无法加载AWS配置: %v", err)
+		return "", fmt.Errorf("无法加载AWS配置: %v", err)
 	}
 	sesClient := ses.NewFromConfig(cfg)
 
@@ -148,9 +85,20 @@ func SendEmailByAwsSesWithRawMessage(rawMessage []byte, from string, to, cc, bcc
 
 	result, err := sesClient.SendEmail(ctx, input)
 	if err != nil {
-		return fmt.Errorf("发送邮件失败: %v", err)
+		return "", fmt.Errorf("发送邮件失败: %w", err)
 	}
 
 	log.Printf("原始邮件发送成功，消息ID: %s", *result.MessageId)
-	return nil
+	return aws.ToString(result.MessageId), nil
+}
+
+// IsPermanentSendError SES 拒绝发送且重试无效的错误，如邮件被拒、发件域名未验证
+// 其余错误(限流、发送暂停、网络等)可以稍后重试
+func IsPermanentSendError(err error) bool {
+	var rejected *types.MessageRejected
+	var notVerified *types.MailFromDomainNotVerifiedException
+	var badRequest *types.BadRequestException
+	var notFound *types.NotFoundException
+	return errors.As(err, &rejected) || errors.As(err, &notVerified) ||
+		errors.As(err, &badRequest) || errors.As(err, &notFound)
 }
@@ -1,29 +1,22 @@
 package aws
 
 import (
+	"crypto/tls"
 	"email/dao"
 	"email/global"
 	"email/models"
 	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
 	mail "github.com/xhit/go-simple-mail/v2"
 )
 
-func SendEmailByAwsSmtp(senderEmail string, webSendEmailReq *models.WebSendEmailRequest) (string, string, []models.Attachment, error) {
-	server := mail.NewSMTPClient()
-	server.Host = global.Config.AWS.SmtpHost
-	server.Port = global.Config.AWS.SmtpPort
-	server.Username = global.Config.AWS.SmtpUsername
-	server.Password = global.Config.AWS.SmtpPassword
-	server.Encryption = mail.EncryptionSTARTTLS
-
-	smtpClient, err := server.Connect()
-	if err != nil {
-		return "", "", nil, err
-	}
-
+// SendEmailByAwsSmtp 通过 SES SMTP 接口发送网页端邮件，返回原始邮件、Message-ID、SES 消息 ID 与附件
+func SendEmailByAwsSmtp(senderEmail string, webSendEmailReq *models.WebSendEmailRequest) (string, string, string, []models.Attachment, error) {
 	msgId := fmt.Sprintf("<%s@%s>", uuid.New().String(), strings.Split(senderEmail, "@")[1])
 	email := mail.NewMSG()
 	email.AddHeader("Message-ID", msgId)
@@ -34,32 +27,117 @@ func SendEmailByAwsSmtp(senderEmail string, webSendEmailReq *models.WebSendEmail
 	email.SetSubject(webSendEmailReq.Subject)
 
 	var attachments []models.Attachment
+	htmlBody := webSendEmailReq.HtmlBody
 
 	if len(webSendEmailReq.Attachments) > 0 {
 		fileList, atts, err := dao.GetAttachmentsDataByCodes(webSendEmailReq.Attachments)
 		if err != nil {
-			return "", "", nil, err
+			return "", "", "", nil, err
+		}
+		inlineCodes := map[string]bool{}
+		for _, a := range webSendEmailReq.Attachments {
+			if a.Inline {
+				inlineCodes[a.Code] = true
+			}
+		}
+		// 已保存的邮件正文仍以 cid:<code> 引用内嵌图片
+		for i := range atts {
+			if inlineCodes[atts[i].ShortUrlCode] {
+				atts[i].ContentID, atts[i].Inline = atts[i].ShortUrlCode, true
+			}
 		}
 		attachments = atts
+		inlineNames := map[string]bool{}
 		for _, file := range fileList {
 			path, err := DownloadAttachmentFromS3(file.FileKey)
 			if err != nil {
-				return "", "", nil, err
+				return "", "", "", nil, err
 			}
-			email.Attach(&mail.File{FilePath: path, Name: file.FileName})
+			if !inlineCodes[file.FileCode] {
+				email.Attach(&mail.File{FilePath: path, Name: file.FileName})
+				continue
+			}
+			// 发信库按文件名生成 Content-ID，因此将正文中的 cid:<code> 替换为唯一的文件名
+			base := file.FileName
+			if base == "" {
+				base = file.FileCode
+			}
+			name := base
+			for n := 2; inlineNames[name]; n++ {
+				name = fmt.Sprintf("%d-%s", n, base)
+			}
+			inlineNames[name] = true
+			htmlBody = strings.ReplaceAll(htmlBody, "cid:"+file.FileCode, "cid:"+name)
+			email.Attach(&mail.File{FilePath: path, Name: name, Inline: true})
 		}
 	}
 
 	email.SetBody(mail.TextPlain, webSendEmailReq.TextBody)
-	email.AddAlternative(mail.TextHTML, webSendEmailReq.HtmlBody)
+	email.AddAlternative(mail.TextHTML, htmlBody)
 
 	if email.Error != nil {
-		return "", "", nil, email.Error
+		return "", "", "", nil, email.Error
 	}
 
-	if err = email.Send(smtpClient); err != nil {
-		return "", "", nil, err
+	// 每次生成的 boundary 不同，发送与保存使用同一份原文
+	rawMessage := email.GetMessage()
+	sesMessageID, err := sendRawEmailBySmtp(rawMessage, senderEmail, email.GetRecipients())
+	if err != nil {
+		return "", "", "", nil, err
 	}
 
-	return email.GetMessage(), msgId, attachments, nil
+	return rawMessage, msgId, sesMessageID, attachments, nil
+}
+
+// sendRawEmailBySmtp 通过 SES SMTP 接口发送原始邮件
+// SES 在 DATA 结束的响应中返回消息 ID(250 Ok <id>)，发信库不提供该响应，因此直接完成 SMTP 会话
+func sendRawEmailBySmtp(rawMessage, from string, recipients []string) (string, error) {
+	host := global.Config.AWS.SmtpHost
+	c, err := smtp.Dial(net.JoinHostPort(host, strconv.Itoa(global.Config.AWS.SmtpPort)))
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+		return "", err
+	}
+	if err = c.Auth(smtp.PlainAuth("", global.Config.AWS.SmtpUsername, global.Config.AWS.SmtpPassword, host)); err != nil {
+		return "", err
+	}
+	if err = c.Mail(from); err != nil {
+		return "", err
+	}
+	for _, r := range recipients {
+		if err = c.Rcpt(r); err != nil {
+			return "", err
+		}
+	}
+	id, err := c.Text.Cmd("DATA")
+	if err != nil {
+		return "", err
+	}
+	c.Text.StartResponse(id)
+	_, _, err = c.Text.ReadResponse(354)
+	c.Text.EndResponse(id)
+	if err != nil {
+		return "", err
+	}
+	w := c.Text.DotWriter()
+	if _, err = w.Write([]byte(rawMessage)); err != nil {
+		return "", err
+	}
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	_, msg, err := c.Text.ReadResponse(250)
+	if err != nil {
+		return "", err
+	}
+	_ = c.Quit()
+	fields := strings.Fields(msg)
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "Ok") {
+		global.Log.Warnf("无法从 SMTP 响应 [ %s ] 中获取 SES 消息 ID", msg)
+		return "", nil
+	}
+	return fields[1], nil
 }
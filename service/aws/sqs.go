@@ -6,7 +6,6 @@ import (
 	"email/utils"
 	"encoding/json"
 	"fmt"
-	"gorm.io/gorm"
 	"log"
 
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -63,11 +62,20 @@ func ProcessSQSEmailMessages() {
 				global.Log.Errorf("解析消息失败: %v", err)
 				continue
 			}
-			if len(s.NotificationType) == 0 {
+			if len(s.Type()) == 0 {
 				global.Log.Error("解析消息失败: 缺少必要字段")
 				DeleteQueueMessage(queueURL, message.ReceiptHandle, client, ctx)
 				continue
 			}
+			// 发出邮件的退信、投诉与投递通知，失败时保留消息等待重新投递
+			if isDeliveryNotification(&s) {
+				if err = ProcessSESNotification(&s); err != nil {
+					global.Log.Errorf("处理 %s 通知失败: %v", s.Type(), err)
+				} else {
+					DeleteQueueMessage(queueURL, message.ReceiptHandle, client, ctx)
+				}
+				continue
+			}
 			if !VerifyEvent(&s) {
 				global.Log.Error("事件验证失败")
 				DeleteQueueMessage(queueURL, message.ReceiptHandle, client, ctx)
@@ -75,17 +83,10 @@ func ProcessSQSEmailMessages() {
 			}
 
 			recipients := ClassifyRecipients(&s)
-			b, _ := json.Marshal(recipients)
-			global.Log.Error(string(b))
-			//fmt.Println(string(b))
 
-			//处理邮件
-			err = S3EmailDataProcessor(s.Receipt.Action.ObjectKey, &recipients)
+			//处理邮件，失败时保留消息等待 SQS 重新投递，超过重试次数的邮件已转入死信
+			err = IngestS3Email(s.Receipt.Action.ObjectKey, &recipients)
 			if err != nil {
-				fmt.Println(err)
-				if err == gorm.ErrRecordNotFound {
-					DeleteQueueMessage(queueURL, message.ReceiptHandle, client, ctx)
-				}
 				global.Log.Errorf("处理邮件失败: %v", err)
 			} else {
 				DeleteQueueMessage(queueURL, message.ReceiptHandle, client, ctx)
@@ -145,9 +146,18 @@ func ClassifyRecipients(event *models.AwsSnsEvent) models.Recipients {
 	}
 }
 
+// isDeliveryNotification 是否为发出邮件的投递相关通知
+func isDeliveryNotification(event *models.AwsSnsEvent) bool {
+	switch event.Type() {
+	case models.SesNotificationBounce, models.SesNotificationComplaint, models.SesNotificationDelivery, models.SesNotificationDeliveryDelay:
+		return event.Mail.MessageId != ""
+	}
+	return false
+}
+
 // 验证事件
 func VerifyEvent(event *models.AwsSnsEvent) bool {
-	if event.NotificationType != "Received" {
+	if event.NotificationType != models.SesNotificationReceived {
 		return false
 	}
 	if event.Receipt.Action.Type != "S3" ||
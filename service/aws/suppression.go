@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"email/dao"
+	"email/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRecipientSuppressed 收件人位于禁止发送列表中
+var ErrRecipientSuppressed = errors.New("recipient is on the suppression list")
+
+// suppressionLookup 查询禁止发送列表，测试时替换
+var suppressionLookup = dao.GetSuppressedAddresses
+
+// CheckSuppressedRecipients 检查收件人是否位于全局或发件人所属域名的禁止发送列表中
+// 所有发信路径(网页、SMTP、自动回复、转发、发信队列)在调用 SES 之前都需检查
+func CheckSuppressedRecipients(domainID uint, recipients []string) error {
+	_, suppressed, err := splitSuppressedRecipients(domainID, recipients)
+	if err != nil || len(suppressed) == 0 {
+		return err
+	}
+	addresses := make([]string, 0, len(suppressed))
+	for _, s := range suppressed {
+		addresses = append(addresses, fmt.Sprintf("%s (%s)", s.Address, s.Reason))
+	}
+	return fmt.Errorf("%w [ %s ]", ErrRecipientSuppressed, strings.Join(addresses, ", "))
+}
+
+// splitSuppressedRecipients 将收件人分为可发送与位于禁止发送列表中的两部分，同一地址只返回一条记录
+func splitSuppressedRecipients(domainID uint, recipients []string) ([]string, []models.Suppression, error) {
+	list, err := suppressionLookup(domainID, recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocked := make(map[string]bool)
+	var suppressed []models.Suppression
+	for _, s := range list {
+		if !blocked[s.Address] {
+			blocked[s.Address] = true
+			suppressed = append(suppressed, s)
+		}
+	}
+	allowed := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		if !blocked[strings.ToLower(strings.TrimSpace(r))] {
+			allowed = append(allowed, r)
+		}
+	}
+	return allowed, suppressed, nil
+}
@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"email/config"
+	"email/global"
+	"email/models"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jhillyerd/enmime"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	global.Log = logrus.New()
+	global.Log.SetOutput(io.Discard)
+	global.Config = &config.Config{}
+	os.Exit(m.Run())
+}
+
+// stubSuppression 以固定的禁止发送列表替换查询，并记录发出的邮件
+func stubSuppression(t *testing.T, suppressed ...string) *[][]string {
+	t.Helper()
+	lookup, sender := suppressionLookup, rawEmailSender
+	t.Cleanup(func() { suppressionLookup, rawEmailSender = lookup, sender })
+	suppressionLookup = func(domainID uint, addresses []string) ([]models.Suppression, error) {
+		var list []models.Suppression
+		for _, a := range addresses {
+			for _, s := range suppressed {
+				if strings.EqualFold(strings.TrimSpace(a), s) {
+					list = append(list, models.Suppression{DomainID: domainID, Address: s, Reason: "bounce"})
+				}
+			}
+		}
+		return list, nil
+	}
+	sent := &[][]string{}
+	rawEmailSender = func(rawMessage []byte, from string, to, cc, bcc []string) (string, error) {
+		*sent = append(*sent, append(append(append([]string{}, to...), cc...), bcc...))
+		return "ses-id", nil
+	}
+	return sent
+}
+
+func testEnvelope(t *testing.T, headers string) *enmime.Envelope {
+	t.Helper()
+	env, err := enmime.ReadEnvelope(strings.NewReader(headers + "Subject: hello\r\nMessage-Id: <1@example.com>\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func TestSplitSuppressedRecipients(t *testing.T) {
+	stubSuppression(t, "bounced@example.com")
+	allowed, suppressed, err := splitSuppressedRecipients(1, []string{"a@example.com", "Bounced@Example.com", "bounced@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allowed) != 1 || allowed[0] != "a@example.com" {
+		t.Errorf("allowed = %v", allowed)
+	}
+	if len(suppressed) != 1 || suppressed[0].Address != "bounced@example.com" {
+		t.Errorf("suppressed = %+v", suppressed)
+	}
+	if err := CheckSuppressedRecipients(1, []string{"BOUNCED@example.com"}); !errors.Is(err, ErrRecipientSuppressed) {
+		t.Errorf("CheckSuppressedRecipients error = %v", err)
+	}
+	if err := CheckSuppressedRecipients(1, []string{"a@example.com"}); err != nil {
+		t.Errorf("CheckSuppressedRecipients error = %v", err)
+	}
+}
+
+func TestVacationReplyRecipient(t *testing.T) {
+	stubSuppression(t, "bounced@example.com")
+	account := &models.EmailAccount{ID: 1, DomainID: 1, EmailAddress: "user@mail.example"}
+	tests := []struct {
+		name       string
+		headers    string
+		recipients models.Recipients
+		want       string
+	}{
+		{"direct", "From: Alice <alice@example.com>\r\n", models.Recipients{To: []string{"user@mail.example"}}, "alice@example.com"},
+		{"return path preferred", "Return-Path: <bounces@example.com>\r\nFrom: alice@example.com\r\n", models.Recipients{To: []string{"user@mail.example"}}, "bounces@example.com"},
+		{"bcc only", "From: alice@example.com\r\n", models.Recipients{Bcc: []string{"user@mail.example"}}, ""},
+		{"suppressed sender", "From: Bounced <bounced@example.com>\r\n", models.Recipients{To: []string{"user@mail.example"}}, ""},
+		{"auto submitted", "From: alice@example.com\r\nAuto-Submitted: auto-generated\r\n", models.Recipients{To: []string{"user@mail.example"}}, ""},
+		{"self", "From: user@mail.example\r\n", models.Recipients{To: []string{"user@mail.example"}}, ""},
+		{"recipient case differs", "From: alice@example.com\r\n", models.Recipients{Cc: []string{"User@Mail.Example"}}, "alice@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := vacationReplyRecipient(account, testEnvelope(t, tt.headers), &tt.recipients)
+			if got != tt.want {
+				t.Errorf("vacationReplyRecipient() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardRawEmailSkipsSuppressed(t *testing.T) {
+	sent := stubSuppression(t, "bounced@example.com")
+	account := &models.EmailAccount{ID: 1, DomainID: 1, EmailAddress: "user@mail.example", DomainName: "mail.example"}
+	raw := []byte("From: alice@example.com\r\nTo: user@mail.example\r\nSubject: hello\r\n\r\nbody\r\n")
+	env := testEnvelope(t, "From: alice@example.com\r\n")
+
+	err := ForwardRawEmail(account, env, raw, "Bounced@example.com")
+	if !errors.Is(err, ErrRecipientSuppressed) {
+		t.Errorf("ForwardRawEmail error = %v, want %v", err, ErrRecipientSuppressed)
+	}
+	if len(*sent) != 0 {
+		t.Fatalf("suppressed forward was sent to %v", *sent)
+	}
+	if err := ForwardRawEmail(account, env, raw, "other@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*sent) != 1 || (*sent)[0][0] != "other@example.com" {
+		t.Errorf("sent = %v", *sent)
+	}
+}
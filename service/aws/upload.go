@@ -0,0 +1,209 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"email/global"
+	"email/models"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3PartSize 分片上传的分片大小，S3 要求除最后一片外不小于 5MB
+const S3PartSize = 8 * 1024 * 1024
+
+// ErrFileTooLarge 文件超过允许的最大大小
+var ErrFileTooLarge = errors.New("file exceeds the maximum allowed size")
+
+// ErrInvalidRange 请求的范围超出文件大小
+var ErrInvalidRange = errors.New("requested range not satisfiable")
+
+// StreamUploadToS3 流式上传到 S3，同时计算 SHA-256，超过一个分片时使用分片上传，内存中最多保留一个分片
+func StreamUploadToS3(objectKey, contentType string, r io.Reader, maxSize int64) (string, int64, error) {
+	client, err := CreateS3Client()
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	src := io.TeeReader(io.LimitReader(r, maxSize+1), h)
+	buf := make([]byte, S3PartSize)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", 0, err
+	}
+	// 不足一个分片，直接上传
+	if n < S3PartSize {
+		if int64(n) > maxSize {
+			return "", 0, ErrFileTooLarge
+		}
+		_, err = client.PutObject(context.TODO(), &s3.PutObjectInput{
+			Bucket:      aws.String(global.Config.AWS.S3Bucket),
+			Key:         aws.String(objectKey),
+			Body:        bytes.NewReader(buf[:n]),
+			ContentType: aws.String(contentType),
+		})
+		if err != nil {
+			return "", 0, fmt.Errorf("upload to s3 failed: %w", err)
+		}
+		return hex.EncodeToString(h.Sum(nil)), int64(n), nil
+	}
+	uploadID, err := CreateMultipartUpload(objectKey, contentType)
+	if err != nil {
+		return "", 0, err
+	}
+	size, parts, err := uploadParts(objectKey, uploadID, src, buf, n, maxSize)
+	if err == nil {
+		err = CompleteMultipartUpload(objectKey, uploadID, parts)
+	}
+	if err != nil {
+		AbortMultipartUpload(objectKey, uploadID)
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// uploadParts 依次读取并上传分片，buf 中已有 n 字节的第一片
+func uploadParts(objectKey, uploadID string, src io.Reader, buf []byte, n int, maxSize int64) (int64, []models.UploadPart, error) {
+	var parts []models.UploadPart
+	var size int64
+	for number := int32(1); n > 0; number++ {
+		size += int64(n)
+		if size > maxSize {
+			return 0, nil, ErrFileTooLarge
+		}
+		etag, err := UploadPart(objectKey, uploadID, number, buf[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+		parts = append(parts, models.UploadPart{PartNumber: number, ETag: etag})
+		n, err = io.ReadFull(src, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, nil, err
+		}
+	}
+	return size, parts, nil
+}
+
+// CreateMultipartUpload 创建分片上传
+func CreateMultipartUpload(objectKey, contentType string) (string, error) {
+	client, err := CreateS3Client()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.CreateMultipartUpload(context.TODO(), &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(global.Config.AWS.S3Bucket),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("create multipart upload failed: %w", err)
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+// UploadPart 上传一个分片，返回分片的 ETag
+func UploadPart(objectKey, uploadID string, partNumber int32, data []byte) (string, error) {
+	client, err := CreateS3Client()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.UploadPart(context.TODO(), &s3.UploadPartInput{
+		Bucket:        aws.String(global.Config.AWS.S3Bucket),
+		Key:           aws.String(objectKey),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(partNumber),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		return "", fmt.Errorf("upload part %d failed: %w", partNumber, err)
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+// CompleteMultipartUpload 合并分片
+func CompleteMultipartUpload(objectKey, uploadID string, parts []models.UploadPart) error {
+	client, err := CreateS3Client()
+	if err != nil {
+		return err
+	}
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		completed = append(completed, types.CompletedPart{PartNumber: aws.Int32(p.PartNumber), ETag: aws.String(p.ETag)})
+	}
+	_, err = client.CompleteMultipartUpload(context.TODO(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(global.Config.AWS.S3Bucket),
+		Key:             aws.String(objectKey),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("complete multipart upload failed: %w", err)
+	}
+	return nil
+}
+
+// AbortMultipartUpload 取消分片上传，释放已上传的分片
+func AbortMultipartUpload(objectKey, uploadID string) {
+	client, err := CreateS3Client()
+	if err != nil {
+		global.Log.Errorf("取消分片上传 [ %s ] 失败: %v", objectKey, err)
+		return
+	}
+	_, err = client.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(global.Config.AWS.S3Bucket),
+		Key:      aws.String(objectKey),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		global.Log.Errorf("取消分片上传 [ %s ] 失败: %v", objectKey, err)
+	}
+}
+
+// DeleteS3Object 删除 S3 对象
+func DeleteS3Object(objectKey string) error {
+	client, err := CreateS3Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(global.Config.AWS.S3Bucket),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return fmt.Errorf("delete s3 object failed: %w", err)
+	}
+	return nil
+}
+
+// GetS3ObjectRange 获取 S3 对象，rangeHeader 为 HTTP Range 请求头，为空时获取整个对象
+func GetS3ObjectRange(objectKey, rangeHeader string) (*s3.GetObjectOutput, error) {
+	client, err := CreateS3Client()
+	if err != nil {
+		return nil, err
+	}
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(global.Config.AWS.S3Bucket),
+		Key:    aws.String(objectKey),
+	}
+	if rangeHeader != "" {
+		input.Range = aws.String(rangeHeader)
+	}
+	out, err := client.GetObject(context.TODO(), input)
+	if err != nil {
+		var re interface{ HTTPStatusCode() int }
+		if errors.As(err, &re) && re.HTTPStatusCode() == http.StatusRequestedRangeNotSatisfiable {
+			return nil, ErrInvalidRange
+		}
+		return nil, fmt.Errorf("get s3 object failed: %w", err)
+	}
+	return out, nil
+}
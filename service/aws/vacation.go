@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// VacationAutoReplyProcessor 处理收件后的自动回复 (RFC 3834)
+func VacationAutoReplyProcessor(accountData *models.EmailAccount, env *enmime.Envelope, recipients *models.Recipients) {
+	v, err := dao.GetVacationResponder(accountData.ID)
+	if err != nil || v == nil || !v.IsActive(time.Now()) {
+		return
+	}
+	replyTo := vacationReplyRecipient(accountData, env, recipients)
+	if replyTo == "" {
+		return
+	}
+	// 间隔期内不重复回复同一发件人
+	interval := time.Duration(v.ResendIntervalDays) * 24 * time.Hour
+	sender := strings.ToLower(replyTo)
+	// 数据库时间精度为微秒，截断后释放时才能按时间匹配
+	claimedAt := time.Now().Truncate(time.Microsecond)
+	ok, err := dao.ClaimVacationReply(accountData.ID, sender, claimedAt, interval)
+	if err != nil || !ok {
+		return
+	}
+
+	subject := v.Subject
+	if subject == "" {
+		subject = "Auto: " + decodeText(env.GetHeader("Subject"))
+	}
+	headers := []models.EmailHeader{{Name: "Auto-Submitted", Value: "auto-replied"}}
+	if msgID := env.GetHeader("Message-Id"); msgID != "" {
+		headers = append(headers,
+			models.EmailHeader{Name: "In-Reply-To", Value: msgID},
+			models.EmailHeader{Name: "References", Value: strings.TrimSpace(env.GetHeader("References") + " " + msgID)},
+		)
+	}
+	raw, messageID := utils.GenerateEmailRawMessage(&models.EmailContent{
+		From:     fmt.Sprintf("%s <%s>", accountData.UserName, accountData.EmailAddress),
+		To:       replyTo,
+		Subject:  subject,
+		TextBody: v.BodyText,
+		HtmlBody: v.BodyHTML,
+		Headers:  headers,
+	})
+	_, err = SendEmailByAwsSesWithRawMessage([]byte(raw), accountData.EmailAddress, []string{replyTo}, nil, nil)
+	if err != nil {
+		global.Log.Errorf("账户 [ %s ] 自动回复 [ %s ] 失败: %v", accountData.EmailAddress, replyTo, err)
+		// 未发出的回复不占用间隔期，下一封邮件仍会回复
+		dao.ReleaseVacationReply(accountData.ID, sender, claimedAt)
+		return
+	}
+	global.Log.Infof("账户 [ %s ] 已自动回复 [ %s ] [ %s ]", accountData.EmailAddress, replyTo, messageID)
+}
+
+// vacationReplyRecipient 返回自动回复的收件人，不应回复时返回空字符串
+func vacationReplyRecipient(accountData *models.EmailAccount, env *enmime.Envelope, recipients *models.Recipients) string {
+	// 只回复直接发送给本账户的邮件，密送和列表转发不回复
+	if !containsAddress(recipients.To, accountData.EmailAddress) && !containsAddress(recipients.Cc, accountData.EmailAddress) {
+		global.Log.Infof("账户 [ %s ] 不在收件人/抄送中，跳过自动回复", accountData.EmailAddress)
+		return ""
+	}
+	if reason := utils.IsAutoReplySuppressed(env.GetHeader); reason != "" {
+		global.Log.Infof("邮件 [ %s ] 不满足自动回复条件: %s", env.GetHeader("Message-Id"), reason)
+		return ""
+	}
+	// 优先回复退信地址，其次为发件人
+	replyTo := utils.ParseFromEmailAddress(env.GetHeader("Return-Path")).Address
+	if replyTo == "" {
+		replyTo = utils.ParseFromEmailAddress(env.GetHeader("From")).Address
+	}
+	replyTo = strings.TrimSpace(replyTo)
+	if replyTo == "" || strings.EqualFold(replyTo, accountData.EmailAddress) || utils.IsAutoReplyIgnoredSender(replyTo) {
+		return ""
+	}
+	if err := CheckSuppressedRecipients(accountData.DomainID, []string{replyTo}); err != nil {
+		global.Log.Infof("账户 [ %s ] 跳过自动回复: %v", accountData.EmailAddress, err)
+		return ""
+	}
+	return replyTo
+}
+
+// containsAddress 地址列表中是否包含该地址，不区分大小写
+func containsAddress(list []string, address string) bool {
+	for _, a := range list {
+		if strings.EqualFold(strings.TrimSpace(a), address) {
+			return true
+		}
+	}
+	return false
+}
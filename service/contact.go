@@ -0,0 +1,227 @@
+package service
+
+import (
+	"email/controller/response"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"encoding/json"
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 自动补全默认返回数量
+const contactSuggestionLimit = 10
+
+// GetContactListProcess 获取联系人列表
+func GetContactListProcess(userID uint, page, group, recent string) (*models.ContactList, error) {
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt <= 0 {
+		return nil, errors.New(response.IncorrectPageParameterCode.ErrMessage)
+	}
+	var recentFilter *bool
+	if recent != "" {
+		r := recent == "true"
+		recentFilter = &r
+	}
+	contacts, total, err := dao.GetContacts(userID, pageInt, global.Config.API.EmailCountPerPage, group, recentFilter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ContactList{Contacts: contacts, Total: total, Page: pageInt}, nil
+}
+
+// GetContactDetailsProcess 获取联系人详情
+func GetContactDetailsProcess(userID, contactID uint) (*models.Contact, error) {
+	return dao.GetContactByID(userID, contactID)
+}
+
+// AddContactProcess 新增联系人
+func AddContactProcess(userID uint, req models.ContactRequest) (*models.Contact, error) {
+	contact, err := buildContact(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.AddContact(contact); err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// UpdateContactProcess 更新联系人
+func UpdateContactProcess(userID uint, req models.ContactRequest) (*models.Contact, error) {
+	if req.ID == 0 {
+		return nil, errors.New("contact id is required")
+	}
+	contact, err := buildContact(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.UpdateContact(contact); err != nil {
+		return nil, err
+	}
+	return dao.GetContactByID(userID, req.ID)
+}
+
+// DeleteContactProcess 删除联系人
+func DeleteContactProcess(userID, contactID uint) error {
+	return dao.DeleteContact(userID, contactID)
+}
+
+// AutocompleteContactsProcess 联系人自动补全
+func AutocompleteContactsProcess(userID uint, keyword, limit string) ([]models.ContactSuggestion, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return []models.ContactSuggestion{}, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 || n > 50 {
+		n = contactSuggestionLimit
+	}
+	return dao.SearchContactSuggestions(userID, keyword, n)
+}
+
+// ImportVCardProcess 导入 vCard，UID 相同的联系人会被覆盖
+func ImportVCardProcess(userID uint, data string) (int, error) {
+	cards, err := utils.ParseVCards(data)
+	if err != nil {
+		return 0, err
+	}
+	contacts := make([]models.Contact, 0, len(cards))
+	for _, card := range cards {
+		contact, err := ContactFromVCard(userID, card)
+		if err != nil {
+			global.Log.Warnf("跳过无法导入的联系人 [ %s ]: %v", card.FullName, err)
+			continue
+		}
+		contacts = append(contacts, *contact)
+	}
+	if len(contacts) == 0 {
+		return 0, errors.New("no valid contacts found")
+	}
+	if err := dao.UpsertContactsByUID(contacts); err != nil {
+		return 0, err
+	}
+	return len(contacts), nil
+}
+
+// ExportVCardProcess 导出全部已保存联系人为 vCard 4.0
+func ExportVCardProcess(userID uint) (string, error) {
+	contacts, err := dao.GetAllContacts(userID)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, c := range contacts {
+		b.WriteString(utils.FormatVCard(ContactToVCard(&c)))
+	}
+	return b.String(), nil
+}
+
+// HarvestRecipientsProcess 将发件的收件人收集为最近联系人
+func HarvestRecipientsProcess(userID uint, ownAddress string, lists ...[]string) {
+	for _, list := range lists {
+		for _, entry := range list {
+			addrs, err := mail.ParseAddressList(entry)
+			if err != nil {
+				continue
+			}
+			for _, a := range addrs {
+				if strings.EqualFold(a.Address, ownAddress) {
+					continue
+				}
+				if err := dao.RecordContactUsage(userID, a.Name, a.Address); err != nil {
+					global.Log.Warnf("记录联系人 [ %s ] 失败: %v", a.Address, err)
+				}
+			}
+		}
+	}
+}
+
+// ContactFromVCard 将 vCard 转换为联系人
+func ContactFromVCard(userID uint, card utils.VCard) (*models.Contact, error) {
+	uid := card.UID
+	if uid == "" {
+		uid = uuid.New().String()
+	}
+	name := card.FullName
+	if name == "" && len(card.Emails) > 0 {
+		name = card.Emails[0]
+	}
+	if strings.TrimSpace(name) == "" {
+		name = card.Phone
+	}
+	return buildContact(userID, models.ContactRequest{
+		Name:   name,
+		Emails: card.Emails,
+		Phone:  card.Phone,
+		Notes:  card.Note,
+		Groups: card.Categories,
+	}, uid)
+}
+
+// ContactToVCard 将联系人转换为 vCard
+func ContactToVCard(c *models.Contact) utils.VCard {
+	var emails, groups []string
+	_ = json.Unmarshal(c.Emails, &emails)
+	_ = json.Unmarshal(c.Groups, &groups)
+	return utils.VCard{
+		UID:        c.UID,
+		FullName:   c.Name,
+		Emails:     emails,
+		Phone:      c.Phone,
+		Note:       c.Notes,
+		Categories: groups,
+		Rev:        c.UpdatedAt,
+	}
+}
+
+// buildContact 校验并规范化联系人，地址统一转为小写并去重
+func buildContact(userID uint, req models.ContactRequest, uid ...string) (*models.Contact, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("contact name cannot be empty")
+	}
+	seen := map[string]bool{}
+	emails := []string{}
+	for _, e := range req.Emails {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		if err := utils.ValidateEmailAddress(e); err != nil {
+			return nil, err
+		}
+		seen[e] = true
+		emails = append(emails, e)
+	}
+	// 手机客户端同步的联系人常常只有电话
+	phone := strings.TrimSpace(req.Phone)
+	if len(emails) == 0 && phone == "" {
+		return nil, errors.New("contact must have an email address or a phone number")
+	}
+	groups := []string{}
+	for _, g := range req.Groups {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	contact := &models.Contact{
+		ID:             req.ID,
+		EmailAccountID: userID,
+		Name:           name,
+		Emails:         utils.ParseSliceJson(emails),
+		Phone:          phone,
+		Notes:          req.Notes,
+		Groups:         utils.ParseSliceJson(groups),
+	}
+	if len(uid) > 0 {
+		contact.UID = uid[0]
+	}
+	return contact, nil
+}
@@ -0,0 +1,547 @@
+package service
+
+import (
+	"crypto/sha1"
+	"email/dao"
+	"email/models"
+	"email/service/dav"
+	"email/utils"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DavPrefix CardDAV/CalDAV 服务的路径前缀
+const DavPrefix = "/dav"
+
+// 当前用户对通讯录与日历拥有的权限
+const davPrivileges = "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>" +
+	"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>"
+
+var (
+	errDavNotFound  = errors.New("dav resource not found")
+	errDavForbidden = errors.New("dav resource belongs to another account")
+)
+
+// DavResult DAV 请求处理结果
+type DavResult struct {
+	Status      int
+	ContentType string
+	ETag        string
+	Body        []byte
+}
+
+type davKind int
+
+const (
+	davRoot davKind = iota
+	davPrincipal
+	davAddressbookHome
+	davAddressbook
+	davContact
+	davCalendarHome
+	davCalendar
+	davEvent
+)
+
+// davTarget 请求路径对应的资源
+type davTarget struct {
+	kind     davKind
+	resource string // 联系人或事件的资源名，不含扩展名
+}
+
+// davItem 通讯录或日历中的一个资源
+type davItem struct {
+	name string
+	data string
+	etag string
+}
+
+// DavPropfindProcess 处理 PROPFIND
+func DavPropfindProcess(account *models.EmailAccount, path, depth string, body []byte) (*DavResult, error) {
+	t, err := parseDavPath(account, path)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	req, err := dav.ParsePropfind(body)
+	if err != nil {
+		return &DavResult{Status: http.StatusBadRequest}, nil
+	}
+	var responses []dav.Response
+	add := func(t davTarget, props []dav.Prop) {
+		found, missing := dav.Select(props, req)
+		responses = append(responses, dav.Response{Href: davHref(account, t), Found: found, NotFound: missing})
+	}
+	if t.kind == davContact || t.kind == davEvent {
+		item, err := getDavItem(account, t)
+		if err != nil {
+			return davErrorResult(err), nil
+		}
+		add(*t, davItemProps(t.kind, item, false))
+		return davMultiStatus(responses), nil
+	}
+	props, items, err := davCollectionProps(account, t.kind)
+	if err != nil {
+		return nil, err
+	}
+	add(*t, props)
+	if depth == "0" {
+		return davMultiStatus(responses), nil
+	}
+	itemKind := davContact
+	if t.kind == davCalendar {
+		itemKind = davEvent
+	}
+	for _, item := range items {
+		add(davTarget{kind: itemKind, resource: item.name}, davItemProps(itemKind, item, false))
+	}
+	for _, child := range davChildren(t.kind) {
+		props, _, err := davCollectionProps(account, child)
+		if err != nil {
+			return nil, err
+		}
+		add(davTarget{kind: child}, props)
+	}
+	return davMultiStatus(responses), nil
+}
+
+// DavProppatchProcess 处理 PROPPATCH，集合属性不允许修改，统一返回 403
+func DavProppatchProcess(account *models.EmailAccount, path string, body []byte) (*DavResult, error) {
+	t, err := parseDavPath(account, path)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	req, err := dav.ParsePropfind(body)
+	if err != nil {
+		return &DavResult{Status: http.StatusBadRequest}, nil
+	}
+	return &DavResult{
+		Status:      http.StatusMultiStatus,
+		ContentType: "application/xml; charset=utf-8",
+		Body:        dav.PropStatus(davHref(account, *t), req.Props, http.StatusForbidden),
+	}, nil
+}
+
+// DavReportProcess 处理 REPORT，支持 multiget 与 query
+func DavReportProcess(account *models.EmailAccount, path string, body []byte) (*DavResult, error) {
+	t, err := parseDavPath(account, path)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	r, err := dav.ParseReport(body)
+	if err != nil {
+		return &DavResult{Status: http.StatusBadRequest}, nil
+	}
+	itemKind, ns := davContact, dav.NsCardDAV
+	prefix := "addressbook"
+	if t.kind == davCalendar {
+		itemKind, ns, prefix = davEvent, dav.NsCalDAV, "calendar"
+	}
+	if (t.kind != davAddressbook && t.kind != davCalendar) || r.Name.Space != ns {
+		return davUnsupportedReport(), nil
+	}
+	req := &dav.PropRequest{Props: r.Props, AllProp: len(r.Props) == 0}
+	var responses []dav.Response
+	switch r.Name.Local {
+	case prefix + "-multiget":
+		for _, href := range r.Hrefs {
+			child, err := parseDavHref(account, href)
+			if err != nil || child.kind != itemKind {
+				responses = append(responses, dav.Response{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			item, err := getDavItem(account, child)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				responses = append(responses, dav.Response{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			found, missing := dav.Select(davItemProps(itemKind, item, true), req)
+			responses = append(responses, dav.Response{Href: href, Found: found, NotFound: missing})
+		}
+	case prefix + "-query":
+		items, err := listDavItems(account, t.kind, r.Start, r.End)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			found, missing := dav.Select(davItemProps(itemKind, item, true), req)
+			responses = append(responses, dav.Response{
+				Href:     davHref(account, davTarget{kind: itemKind, resource: item.name}),
+				Found:    found,
+				NotFound: missing,
+			})
+		}
+	default:
+		return davUnsupportedReport(), nil
+	}
+	return davMultiStatus(responses), nil
+}
+
+// DavGetProcess 获取联系人 vCard 或事件 iCalendar
+func DavGetProcess(account *models.EmailAccount, path string) (*DavResult, error) {
+	t, err := parseDavPath(account, path)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	if t.kind != davContact && t.kind != davEvent {
+		return &DavResult{Status: http.StatusMethodNotAllowed}, nil
+	}
+	item, err := getDavItem(account, t)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	return &DavResult{Status: http.StatusOK, ContentType: davContentType(t.kind), ETag: item.etag, Body: []byte(item.data)}, nil
+}
+
+// DavPutProcess 新增或覆盖联系人/事件，支持 If-Match 与 If-None-Match 条件请求
+func DavPutProcess(account *models.EmailAccount, path string, body []byte, ifMatch, ifNoneMatch string) (*DavResult, error) {
+	t, err := parseDavPath(account, path)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	if t.kind != davContact && t.kind != davEvent {
+		return &DavResult{Status: http.StatusMethodNotAllowed}, nil
+	}
+	existing, err := getDavItem(account, t)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if !davPreconditionOK(existing, ifMatch, ifNoneMatch) {
+		return &DavResult{Status: http.StatusPreconditionFailed}, nil
+	}
+	data := string(body)
+	switch t.kind {
+	case davContact:
+		cards, err := utils.ParseVCards(data)
+		if err != nil || len(cards) != 1 {
+			return davPreconditionFailed(dav.Name(dav.NsCardDAV, "valid-address-data")), nil
+		}
+		contact, err := ContactFromVCard(account.ID, cards[0])
+		if err != nil {
+			return davPreconditionFailed(dav.Name(dav.NsCardDAV, "valid-address-data")), nil
+		}
+		contact.UID = t.resource
+		contact.VCard = data
+		if err := dao.UpsertContactsByUID([]models.Contact{*contact}); err != nil {
+			return nil, err
+		}
+	case davEvent:
+		ev, err := utils.ParseICalendar(data)
+		if err != nil {
+			return davPreconditionFailed(dav.Name(dav.NsCalDAV, "valid-calendar-data")), nil
+		}
+		err = dao.SaveCalendarEvent(&models.CalendarEvent{
+			EmailAccountID: account.ID,
+			UID:            t.resource,
+			Summary:        ev.Summary,
+			StartAt:        ev.Start,
+			EndAt:          ev.End,
+			Recurring:      ev.Recurring,
+			Data:           data,
+			Source:         models.CalendarSourceDAV,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	status := http.StatusNoContent
+	if existing == nil {
+		status = http.StatusCreated
+	}
+	return &DavResult{Status: status, ETag: davETag(data)}, nil
+}
+
+// DavDeleteProcess 删除联系人/事件
+func DavDeleteProcess(account *models.EmailAccount, path, ifMatch string) (*DavResult, error) {
+	t, err := parseDavPath(account, path)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	if t.kind != davContact && t.kind != davEvent {
+		return &DavResult{Status: http.StatusForbidden}, nil
+	}
+	existing, err := getDavItem(account, t)
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	if !davPreconditionOK(existing, ifMatch, "") {
+		return &DavResult{Status: http.StatusPreconditionFailed}, nil
+	}
+	if t.kind == davContact {
+		err = dao.DeleteContactByUID(account.ID, t.resource)
+	} else {
+		err = dao.DeleteCalendarEventByUID(account.ID, t.resource)
+	}
+	if err != nil {
+		return davErrorResult(err), nil
+	}
+	return &DavResult{Status: http.StatusNoContent}, nil
+}
+
+// parseDavPath 解析 /dav 之后的路径，账户段必须与认证账户一致
+func parseDavPath(account *models.EmailAccount, p string) (*davTarget, error) {
+	segs := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segs) == 1 && segs[0] == "" {
+		return &davTarget{kind: davRoot}, nil
+	}
+	if len(segs) < 2 {
+		return nil, errDavNotFound
+	}
+	if !strings.EqualFold(segs[1], account.EmailAddress) {
+		return nil, errDavForbidden
+	}
+	var home, collection, item davKind
+	var ext string
+	switch segs[0] {
+	case "principals":
+		if len(segs) == 2 {
+			return &davTarget{kind: davPrincipal}, nil
+		}
+		return nil, errDavNotFound
+	case "addressbooks":
+		home, collection, item, ext = davAddressbookHome, davAddressbook, davContact, ".vcf"
+	case "calendars":
+		home, collection, item, ext = davCalendarHome, davCalendar, davEvent, ".ics"
+	default:
+		return nil, errDavNotFound
+	}
+	switch {
+	case len(segs) == 2:
+		return &davTarget{kind: home}, nil
+	case segs[2] != "default":
+		return nil, errDavNotFound
+	case len(segs) == 3:
+		return &davTarget{kind: collection}, nil
+	case len(segs) == 4 && strings.HasSuffix(segs[3], ext) && len(segs[3]) > len(ext):
+		return &davTarget{kind: item, resource: strings.TrimSuffix(segs[3], ext)}, nil
+	}
+	return nil, errDavNotFound
+}
+
+// parseDavHref 解析 multiget 中的 href，可以是绝对 URL 或路径
+func parseDavHref(account *models.EmailAccount, href string) (*davTarget, error) {
+	u, err := url.Parse(href)
+	if err != nil || !strings.HasPrefix(u.Path, DavPrefix+"/") {
+		return nil, errDavNotFound
+	}
+	return parseDavPath(account, strings.TrimPrefix(u.Path, DavPrefix))
+}
+
+// davHref 生成资源的 href
+func davHref(account *models.EmailAccount, t davTarget) string {
+	acct := url.PathEscape(account.EmailAddress)
+	switch t.kind {
+	case davPrincipal:
+		return DavPrefix + "/principals/" + acct + "/"
+	case davAddressbookHome:
+		return DavPrefix + "/addressbooks/" + acct + "/"
+	case davAddressbook:
+		return DavPrefix + "/addressbooks/" + acct + "/default/"
+	case davContact:
+		return DavPrefix + "/addressbooks/" + acct + "/default/" + url.PathEscape(t.resource) + ".vcf"
+	case davCalendarHome:
+		return DavPrefix + "/calendars/" + acct + "/"
+	case davCalendar:
+		return DavPrefix + "/calendars/" + acct + "/default/"
+	case davEvent:
+		return DavPrefix + "/calendars/" + acct + "/default/" + url.PathEscape(t.resource) + ".ics"
+	}
+	return DavPrefix + "/"
+}
+
+// davChildren 集合的下级集合
+func davChildren(kind davKind) []davKind {
+	switch kind {
+	case davRoot:
+		return []davKind{davPrincipal, davAddressbookHome, davCalendarHome}
+	case davAddressbookHome:
+		return []davKind{davAddressbook}
+	case davCalendarHome:
+		return []davKind{davCalendar}
+	}
+	return nil
+}
+
+// davCollectionProps 集合属性，通讯录与日历同时返回其中的资源
+func davCollectionProps(account *models.EmailAccount, kind davKind) ([]dav.Prop, []*davItem, error) {
+	principal := davHref(account, davTarget{kind: davPrincipal})
+	props := []dav.Prop{
+		dav.Hrefs(dav.Name(dav.NsDAV, "current-user-principal"), principal),
+	}
+	switch kind {
+	case davRoot, davAddressbookHome, davCalendarHome:
+		props = append(props, dav.Raw(dav.Name(dav.NsDAV, "resourcetype"), "<d:collection/>"))
+	case davPrincipal:
+		props = append(props,
+			dav.Raw(dav.Name(dav.NsDAV, "resourcetype"), "<d:principal/>"),
+			dav.Text(dav.Name(dav.NsDAV, "displayname"), account.UserName),
+			dav.Hrefs(dav.Name(dav.NsDAV, "principal-URL"), principal),
+			dav.Hrefs(dav.Name(dav.NsCardDAV, "addressbook-home-set"), davHref(account, davTarget{kind: davAddressbookHome})),
+			dav.Hrefs(dav.Name(dav.NsCalDAV, "calendar-home-set"), davHref(account, davTarget{kind: davCalendarHome})),
+			dav.Hrefs(dav.Name(dav.NsCalDAV, "calendar-user-address-set"), "mailto:"+account.EmailAddress),
+		)
+	case davAddressbook, davCalendar:
+		items, err := listDavItems(account, kind, nil, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		props = append(props,
+			dav.Hrefs(dav.Name(dav.NsDAV, "owner"), principal),
+			dav.Text(dav.Name(dav.NsCalServer, "getctag"), davCTag(items)),
+			dav.Raw(dav.Name(dav.NsDAV, "current-user-privilege-set"), davPrivileges),
+		)
+		if kind == davAddressbook {
+			props = append(props,
+				dav.Raw(dav.Name(dav.NsDAV, "resourcetype"), "<d:collection/><card:addressbook/>"),
+				dav.Text(dav.Name(dav.NsDAV, "displayname"), "Contacts"),
+				dav.Raw(dav.Name(dav.NsCardDAV, "supported-address-data"),
+					`<card:address-data-type content-type="text/vcard" version="3.0"/><card:address-data-type content-type="text/vcard" version="4.0"/>`),
+				dav.Raw(dav.Name(dav.NsDAV, "supported-report-set"),
+					"<d:supported-report><d:report><card:addressbook-multiget/></d:report></d:supported-report>"+
+						"<d:supported-report><d:report><card:addressbook-query/></d:report></d:supported-report>"),
+			)
+		} else {
+			props = append(props,
+				dav.Raw(dav.Name(dav.NsDAV, "resourcetype"), "<d:collection/><cal:calendar/>"),
+				dav.Text(dav.Name(dav.NsDAV, "displayname"), "Calendar"),
+				dav.Raw(dav.Name(dav.NsCalDAV, "supported-calendar-component-set"), `<cal:comp name="VEVENT"/>`),
+				dav.Raw(dav.Name(dav.NsDAV, "supported-report-set"),
+					"<d:supported-report><d:report><cal:calendar-multiget/></d:report></d:supported-report>"+
+						"<d:supported-report><d:report><cal:calendar-query/></d:report></d:supported-report>"),
+			)
+		}
+		return props, items, nil
+	}
+	return props, nil, nil
+}
+
+// davItemProps 资源属性，withData 为 true 时包含 vCard / iCalendar 内容
+func davItemProps(kind davKind, item *davItem, withData bool) []dav.Prop {
+	props := []dav.Prop{
+		dav.Raw(dav.Name(dav.NsDAV, "resourcetype"), ""),
+		dav.Text(dav.Name(dav.NsDAV, "getetag"), item.etag),
+		dav.Text(dav.Name(dav.NsDAV, "getcontenttype"), davContentType(kind)),
+	}
+	if withData {
+		if kind == davContact {
+			props = append(props, dav.Text(dav.Name(dav.NsCardDAV, "address-data"), item.data))
+		} else {
+			props = append(props, dav.Text(dav.Name(dav.NsCalDAV, "calendar-data"), item.data))
+		}
+	}
+	return props
+}
+
+// listDavItems 获取通讯录或日历中的资源
+func listDavItems(account *models.EmailAccount, kind davKind, start, end *time.Time) ([]*davItem, error) {
+	var items []*davItem
+	if kind == davAddressbook {
+		contacts, err := dao.GetAllContacts(account.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range contacts {
+			items = append(items, newDavItem(contacts[i].UID, davContactData(&contacts[i])))
+		}
+		return items, nil
+	}
+	events, err := dao.GetCalendarEvents(account.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		items = append(items, newDavItem(e.UID, e.Data))
+	}
+	return items, nil
+}
+
+// getDavItem 获取单个联系人或事件
+func getDavItem(account *models.EmailAccount, t *davTarget) (*davItem, error) {
+	if t.kind == davContact {
+		c, err := dao.GetContactByUID(account.ID, t.resource)
+		if err != nil {
+			return nil, err
+		}
+		return newDavItem(c.UID, davContactData(c)), nil
+	}
+	e, err := dao.GetCalendarEventByUID(account.ID, t.resource)
+	if err != nil {
+		return nil, err
+	}
+	return newDavItem(e.UID, e.Data), nil
+}
+
+// davContactData 优先返回客户端上传的原始 vCard，以免丢失未建模的字段
+func davContactData(c *models.Contact) string {
+	if c.VCard != "" {
+		return c.VCard
+	}
+	return utils.FormatVCard(ContactToVCard(c))
+}
+
+func newDavItem(name, data string) *davItem {
+	return &davItem{name: name, data: data, etag: davETag(data)}
+}
+
+func davETag(data string) string {
+	sum := sha1.Sum([]byte(data))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// davCTag 集合内任何资源变化都会改变 ctag
+func davCTag(items []*davItem) string {
+	h := sha1.New()
+	for _, item := range items {
+		h.Write([]byte(item.name + ":" + item.etag + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func davContentType(kind davKind) string {
+	if kind == davContact {
+		return "text/vcard; charset=utf-8"
+	}
+	return "text/calendar; charset=utf-8; component=vevent"
+}
+
+// davPreconditionOK 校验 If-Match / If-None-Match
+func davPreconditionOK(existing *davItem, ifMatch, ifNoneMatch string) bool {
+	if ifNoneMatch == "*" && existing != nil {
+		return false
+	}
+	if ifMatch != "" && (existing == nil || (ifMatch != "*" && ifMatch != existing.etag)) {
+		return false
+	}
+	return true
+}
+
+func davMultiStatus(responses []dav.Response) *DavResult {
+	return &DavResult{Status: http.StatusMultiStatus, ContentType: "application/xml; charset=utf-8", Body: dav.MultiStatus(responses)}
+}
+
+func davPreconditionFailed(condition xml.Name) *DavResult {
+	return &DavResult{Status: http.StatusForbidden, ContentType: "application/xml; charset=utf-8", Body: dav.ErrorBody(condition)}
+}
+
+func davUnsupportedReport() *DavResult {
+	return davPreconditionFailed(dav.Name(dav.NsDAV, "supported-report"))
+}
+
+func davErrorResult(err error) *DavResult {
+	switch {
+	case errors.Is(err, errDavForbidden):
+		return &DavResult{Status: http.StatusForbidden}
+	case errors.Is(err, errDavNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return &DavResult{Status: http.StatusNotFound}
+	}
+	return &DavResult{Status: http.StatusInternalServerError}
+}
@@ -0,0 +1,311 @@
+// Package dav 实现 WebDAV / CardDAV / CalDAV 所需的 XML 请求解析与 multistatus 响应生成
+package dav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 命名空间
+const (
+	NsDAV       = "DAV:"
+	NsCardDAV   = "urn:ietf:params:xml:ns:carddav"
+	NsCalDAV    = "urn:ietf:params:xml:ns:caldav"
+	NsCalServer = "http://calendarserver.org/ns/"
+)
+
+// 响应根元素的命名空间声明
+const rootNamespaces = `xmlns:d="DAV:" xmlns:card="` + NsCardDAV + `" xmlns:cal="` + NsCalDAV + `" xmlns:cs="` + NsCalServer + `"`
+
+// 响应中使用的命名空间前缀
+var prefixes = map[string]string{
+	NsDAV:       "d",
+	NsCardDAV:   "card",
+	NsCalDAV:    "cal",
+	NsCalServer: "cs",
+}
+
+// Name 构造属性名
+func Name(space, local string) xml.Name {
+	return xml.Name{Space: space, Local: local}
+}
+
+// PropRequest PROPFIND / PROPPATCH 请求中要求的属性
+type PropRequest struct {
+	AllProp  bool
+	PropName bool
+	Props    []xml.Name
+}
+
+// Report REPORT 请求
+type Report struct {
+	Name  xml.Name   // 报告类型，如 addressbook-multiget
+	Props []xml.Name // 要求返回的属性
+	Hrefs []string   // multiget 指定的资源
+	Start *time.Time // calendar-query 的 time-range
+	End   *time.Time
+}
+
+// ParsePropfind 解析 PROPFIND / PROPPATCH 请求体，空请求体视为 allprop
+func ParsePropfind(body []byte) (*PropRequest, error) {
+	req := &PropRequest{}
+	if len(bytes.TrimSpace(body)) == 0 {
+		req.AllProp = true
+		return req, nil
+	}
+	err := walk(body, func(path []xml.Name, el xml.StartElement) {
+		switch {
+		case el.Name == Name(NsDAV, "allprop"):
+			req.AllProp = true
+		case el.Name == Name(NsDAV, "propname"):
+			req.PropName = true
+		case len(path) > 0 && path[len(path)-1] == Name(NsDAV, "prop"):
+			req.Props = append(req.Props, el.Name)
+		}
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !req.PropName && len(req.Props) == 0 {
+		req.AllProp = true
+	}
+	return req, nil
+}
+
+// ParseReport 解析 REPORT 请求体
+func ParseReport(body []byte) (*Report, error) {
+	r := &Report{}
+	err := walk(body, func(path []xml.Name, el xml.StartElement) {
+		if len(path) == 0 {
+			r.Name = el.Name
+			return
+		}
+		parent := path[len(path)-1]
+		switch {
+		case parent == Name(NsDAV, "prop") && len(path) == 2:
+			r.Props = append(r.Props, el.Name)
+		case el.Name == Name(NsCalDAV, "time-range"):
+			for _, a := range el.Attr {
+				t, err := time.Parse("20060102T150405Z", a.Value)
+				if err != nil {
+					continue
+				}
+				switch a.Name.Local {
+				case "start":
+					r.Start = &t
+				case "end":
+					r.End = &t
+				}
+			}
+		}
+	}, func(path []xml.Name, text string) {
+		if len(path) == 2 && path[1] == Name(NsDAV, "href") {
+			if href := strings.TrimSpace(text); href != "" {
+				r.Hrefs = append(r.Hrefs, href)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r.Name.Local == "" {
+		return nil, errors.New("empty report request")
+	}
+	return r, nil
+}
+
+// walk 遍历 XML，path 为当前元素的祖先列表
+func walk(body []byte, onStart func(path []xml.Name, el xml.StartElement), onText func(path []xml.Name, text string)) error {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var path []xml.Name
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if onStart != nil {
+				onStart(path, t)
+			}
+			path = append(path, t.Name)
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		case xml.CharData:
+			if onText != nil {
+				onText(path, string(t))
+			}
+		}
+	}
+}
+
+// Prop 响应中的一个属性，Inner 为已转义的 XML 内容
+type Prop struct {
+	Name  xml.Name
+	Inner string
+}
+
+// Text 文本属性
+func Text(name xml.Name, s string) Prop {
+	return Prop{Name: name, Inner: Escape(s)}
+}
+
+// Raw 由子元素组成的属性
+func Raw(name xml.Name, inner string) Prop {
+	return Prop{Name: name, Inner: inner}
+}
+
+// Hrefs 由 href 列表组成的属性
+func Hrefs(name xml.Name, hrefs ...string) Prop {
+	var b strings.Builder
+	for _, h := range hrefs {
+		b.WriteString("<d:href>" + Escape(h) + "</d:href>")
+	}
+	return Prop{Name: name, Inner: b.String()}
+}
+
+// Element 生成空元素，如 <d:collection/>
+func Element(name xml.Name) string {
+	return openTag(name, true)
+}
+
+// Escape 转义 XML 文本
+func Escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Response multistatus 中的一项，Status 不为 0 时整体返回该状态而不返回属性
+type Response struct {
+	Href     string
+	Status   int
+	Found    []Prop
+	NotFound []xml.Name
+}
+
+// Select 按请求筛选资源属性，未提供的属性归入 NotFound
+func Select(props []Prop, req *PropRequest) ([]Prop, []xml.Name) {
+	if req.AllProp {
+		return props, nil
+	}
+	if req.PropName {
+		names := make([]Prop, 0, len(props))
+		for _, p := range props {
+			names = append(names, Prop{Name: p.Name})
+		}
+		return names, nil
+	}
+	var found []Prop
+	var missing []xml.Name
+	for _, name := range req.Props {
+		ok := false
+		for _, p := range props {
+			if p.Name == name {
+				found = append(found, p)
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			missing = append(missing, name)
+		}
+	}
+	return found, missing
+}
+
+// MultiStatus 生成 207 响应体
+func MultiStatus(responses []Response) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString("<d:multistatus " + rootNamespaces + ">")
+	for _, r := range responses {
+		b.WriteString("<d:response><d:href>" + Escape(r.Href) + "</d:href>")
+		if r.Status != 0 {
+			b.WriteString("<d:status>" + statusLine(r.Status) + "</d:status></d:response>")
+			continue
+		}
+		if len(r.Found) > 0 || len(r.NotFound) == 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range r.Found {
+				writeProp(&b, p)
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(r.NotFound) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, n := range r.NotFound {
+				b.WriteString(openTag(n, true))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+	return b.Bytes()
+}
+
+// PropStatus 生成所有属性统一返回同一状态的 207 响应体，用于拒绝 PROPPATCH
+func PropStatus(href string, names []xml.Name, status int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString("<d:multistatus " + rootNamespaces + "><d:response><d:href>" + Escape(href) + "</d:href><d:propstat><d:prop>")
+	for _, n := range names {
+		b.WriteString(openTag(n, true))
+	}
+	b.WriteString("</d:prop><d:status>" + statusLine(status) + "</d:status></d:propstat></d:response></d:multistatus>")
+	return b.Bytes()
+}
+
+// ErrorBody 生成前置条件错误响应体 (RFC 4918 16)
+func ErrorBody(condition xml.Name) []byte {
+	return []byte(`<?xml version="1.0" encoding="utf-8"?>` + "\n" +
+		"<d:error " + rootNamespaces + ">" + openTag(condition, true) + "</d:error>")
+}
+
+func writeProp(b *bytes.Buffer, p Prop) {
+	if p.Inner == "" {
+		b.WriteString(openTag(p.Name, true))
+		return
+	}
+	b.WriteString(openTag(p.Name, false))
+	b.WriteString(p.Inner)
+	b.WriteString("</" + qualified(p.Name) + ">")
+}
+
+// openTag 生成开始标签，未知命名空间在元素上单独声明
+func openTag(name xml.Name, empty bool) string {
+	tag := "<" + qualified(name)
+	if _, ok := prefixes[name.Space]; !ok && name.Space != "" {
+		tag += ` xmlns:x="` + Escape(name.Space) + `"`
+	}
+	if empty {
+		return tag + "/>"
+	}
+	return tag + ">"
+}
+
+func qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	if p, ok := prefixes[name.Space]; ok {
+		return p + ":" + name.Local
+	}
+	return "x:" + name.Local
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
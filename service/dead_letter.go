@@ -0,0 +1,72 @@
+package service
+
+import (
+	"email/controller/response"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// GetDeadLettersProcess 获取域名下的收信死信，status 为空时返回全部
+func GetDeadLettersProcess(emailAddress, page, status string) (*models.InboundDeadLetterList, error) {
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt <= 0 {
+		return nil, errors.New(response.IncorrectPageParameterCode.ErrMessage)
+	}
+	switch status {
+	case "", models.DeadLetterOpen, models.DeadLetterReprocessed, models.DeadLetterDiscarded:
+	default:
+		return nil, errors.New("status must be one of open, reprocessed, discarded")
+	}
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	list, total, err := dao.GetInboundDeadLetters(strings.ToLower(domainDetails.DomainName), status, pageInt, global.Config.API.EmailCountPerPage)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InboundDeadLetterList{DeadLetters: list, Total: total, Page: pageInt}, nil
+}
+
+// ReprocessDeadLettersProcess 重新处理域名下的死信，邮件从失败的阶段继续，已完成的收件人不会重复投递
+func ReprocessDeadLettersProcess(emailAddress string, req models.DeadLetterIDsRequest) (*models.DeadLetterActionResult, error) {
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.DeadLetterActionResult{}
+	for _, id := range req.IDs {
+		messageID, ok, err := dao.ReopenInboundDeadLetter(strings.ToLower(domainDetails.DomainName), id, emailAddress)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		result.Affected++
+		go func(messageID uint) {
+			if err := aws.ReprocessInboundMessage(messageID); err != nil {
+				global.Log.Errorf("重新处理收信 [ID: %d] 失败: %v", messageID, err)
+			}
+		}(messageID)
+	}
+	return result, nil
+}
+
+// DiscardDeadLettersProcess 丢弃域名下的死信，对应邮件不再处理
+func DiscardDeadLettersProcess(emailAddress string, req models.DeadLetterIDsRequest) (*models.DeadLetterActionResult, error) {
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := dao.DiscardInboundDeadLetters(strings.ToLower(domainDetails.DomainName), req.IDs, emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DeadLetterActionResult{Affected: affected}, nil
+}
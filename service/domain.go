@@ -3,10 +3,12 @@ package service
 import (
 	"email/controller/response"
 	"email/dao"
+	"email/global"
 	"email/models"
 	"email/utils"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"gorm.io/gorm"
@@ -57,6 +59,15 @@ func AddDomainEmailAccountProcess(emailAddress string, addAccountReq models.AddD
 
 		return nil, errors.New(fmt.Sprintf("%s [ Reason: %s ]", response.CreateEmailAccountFailedCode.ErrMessage, err.Error()))
 	}
+	// 通知订阅了账户创建事件的域名 Webhook
+	newAddress := strings.Split(addAccountReq.EmailAddress, "@")[0] + "@" + domainDetails.DomainName
+	if account, err := dao.IsAccountExist(newAddress, domainDetails.DomainName); err == nil {
+		dao.PublishWebhookEvent(account.ID, account.DomainID, account.EmailAddress, models.WebhookAccountCreated, &models.WebhookAccountData{
+			EmailAddress: account.EmailAddress,
+			UserName:     account.UserName,
+			Domain:       account.DomainName,
+		})
+	}
 	// 创建成功，返回新账户的详细信息
 	return &models.NewAccountDetails{
 		EmailAddress: strings.Split(addAccountReq.EmailAddress, "@")[0] + "@" + addAccountReq.DomainName,
@@ -79,3 +90,59 @@ func IsDomainAdmin(emailAddress string) (*models.Domain, error) {
 	}
 	return domainDetails, nil
 }
+
+// GetDomainPolicyProcess 获取域名策略，未设置时返回默认值
+func GetDomainPolicyProcess(emailAddress string) (*models.DomainPolicy, error) {
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := dao.GetDomainPolicy(domainDetails.ID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = &models.DomainPolicy{DomainID: domainDetails.ID, OrphanGraceHours: models.DefaultOrphanGraceHours}
+	}
+	return policy, nil
+}
+
+// UpdateDomainPolicyProcess 更新域名策略
+func UpdateDomainPolicyProcess(emailAddress string, req models.UpdateDomainPolicyRequest) (*models.DomainPolicy, error) {
+	policy, err := GetDomainPolicyProcess(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	policy.AttachmentReaperEnabled = *req.AttachmentReaperEnabled
+	if req.OrphanGraceHours != nil {
+		// 保留时长不超过 90 天
+		if *req.OrphanGraceHours < 0 || *req.OrphanGraceHours > 90*24 {
+			return nil, errors.New("orphan_grace_hours must be between 0 and 2160")
+		}
+		policy.OrphanGraceHours = *req.OrphanGraceHours
+	}
+	if req.RequireTwoFactor != nil {
+		policy.RequireTwoFactor = *req.RequireTwoFactor
+	}
+	if err := dao.SaveDomainPolicy(policy); err != nil {
+		return nil, err
+	}
+	return dao.GetDomainPolicy(policy.DomainID)
+}
+
+// GetReaperReportsProcess 获取域名的附件回收报告
+func GetReaperReportsProcess(emailAddress, page string) (*models.AttachmentReaperReportList, error) {
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt <= 0 {
+		return nil, errors.New(response.IncorrectPageParameterCode.ErrMessage)
+	}
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	reports, total, err := dao.GetAttachmentReaperReports(domainDetails.ID, pageInt, global.Config.API.EmailCountPerPage)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AttachmentReaperReportList{Reports: reports, Total: total, Page: pageInt}, nil
+}
@@ -2,13 +2,16 @@ package dovecot
 
 import (
 	"bytes"
+	"crypto/tls"
 	"email/global"
 	"email/models"
 	"errors"
 	"fmt"
+	"net"
+	"time"
+
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
-	"time"
 )
 
 type ImapConfig struct {
@@ -16,6 +19,11 @@ type ImapConfig struct {
 	Port     int // 使用 143 作为非加密端口
 	Username string
 	Password string
+	// 以下字段用于连接外部 IMAP 服务器
+	OAuthToken string      // 不为空时使用 XOAUTH2 认证
+	StartTLS   bool        // 先以明文连接再升级为 TLS
+	Dialer     *net.Dialer // 为空时使用默认 Dialer
+	Timeout    time.Duration
 }
 
 type MailMover struct {
@@ -31,20 +39,39 @@ func NewMailMover(config ImapConfig) *MailMover {
 
 // Imap连接
 func (m *MailMover) Connect() error {
-	fmt.Printf("Attempting to connect to %s:%d\n", m.config.Server, m.config.Port)
-
-	c, err := client.DialTLS(fmt.Sprintf("%s:%d", m.config.Server, m.config.Port), nil)
+	addr := fmt.Sprintf("%s:%d", m.config.Server, m.config.Port)
+	global.Log.Debugf("Attempting to connect to %s", addr)
+
+	dialer := m.config.Dialer
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+	var c *client.Client
+	var err error
+	if m.config.StartTLS {
+		if c, err = client.DialWithDialer(dialer, addr); err == nil {
+			if err = c.StartTLS(&tls.Config{ServerName: m.config.Server}); err != nil {
+				c.Logout()
+			}
+		}
+	} else {
+		c, err = client.DialWithDialerTLS(dialer, addr, nil)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to IMAP server: %v", err)
 	}
-	fmt.Println("Connection established")
+	c.Timeout = m.config.Timeout
 
-	fmt.Printf("Attempting to login as %s\n", m.config.Username)
-	if err := c.Login(m.config.Username, m.config.Password); err != nil {
+	global.Log.Debugf("Attempting to login as %s", m.config.Username)
+	if m.config.OAuthToken != "" {
+		err = c.Authenticate(&xoauth2Client{username: m.config.Username, token: m.config.OAuthToken})
+	} else {
+		err = c.Login(m.config.Username, m.config.Password)
+	}
+	if err != nil {
 		c.Logout()
 		return fmt.Errorf("failed to login: %v", err)
 	}
-	fmt.Println("Login successful")
 
 	m.client = c
 	return nil
@@ -118,14 +145,12 @@ func (m *MailMover) IsEmailExist(mailbox, messageID string) (bool, error) {
 }
 
 // 添加邮件到本地
-func (m *MailMover) AppendRawEmail(mailbox string, rawMessage []byte) error {
+func (m *MailMover) AppendRawEmail(mailbox string, flags []string, rawMessage []byte) error {
 	// 选择邮箱
 	if _, err := m.client.Select(mailbox, false); err != nil {
 		return err
 	}
 
-	flags := []string{}
-
 	err := m.client.Append(mailbox, flags, time.Now(), bytes.NewReader(rawMessage))
 	if err != nil {
 		return err
@@ -161,7 +186,14 @@ func ImapOperatorEmail(option models.ImapOperatorOptions) error {
 			return err
 		}
 		if !exist {
-			return mover.AppendRawEmail(option.TargetMailBox, option.EmailRawMessage)
+			flags := []string{}
+			if option.ReadStatus {
+				flags = append(flags, imap.SeenFlag)
+			}
+			if option.Flagged {
+				flags = append(flags, imap.FlaggedFlag)
+			}
+			return mover.AppendRawEmail(option.TargetMailBox, flags, option.EmailRawMessage)
 		}
 		return nil
 	}
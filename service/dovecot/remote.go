@@ -0,0 +1,121 @@
+package dovecot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// xoauth2Client 实现 XOAUTH2 认证，供 Gmail、Outlook 等只允许 OAuth 的服务器使用
+type xoauth2Client struct {
+	username string
+	token    string
+}
+
+func (a *xoauth2Client) Start() (string, []byte, error) {
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+// Next 认证失败时服务器返回错误详情，回复空行后服务器会结束认证
+func (a *xoauth2Client) Next(challenge []byte) ([]byte, error) {
+	return []byte{}, nil
+}
+
+// RemoteMessage 远程邮件的元数据
+type RemoteMessage struct {
+	UID          uint32
+	MessageID    string
+	Flags        []string
+	InternalDate time.Time
+	Size         uint32
+}
+
+// ListMailboxes 列出所有文件夹
+func (m *MailMover) ListMailboxes() ([]*imap.MailboxInfo, error) {
+	ch := make(chan *imap.MailboxInfo, 16)
+	done := make(chan error, 1)
+	go func() { done <- m.client.List("", "*", ch) }()
+	var boxes []*imap.MailboxInfo
+	for box := range ch {
+		boxes = append(boxes, box)
+	}
+	return boxes, <-done
+}
+
+// SelectReadOnly 以只读方式选择文件夹，不会改变远程邮件的已读状态
+func (m *MailMover) SelectReadOnly(mailbox string) (*imap.MailboxStatus, error) {
+	return m.client.Select(mailbox, true)
+}
+
+// SearchUIDsAfter 返回当前文件夹中 UID 大于 uid 的邮件，按 UID 升序排列
+func (m *MailMover) SearchUIDsAfter(uid uint32) ([]uint32, error) {
+	criteria := imap.NewSearchCriteria()
+	criteria.Uid = new(imap.SeqSet)
+	criteria.Uid.AddRange(uid+1, 0)
+	uids, err := m.client.UidSearch(criteria)
+	if err != nil {
+		return nil, err
+	}
+	// "n:*" 在没有更大 UID 时仍会返回最后一封邮件
+	result := uids[:0]
+	for _, u := range uids {
+		if u > uid {
+			result = append(result, u)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result, nil
+}
+
+// FetchMessages 获取当前文件夹中指定邮件的元数据
+func (m *MailMover) FetchMessages(uids []uint32) ([]RemoteMessage, error) {
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+	ch := make(chan *imap.Message, 16)
+	done := make(chan error, 1)
+	items := []imap.FetchItem{imap.FetchUid, imap.FetchFlags, imap.FetchEnvelope, imap.FetchInternalDate, imap.FetchRFC822Size}
+	go func() { done <- m.client.UidFetch(seqSet, items, ch) }()
+	var result []RemoteMessage
+	for msg := range ch {
+		rm := RemoteMessage{UID: msg.Uid, Flags: msg.Flags, InternalDate: msg.InternalDate, Size: msg.Size}
+		if msg.Envelope != nil {
+			rm.MessageID = msg.Envelope.MessageId
+		}
+		result = append(result, rm)
+	}
+	if err := <-done; err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UID < result[j].UID })
+	return result, nil
+}
+
+// FetchRawMessage 获取当前文件夹中一封邮件的原文，使用 BODY.PEEK 不设置 \Seen
+func (m *MailMover) FetchRawMessage(uid uint32) ([]byte, error) {
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+	section := &imap.BodySectionName{Peek: true}
+	ch := make(chan *imap.Message, 4)
+	done := make(chan error, 1)
+	go func() { done <- m.client.UidFetch(seqSet, []imap.FetchItem{section.FetchItem()}, ch) }()
+	var raw []byte
+	var readErr error
+	for msg := range ch {
+		if body := msg.GetBody(section); body != nil && raw == nil {
+			raw, readErr = io.ReadAll(body)
+		}
+	}
+	if err := <-done; err != nil {
+		return nil, err
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("message %d not found", uid)
+	}
+	return raw, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"email/dao"
 	"email/global"
+	"email/models"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -310,11 +311,17 @@ func UpdateEmailType(emailMessageID, emailAddress string, targetType string) err
 	if err != nil {
 		return err
 	}
+	states := emailStates(accountId, emailMessageID)
 	//根据messageid和emailaddress更新邮件分类
 	err = dao.MoveEmailDirect(emailMessageID, accountId, targetType)
 	if err != nil {
 		return err
 	}
+	for _, s := range states {
+		if s.EmailType != targetType {
+			dao.PublishMoveEmailEvent(accountId, s.ID, emailMessageID, s.EmailType, targetType)
+		}
+	}
 	return nil
 
 }
@@ -325,10 +332,14 @@ func DeleteEmail(emailMessageID, emailAddress string) error {
 	if err != nil {
 		return err
 	}
+	states := emailStates(accountId, emailMessageID)
 	err = dao.DeleteEmailByMessageID(accountId, emailMessageID)
 	if err != nil {
 		return err
 	}
+	for _, s := range states {
+		dao.PublishDeleteEmailEvent(accountId, s.ID, emailMessageID, s.EmailType)
+	}
 	return nil
 
 }
@@ -339,9 +350,24 @@ func UpdateEmailReadStatus(emailMessageID, emailAddress string, readStatus bool)
 	if err != nil {
 		return err
 	}
+	states := emailStates(accountId, emailMessageID)
 	err = dao.UpdateEmailReadStatusByMessageID(accountId, emailMessageID, readStatus)
 	if err != nil {
 		return err
 	}
+	for _, s := range states {
+		if s.IsRead != readStatus {
+			dao.PublishReadStatusEvent(accountId, s.ID, emailMessageID, s.EmailType, readStatus)
+		}
+	}
 	return nil
 }
+
+// emailStates 获取变更前的邮件状态，用于只对实际变化的邮件发布事件
+func emailStates(accountID uint, emailMessageID string) []models.EmailDetails {
+	states, err := dao.GetEmailStatesByMessageID(accountID, emailMessageID)
+	if err != nil {
+		global.Log.Warnf("获取邮件 [ %s ] 状态失败，将不发布变更事件: %v", emailMessageID, err)
+	}
+	return states
+}
@@ -33,6 +33,10 @@ func GetEmailListProcess(emailAddress string, userID uint, emailType string, pag
 		//获取邮件失败，返回实际错误
 		return nil, err
 	}
+	sanitizeEmailList(emails)
+	if emailType == global.EmailTypeSent {
+		attachDeliveryStatuses(userID, emails)
+	}
 	return &models.EmailList{
 		EmailAddress: emailAddress,
 		Emails:       emails,
@@ -86,17 +90,48 @@ func MoveEmailProcess(userID uint, moveEmailReq models.MoveEmailRequest) (bool,
 	if err != nil {
 		return false, err
 	}
+	dao.PublishMoveEmailEvent(userID, uint(moveEmailReq.EmailID), "", moveEmailReq.SourceType, moveEmailReq.TargetType)
+	go publishMovedWebhook(userID, moveEmailReq)
 
 	return true, nil
 }
 
+// publishMovedWebhook 发布邮件移动的 Webhook 事件
+func publishMovedWebhook(userID uint, moveEmailReq models.MoveEmailRequest) {
+	account, err := dao.GetAccountByID(userID)
+	if err != nil {
+		return
+	}
+	emailDetail, err := dao.GetEmailDetailFullFileds(moveEmailReq.EmailID, userID)
+	if err != nil {
+		return
+	}
+	data := models.NewWebhookMessageData(emailDetail)
+	data.FromFolder, data.Folder = moveEmailReq.SourceType, moveEmailReq.TargetType
+	dao.PublishWebhookEvent(account.ID, account.DomainID, account.EmailAddress, models.WebhookMessageMoved, data)
+}
+
 // GetEmailDetailsProcess 获取邮件详情
-func GetEmailDetailsProcess(userID uint, emailId int) (*models.EmailDetailsResponse, error) {
+// loadImages 为 true 时本次加载远程图片，否则按发件人偏好设置决定
+func GetEmailDetailsProcess(userID uint, emailId int, loadImages bool) (*models.EmailDetailsResponse, error) {
 	// 调用数据访问层获取邮件详情
 	email, err := dao.GetEmailDetails(emailId, userID)
 	if err != nil {
 		return nil, err
 	}
+	if !loadImages {
+		if loadImages, err = dao.IsSenderImagesAllowed(userID, email.SenderEmail); err != nil {
+			global.Log.Warnf("查询发件人 [ %s ] 偏好设置失败: %v", email.SenderEmail, err)
+		}
+	}
+	// 先清洗正文再改写 cid: 引用，内嵌图片不受远程图片屏蔽影响
+	sanitizeEmailBody(email, loadImages)
+	// 拆分附件与内嵌图片，并将正文中的 cid: 引用替换为下载链接
+	resolveInlineImages(email)
+	// 只有发出的邮件有投递状态，查询失败时不影响详情
+	if statuses, err := dao.GetEmailDeliveryStatuses(userID, []uint{email.ID}); err == nil {
+		email.DeliveryStatus = statuses[email.ID]
+	}
 	return email, nil
 }
 
@@ -122,8 +157,12 @@ func SendNewEmailProcess(userID uint, emailAddress string, sendNewEmailReq model
 	if account.EmailAddress != emailAddress {
 		return 0, errors.New(response.AccountNotFoundCode.ErrMessage)
 	}
+	//收件人是否位于禁止发送列表
+	if err := checkSuppressedRecipients(account.DomainID, []string{sendNewEmailReq.To}); err != nil {
+		return 0, err
+	}
 	//调用AWS SES 服务
-	err = aws.SendNewEmailByAwsSes(&sendNewEmailReq, account.EmailAddress, account.UserName)
+	rawMessage, msgId, sesMessageID, err := aws.SendNewEmailByAwsSes(&sendNewEmailReq, account.EmailAddress, account.UserName)
 	if err != nil {
 		return 0, err
 	}
@@ -134,7 +173,9 @@ func SendNewEmailProcess(userID uint, emailAddress string, sendNewEmailReq model
 		EmailAccountID: account.ID,
 		EmailAddress:   account.EmailAddress,
 		RecipientEmail: sendNewEmailReq.To,
-		S3Key:          "N/A",
+		S3Key:          saveSentRawEmail(rawMessage),
+		EmailMessageID: msgId,
+		EmailHash:      utils.ComputeContentHash([]byte(rawMessage)),
 		SenderName:     account.UserName,
 		SenderEmail:    account.EmailAddress,
 		Subject:        sendNewEmailReq.Subject,
@@ -149,6 +190,9 @@ func SendNewEmailProcess(userID uint, emailAddress string, sendNewEmailReq model
 	if err != nil {
 		return 0, err
 	}
+	dao.AddDeliveryStatuses(sesMessageID, account.ID, eid, []string{sendNewEmailReq.To})
+	dao.PublishNewEmailEvent(&newEmail)
+	dao.PublishEmailWebhook(models.WebhookMessageSent, &newEmail, account)
 	// 返回成功响应
 	return int(eid), nil
 }
@@ -171,8 +215,16 @@ func WebSendNewEmailProcess(userID uint, emailAddress string, webSendEmailReq *m
 	if account.EmailAddress != emailAddress {
 		return 0, errors.New(response.AccountNotFoundCode.ErrMessage)
 	}
+	// 只能发送当前账户有权访问的附件
+	if err := authorizeAttachmentCodes(account.ID, webSendEmailReq.Attachments); err != nil {
+		return 0, err
+	}
+	//收件人是否位于禁止发送列表
+	if err := checkSuppressedRecipients(account.DomainID, envelopeRecipients(webSendEmailReq.To, webSendEmailReq.Cc, webSendEmailReq.Bcc)); err != nil {
+		return 0, err
+	}
 	////调用AWS SES 服务
-	rawMessage, msgId, atts, err := aws.SendEmailByAwsSmtp(emailAddress, webSendEmailReq)
+	rawMessage, msgId, sesMessageID, atts, err := aws.SendEmailByAwsSmtp(emailAddress, webSendEmailReq)
 	if err != nil {
 		return 0, err
 	}
@@ -185,7 +237,7 @@ func WebSendNewEmailProcess(userID uint, emailAddress string, webSendEmailReq *m
 		RecipientEmail: strings.Join(webSendEmailReq.To, ","),
 		Cc:             strings.Join(webSendEmailReq.Cc, ","),
 		Bcc:            strings.Join(webSendEmailReq.Bcc, ","),
-		S3Key:          "N/A",
+		S3Key:          saveSentRawEmail(rawMessage),
 		SenderName:     account.UserName,
 		SenderEmail:    account.EmailAddress,
 		Subject:        webSendEmailReq.Subject,
@@ -203,9 +255,12 @@ func WebSendNewEmailProcess(userID uint, emailAddress string, webSendEmailReq *m
 	if err != nil {
 		return 0, err
 	}
+	dao.AddDeliveryStatuses(sesMessageID, account.ID, eid, envelopeRecipients(webSendEmailReq.To, webSendEmailReq.Cc, webSendEmailReq.Bcc))
+	dao.PublishNewEmailEvent(&newEmail)
+	dao.PublishEmailWebhook(models.WebhookMessageSent, &newEmail, account)
+	// 收集最近联系人
+	go HarvestRecipientsProcess(account.ID, account.EmailAddress, webSendEmailReq.To, webSendEmailReq.Cc, webSendEmailReq.Bcc)
 	// 返回成功响应
-	fmt.Println(rawMessage)
-
 	if global.Config.System.Env {
 
 		options := models.ImapOperatorOptions{
@@ -245,20 +300,16 @@ func ReplyEmailProcess(userID uint, emailAddress, userName string, replyEmailReq
 	if emailDetails.EmailAddress != emailAddress {
 		return 0, errors.New(response.AccountNotFoundCode.ErrMessage)
 	}
+	//收件人是否位于禁止发送列表
+	if err := checkSuppressedRecipients(emailDetails.DomainID, []string{replyEmailReq.To}); err != nil {
+		return 0, err
+	}
 	//调用AWS SES 服务
-	err = aws.ReplyEmailByAwsSes(&replyEmailReq, emailAddress, userName)
+	emailContent, messageId, sesMessageID, err := aws.ReplyEmailByAwsSes(&replyEmailReq, emailAddress, userName)
 	if err != nil {
 		return 0, err
 	}
 
-	emailContent, messageId := utils.GenerateEmailRawMessage(&models.EmailContent{
-		From:     fmt.Sprintf("%s <%s>", userName, emailAddress),
-		To:       replyEmailReq.To,
-		Subject:  replyEmailReq.Subject,
-		TextBody: replyEmailReq.TextBody,
-		HtmlBody: replyEmailReq.HtmlBody,
-	})
-
 	if global.Config.System.Env {
 		options := models.ImapOperatorOptions{
 			UserName:        emailAddress,
@@ -283,7 +334,7 @@ func ReplyEmailProcess(userID uint, emailAddress, userName string, replyEmailReq
 		EmailAccountID: emailDetails.EmailAccountID,
 		EmailAddress:   emailDetails.EmailAddress,
 		RecipientEmail: emailDetails.SenderEmail,
-		S3Key:          "N/A",
+		S3Key:          saveSentRawEmail(emailContent),
 		SenderName:     userName,
 		SenderEmail:    emailDetails.EmailAddress,
 		Subject:        replyEmailReq.Subject,
@@ -295,10 +346,14 @@ func ReplyEmailProcess(userID uint, emailAddress, userName string, replyEmailReq
 		ReceivedAt:     time.Now(),
 		LastUpdateAt:   time.Now(),
 	}
-	eid, err := dao.AddNewEmailToDB(&newEmail, &models.EmailAccount{ID: emailDetails.EmailAccountID, EmailAddress: emailDetails.EmailAddress, DomainID: emailDetails.DomainID}, nil)
+	account := &models.EmailAccount{ID: emailDetails.EmailAccountID, EmailAddress: emailDetails.EmailAddress, DomainID: emailDetails.DomainID}
+	eid, err := dao.AddNewEmailToDB(&newEmail, account, nil)
 	if err != nil {
 		return 0, err
 	}
+	dao.AddDeliveryStatuses(sesMessageID, account.ID, eid, []string{replyEmailReq.To})
+	dao.PublishNewEmailEvent(&newEmail)
+	dao.PublishEmailWebhook(models.WebhookMessageSent, &newEmail, account)
 	return int(eid), nil
 }
 
@@ -311,6 +366,7 @@ func GetLatestInboxEmailListProcess(userID uint, emailAddress string, lastEmailI
 		//获取邮件失败，返回实际错误
 		return nil, err
 	}
+	sanitizeEmailList(emails)
 	return &models.EmailList{
 			EmailAddress: emailAddress,
 			Emails:       emails,
@@ -328,6 +384,10 @@ func GetEmailListByEmailIDProcess(userID uint, emailAddress string, emailType st
 	if err != nil {
 		return nil, err
 	}
+	sanitizeEmailList(emails)
+	if emailType == global.EmailTypeSent {
+		attachDeliveryStatuses(userID, emails)
+	}
 	return &models.EmailList{
 		EmailAddress: emailAddress,
 		Emails:       emails,
@@ -370,6 +430,9 @@ func MakeEmailReadByEmailIDProcess(emailDetail *models.EmailDetails) error {
 	if err != nil {
 		return err
 	}
+	if !emailDetail.IsRead {
+		dao.PublishReadStatusEvent(emailDetail.EmailAccountID, emailDetail.ID, emailDetail.EmailMessageID, emailDetail.EmailType, true)
+	}
 	return nil
 }
 
@@ -407,30 +470,66 @@ func MakeEmailUnReadByEmailIDProcess(emailDetail *models.EmailDetails) error {
 	if err != nil {
 		return err
 	}
+	if emailDetail.IsRead {
+		dao.PublishReadStatusEvent(emailDetail.EmailAccountID, emailDetail.ID, emailDetail.EmailMessageID, emailDetail.EmailType, false)
+	}
 	return nil
 
 }
 
 // ----------------------------------------------------------------------------------------------------------------------
-// SaveThirtyPartySendEmailProcess 保存第三方发送的邮件
-func SaveThirtyPartySendEmailProcess(env *enmime.Envelope, from string, to, cc, bcc []string) error {
+// saveSentRawEmail 保存发出邮件的原文，邮件已发出，保存失败时只记录日志
+func saveSentRawEmail(rawMessage string) string {
+	key, err := aws.SaveSentRawEmail([]byte(rawMessage))
+	if err != nil {
+		return "N/A"
+	}
+	return key
+}
+
+// attachDeliveryStatuses 为发出的邮件列表附加各收件人的投递状态，查询失败时不影响列表
+func attachDeliveryStatuses(accountID uint, emails []models.EmailDetailsResponse) {
+	ids := make([]uint, 0, len(emails))
+	for _, e := range emails {
+		ids = append(ids, e.ID)
+	}
+	statuses, err := dao.GetEmailDeliveryStatuses(accountID, ids)
+	if err != nil {
+		return
+	}
+	for i := range emails {
+		emails[i].DeliveryStatus = statuses[emails[i].ID]
+	}
+}
+
+// envelopeRecipients 合并收件人、抄送与密送，用于记录各收件人的投递状态
+func envelopeRecipients(to, cc, bcc []string) []string {
+	list := make([]string, 0, len(to)+len(cc)+len(bcc))
+	list = append(list, to...)
+	list = append(list, cc...)
+	return append(list, bcc...)
+}
+
+// SaveThirtyPartySendEmailProcess 保存第三方发送的邮件，返回保存的邮件
+func SaveThirtyPartySendEmailProcess(env *enmime.Envelope, rawMessage []byte, sesMessageID, from string, to, cc, bcc []string) (*models.EmailDetails, error) {
 	ad, err := dao.IsAccountExist(from, strings.Split(from, "@")[1])
 	if err != nil {
-		return err
+		return nil, err
 	}
 	receivedTime, err := utils.ParseTime(env.GetHeader("Date"))
 	if err != nil {
-		return fmt.Errorf("解析邮件时间失败: %v", err)
+		return nil, fmt.Errorf("解析邮件时间失败: %v", err)
 	}
-	s3Key, err := utils.GenerateSendID()
+	// 保存原文，附件路径同样以原文的对象键命名
+	s3Key, err := aws.SaveSentRawEmail(rawMessage)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	client, err := aws.CreateS3Client()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	atts := aws.EmailAttachmentProcessor(client, global.Config.AWS.S3Bucket, s3Key, env.Attachments)
+	atts := aws.EmailAttachmentProcessor(client, global.Config.AWS.S3Bucket, s3Key, append(env.Attachments, aws.InlineParts(env)...))
 	// 创建邮件详情对象
 	emailDetails := &models.EmailDetails{
 		DomainName:     strings.Split(from, "@")[1],
@@ -451,9 +550,12 @@ func SaveThirtyPartySendEmailProcess(env *enmime.Envelope, from string, to, cc,
 	}
 
 	// 保存邮件到数据库
-	_, err = dao.AddNewEmailToDB(emailDetails, ad, atts)
+	eid, err := dao.AddNewEmailToDB(emailDetails, ad, atts)
 	if err != nil {
-		return fmt.Errorf("保存邮件到数据库失败: %v", err)
+		return nil, fmt.Errorf("保存邮件到数据库失败: %v", err)
 	}
-	return nil
+	dao.AddDeliveryStatuses(sesMessageID, ad.ID, eid, envelopeRecipients(to, cc, bcc))
+	dao.PublishNewEmailEvent(emailDetails)
+	dao.PublishEmailWebhook(models.WebhookMessageSent, emailDetails, ad)
+	return emailDetails, nil
 }
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"email/dao"
+	"email/models"
+	"email/service/aws"
+	"email/service/filter"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// FilterTestResult 规则测试结果
+type FilterTestResult struct {
+	Matched bool           `json:"matched"`
+	Result  *filter.Result `json:"result"`
+}
+
+// GetFilterRulesProcess 获取账户的过滤规则
+func GetFilterRulesProcess(userID uint) ([]models.FilterRule, error) {
+	return dao.GetFilterRules(userID)
+}
+
+// AddFilterRuleProcess 新增过滤规则
+func AddFilterRuleProcess(userID uint, req models.FilterRuleRequest) (*models.FilterRule, error) {
+	rule, err := buildFilterRule(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.AddFilterRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateFilterRuleProcess 更新过滤规则
+func UpdateFilterRuleProcess(userID uint, req models.FilterRuleRequest) (*models.FilterRule, error) {
+	if req.ID == 0 {
+		return nil, errors.New("rule id is required")
+	}
+	rule, err := buildFilterRule(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.UpdateFilterRule(rule); err != nil {
+		return nil, err
+	}
+	return dao.GetFilterRuleByID(userID, req.ID)
+}
+
+// DeleteFilterRuleProcess 删除过滤规则
+func DeleteFilterRuleProcess(userID, ruleID uint) error {
+	return dao.DeleteFilterRule(userID, ruleID)
+}
+
+// ImportSieveProcess 从 Sieve 脚本导入过滤规则
+func ImportSieveProcess(userID uint, req models.ImportSieveRequest) ([]models.FilterRule, error) {
+	rules, err := filter.ImportSieve(req.Script)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Replace {
+		// 追加在现有规则之后
+		existing, err := dao.GetFilterRules(userID)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(existing); n > 0 {
+			base := existing[n-1].Priority
+			for i := range rules {
+				rules[i].Priority += base
+			}
+		}
+	}
+	if err := dao.ImportFilterRules(userID, rules, req.Replace); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ExportSieveProcess 将账户的过滤规则导出为 Sieve 脚本
+func ExportSieveProcess(userID uint) (string, error) {
+	rules, err := dao.GetFilterRules(userID)
+	if err != nil {
+		return "", err
+	}
+	return filter.ExportSieve(rules)
+}
+
+// TestFilterRuleProcess 使用已有邮件试运行过滤规则，不执行任何动作
+func TestFilterRuleProcess(userID uint, req models.TestFilterRuleRequest) (*FilterTestResult, error) {
+	var rule *models.FilterRule
+	var err error
+	switch {
+	case req.Rule != nil:
+		rule, err = buildFilterRule(userID, *req.Rule)
+	case req.RuleID != 0:
+		rule, err = dao.GetFilterRuleByID(userID, req.RuleID)
+	default:
+		return nil, errors.New("rule_id or rule is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	emailDetail, err := dao.GetEmailDetailFullFileds(req.EmailID, userID)
+	if err != nil {
+		return nil, err
+	}
+	rule.Enabled = true
+	res := filter.Evaluate([]models.FilterRule{*rule}, filterMessageFromEmail(emailDetail))
+	return &FilterTestResult{Matched: len(res.Matched) > 0, Result: res}, nil
+}
+
+// filterMessageFromEmail 优先使用S3中的原始邮件，不存在时由数据库字段构造邮件头
+func filterMessageFromEmail(e *models.EmailDetails) *filter.Message {
+	if e.S3Key != "" && e.S3Key != "N/A" {
+		if raw, err := aws.GetS3ObjectBytes(e.S3Key); err == nil {
+			if env, err := enmime.ReadEnvelope(strings.NewReader(string(raw))); err == nil {
+				return filter.NewMessageFromEnvelope(env, len(raw))
+			}
+		}
+	}
+	headers := map[string]string{
+		"from":       fmt.Sprintf("%s <%s>", e.SenderName, e.SenderEmail),
+		"to":         e.RecipientEmail,
+		"cc":         e.Cc,
+		"subject":    e.Subject,
+		"message-id": e.EmailMessageID,
+	}
+	var atts []json.RawMessage
+	_ = json.Unmarshal(e.AttachmentInfo, &atts)
+	return &filter.Message{
+		Header:        func(name string) string { return headers[strings.ToLower(name)] },
+		Size:          int64(len(e.BodyText) + len(e.BodyHTML)),
+		HasAttachment: len(atts) > 0,
+	}
+}
+
+// buildFilterRule 由请求构造规则并校验
+func buildFilterRule(userID uint, req models.FilterRuleRequest) (*models.FilterRule, error) {
+	rule := &models.FilterRule{
+		ID:             req.ID,
+		EmailAccountID: userID,
+		Name:           req.Name,
+		Priority:       req.Priority,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+		MatchType:      req.MatchType,
+		Stop:           req.Stop,
+	}
+	if err := filter.EncodeRule(rule, req.Conditions, req.Actions); err != nil {
+		return nil, err
+	}
+	if err := filter.Validate(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,415 @@
+// Package filter 实现账户级的收件过滤规则匹配，以及与 Sieve 脚本的相互转换
+package filter
+
+import (
+	"email/global"
+	"email/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jhillyerd/enmime"
+)
+
+// 条件字段
+const (
+	FieldHeader        = "header"
+	FieldFrom          = "from"
+	FieldTo            = "to"
+	FieldCc            = "cc"
+	FieldSubject       = "subject"
+	FieldSize          = "size"
+	FieldHasAttachment = "has_attachment"
+)
+
+// 条件运算符
+const (
+	OpIs       = "is"
+	OpContains = "contains"
+	OpMatches  = "matches" // 通配符 * ?
+	OpRegex    = "regex"
+	OpExists   = "exists"
+	OpOver     = "over"
+	OpUnder    = "under"
+)
+
+// 动作类型
+const (
+	ActionMove     = "move"
+	ActionMarkRead = "mark_read"
+	ActionFlag     = "flag"
+	ActionForward  = "forward"
+	ActionDiscard  = "discard"
+	ActionReject   = "reject"
+)
+
+// 条件组合方式
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// Message 参与规则匹配的邮件信息
+type Message struct {
+	Header        func(name string) string // 返回已解码的头部值
+	Size          int64
+	HasAttachment bool
+}
+
+// NewMessageFromEnvelope 由解析后的邮件构造匹配信息
+func NewMessageFromEnvelope(env *enmime.Envelope, size int) *Message {
+	return &Message{
+		Header:        env.GetHeader,
+		Size:          int64(size),
+		HasAttachment: len(env.Attachments) > 0,
+	}
+}
+
+// Result 规则执行结果
+type Result struct {
+	Folder   string   `json:"folder"` // 目标分类，为空表示保持在收件箱
+	MarkRead bool     `json:"mark_read"`
+	Flag     bool     `json:"flag"`
+	Forward  []string `json:"forward"`
+	Discard  bool     `json:"discard"`
+	Reject   string   `json:"reject"`
+	Matched  []uint   `json:"matched"` // 命中的规则 ID
+}
+
+// TargetFolder 返回最终保存的分类
+func (r *Result) TargetFolder() string {
+	if r.Folder == "" {
+		return global.EmailTypeInbox
+	}
+	return r.Folder
+}
+
+// Store 是否需要保存到本地；丢弃时仍保留显式移动的邮件，与 Sieve 的 discard 语义一致
+func (r *Result) Store() bool {
+	return r.Reject == "" && (!r.Discard || r.Folder != "")
+}
+
+// Evaluate 按顺序对邮件执行规则，rules 需已按优先级排序
+func Evaluate(rules []models.FilterRule, msg *Message) *Result {
+	res := &Result{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		conds, actions, err := DecodeRule(&rule)
+		if err != nil {
+			global.Log.Warnf("过滤规则 [ID: %d] 解析失败，已跳过: %v", rule.ID, err)
+			continue
+		}
+		if !Match(rule.MatchType, conds, msg) {
+			continue
+		}
+		res.Matched = append(res.Matched, rule.ID)
+		apply(res, actions)
+		if rule.Stop {
+			break
+		}
+	}
+	return res
+}
+
+// Match 判断条件是否满足，没有条件时视为全部匹配
+func Match(matchType string, conds []models.FilterCondition, msg *Message) bool {
+	if len(conds) == 0 {
+		return true
+	}
+	for _, c := range conds {
+		ok := matchCondition(c, msg)
+		if matchType == MatchAny && ok {
+			return true
+		}
+		if matchType != MatchAny && !ok {
+			return false
+		}
+	}
+	return matchType != MatchAny
+}
+
+func apply(res *Result, actions []models.FilterAction) {
+	for _, a := range actions {
+		switch a.Type {
+		case ActionMove:
+			res.Folder = a.Value
+		case ActionMarkRead:
+			res.MarkRead = true
+		case ActionFlag:
+			res.Flag = true
+		case ActionForward:
+			res.Forward = append(res.Forward, a.Value)
+		case ActionDiscard:
+			res.Discard = true
+		case ActionReject:
+			res.Reject = a.Value
+			if res.Reject == "" {
+				res.Reject = "Message rejected by recipient filter"
+			}
+		}
+	}
+}
+
+func matchCondition(c models.FilterCondition, msg *Message) bool {
+	var ok bool
+	switch c.Field {
+	case FieldSize:
+		n, _ := ParseSize(c.Value)
+		ok = (c.Operator == OpOver && msg.Size > n) || (c.Operator == OpUnder && msg.Size < n)
+	case FieldHasAttachment:
+		ok = msg.HasAttachment
+	default:
+		value := msg.Header(headerName(c))
+		switch {
+		case c.Operator == OpExists:
+			ok = value != ""
+		case isAddressField(c.Field):
+			for _, addr := range parseAddresses(value) {
+				if matchString(c.Operator, addr, c.Value) {
+					ok = true
+					break
+				}
+			}
+		default:
+			ok = matchString(c.Operator, value, c.Value)
+		}
+	}
+	return ok != c.Negate
+}
+
+func matchString(op, value, key string) bool {
+	switch op {
+	case OpIs:
+		return strings.EqualFold(value, key)
+	case OpContains:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(key))
+	case OpMatches:
+		re, err := regexp.Compile(wildcardToRegexp(key))
+		return err == nil && re.MatchString(value)
+	case OpRegex:
+		re, err := regexp.Compile("(?i)" + key)
+		return err == nil && re.MatchString(value)
+	}
+	return false
+}
+
+// wildcardToRegexp 将 Sieve :matches 通配符转换为正则
+func wildcardToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func headerName(c models.FilterCondition) string {
+	switch c.Field {
+	case FieldFrom:
+		return "From"
+	case FieldTo:
+		return "To"
+	case FieldCc:
+		return "Cc"
+	case FieldSubject:
+		return "Subject"
+	}
+	return c.Header
+}
+
+func isAddressField(field string) bool {
+	return field == FieldFrom || field == FieldTo || field == FieldCc
+}
+
+// parseAddresses 解析地址列表，解析失败时退回按逗号拆分
+func parseAddresses(value string) []string {
+	if value == "" {
+		return nil
+	}
+	list, err := mail.ParseAddressList(value)
+	if err == nil {
+		addrs := make([]string, 0, len(list))
+		for _, a := range list {
+			addrs = append(addrs, a.Address)
+		}
+		return addrs
+	}
+	var addrs []string
+	for _, part := range strings.Split(value, ",") {
+		addrs = append(addrs, strings.Trim(strings.TrimSpace(part), "<>"))
+	}
+	return addrs
+}
+
+// ParseSize 解析大小，支持 K/M/G 后缀
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	mul := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mul = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mul = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mul = 1 << 30
+	}
+	if mul > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mul, nil
+}
+
+// DecodeRule 解析规则中的条件与动作
+func DecodeRule(rule *models.FilterRule) ([]models.FilterCondition, []models.FilterAction, error) {
+	var conds []models.FilterCondition
+	var actions []models.FilterAction
+	if len(rule.Conditions) > 0 {
+		if err := json.Unmarshal(rule.Conditions, &conds); err != nil {
+			return nil, nil, fmt.Errorf("invalid conditions: %w", err)
+		}
+	}
+	if len(rule.Actions) > 0 {
+		if err := json.Unmarshal(rule.Actions, &actions); err != nil {
+			return nil, nil, fmt.Errorf("invalid actions: %w", err)
+		}
+	}
+	return conds, actions, nil
+}
+
+// EncodeRule 将条件与动作写回规则
+func EncodeRule(rule *models.FilterRule, conds []models.FilterCondition, actions []models.FilterAction) error {
+	if conds == nil {
+		conds = []models.FilterCondition{}
+	}
+	c, err := json.Marshal(conds)
+	if err != nil {
+		return err
+	}
+	a, err := json.Marshal(actions)
+	if err != nil {
+		return err
+	}
+	rule.Conditions = c
+	rule.Actions = a
+	return nil
+}
+
+// Validate 校验规则是否合法
+func Validate(rule *models.FilterRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return errors.New("rule name cannot be empty")
+	}
+	if rule.MatchType == "" {
+		rule.MatchType = MatchAll
+	}
+	if rule.MatchType != MatchAll && rule.MatchType != MatchAny {
+		return fmt.Errorf("invalid match_type %q", rule.MatchType)
+	}
+	conds, actions, err := DecodeRule(rule)
+	if err != nil {
+		return err
+	}
+	for _, c := range conds {
+		if err := validateCondition(c); err != nil {
+			return err
+		}
+	}
+	if len(actions) == 0 {
+		return errors.New("rule must have at least one action")
+	}
+	for _, a := range actions {
+		if err := validateAction(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCondition(c models.FilterCondition) error {
+	switch c.Field {
+	case FieldSize:
+		if c.Operator != OpOver && c.Operator != OpUnder {
+			return fmt.Errorf("size condition only supports %s/%s", OpOver, OpUnder)
+		}
+		_, err := ParseSize(c.Value)
+		return err
+	case FieldHasAttachment:
+		return nil
+	case FieldHeader:
+		if strings.TrimSpace(c.Header) == "" {
+			return errors.New("header condition requires a header name")
+		}
+	case FieldFrom, FieldTo, FieldCc, FieldSubject:
+	default:
+		return fmt.Errorf("unsupported condition field %q", c.Field)
+	}
+	switch c.Operator {
+	case OpIs, OpContains, OpMatches, OpExists:
+	case OpRegex:
+		if _, err := regexp.Compile(c.Value); err != nil {
+			return fmt.Errorf("invalid regex %q: %v", c.Value, err)
+		}
+	default:
+		return fmt.Errorf("unsupported operator %q for field %q", c.Operator, c.Field)
+	}
+	return nil
+}
+
+func validateAction(a models.FilterAction) error {
+	switch a.Type {
+	case ActionMove:
+		if _, ok := mailboxNames[a.Value]; !ok {
+			return fmt.Errorf("invalid target folder %q", a.Value)
+		}
+	case ActionForward:
+		if _, err := mail.ParseAddress(a.Value); err != nil {
+			return fmt.Errorf("invalid forward address %q", a.Value)
+		}
+	case ActionMarkRead, ActionFlag, ActionDiscard, ActionReject:
+	default:
+		return fmt.Errorf("unsupported action %q", a.Type)
+	}
+	return nil
+}
+
+// 分类与 IMAP 文件夹的对应关系，与移动邮件时保持一致
+var mailboxNames = map[string]string{
+	global.EmailTypeInbox:   "Inbox",
+	global.EmailTypeTrash:   "Junk",
+	global.EmailTypeDeleted: "Trash",
+}
+
+// 不能作为过滤规则目标、仅在导入邮件时使用的分类
+var importMailboxNames = map[string]string{
+	global.EmailTypeSent:  "Sent",
+	global.EmailTypeDraft: "Drafts",
+}
+
+// MailboxName 返回分类对应的 IMAP 文件夹
+func MailboxName(folder string) string {
+	if name, ok := mailboxNames[folder]; ok {
+		return name
+	}
+	if name, ok := importMailboxNames[folder]; ok {
+		return name
+	}
+	return "Inbox"
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"email/models"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	headers := map[string]string{
+		"From":       `"Alice Smith" <alice@example.com>`,
+		"To":         "bob@example.org, carol@example.net",
+		"Subject":    "Re: [urgent] Quarterly report",
+		"X-Priority": "1",
+	}
+	msg := &Message{
+		Header: func(name string) string { return headers[name] },
+		Size:   2 << 20,
+	}
+	tests := []struct {
+		name      string
+		matchType string
+		conds     []models.FilterCondition
+		want      bool
+	}{
+		{"no conditions", MatchAll, nil, true},
+		{"address is", MatchAll, []models.FilterCondition{{Field: FieldFrom, Operator: OpIs, Value: "ALICE@example.com"}}, true},
+		{"address list", MatchAll, []models.FilterCondition{{Field: FieldTo, Operator: OpIs, Value: "carol@example.net"}}, true},
+		{"display name is not the address", MatchAll, []models.FilterCondition{{Field: FieldFrom, Operator: OpContains, Value: "Smith"}}, false},
+		{"subject contains", MatchAll, []models.FilterCondition{{Field: FieldSubject, Operator: OpContains, Value: "QUARTERLY"}}, true},
+		{"wildcard", MatchAll, []models.FilterCondition{{Field: FieldSubject, Operator: OpMatches, Value: "re: [urgent]*"}}, true},
+		{"wildcard anchored", MatchAll, []models.FilterCondition{{Field: FieldSubject, Operator: OpMatches, Value: "[urgent]*"}}, false},
+		{"regex", MatchAll, []models.FilterCondition{{Field: FieldHeader, Header: "X-Priority", Operator: OpRegex, Value: `^[12]$`}}, true},
+		{"exists", MatchAll, []models.FilterCondition{{Field: FieldHeader, Header: "List-Id", Operator: OpExists}}, false},
+		{"negate", MatchAll, []models.FilterCondition{{Field: FieldHeader, Header: "List-Id", Operator: OpExists, Negate: true}}, true},
+		{"size over", MatchAll, []models.FilterCondition{{Field: FieldSize, Operator: OpOver, Value: "1M"}}, true},
+		{"size under", MatchAll, []models.FilterCondition{{Field: FieldSize, Operator: OpUnder, Value: "1M"}}, false},
+		{"all fails", MatchAll, []models.FilterCondition{
+			{Field: FieldSubject, Operator: OpContains, Value: "report"},
+			{Field: FieldHasAttachment, Operator: OpExists},
+		}, false},
+		{"any passes", MatchAny, []models.FilterCondition{
+			{Field: FieldSubject, Operator: OpContains, Value: "report"},
+			{Field: FieldHasAttachment, Operator: OpExists},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.matchType, tt.conds, msg); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	msg := &Message{Header: func(name string) string {
+		if name == "Subject" {
+			return "Weekly newsletter"
+		}
+		return ""
+	}}
+	rules := []models.FilterRule{
+		newRule(t, "disabled", false, false, MatchAll, nil, []models.FilterAction{{Type: ActionReject}}),
+		newRule(t, "flag", true, false, MatchAll,
+			[]models.FilterCondition{{Field: FieldSubject, Operator: OpContains, Value: "newsletter"}},
+			[]models.FilterAction{{Type: ActionFlag}}),
+		newRule(t, "discard", true, true, MatchAll, nil, []models.FilterAction{{Type: ActionDiscard}}),
+		newRule(t, "after stop", true, false, MatchAll, nil, []models.FilterAction{{Type: ActionMarkRead}}),
+	}
+	for i := range rules {
+		rules[i].ID = uint(i + 1)
+	}
+	res := Evaluate(rules, msg)
+	if !res.Flag || !res.Discard || res.MarkRead || res.Reject != "" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if len(res.Matched) != 2 || res.Matched[0] != 2 || res.Matched[1] != 3 {
+		t.Errorf("matched = %v", res.Matched)
+	}
+	if res.Store() {
+		t.Error("discarded message should not be stored")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  bool
+	}{
+		{"100", 100, false},
+		{"2k", 2048, false},
+		{" 3M ", 3 << 20, false},
+		{"1G", 1 << 30, false},
+		{"-1", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
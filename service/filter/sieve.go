@@ -0,0 +1,819 @@
+package filter
+
+import (
+	"email/global"
+	"email/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+	支持的 Sieve 子集 (RFC 5228 及常用扩展)：
+	* 测试：header、address、exists、size、allof、anyof、not、true、false
+	* 匹配：:is :contains :matches :regex
+	* 动作：fileinto、addflag/setflag (\\Seen \\Flagged)、redirect [:copy]、keep、discard、reject/ereject、stop
+	* 附件条件导出为 header :mime :anychild :contains "Content-Disposition" "attachment"
+	* 每条规则前的 "# rule: 名称" 注释保存规则名称，禁用的规则导出为 allof (false, ...)
+*/
+
+// 分类对应的 Sieve 文件夹名称
+var sieveFolders = map[string]string{
+	global.EmailTypeInbox:   "INBOX",
+	global.EmailTypeTrash:   "Junk",
+	global.EmailTypeDeleted: "Trash",
+}
+
+// 导入时可识别的文件夹名称
+var sieveFolderAliases = map[string]string{
+	"inbox":         global.EmailTypeInbox,
+	"junk":          global.EmailTypeTrash,
+	"spam":          global.EmailTypeTrash,
+	"trash":         global.EmailTypeDeleted,
+	"deleted":       global.EmailTypeDeleted,
+	"deleted items": global.EmailTypeDeleted,
+}
+
+const ruleNamePrefix = "rule:"
+
+// ExportSieve 将规则导出为 Sieve 脚本
+func ExportSieve(rules []models.FilterRule) (string, error) {
+	requires := map[string]bool{}
+	var body strings.Builder
+	for _, rule := range rules {
+		conds, actions, err := DecodeRule(&rule)
+		if err != nil {
+			return "", fmt.Errorf("rule %d: %w", rule.ID, err)
+		}
+		test := exportTest(rule.MatchType, conds, requires)
+		if !rule.Enabled {
+			test = "allof (false, " + test + ")"
+		}
+		fmt.Fprintf(&body, "# %s %s\n", ruleNamePrefix, strings.ReplaceAll(rule.Name, "\n", " "))
+		fmt.Fprintf(&body, "if %s {\n", test)
+		for _, a := range actions {
+			fmt.Fprintf(&body, "    %s;\n", exportAction(a, requires))
+		}
+		if rule.Stop {
+			body.WriteString("    stop;\n")
+		}
+		body.WriteString("}\n\n")
+	}
+	var out strings.Builder
+	if len(requires) > 0 {
+		exts := make([]string, 0, len(requires))
+		for ext := range requires {
+			exts = append(exts, quote(ext))
+		}
+		sort.Strings(exts)
+		fmt.Fprintf(&out, "require [%s];\n\n", strings.Join(exts, ", "))
+	}
+	out.WriteString(body.String())
+	return out.String(), nil
+}
+
+func exportTest(matchType string, conds []models.FilterCondition, requires map[string]bool) string {
+	if len(conds) == 0 {
+		return "true"
+	}
+	tests := make([]string, 0, len(conds))
+	for _, c := range conds {
+		tests = append(tests, exportCondition(c, requires))
+	}
+	if len(tests) == 1 {
+		return tests[0]
+	}
+	if matchType == MatchAny {
+		return "anyof (" + strings.Join(tests, ", ") + ")"
+	}
+	return "allof (" + strings.Join(tests, ", ") + ")"
+}
+
+func exportCondition(c models.FilterCondition, requires map[string]bool) string {
+	var t string
+	switch {
+	case c.Field == FieldSize:
+		t = fmt.Sprintf("size :%s %s", c.Operator, strings.ToUpper(strings.TrimSpace(c.Value)))
+	case c.Field == FieldHasAttachment:
+		requires["mime"] = true
+		t = `header :mime :anychild :contains "Content-Disposition" "attachment"`
+	case c.Operator == OpExists:
+		t = "exists " + quote(strings.ToLower(headerName(c)))
+	default:
+		if c.Operator == OpRegex {
+			requires["regex"] = true
+		}
+		cmd := "header"
+		if isAddressField(c.Field) {
+			cmd = "address"
+		}
+		t = fmt.Sprintf("%s :%s %s %s", cmd, c.Operator, quote(strings.ToLower(headerName(c))), quote(c.Value))
+	}
+	if c.Negate {
+		return "not " + t
+	}
+	return t
+}
+
+func exportAction(a models.FilterAction, requires map[string]bool) string {
+	switch a.Type {
+	case ActionMove:
+		requires["fileinto"] = true
+		return "fileinto " + quote(sieveFolders[a.Value])
+	case ActionMarkRead:
+		requires["imap4flags"] = true
+		return `addflag "\\Seen"`
+	case ActionFlag:
+		requires["imap4flags"] = true
+		return `addflag "\\Flagged"`
+	case ActionForward:
+		// 转发时保留本地副本，是否保存由 discard/fileinto 决定
+		requires["copy"] = true
+		return "redirect :copy " + quote(a.Value)
+	case ActionDiscard:
+		return "discard"
+	case ActionReject:
+		requires["reject"] = true
+		return "reject " + quote(a.Value)
+	}
+	return "# unsupported action " + a.Type
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// ImportSieve 解析 Sieve 脚本并转换为规则，不支持的语法返回错误
+func ImportSieve(script string) ([]models.FilterRule, error) {
+	toks, err := lex(script)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	var rules []models.FilterRule
+	var pending *sieveBlock // 顶层的无条件动作
+	name := ""
+	flush := func() error {
+		if pending == nil {
+			return nil
+		}
+		rule, err := buildRule(name, len(rules), &sieveTest{name: "true"}, pending)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, *rule)
+		pending, name = nil, ""
+		return nil
+	}
+	for !p.eof() {
+		tok := p.next()
+		switch {
+		case tok.kind == tkComment:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			name = tok.text
+		case tok.kind != tkIdent:
+			return nil, p.errorf(tok, "unexpected %q", tok.text)
+		case tok.text == "require":
+			if _, err := p.parseArgs(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(tkSemicolon); err != nil {
+				return nil, err
+			}
+		case tok.text == "if":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			test, err := p.parseTest()
+			if err != nil {
+				return nil, err
+			}
+			block, err := p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+			if next := p.peek(); next.kind == tkIdent && (next.text == "elsif" || next.text == "else") {
+				return nil, p.errorf(next, "%s is not supported, split it into separate rules", next.text)
+			}
+			rule, err := buildRule(name, len(rules), test, block)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, *rule)
+			name = ""
+		case tok.text == "stop":
+			// 之后的命令不会执行
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			return numberRules(rules), nil
+		default:
+			if pending == nil {
+				pending = &sieveBlock{}
+			}
+			if err := p.parseAction(tok, pending); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return numberRules(rules), nil
+}
+
+func numberRules(rules []models.FilterRule) []models.FilterRule {
+	for i := range rules {
+		rules[i].Priority = (i + 1) * 10
+	}
+	return rules
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 语法树
+
+type sieveTest struct {
+	name     string
+	tags     []string
+	args     [][]string
+	children []*sieveTest
+	tok      token
+}
+
+func (t *sieveTest) hasTag(tag string) bool {
+	for _, v := range t.tags {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOp 返回测试的匹配方式，默认为 :is
+func (t *sieveTest) matchOp() string {
+	for _, v := range t.tags {
+		switch v {
+		case ":is", ":contains", ":matches", ":regex":
+			return strings.TrimPrefix(v, ":")
+		}
+	}
+	return OpIs
+}
+
+type sieveBlock struct {
+	actions  []models.FilterAction
+	redirect bool // 存在不带 :copy 的 redirect
+	keep     bool // 存在 keep 或 fileinto
+	stop     bool
+}
+
+func buildRule(name string, index int, test *sieveTest, block *sieveBlock) (*models.FilterRule, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fmt.Sprintf("Sieve rule %d", index+1)
+	}
+	rule := &models.FilterRule{Name: name, Enabled: true, MatchType: MatchAll, Stop: block.stop}
+	// 禁用的规则导出为 allof (false, ...)
+	if test.name == "allof" && len(test.children) > 0 && test.children[0].name == "false" {
+		rule.Enabled = false
+		rest := test.children[1:]
+		switch len(rest) {
+		case 0:
+			test = &sieveTest{name: "true"}
+		case 1:
+			test = rest[0]
+		default:
+			test = &sieveTest{name: "allof", children: rest, tok: test.tok}
+		}
+	}
+	if test.name == "false" {
+		rule.Enabled = false
+		test = &sieveTest{name: "true"}
+	}
+
+	var conds []models.FilterCondition
+	switch test.name {
+	case "true":
+	case "allof", "anyof":
+		anyOf := test.name == "anyof"
+		if anyOf {
+			rule.MatchType = MatchAny
+		}
+		for _, child := range test.children {
+			if child.name == "true" && !anyOf {
+				continue
+			}
+			cs, all, err := convertTest(child)
+			if err != nil {
+				return nil, err
+			}
+			if len(cs) > 1 && all == anyOf {
+				return nil, errorAt(child.tok, "test with multiple keys cannot be nested here")
+			}
+			conds = append(conds, cs...)
+		}
+	default:
+		cs, all, err := convertTest(test)
+		if err != nil {
+			return nil, err
+		}
+		// 只有一个条件时两种组合方式等价，保持默认的 all，导出后再导入不变
+		if !all && len(cs) > 1 {
+			rule.MatchType = MatchAny
+		}
+		conds = cs
+	}
+
+	actions := block.actions
+	// 不带 :copy 的 redirect 会取消默认保存
+	if block.redirect && !block.keep {
+		actions = append(actions, models.FilterAction{Type: ActionDiscard})
+	}
+	if len(actions) == 0 {
+		// 仅 keep 的规则等同于移动到收件箱
+		actions = append(actions, models.FilterAction{Type: ActionMove, Value: global.EmailTypeInbox})
+	}
+	if err := EncodeRule(rule, conds, actions); err != nil {
+		return nil, err
+	}
+	if err := Validate(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// convertTest 将单个测试转换为条件，all 表示多个条件需同时满足
+func convertTest(t *sieveTest) ([]models.FilterCondition, bool, error) {
+	switch t.name {
+	case "not":
+		cs, all, err := convertTest(t.children[0])
+		if err != nil {
+			return nil, false, err
+		}
+		if len(cs) != 1 {
+			return nil, false, errorAt(t.tok, "not with multiple keys is not supported")
+		}
+		cs[0].Negate = !cs[0].Negate
+		return cs, all, nil
+	case "size":
+		op := OpOver
+		if t.hasTag(":under") {
+			op = OpUnder
+		} else if !t.hasTag(":over") {
+			return nil, false, errorAt(t.tok, "size requires :over or :under")
+		}
+		if len(t.args) != 1 || len(t.args[0]) != 1 {
+			return nil, false, errorAt(t.tok, "size requires a single number")
+		}
+		return []models.FilterCondition{{Field: FieldSize, Operator: op, Value: t.args[0][0]}}, true, nil
+	case "exists":
+		if len(t.args) != 1 {
+			return nil, false, errorAt(t.tok, "exists requires a header list")
+		}
+		var cs []models.FilterCondition
+		for _, h := range t.args[0] {
+			cs = append(cs, headerCondition(h, OpExists, "", false))
+		}
+		return cs, true, nil
+	case "header", "address":
+		if len(t.args) != 2 {
+			return nil, false, errorAt(t.tok, "%s requires a header list and a key list", t.name)
+		}
+		if t.name == "header" && t.hasTag(":mime") {
+			if len(t.args[0]) == 1 && strings.EqualFold(t.args[0][0], "content-disposition") &&
+				len(t.args[1]) == 1 && strings.EqualFold(t.args[1][0], "attachment") {
+				return []models.FilterCondition{{Field: FieldHasAttachment, Operator: OpExists}}, true, nil
+			}
+			return nil, false, errorAt(t.tok, "only the attachment form of header :mime is supported")
+		}
+		op := t.matchOp()
+		var cs []models.FilterCondition
+		for _, h := range t.args[0] {
+			for _, key := range t.args[1] {
+				value := key
+				if t.name == "address" && op == OpIs {
+					// :localpart / :domain 转换为通配符匹配整个地址
+					if t.hasTag(":domain") {
+						value, op = "*@"+key, OpMatches
+					} else if t.hasTag(":localpart") {
+						value, op = key+"@*", OpMatches
+					}
+				}
+				cs = append(cs, headerCondition(h, op, value, t.name == "address"))
+			}
+		}
+		return cs, false, nil
+	case "true", "false", "allof", "anyof":
+		return nil, false, errorAt(t.tok, "nested %s is not supported", t.name)
+	}
+	return nil, false, errorAt(t.tok, "unsupported test %q", t.name)
+}
+
+func headerCondition(header, op, value string, address bool) models.FilterCondition {
+	h := strings.ToLower(strings.TrimSpace(header))
+	switch h {
+	case FieldSubject:
+		return models.FilterCondition{Field: FieldSubject, Operator: op, Value: value}
+	case FieldFrom, FieldTo, FieldCc:
+		if address || op == OpExists {
+			return models.FilterCondition{Field: h, Operator: op, Value: value}
+		}
+	}
+	return models.FilterCondition{Field: FieldHeader, Header: header, Operator: op, Value: value}
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 语法分析
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) eof() bool { return p.peek().kind == tkEOF }
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tkEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind) error {
+	if t := p.next(); t.kind != kind {
+		return p.errorf(t, "expected %s, got %q", kind, t.text)
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return errorAt(t, format, args...)
+}
+
+func errorAt(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("sieve line %d: %s", t.line, fmt.Sprintf(format, args...))
+}
+
+// parseArgs 读取参数，直到遇到非参数记号
+func (p *parser) parseArgs() ([]string, error) {
+	var out []string
+	for {
+		t := p.peek()
+		switch t.kind {
+		case tkTag, tkString, tkNumber:
+			p.next()
+			out = append(out, t.text)
+		case tkLBracket:
+			list, err := p.parseStringList()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, list...)
+		default:
+			return out, nil
+		}
+	}
+}
+
+func (p *parser) parseStringList() ([]string, error) {
+	if err := p.expect(tkLBracket); err != nil {
+		return nil, err
+	}
+	var list []string
+	for {
+		t := p.next()
+		if t.kind != tkString {
+			return nil, p.errorf(t, "expected string in list, got %q", t.text)
+		}
+		list = append(list, t.text)
+		t = p.next()
+		if t.kind == tkRBracket {
+			return list, nil
+		}
+		if t.kind != tkComma {
+			return nil, p.errorf(t, "expected , or ] in list, got %q", t.text)
+		}
+	}
+}
+
+func (p *parser) parseTest() (*sieveTest, error) {
+	t := p.next()
+	if t.kind != tkIdent {
+		return nil, p.errorf(t, "expected test, got %q", t.text)
+	}
+	test := &sieveTest{name: strings.ToLower(t.text), tok: t}
+	switch test.name {
+	case "not":
+		child, err := p.parseTest()
+		if err != nil {
+			return nil, err
+		}
+		test.children = []*sieveTest{child}
+		return test, nil
+	case "allof", "anyof":
+		if err := p.expect(tkLParen); err != nil {
+			return nil, err
+		}
+		for {
+			child, err := p.parseTest()
+			if err != nil {
+				return nil, err
+			}
+			test.children = append(test.children, child)
+			n := p.next()
+			if n.kind == tkRParen {
+				return test, nil
+			}
+			if n.kind != tkComma {
+				return nil, p.errorf(n, "expected , or ) in %s, got %q", test.name, n.text)
+			}
+		}
+	}
+	for {
+		a := p.peek()
+		switch a.kind {
+		case tkTag:
+			p.next()
+			tag := strings.ToLower(a.text)
+			test.tags = append(test.tags, tag)
+			// :comparator 带一个字符串参数，比较器统一按大小写不敏感处理
+			if tag == ":comparator" {
+				if n := p.next(); n.kind != tkString {
+					return nil, p.errorf(n, "expected comparator name")
+				}
+			}
+		case tkString, tkNumber:
+			p.next()
+			test.args = append(test.args, []string{a.text})
+		case tkLBracket:
+			list, err := p.parseStringList()
+			if err != nil {
+				return nil, err
+			}
+			test.args = append(test.args, list)
+		default:
+			return test, nil
+		}
+	}
+}
+
+func (p *parser) parseBlock() (*sieveBlock, error) {
+	if err := p.expect(tkLBrace); err != nil {
+		return nil, err
+	}
+	block := &sieveBlock{}
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tkRBrace:
+			return block, nil
+		case t.kind == tkComment:
+			continue
+		case t.kind != tkIdent:
+			return nil, p.errorf(t, "unexpected %q", t.text)
+		case t.text == "if":
+			return nil, p.errorf(t, "nested if is not supported")
+		}
+		if err := p.parseAction(t, block); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseAction(cmd token, block *sieveBlock) error {
+	args, err := p.parseArgs()
+	if err != nil {
+		return err
+	}
+	if err := p.expect(tkSemicolon); err != nil {
+		return err
+	}
+	var strs []string
+	copyTag := false
+	for _, a := range args {
+		if strings.HasPrefix(a, ":") {
+			copyTag = copyTag || strings.EqualFold(a, ":copy")
+			continue
+		}
+		strs = append(strs, a)
+	}
+	switch strings.ToLower(cmd.text) {
+	case "keep":
+		block.keep = true
+	case "fileinto":
+		if len(strs) != 1 {
+			return errorAt(cmd, "fileinto requires a folder")
+		}
+		folder, ok := sieveFolderAliases[strings.ToLower(strings.TrimPrefix(strs[0], "INBOX."))]
+		if !ok {
+			return errorAt(cmd, "unknown folder %q", strs[0])
+		}
+		block.keep = true
+		block.actions = append(block.actions, models.FilterAction{Type: ActionMove, Value: folder})
+	case "addflag", "setflag":
+		for _, s := range strs {
+			for _, flag := range strings.Fields(s) {
+				switch strings.ToLower(flag) {
+				case `\seen`:
+					block.actions = append(block.actions, models.FilterAction{Type: ActionMarkRead})
+				case `\flagged`:
+					block.actions = append(block.actions, models.FilterAction{Type: ActionFlag})
+				}
+			}
+		}
+	case "redirect":
+		if len(strs) != 1 {
+			return errorAt(cmd, "redirect requires an address")
+		}
+		if !copyTag {
+			block.redirect = true
+		}
+		block.actions = append(block.actions, models.FilterAction{Type: ActionForward, Value: strs[0]})
+	case "discard":
+		block.actions = append(block.actions, models.FilterAction{Type: ActionDiscard})
+	case "reject", "ereject":
+		reason := ""
+		if len(strs) > 0 {
+			reason = strings.TrimSpace(strs[0])
+		}
+		block.actions = append(block.actions, models.FilterAction{Type: ActionReject, Value: reason})
+	case "stop":
+		block.stop = true
+	default:
+		return errorAt(cmd, "unsupported command %q", cmd.text)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 词法分析
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkIdent
+	tkTag
+	tkString
+	tkNumber
+	tkComment // 仅保留 "# rule:" 注释
+	tkLParen
+	tkRParen
+	tkLBracket
+	tkRBracket
+	tkLBrace
+	tkRBrace
+	tkComma
+	tkSemicolon
+)
+
+func (k tokenKind) String() string {
+	return [...]string{"end of script", "identifier", "tag", "string", "number", "comment", "(", ")", "[", "]", "{", "}", ",", ";"}[k]
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+var punctuation = map[byte]tokenKind{
+	'(': tkLParen, ')': tkRParen, '[': tkLBracket, ']': tkRBracket,
+	'{': tkLBrace, '}': tkRBrace, ',': tkComma, ';': tkSemicolon,
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			text := strings.TrimSpace(src[i+1 : i+end])
+			if strings.HasPrefix(strings.ToLower(text), ruleNamePrefix) {
+				toks = append(toks, token{tkComment, strings.TrimSpace(text[len(ruleNamePrefix):]), line})
+			}
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("sieve line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"':
+			var b strings.Builder
+			start := line
+			i++
+			for {
+				if i >= len(src) {
+					return nil, fmt.Errorf("sieve line %d: unterminated string", start)
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					b.WriteByte(src[i+1])
+					i += 2
+					continue
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				if src[i] == '\n' {
+					line++
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			toks = append(toks, token{tkString, b.String(), start})
+		case strings.HasPrefix(src[i:], "text:"):
+			text, n, lines, err := lexMultiline(src[i+5:])
+			if err != nil {
+				return nil, fmt.Errorf("sieve line %d: %v", line, err)
+			}
+			toks = append(toks, token{tkString, text, line})
+			line += lines
+			i += 5 + n
+		case c == ':':
+			j := i + 1
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("sieve line %d: invalid tag", line)
+			}
+			toks = append(toks, token{tkTag, src[i:j], line})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+				j++
+			}
+			if j < len(src) && strings.ContainsRune("KkMmGg", rune(src[j])) {
+				j++
+			}
+			toks = append(toks, token{tkNumber, src[i:j], line})
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			toks = append(toks, token{tkIdent, src[i:j], line})
+			i = j
+		default:
+			kind, ok := punctuation[c]
+			if !ok {
+				return nil, fmt.Errorf("sieve line %d: unexpected character %q", line, c)
+			}
+			toks = append(toks, token{kind, string(c), line})
+			i++
+		}
+	}
+	return append(toks, token{tkEOF, "", line}), nil
+}
+
+// lexMultiline 解析 text: 多行字符串，以单独一行的 "." 结束
+func lexMultiline(src string) (string, int, int, error) {
+	nl := strings.IndexByte(src, '\n')
+	if nl < 0 {
+		return "", 0, 0, errors.New("unterminated multi-line string")
+	}
+	pos, lines := nl+1, 1
+	var b strings.Builder
+	for {
+		end := strings.IndexByte(src[pos:], '\n')
+		if end < 0 {
+			return "", 0, 0, errors.New("unterminated multi-line string")
+		}
+		l := strings.TrimSuffix(src[pos:pos+end], "\r")
+		pos += end + 1
+		lines++
+		if l == "." {
+			return b.String(), pos, lines, nil
+		}
+		b.WriteString(strings.TrimPrefix(l, "."))
+		b.WriteString("\n")
+	}
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,186 @@
+package filter
+
+import (
+	"email/global"
+	"email/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newRule(t *testing.T, name string, enabled, stop bool, matchType string, conds []models.FilterCondition, actions []models.FilterAction) models.FilterRule {
+	t.Helper()
+	rule := models.FilterRule{Name: name, Enabled: enabled, Stop: stop, MatchType: matchType}
+	if err := EncodeRule(&rule, conds, actions); err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+func TestSieveRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		stop      bool
+		matchType string
+		conds     []models.FilterCondition
+		actions   []models.FilterAction
+	}{
+		{
+			name: "newsletter", enabled: true, matchType: MatchAll,
+			conds:   []models.FilterCondition{{Field: FieldFrom, Operator: OpContains, Value: "news@example.com"}},
+			actions: []models.FilterAction{{Type: ActionMove, Value: global.EmailTypeTrash}, {Type: ActionMarkRead}},
+		},
+		{
+			name: "any of", enabled: true, stop: true, matchType: MatchAny,
+			conds: []models.FilterCondition{
+				{Field: FieldSubject, Operator: OpMatches, Value: "*[urgent]*"},
+				{Field: FieldHeader, Header: "x-priority", Operator: OpIs, Value: "1"},
+			},
+			actions: []models.FilterAction{{Type: ActionFlag}},
+		},
+		{
+			name: "negated regex", enabled: true, matchType: MatchAll,
+			conds: []models.FilterCondition{
+				{Field: FieldTo, Operator: OpRegex, Value: `^team-.*@example\.com$`, Negate: true},
+				{Field: FieldSize, Operator: OpOver, Value: "10M"},
+			},
+			actions: []models.FilterAction{{Type: ActionForward, Value: "archive@example.com"}},
+		},
+		{
+			name: "attachments", enabled: true, matchType: MatchAll,
+			conds: []models.FilterCondition{
+				{Field: FieldHasAttachment, Operator: OpExists},
+				{Field: FieldHeader, Header: "x-mailer", Operator: OpExists},
+			},
+			actions: []models.FilterAction{{Type: ActionReject, Value: `No "attachments" please`}},
+		},
+		{
+			name: "disabled", enabled: false, matchType: MatchAll,
+			conds:   []models.FilterCondition{{Field: FieldCc, Operator: OpIs, Value: "boss@example.com"}},
+			actions: []models.FilterAction{{Type: ActionDiscard}},
+		},
+		{
+			name: "always", enabled: true, matchType: MatchAll,
+			actions: []models.FilterAction{{Type: ActionMarkRead}},
+		},
+	}
+	var rules []models.FilterRule
+	for _, tt := range tests {
+		rules = append(rules, newRule(t, tt.name, tt.enabled, tt.stop, tt.matchType, tt.conds, tt.actions))
+	}
+	script, err := ExportSieve(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportSieve(script)
+	if err != nil {
+		t.Fatalf("ImportSieve: %v\n%s", err, script)
+	}
+	if len(imported) != len(tests) {
+		t.Fatalf("got %d rules, want %d\n%s", len(imported), len(tests), script)
+	}
+	for i, tt := range tests {
+		got := imported[i]
+		conds, actions, err := DecodeRule(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != tt.name || got.Enabled != tt.enabled || got.Stop != tt.stop || got.MatchType != tt.matchType {
+			t.Errorf("rule %q: got name=%q enabled=%v stop=%v match=%q", tt.name, got.Name, got.Enabled, got.Stop, got.MatchType)
+		}
+		if len(conds) == 0 && len(tt.conds) == 0 {
+			conds = tt.conds
+		}
+		if !reflect.DeepEqual(conds, tt.conds) {
+			t.Errorf("rule %q: conditions = %+v, want %+v", tt.name, conds, tt.conds)
+		}
+		if !reflect.DeepEqual(actions, tt.actions) {
+			t.Errorf("rule %q: actions = %+v, want %+v", tt.name, actions, tt.actions)
+		}
+		if got.Priority != (i+1)*10 {
+			t.Errorf("rule %q: priority = %d", tt.name, got.Priority)
+		}
+	}
+}
+
+func TestImportSieve(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		conds   []models.FilterCondition
+		actions []models.FilterAction
+	}{
+		{
+			name: "address domain",
+			script: `require "fileinto";
+if address :domain "from" "example.com" { fileinto "Junk"; }`,
+			conds:   []models.FilterCondition{{Field: FieldFrom, Operator: OpMatches, Value: "*@example.com"}},
+			actions: []models.FilterAction{{Type: ActionMove, Value: global.EmailTypeTrash}},
+		},
+		{
+			name:    "redirect without copy discards",
+			script:  `if header :contains "subject" ["invoice", "receipt"] { redirect "billing@example.com"; }`,
+			conds:   []models.FilterCondition{{Field: FieldSubject, Operator: OpContains, Value: "invoice"}, {Field: FieldSubject, Operator: OpContains, Value: "receipt"}},
+			actions: []models.FilterAction{{Type: ActionForward, Value: "billing@example.com"}, {Type: ActionDiscard}},
+		},
+		{
+			name:    "multiline string",
+			script:  "require \"reject\";\nif size :under 1K {\n  reject text:\nToo small\n.\n;\n}",
+			conds:   []models.FilterCondition{{Field: FieldSize, Operator: OpUnder, Value: "1K"}},
+			actions: []models.FilterAction{{Type: ActionReject, Value: "Too small"}},
+		},
+		{
+			name:    "top level action",
+			script:  `require "imap4flags"; addflag "\\Flagged";`,
+			actions: []models.FilterAction{{Type: ActionFlag}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ImportSieve(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rules) != 1 {
+				t.Fatalf("got %d rules", len(rules))
+			}
+			conds, actions, err := DecodeRule(&rules[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conds) == 0 && len(tt.conds) == 0 {
+				conds = tt.conds
+			}
+			if !reflect.DeepEqual(conds, tt.conds) {
+				t.Errorf("conditions = %+v, want %+v", conds, tt.conds)
+			}
+			if !reflect.DeepEqual(actions, tt.actions) {
+				t.Errorf("actions = %+v, want %+v", actions, tt.actions)
+			}
+		})
+	}
+}
+
+func TestImportSieveErrors(t *testing.T) {
+	tests := []struct {
+		name, script, want string
+	}{
+		{"else", `if true { discard; } else { keep; }`, "not supported"},
+		{"unknown test", `if envelope :is "from" "a@b" { discard; }`, "unsupported test"},
+		{"unterminated string", `if header :is "subject" "abc { discard; }`, ""},
+		{"missing semicolon", `if true { discard }`, ""},
+		{"size without tag", `if size 10 { discard; }`, "size requires"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportSieve(tt.script)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
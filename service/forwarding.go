@@ -0,0 +1,44 @@
+package service
+
+import (
+	"email/dao"
+	"email/models"
+	"email/utils"
+	"errors"
+	"strings"
+)
+
+// GetForwardingProcess 获取账户自动转发设置
+func GetForwardingProcess(userID uint) (*models.ForwardingRule, error) {
+	f, err := dao.GetForwardingRule(userID)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return &models.ForwardingRule{EmailAccountID: userID, KeepCopy: true}, nil
+	}
+	return f, nil
+}
+
+// UpdateForwardingProcess 更新账户自动转发设置
+func UpdateForwardingProcess(userID uint, emailAddress string, req models.UpdateForwardingRequest) (*models.ForwardingRule, error) {
+	req.ForwardTo = strings.TrimSpace(req.ForwardTo)
+	if req.Enabled || req.ForwardTo != "" {
+		if err := utils.ValidateEmailAddress(req.ForwardTo); err != nil {
+			return nil, errors.New("invalid forward_to address")
+		}
+	}
+	if strings.EqualFold(req.ForwardTo, emailAddress) {
+		return nil, errors.New("Cannot forward email to yourself.")
+	}
+	f := &models.ForwardingRule{
+		EmailAccountID: userID,
+		Enabled:        req.Enabled,
+		ForwardTo:      req.ForwardTo,
+		KeepCopy:       req.KeepCopy,
+	}
+	if err := dao.SaveForwardingRule(f); err != nil {
+		return nil, err
+	}
+	return dao.GetForwardingRule(userID)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"email/global"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ImageProxyPath 图片代理接口路径
+const ImageProxyPath = "/api/v1/image-proxy"
+
+var (
+	// ErrImageProxyDisabled 未开启图片代理
+	ErrImageProxyDisabled = errors.New("image proxy is disabled")
+	// ErrImageProxySignature 代理链接签名无效或已过期
+	ErrImageProxySignature = errors.New("invalid or expired image proxy link")
+	// 远程地址解析为内网地址
+	errImageProxyAddress = errors.New("remote address is not allowed")
+)
+
+// 运营商级 NAT 地址段
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// ProxiedImage 代理获取的图片
+type ProxiedImage struct {
+	ContentType string
+	Data        []byte
+}
+
+// SignImageProxyURL 生成带签名与有效期的图片代理地址，防止被当作开放代理使用
+func SignImageProxyURL(rawURL string) string {
+	ttl := global.Config.ImageProxy.LinkTTLHours
+	if ttl <= 0 {
+		ttl = 24
+	}
+	exp := strconv.FormatInt(time.Now().Add(time.Duration(ttl)*time.Hour).Unix(), 10)
+	q := url.Values{}
+	q.Set("url", rawURL)
+	q.Set("exp", exp)
+	q.Set("sig", imageProxySignature(rawURL, exp))
+	return ImageProxyPath + "?" + q.Encode()
+}
+
+func imageProxySignature(rawURL, exp string) string {
+	mac := hmac.New(sha256.New, []byte(global.Config.Jwt.SecretKey))
+	mac.Write([]byte("image-proxy\n" + exp + "\n" + rawURL))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ImageProxyProcess 校验签名后获取远程图片，只允许公网地址与非 SVG 的图片类型
+func ImageProxyProcess(rawURL, exp, sig string) (*ProxiedImage, error) {
+	cfg := global.Config.ImageProxy
+	if !cfg.Enabled {
+		return nil, ErrImageProxyDisabled
+	}
+	expAt, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expAt ||
+		!hmac.Equal([]byte(sig), []byte(imageProxySignature(rawURL, exp))) {
+		return nil, ErrImageProxySignature
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid image url: %s", rawURL)
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	maxSize := int64(cfg.MaxSizeMB) * 1024 * 1024
+	if maxSize <= 0 {
+		maxSize = 5 * 1024 * 1024
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// 不转发读者的任何信息
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; EmailImageProxy/1.0)")
+	req.Header.Set("Accept", "image/*")
+	resp, err := imageProxyClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote server returned %s", resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") || mediaType == "image/svg+xml" {
+		return nil, fmt.Errorf("unsupported content type: %s", mediaType)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxSize)
+	}
+	return &ProxiedImage{ContentType: mediaType, Data: data}, nil
+}
+
+// imageProxyClient 只连接公网地址，连接时校验解析后的 IP，重定向同样受限
+var imageProxyClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return errImageProxyAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       60 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 3 {
+			return errors.New("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("unsupported redirect scheme: %s", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		cgnatRange.Contains(ip))
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"email/service/dovecot"
+	"email/service/mailbox"
+	"email/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+const (
+	// 每批获取的远程邮件数
+	imapMigrationBatchSize = 50
+	// 单个 IMAP 命令的超时时间
+	imapMigrationTimeout = 2 * time.Minute
+	// 文件夹对应关系中表示不迁移的值
+	imapFolderSkip = "skip"
+)
+
+var (
+	// ErrImapMigrationState 任务当前状态不允许该操作
+	ErrImapMigrationState = errors.New("migration cannot be changed in its current state")
+	// errImapMigrationCancelled 执行中的任务被取消
+	errImapMigrationCancelled = errors.New("migration cancelled")
+	// errImapAddress 远程服务器地址不是公网地址
+	errImapAddress = errors.New("remote IMAP server must be a public address")
+)
+
+// 待执行的迁移任务，由 ImapMigrationInit 启动的 worker 消费
+var imapMigrationQueue = make(chan uint, 1024)
+
+// imapMigrationDialer 只连接公网地址，避免借迁移访问内网服务
+var imapMigrationDialer = &net.Dialer{
+	Timeout: 30 * time.Second,
+	Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+			return errImapAddress
+		}
+		return nil
+	},
+}
+
+// ImapMigrationInit 启动迁移任务的 worker，重启前执行中的任务从记录的进度继续
+func ImapMigrationInit() {
+	if err := dao.RequeueRunningImapMigrations(); err != nil {
+		global.Log.Errorf("恢复中断的 IMAP 迁移任务失败: %v", err)
+	}
+	workers := global.Config.MailboxTransfer.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range imapMigrationQueue {
+				runImapMigration(id)
+			}
+		}()
+	}
+	ids, err := dao.GetPendingImapMigrationIDs()
+	if err != nil {
+		global.Log.Errorf("获取待执行的 IMAP 迁移任务失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		imapMigrationQueue <- id
+	}
+}
+
+// CreateImapMigrationProcess 创建迁移任务，凭证加密保存，任务完成后清空
+func CreateImapMigrationProcess(userID uint, req models.CreateImapMigrationRequest) (*models.ImapMigration, error) {
+	req.Host = strings.TrimSpace(req.Host)
+	if req.AuthType == "" {
+		req.AuthType = models.ImapAuthPassword
+	}
+	secret := req.Password
+	switch req.AuthType {
+	case models.ImapAuthPassword:
+	case models.ImapAuthXOAuth2:
+		secret = req.Token
+	default:
+		return nil, fmt.Errorf("unsupported auth type %q", req.AuthType)
+	}
+	if secret == "" {
+		return nil, errors.New("password or token is required")
+	}
+	if req.Port == 0 {
+		req.Port = 993
+		if req.StartTLS {
+			req.Port = 143
+		}
+	}
+	if req.Port < 0 || req.Port > 65535 {
+		return nil, errors.New("invalid port")
+	}
+	for name, folder := range req.FolderMap {
+		if folder != imapFolderSkip && !utils.Contains(global.EmailType, folder) {
+			return nil, fmt.Errorf("invalid folder %q for %q", folder, name)
+		}
+	}
+	// 创建前先验证能否登录，避免保存错误的凭证
+	mover := newImapMigrationMover(req.Host, req.Port, req.StartTLS, req.Username, req.AuthType, secret)
+	if err := mover.Connect(); err != nil {
+		return nil, err
+	}
+	mover.Close()
+
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	folderMap, _ := json.Marshal(req.FolderMap)
+	m := &models.ImapMigration{
+		EmailAccountID: userID,
+		Host:           req.Host,
+		Port:           req.Port,
+		StartTLS:       req.StartTLS,
+		Username:       req.Username,
+		AuthType:       req.AuthType,
+		Secret:         encrypted,
+		FolderMap:      folderMap,
+		Status:         models.ImapMigrationPending,
+	}
+	if err := dao.AddImapMigration(m); err != nil {
+		return nil, err
+	}
+	enqueueImapMigration(m.ID)
+	return m, nil
+}
+
+// GetImapMigrationProcess 获取迁移任务及各文件夹进度
+func GetImapMigrationProcess(userID, id uint) (*models.ImapMigration, error) {
+	return dao.GetImapMigration(userID, id)
+}
+
+// GetImapMigrationsProcess 分页获取迁移任务列表
+func GetImapMigrationsProcess(userID uint, page int) (*models.ImapMigrationList, error) {
+	if page <= 0 {
+		page = 1
+	}
+	list, total, err := dao.GetImapMigrations(userID, page, 20)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ImapMigrationList{Migrations: list, Total: total, Page: page}, nil
+}
+
+// ResumeImapMigrationProcess 恢复失败或已取消的迁移任务，从各文件夹记录的 UID 继续
+func ResumeImapMigrationProcess(userID uint, req models.ImapMigrationActionRequest) error {
+	m, err := dao.GetImapMigration(userID, req.ID)
+	if err != nil {
+		return err
+	}
+	secret := req.Password
+	if m.AuthType == models.ImapAuthXOAuth2 {
+		secret = req.Token
+	}
+	encrypted := ""
+	if secret != "" {
+		if encrypted, err = utils.EncryptSecret(secret); err != nil {
+			return err
+		}
+	} else if m.Secret == "" {
+		return errors.New("password or token is required")
+	}
+	ok, err := dao.RestartImapMigration(userID, req.ID, encrypted)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrImapMigrationState
+	}
+	enqueueImapMigration(req.ID)
+	return nil
+}
+
+// CancelImapMigrationProcess 取消迁移任务，已导入的邮件保留
+func CancelImapMigrationProcess(userID, id uint) error {
+	if _, err := dao.GetImapMigration(userID, id); err != nil {
+		return err
+	}
+	ok, err := dao.CancelImapMigration(userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrImapMigrationState
+	}
+	return nil
+}
+
+func enqueueImapMigration(id uint) {
+	// 队列已满时不阻塞请求
+	go func() { imapMigrationQueue <- id }()
+}
+
+func newImapMigrationMover(host string, port int, startTLS bool, username, authType, secret string) *dovecot.MailMover {
+	cfg := dovecot.ImapConfig{
+		Server:   host,
+		Port:     port,
+		Username: username,
+		StartTLS: startTLS,
+		Dialer:   imapMigrationDialer,
+		Timeout:  imapMigrationTimeout,
+	}
+	if authType == models.ImapAuthXOAuth2 {
+		cfg.OAuthToken = secret
+	} else {
+		cfg.Password = secret
+	}
+	return dovecot.NewMailMover(cfg)
+}
+
+// runImapMigration 执行迁移任务并记录结果
+func runImapMigration(id uint) {
+	m, ok, err := dao.ClaimImapMigration(id)
+	if err != nil {
+		global.Log.Errorf("领取 IMAP 迁移任务 [ID: %d] 失败: %v", id, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	global.Log.Infof("开始执行 IMAP 迁移任务 [ID: %d]，远程服务器 %s", m.ID, m.Host)
+	err = migrateImapAccount(m)
+	switch {
+	case errors.Is(err, errImapMigrationCancelled):
+		global.Log.Infof("IMAP 迁移任务 [ID: %d] 已取消", m.ID)
+		return
+	case err != nil:
+		global.Log.Errorf("IMAP 迁移任务 [ID: %d] 失败: %v", m.ID, err)
+		m.Status, m.Error = models.ImapMigrationFailed, err.Error()
+	default:
+		m.Status = models.ImapMigrationCompleted
+	}
+	_ = dao.FinishImapMigration(m)
+}
+
+func migrateImapAccount(m *models.ImapMigration) error {
+	account, err := dao.GetAccountByID(m.EmailAccountID)
+	if err != nil {
+		return err
+	}
+	secret, err := utils.DecryptSecret(m.Secret)
+	if err != nil {
+		return fmt.Errorf("decrypt credentials: %w", err)
+	}
+	mover := newImapMigrationMover(m.Host, m.Port, m.StartTLS, m.Username, m.AuthType, secret)
+	if err := mover.Connect(); err != nil {
+		return err
+	}
+	defer mover.Close()
+
+	// 第一次执行时记录远程文件夹，之后恢复时沿用
+	if len(m.Folders) == 0 {
+		if m.Folders, err = listImapMigrationFolders(m, mover); err != nil {
+			return err
+		}
+	}
+	for i := range m.Folders {
+		f := &m.Folders[i]
+		if f.Status == models.ImapMigrationCompleted || f.Status == models.ImapFolderSkipped {
+			continue
+		}
+		if err := migrateImapFolder(m, f, mover, account); err != nil {
+			f.Status, f.Error = models.ImapMigrationFailed, err.Error()
+			if errors.Is(err, errImapMigrationCancelled) {
+				f.Status, f.Error = models.ImapMigrationPending, ""
+			}
+			_ = dao.SaveImapMigrationFolder(f)
+			return err
+		}
+	}
+	return nil
+}
+
+// listImapMigrationFolders 列出远程文件夹并确定对应的分类
+func listImapMigrationFolders(m *models.ImapMigration, mover *dovecot.MailMover) ([]models.ImapMigrationFolder, error) {
+	boxes, err := mover.ListMailboxes()
+	if err != nil {
+		return nil, err
+	}
+	var folderMap map[string]string
+	_ = json.Unmarshal(m.FolderMap, &folderMap)
+	folders := make([]models.ImapMigrationFolder, 0, len(boxes))
+	for _, box := range boxes {
+		target := mapImapFolder(box, folderMap)
+		status := models.ImapMigrationPending
+		if target == imapFolderSkip {
+			target, status = "", models.ImapFolderSkipped
+		}
+		folders = append(folders, models.ImapMigrationFolder{
+			MigrationID:  m.ID,
+			RemoteName:   box.Name,
+			TargetFolder: target,
+			Status:       status,
+		})
+	}
+	if err := dao.AddImapMigrationFolders(folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// mapImapFolder 依次根据用户指定、特殊用途属性(RFC 6154)与文件夹名称确定分类，无法识别的文件夹迁移到收件箱
+func mapImapFolder(box *imap.MailboxInfo, folderMap map[string]string) string {
+	if target, ok := folderMap[box.Name]; ok {
+		return target
+	}
+	for _, attr := range box.Attributes {
+		switch attr {
+		// 无法选择的文件夹，以及 Gmail 中“所有邮件”等与其他文件夹重复的虚拟文件夹
+		case imap.NoSelectAttr, "\\NonExistent", imap.AllAttr, imap.FlaggedAttr, imap.ImportantAttr:
+			return imapFolderSkip
+		case imap.SentAttr:
+			return global.EmailTypeSent
+		case imap.JunkAttr:
+			return global.EmailTypeTrash
+		case imap.TrashAttr:
+			return global.EmailTypeDeleted
+		case imap.DraftsAttr:
+			return global.EmailTypeDraft
+		}
+	}
+	name := box.Name
+	if box.Delimiter != "" {
+		name = name[strings.LastIndex(name, box.Delimiter)+1:]
+	}
+	if folder := mailbox.FolderFromName(name); folder != "" {
+		return folder
+	}
+	return global.EmailTypeInbox
+}
+
+// migrateImapFolder 从上次记录的 UID 之后分批迁移，每批结束后保存进度
+func migrateImapFolder(m *models.ImapMigration, f *models.ImapMigrationFolder, mover *dovecot.MailMover, account *models.EmailAccount) error {
+	status, err := mover.SelectReadOnly(f.RemoteName)
+	if err != nil {
+		return err
+	}
+	// UIDVALIDITY 变化后原有 UID 失效，从头开始，已导入的邮件会被去重跳过
+	if f.UIDValidity != 0 && f.UIDValidity != status.UidValidity {
+		global.Log.Warnf("IMAP 迁移任务 [ID: %d] 文件夹 [ %s ] UIDVALIDITY 已变化，重新迁移", m.ID, f.RemoteName)
+		f.LastUID, f.Processed, f.Imported, f.Skipped, f.Failed = 0, 0, 0, 0, 0
+	}
+	f.UIDValidity = status.UidValidity
+	f.Status, f.Error = models.ImapMigrationRunning, ""
+	uids, err := mover.SearchUIDsAfter(f.LastUID)
+	if err != nil {
+		return err
+	}
+	f.Total = f.Processed + len(uids)
+	if err := dao.SaveImapMigrationFolder(f); err != nil {
+		return err
+	}
+	for start := 0; start < len(uids); start += imapMigrationBatchSize {
+		if s, err := dao.GetImapMigrationStatus(m.ID); err == nil && s == models.ImapMigrationCancelled {
+			return errImapMigrationCancelled
+		}
+		end := start + imapMigrationBatchSize
+		if end > len(uids) {
+			end = len(uids)
+		}
+		messages, err := mover.FetchMessages(uids[start:end])
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			result, err := migrateImapMessage(m, f, msg, mover, account)
+			if err != nil {
+				// 读取远程邮件或数据库出错时中止，恢复后从这封邮件继续
+				return err
+			}
+			f.Processed++
+			f.LastUID = msg.UID
+			switch result {
+			case imapMessageImported:
+				f.Imported++
+			case imapMessageSkipped:
+				f.Skipped++
+			default:
+				f.Failed++
+			}
+		}
+		f.LastUID = uids[end-1]
+		if err := dao.SaveImapMigrationFolder(f); err != nil {
+			return err
+		}
+	}
+	f.Status = models.ImapMigrationCompleted
+	return dao.SaveImapMigrationFolder(f)
+}
+
+// 单封邮件的迁移结果
+const (
+	imapMessageImported = "imported"
+	imapMessageSkipped  = "skipped"
+	imapMessageFailed   = "failed"
+)
+
+// migrateImapMessage 迁移一封邮件，先按 Message-ID 去重避免下载原文，导入时再按哈希去重
+// 邮件本身无法导入时记为失败并继续，返回的 error 表示需要中止任务
+func migrateImapMessage(m *models.ImapMigration, f *models.ImapMigrationFolder, msg dovecot.RemoteMessage, mover *dovecot.MailMover, account *models.EmailAccount) (string, error) {
+	if msg.MessageID != "" {
+		exist, err := dao.IsEmailExistByMessageID(account.ID, msg.MessageID)
+		if err != nil {
+			return "", err
+		}
+		if exist {
+			return imapMessageSkipped, nil
+		}
+	}
+	if msg.Size > maxImportMessageSize {
+		global.Log.Warnf("IMAP 迁移任务 [ID: %d] 文件夹 [ %s ] UID %d 超过大小限制", m.ID, f.RemoteName, msg.UID)
+		return imapMessageFailed, nil
+	}
+	raw, err := mover.FetchRawMessage(msg.UID)
+	if err != nil {
+		return "", err
+	}
+	opts := aws.ImportOptions{EmailType: f.TargetFolder, Date: msg.InternalDate}
+	for _, flag := range msg.Flags {
+		switch flag {
+		case imap.SeenFlag:
+			opts.Read = true
+		case imap.FlaggedFlag:
+			opts.Flagged = true
+		}
+	}
+	imported, err := aws.ImportRawEmail(account, raw, opts)
+	switch {
+	case err != nil:
+		global.Log.Warnf("IMAP 迁移任务 [ID: %d] 文件夹 [ %s ] UID %d 导入失败: %v", m.ID, f.RemoteName, msg.UID, err)
+		return imapMessageFailed, nil
+	case imported:
+		return imapMessageImported, nil
+	}
+	return imapMessageSkipped, nil
+}
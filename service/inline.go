@@ -0,0 +1,69 @@
+package service
+
+import (
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"encoding/json"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 匹配正文中的 cid: 引用，到引号、空白或括号为止
+var cidPattern = regexp.MustCompile(`(?i)\bcid:([^"'\s<>)]+)`)
+
+// resolveInlineImages 将 attachment_info 拆分为附件与内嵌图片，并把 HTML 正文中的 cid: 引用改写为预签名下载链接
+// 邮件所属账户已被授予其中所有附件的访问权限，隔离的附件不生成链接
+func resolveInlineImages(email *models.EmailDetailsResponse) {
+	var atts []models.Attachment
+	if len(email.AttachmentInfo) > 0 {
+		if err := json.Unmarshal(email.AttachmentInfo, &atts); err != nil {
+			global.Log.Warnf("解析邮件 [ID: %d] 附件信息失败: %v", email.ID, err)
+		}
+	}
+	email.AttachmentInfo = nil
+	email.Attachments = []models.Attachment{}
+	email.Inlines = []models.Attachment{}
+	urls := map[string]string{}
+	for _, a := range atts {
+		if !a.Inline {
+			email.Attachments = append(email.Attachments, a)
+			continue
+		}
+		email.Inlines = append(email.Inlines, a)
+		if a.ContentID == "" || a.IsBlocked() {
+			continue
+		}
+		u, err := aws.GeneratePresignedURL(a.S3StoragePath)
+		if err != nil {
+			global.Log.Warnf("生成内嵌图片 [ %s ] 下载链接失败: %v", a.ContentID, err)
+			continue
+		}
+		urls[a.ContentID] = u
+	}
+	if len(urls) > 0 {
+		email.BodyHTML = rewriteCIDs(email.BodyHTML, urls)
+	}
+}
+
+// rewriteCIDs 按 Content-ID 替换正文中的 cid: 引用，未知的引用保持不变
+func rewriteCIDs(body string, urls map[string]string) string {
+	return cidPattern.ReplaceAllStringFunc(body, func(m string) string {
+		id := m[len("cid:"):]
+		if unescaped, err := url.PathUnescape(id); err == nil {
+			id = unescaped
+		}
+		if u, ok := urls[id]; ok {
+			return html.EscapeString(u)
+		}
+		// Content-ID 区分大小写，但部分客户端引用时会改变大小写
+		for cid, u := range urls {
+			if strings.EqualFold(cid, id) {
+				return html.EscapeString(u)
+			}
+		}
+		return m
+	})
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// 每次读取的事件数量
+const MailEventBatchSize = 100
+
+// 未配置时的默认值
+const (
+	defaultMailEventRetentionHours   = 24
+	defaultMailEventHeartbeatSeconds = 25
+)
+
+// mailEventHub 本实例上各账户的事件订阅者，收到通知时唤醒对应账户的订阅者
+type mailEventHub struct {
+	mu   sync.Mutex
+	subs map[uint]map[*MailEventSubscription]struct{}
+}
+
+var eventHub = &mailEventHub{subs: make(map[uint]map[*MailEventSubscription]struct{})}
+
+// MailEventSubscription 一个客户端连接的订阅，C 收到信号时读取游标之后的事件
+type MailEventSubscription struct {
+	AccountID uint
+	C         chan struct{}
+}
+
+// SubscribeMailEvents 订阅账户的邮件事件，连接断开后需调用 Close
+func SubscribeMailEvents(accountID uint) *MailEventSubscription {
+	s := &MailEventSubscription{AccountID: accountID, C: make(chan struct{}, 1)}
+	eventHub.mu.Lock()
+	if eventHub.subs[accountID] == nil {
+		eventHub.subs[accountID] = make(map[*MailEventSubscription]struct{})
+	}
+	eventHub.subs[accountID][s] = struct{}{}
+	eventHub.mu.Unlock()
+	return s
+}
+
+// Close 取消订阅
+func (s *MailEventSubscription) Close() {
+	eventHub.mu.Lock()
+	delete(eventHub.subs[s.AccountID], s)
+	if len(eventHub.subs[s.AccountID]) == 0 {
+		delete(eventHub.subs, s.AccountID)
+	}
+	eventHub.mu.Unlock()
+}
+
+// Wake 唤醒订阅者，已有未处理的信号时不重复发送
+func (s *MailEventSubscription) Wake() {
+	select {
+	case s.C <- struct{}{}:
+	default:
+	}
+}
+
+// notify 唤醒账户的订阅者，accountID 为 0 时唤醒全部
+func (h *mailEventHub) notify(accountID uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, subs := range h.subs {
+		if accountID != 0 && id != accountID {
+			continue
+		}
+		for s := range subs {
+			s.Wake()
+		}
+	}
+}
+
+// MailEventInit 监听 Postgres 的邮件事件通知并分发给本实例的订阅者，同时定期清理过期事件
+func MailEventInit() {
+	go mailEventCleanup()
+	for {
+		if err := listenMailEvents(); err != nil {
+			global.Log.Errorf("邮件事件监听中断，5 秒后重连: %v", err)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// listenMailEvents 使用独立连接 LISTEN，连接断开时返回错误
+func listenMailEvents() error {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, global.Config.Psql.DSN())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{dao.MailEventChannel}.Sanitize()); err != nil {
+		return err
+	}
+	global.Log.Info("开始监听邮件事件通知...")
+	// 重连期间可能错过通知，唤醒全部订阅者按游标补读
+	eventHub.notify(0)
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		accountID, _, _ := strings.Cut(n.Payload, ":")
+		id, err := strconv.ParseUint(accountID, 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		eventHub.notify(uint(id))
+	}
+}
+
+// mailEventCleanup 定期删除超过保留时长的事件
+func mailEventCleanup() {
+	for {
+		retention := global.Config.EventStream.RetentionHours
+		if retention <= 0 {
+			retention = defaultMailEventRetentionHours
+		}
+		n, err := dao.DeleteMailEventsBefore(time.Now().Add(-time.Duration(retention) * time.Hour))
+		if err != nil {
+			global.Log.Errorf("清理过期邮件事件失败: %v", err)
+		} else if n > 0 {
+			global.Log.Infof("已清理 %d 条过期邮件事件", n)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// MailEventHeartbeat 心跳间隔
+func MailEventHeartbeat() time.Duration {
+	if s := global.Config.EventStream.HeartbeatSeconds; s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return defaultMailEventHeartbeatSeconds * time.Second
+}
+
+// MailEventStartCursorProcess 确定事件流的起始游标，cursor 为空时从当前最新事件之后开始
+// 游标早于保留的最早事件时返回 reset 为 true，客户端需重新拉取邮件列表
+func MailEventStartCursorProcess(cursor string) (uint64, bool, error) {
+	minID, maxID, err := dao.GetMailEventIDRange()
+	if err != nil {
+		return 0, false, err
+	}
+	if cursor == "" {
+		return maxID, false, nil
+	}
+	c, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return maxID, true, nil
+	}
+	// 游标之后的事件已被清理，或游标来自重建后的数据库
+	if (minID > 0 && c+1 < minID) || c > maxID {
+		return maxID, true, nil
+	}
+	return c, false, nil
+}
+
+// GetMailEventsProcess 获取账户在游标之后的一批事件
+func GetMailEventsProcess(accountID uint, cursor uint64) ([]models.MailEvent, error) {
+	return dao.GetMailEventsAfter(accountID, cursor, MailEventBatchSize)
+}
@@ -0,0 +1,112 @@
+package mailbox
+
+import (
+	"email/global"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// 常见客户端的文件夹名称与分类的对应关系，名称均为小写
+var folderAliases = map[string]string{
+	"inbox":            global.EmailTypeInbox,
+	"sent":             global.EmailTypeSent,
+	"sent items":       global.EmailTypeSent,
+	"sent messages":    global.EmailTypeSent,
+	"sent mail":        global.EmailTypeSent,
+	"junk":             global.EmailTypeTrash,
+	"spam":             global.EmailTypeTrash,
+	"junk e-mail":      global.EmailTypeTrash,
+	"trash":            global.EmailTypeDeleted,
+	"deleted":          global.EmailTypeDeleted,
+	"deleted items":    global.EmailTypeDeleted,
+	"deleted messages": global.EmailTypeDeleted,
+	"drafts":           global.EmailTypeDraft,
+	"draft":            global.EmailTypeDraft,
+}
+
+// 导出 Maildir 时分类对应的 Maildir++ 子文件夹，收件箱为根目录
+var maildirFolders = map[string]string{
+	global.EmailTypeInbox:   "",
+	global.EmailTypeSent:    ".Sent",
+	global.EmailTypeTrash:   ".Junk",
+	global.EmailTypeDeleted: ".Trash",
+	global.EmailTypeDraft:   ".Drafts",
+}
+
+// FolderFromName 根据文件夹名称识别分类，无法识别时返回空字符串
+func FolderFromName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	// Maildir++ 子文件夹以 . 开头并以 . 分隔层级，只看最后一级
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return folderAliases[name]
+}
+
+// ZipEntry zip 中一封邮件的位置信息
+type ZipEntry struct {
+	Folder  string // 识别出的分类，为空时使用任务的默认分类
+	Read    bool
+	Flagged bool
+}
+
+// ParseZipEntry 识别 zip 中的 Maildir 邮件(cur/new 下的文件)与 .eml 文件，其余文件返回 false
+func ParseZipEntry(name string) (ZipEntry, bool) {
+	// Windows 下压缩的路径以 \ 分隔；已使用 / 分隔时 \ 为文件名中的转义字符
+	if !strings.Contains(name, "/") {
+		name = strings.ReplaceAll(name, "\\", "/")
+	}
+	if strings.HasSuffix(name, "/") {
+		return ZipEntry{}, false
+	}
+	dir, file := path.Split(name)
+	dir = strings.TrimSuffix(dir, "/")
+	if file == "" || strings.HasPrefix(file, ".") || strings.HasPrefix(file, "dovecot") || strings.HasPrefix(dir, "__MACOSX") {
+		return ZipEntry{}, false
+	}
+	if strings.EqualFold(path.Ext(file), ".eml") {
+		return ZipEntry{Folder: FolderFromName(path.Base(dir)), Read: true}, true
+	}
+	sub := path.Base(dir)
+	if sub != "cur" && sub != "new" {
+		return ZipEntry{}, false
+	}
+	entry := ZipEntry{}
+	// 标记位于 ":2," 之后，Windows 下以 "!2," 代替
+	if i := strings.LastIndex(file, "2,"); i > 0 && (file[i-1] == ':' || file[i-1] == '!') {
+		flags := file[i+2:]
+		entry.Read = strings.Contains(flags, "S")
+		entry.Flagged = strings.Contains(flags, "F")
+	}
+	parent := path.Base(path.Dir(dir))
+	switch {
+	case path.Dir(dir) == ".":
+		entry.Folder = global.EmailTypeInbox
+	case strings.HasPrefix(parent, "."):
+		entry.Folder = FolderFromName(parent)
+	default:
+		// 不以 . 开头的目录视为 Maildir 根目录，除非名称本身可识别
+		if entry.Folder = FolderFromName(parent); entry.Folder == "" {
+			entry.Folder = global.EmailTypeInbox
+		}
+	}
+	return entry, true
+}
+
+// maildirHostEscaper 主机名中的 / 与 : 按 Maildir 规范转义
+var maildirHostEscaper = strings.NewReplacer("/", `\057`, ":", `\072`)
+
+// MaildirEntryName 返回导出时邮件在 zip 中的路径，文件名为 "时间.ID.主机名:2,标记"
+func MaildirEntryName(folder string, id uint, t time.Time, host string, read, flagged bool) string {
+	flags := ""
+	if flagged {
+		flags += "F"
+	}
+	if read {
+		flags += "S"
+	}
+	name := fmt.Sprintf("%d.%d.%s:2,%s", t.Unix(), id, maildirHostEscaper.Replace(host), flags)
+	return path.Join("Maildir", maildirFolders[folder], "cur", name)
+}
@@ -0,0 +1,80 @@
+package mailbox
+
+import (
+	"email/global"
+	"testing"
+	"time"
+)
+
+func TestParseZipEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+		want ZipEntry
+	}{
+		{"Maildir/cur/1700000000.1.host:2,S", true, ZipEntry{Folder: global.EmailTypeInbox, Read: true}},
+		{"Maildir/new/1700000000.2.host", true, ZipEntry{Folder: global.EmailTypeInbox}},
+		{"Maildir/.Sent/cur/1.3.host:2,FS", true, ZipEntry{Folder: global.EmailTypeSent, Read: true, Flagged: true}},
+		{"Maildir/.Archive.Junk/cur/1.4.host:2,", true, ZipEntry{Folder: global.EmailTypeTrash}},
+		{"Maildir/.Custom/cur/1.5.host:2,F", true, ZipEntry{Flagged: true}},
+		{"cur/1.6.host!2,S", true, ZipEntry{Folder: global.EmailTypeInbox, Read: true}},
+		{`Maildir\.Drafts\cur\1.7.host:2,D`, true, ZipEntry{Folder: global.EmailTypeDraft}},
+		{"Trash/message.eml", true, ZipEntry{Folder: global.EmailTypeDeleted, Read: true}},
+		{"export/message.EML", true, ZipEntry{Read: true}},
+		{`Maildir/cur/1.8.odd\057host:2,S`, true, ZipEntry{Folder: global.EmailTypeInbox, Read: true}},
+		{"Maildir/tmp/1.9.host", false, ZipEntry{}},
+		{"Maildir/cur/", false, ZipEntry{}},
+		{"Maildir/dovecot-uidlist", false, ZipEntry{}},
+		{"Maildir/cur/.hidden", false, ZipEntry{}},
+		{"__MACOSX/Maildir/cur/1.10.host:2,S", false, ZipEntry{}},
+		{"readme.txt", false, ZipEntry{}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseZipEntry(tt.name)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseZipEntry(%q) = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFolderFromName(t *testing.T) {
+	tests := map[string]string{
+		"INBOX":         global.EmailTypeInbox,
+		" Sent Items ":  global.EmailTypeSent,
+		".Spam":         global.EmailTypeTrash,
+		"INBOX.Deleted": global.EmailTypeDeleted,
+		"Projects":      "",
+	}
+	for name, want := range tests {
+		if got := FolderFromName(name); got != want {
+			t.Errorf("FolderFromName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMaildirEntryName(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	tests := []struct {
+		folder        string
+		host          string
+		read, flagged bool
+		want          string
+	}{
+		{global.EmailTypeInbox, "mail.example.com", true, false, "Maildir/cur/1700000000.7.mail.example.com:2,S"},
+		{global.EmailTypeSent, "mail.example.com", true, true, "Maildir/.Sent/cur/1700000000.7.mail.example.com:2,FS"},
+		{global.EmailTypeDeleted, "host", false, false, "Maildir/.Trash/cur/1700000000.7.host:2,"},
+		{global.EmailTypeInbox, "odd/host:25", false, true, `Maildir/cur/1700000000.7.odd\057host\07225:2,F`},
+	}
+	for _, tt := range tests {
+		got := MaildirEntryName(tt.folder, 7, ts, tt.host, tt.read, tt.flagged)
+		if got != tt.want {
+			t.Errorf("MaildirEntryName(%q, %q) = %q, want %q", tt.folder, tt.host, got, tt.want)
+			continue
+		}
+		// 导出的文件名能被导入识别
+		entry, ok := ParseZipEntry(got)
+		if !ok || entry.Folder != tt.folder || entry.Read != tt.read || entry.Flagged != tt.flagged {
+			t.Errorf("ParseZipEntry(%q) = %+v, %v", got, entry, ok)
+		}
+	}
+}
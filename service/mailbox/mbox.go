@@ -0,0 +1,137 @@
+package mailbox
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Message mbox 中的一封邮件
+type Message struct {
+	Raw       []byte
+	Date      time.Time // From 分隔行中的时间，解析失败时为零值
+	Truncated bool      // 超过大小限制，Raw 不完整
+}
+
+// ReadMbox 逐封读取 mbox 文件，兼容 mboxo 与 mboxrd，超过 maxSize 的邮件标记为 Truncated
+func ReadMbox(r io.Reader, maxSize int64, fn func(*Message) error) error {
+	br := bufio.NewReaderSize(r, 64<<10)
+	var cur *Message
+	var buf bytes.Buffer
+	prevBlank := true
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		// 去掉分隔下一封邮件的空行
+		raw := buf.Bytes()
+		if bytes.HasSuffix(raw, []byte("\r\n\r\n")) {
+			raw = raw[:len(raw)-2]
+		} else if bytes.HasSuffix(raw, []byte("\n\n")) {
+			raw = raw[:len(raw)-1]
+		}
+		cur.Raw = append([]byte(nil), raw...)
+		err := fn(cur)
+		cur = nil
+		buf.Reset()
+		return err
+	}
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case prevBlank && bytes.HasPrefix(line, []byte("From ")):
+				if ferr := flush(); ferr != nil {
+					return ferr
+				}
+				cur = &Message{Date: parseFromLineDate(string(line))}
+			case cur == nil:
+				// 第一个分隔行之前的内容忽略
+			case cur.Truncated:
+			case int64(buf.Len()+len(line)) > maxSize:
+				cur.Truncated = true
+			default:
+				// >From 转义还原
+				if unescaped := bytes.TrimLeft(line, ">"); len(unescaped) < len(line) && bytes.HasPrefix(unescaped, []byte("From ")) {
+					line = line[1:]
+				}
+				buf.Write(line)
+			}
+			prevBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseFromLineDate 解析 "From sender Mon Jan  2 15:04:05 2006" 中的时间
+func parseFromLineDate(line string) time.Time {
+	fields := strings.Fields(line)
+	if len(fields) < 7 {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.ANSIC, strings.Join(fields[2:7], " ")); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// MboxFlags 根据 Status 与 X-Status 头部判断邮件是否已读、已标记
+func MboxFlags(raw []byte) (read, flagged bool) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return false, false
+	}
+	return strings.Contains(msg.Header.Get("Status"), "R"), strings.Contains(msg.Header.Get("X-Status"), "F")
+}
+
+// MboxWriter 以 mboxrd 格式写入邮件
+type MboxWriter struct {
+	w *bufio.Writer
+}
+
+// NewMboxWriter 创建 mbox 写入器，写入完成后需调用 Flush
+func NewMboxWriter(w io.Writer) *MboxWriter {
+	return &MboxWriter{w: bufio.NewWriter(w)}
+}
+
+// Write 写入一封邮件，正文中以 From 开头的行会被转义
+func (m *MboxWriter) Write(sender string, t time.Time, raw []byte) error {
+	if sender == "" {
+		sender = "MAILER-DAEMON"
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if _, err := m.w.WriteString("From " + sender + " " + t.UTC().Format(time.ANSIC) + "\n"); err != nil {
+		return err
+	}
+	for len(raw) > 0 {
+		line := raw
+		if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+			line = raw[:i+1]
+		}
+		raw = raw[len(line):]
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			m.w.WriteByte('>')
+		}
+		m.w.Write(line)
+		if len(raw) == 0 && line[len(line)-1] != '\n' {
+			m.w.WriteByte('\n')
+		}
+	}
+	_, err := m.w.WriteString("\n")
+	return err
+}
+
+// Flush 将缓冲区写入底层 Writer
+func (m *MboxWriter) Flush() error {
+	return m.w.Flush()
+}
@@ -0,0 +1,160 @@
+package mailbox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, data string, maxSize int64) []*Message {
+	t.Helper()
+	var msgs []*Message
+	err := ReadMbox(strings.NewReader(data), maxSize, func(m *Message) error {
+		msgs = append(msgs, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+func TestReadMbox(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "two messages",
+			data: "From a@example.com Mon Jan  2 15:04:05 2006\nSubject: one\n\nbody one\n\n" +
+				"From b@example.com Tue Jan  3 15:04:05 2006\nSubject: two\n\nbody two\n\n",
+			want: []string{"Subject: one\n\nbody one\n", "Subject: two\n\nbody two\n"},
+		},
+		{
+			name: "mboxrd quoted from lines",
+			data: "From a@example.com Mon Jan  2 15:04:05 2006\nSubject: q\n\n>From the start\n>>From twice\n> From spaced\n\n",
+			want: []string{"Subject: q\n\nFrom the start\n>From twice\n> From spaced\n"},
+		},
+		{
+			name: "unquoted from without blank line is content",
+			data: "From a@example.com Mon Jan  2 15:04:05 2006\nSubject: q\n\nline\nFrom here on\n\n",
+			want: []string{"Subject: q\n\nline\nFrom here on\n"},
+		},
+		{
+			name: "missing trailing newline",
+			data: "From a@example.com Mon Jan  2 15:04:05 2006\nSubject: last\n\nno newline",
+			want: []string{"Subject: last\n\nno newline"},
+		},
+		{
+			name: "crlf",
+			data: "From a@example.com Mon Jan  2 15:04:05 2006\r\nSubject: one\r\n\r\nbody\r\n\r\n" +
+				"From b@example.com Mon Jan  2 15:04:05 2006\r\nSubject: two\r\n\r\nbody\r\n",
+			want: []string{"Subject: one\r\n\r\nbody\r\n", "Subject: two\r\n\r\nbody\r\n"},
+		},
+		{
+			name: "text before first separator",
+			data: "garbage\n\nFrom a@example.com Mon Jan  2 15:04:05 2006\nSubject: one\n\nbody\n",
+			want: []string{"Subject: one\n\nbody\n"},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs := readAll(t, tt.data, 1<<20)
+			if len(msgs) != len(tt.want) {
+				t.Fatalf("got %d messages, want %d", len(msgs), len(tt.want))
+			}
+			for i, m := range msgs {
+				if string(m.Raw) != tt.want[i] {
+					t.Errorf("message %d = %q, want %q", i, m.Raw, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadMboxDateAndTruncation(t *testing.T) {
+	data := "From a@example.com Mon Jan  2 15:04:05 2006\nSubject: big\n\n" + strings.Repeat("x", 100) + "\n\n" +
+		"From b@example.com bad date\nSubject: small\n\nok\n"
+	msgs := readAll(t, data, 50)
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages", len(msgs))
+	}
+	if want := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC); !msgs[0].Date.Equal(want) {
+		t.Errorf("date = %v, want %v", msgs[0].Date, want)
+	}
+	if !msgs[0].Truncated || msgs[1].Truncated {
+		t.Errorf("truncated = %v, %v", msgs[0].Truncated, msgs[1].Truncated)
+	}
+	if !msgs[1].Date.IsZero() {
+		t.Errorf("unparsable date = %v, want zero", msgs[1].Date)
+	}
+}
+
+func TestMboxRoundTrip(t *testing.T) {
+	date := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name string
+		raw  string
+		want string // 读取结果，为空时与 raw 相同
+	}{
+		{"plain", "Subject: a\n\nhello\n", ""},
+		{"from lines", "Subject: b\n\nFrom the top\n>From quoted\n\nFrom after blank\n", ""},
+		{"trailing blank line", "Subject: c\n\nbody\n\n", ""},
+		{"missing trailing newline", "Subject: d\n\nno newline", "Subject: d\n\nno newline\n"},
+		{"crlf", "Subject: e\r\n\r\nFrom x\r\nbody\r\n", ""},
+	}
+	var buf bytes.Buffer
+	w := NewMboxWriter(&buf)
+	for _, tt := range tests {
+		if err := w.Write("sender@example.com", date, []byte(tt.raw)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "From sender@example.com Mon Mar  4 05:06:07 2024\n") {
+		t.Errorf("unexpected separator line: %q", strings.SplitN(buf.String(), "\n", 2)[0])
+	}
+	msgs := readAll(t, buf.String(), 1<<20)
+	if len(msgs) != len(tests) {
+		t.Fatalf("got %d messages, want %d\n%s", len(msgs), len(tests), buf.String())
+	}
+	for i, tt := range tests {
+		want := tt.want
+		if want == "" {
+			want = tt.raw
+		}
+		if string(msgs[i].Raw) != want {
+			t.Errorf("%s: got %q, want %q", tt.name, msgs[i].Raw, want)
+		}
+		if !msgs[i].Date.Equal(date) {
+			t.Errorf("%s: date = %v", tt.name, msgs[i].Date)
+		}
+	}
+}
+
+func TestMboxFlags(t *testing.T) {
+	tests := []struct {
+		raw           string
+		read, flagged bool
+	}{
+		{"Status: RO\nX-Status: F\nSubject: x\n\nbody\n", true, true},
+		{"Status: O\nSubject: x\n\nbody\n", false, false},
+		{"X-Status: AF\n\nbody\n", false, true},
+		{"not a message", false, false},
+	}
+	for _, tt := range tests {
+		read, flagged := MboxFlags([]byte(tt.raw))
+		if read != tt.read || flagged != tt.flagged {
+			t.Errorf("MboxFlags(%q) = %v, %v, want %v, %v", tt.raw, read, flagged, tt.read, tt.flagged)
+		}
+	}
+}
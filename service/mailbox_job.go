@@ -0,0 +1,458 @@
+package service
+
+import (
+	"archive/zip"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"email/service/mailbox"
+	"email/utils"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// 单封导入邮件的最大大小
+	maxImportMessageSize = 64 << 20
+	// 每处理多少封邮件保存一次进度
+	mailboxJobProgressInterval = 20
+	// 导出时每批读取的邮件数
+	mailboxExportBatchSize = 200
+	// MailboxExportPath 导出文件下载接口路径
+	MailboxExportPath = "/api/v1/email/transfer/download"
+)
+
+var (
+	// ErrMailboxFormat 不支持的导入导出格式
+	ErrMailboxFormat = errors.New("unsupported mailbox format")
+	// ErrMailboxExportNotReady 导出任务尚未完成
+	ErrMailboxExportNotReady = errors.New("export is not ready for download")
+)
+
+// 待执行的任务，由 MailboxJobInit 启动的 worker 消费
+var mailboxJobQueue = make(chan uint, 1024)
+
+// MailboxJobInit 启动导入导出任务的 worker，并继续执行重启前未开始的任务
+func MailboxJobInit() {
+	if err := dao.FailInterruptedMailboxJobs(); err != nil {
+		global.Log.Errorf("更新中断的邮箱任务失败: %v", err)
+	}
+	workers := global.Config.MailboxTransfer.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range mailboxJobQueue {
+				runMailboxJob(id)
+			}
+		}()
+	}
+	ids, err := dao.GetPendingMailboxJobIDs()
+	if err != nil {
+		global.Log.Errorf("获取待执行的邮箱任务失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		mailboxJobQueue <- id
+	}
+}
+
+// CreateImportJobProcess 上传导入文件并创建导入任务，format 为空时根据文件扩展名判断
+func CreateImportJobProcess(userID uint, fileName, format, folder string, r io.Reader) (*models.MailboxJob, error) {
+	if format == "" {
+		switch strings.ToLower(path.Ext(fileName)) {
+		case ".zip":
+			format = models.MailboxFormatZip
+		case ".eml":
+			format = models.MailboxFormatEML
+		default:
+			format = models.MailboxFormatMbox
+		}
+	}
+	if format == models.MailboxFormatMaildir {
+		format = models.MailboxFormatZip
+	}
+	if format != models.MailboxFormatMbox && format != models.MailboxFormatZip && format != models.MailboxFormatEML {
+		return nil, ErrMailboxFormat
+	}
+	if folder == "" {
+		folder = global.EmailTypeInbox
+	}
+	if !utils.Contains(global.EmailType, folder) {
+		return nil, fmt.Errorf("invalid folder %q", folder)
+	}
+	key := fmt.Sprintf("mailbox-jobs/%d/import-%d%s", userID, time.Now().UnixNano(), path.Ext(fileName))
+	maxSize := int64(global.Config.MailboxTransfer.MaxImportSizeMB) << 20
+	if maxSize <= 0 {
+		maxSize = 2048 << 20
+	}
+	if _, _, err := aws.StreamUploadToS3(key, "application/octet-stream", r, maxSize); err != nil {
+		return nil, err
+	}
+	job := &models.MailboxJob{
+		EmailAccountID: userID,
+		Kind:           models.MailboxJobImport,
+		Format:         format,
+		Folders:        folder,
+		FileName:       fileName,
+		SourceKey:      key,
+		Status:         models.MailboxJobPending,
+	}
+	if err := dao.AddMailboxJob(job); err != nil {
+		deleteMailboxJobObject(key)
+		return nil, err
+	}
+	enqueueMailboxJob(job.ID)
+	return job, nil
+}
+
+// CreateExportJobProcess 创建导出任务
+func CreateExportJobProcess(userID uint, req models.CreateExportJobRequest) (*models.MailboxJob, error) {
+	if req.Format == "" {
+		req.Format = models.MailboxFormatMbox
+	}
+	if req.Format != models.MailboxFormatMbox && req.Format != models.MailboxFormatMaildir {
+		return nil, ErrMailboxFormat
+	}
+	if len(req.Folders) == 0 {
+		return nil, errors.New("folders must not be empty")
+	}
+	for _, f := range req.Folders {
+		if !utils.Contains(global.EmailType, f) {
+			return nil, fmt.Errorf("invalid folder %q", f)
+		}
+	}
+	job := &models.MailboxJob{
+		EmailAccountID: userID,
+		Kind:           models.MailboxJobExport,
+		Format:         req.Format,
+		Folders:        strings.Join(req.Folders, ","),
+		Status:         models.MailboxJobPending,
+	}
+	if err := dao.AddMailboxJob(job); err != nil {
+		return nil, err
+	}
+	enqueueMailboxJob(job.ID)
+	return job, nil
+}
+
+// GetMailboxJobProcess 获取任务进度
+func GetMailboxJobProcess(userID, id uint) (*models.MailboxJob, error) {
+	job, err := dao.GetMailboxJob(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	setMailboxJobDownloadURL(job)
+	return job, nil
+}
+
+// GetMailboxJobsProcess 分页获取任务列表
+func GetMailboxJobsProcess(userID uint, page int) (*models.MailboxJobList, error) {
+	if page <= 0 {
+		page = 1
+	}
+	jobs, total, err := dao.GetMailboxJobs(userID, page, 20)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		setMailboxJobDownloadURL(&jobs[i])
+	}
+	return &models.MailboxJobList{Jobs: jobs, Total: total, Page: page}, nil
+}
+
+// OpenMailboxExportProcess 获取已完成的导出文件，返回的 Body 需由调用方关闭
+func OpenMailboxExportProcess(userID, id uint) (*models.MailboxJob, *s3.GetObjectOutput, error) {
+	job, err := dao.GetMailboxJob(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Kind != models.MailboxJobExport || job.Status != models.MailboxJobCompleted || job.ResultKey == "" {
+		return nil, nil, ErrMailboxExportNotReady
+	}
+	out, err := aws.GetS3ObjectRange(job.ResultKey, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, out, nil
+}
+
+func setMailboxJobDownloadURL(job *models.MailboxJob) {
+	if job.Kind == models.MailboxJobExport && job.Status == models.MailboxJobCompleted {
+		job.DownloadURL = fmt.Sprintf("%s?id=%d", MailboxExportPath, job.ID)
+	}
+}
+
+func enqueueMailboxJob(id uint) {
+	// 队列已满时不阻塞请求
+	go func() { mailboxJobQueue <- id }()
+}
+
+// runMailboxJob 执行任务并记录结果
+func runMailboxJob(id uint) {
+	job, ok, err := dao.ClaimMailboxJob(id)
+	if err != nil {
+		global.Log.Errorf("领取邮箱任务 [ID: %d] 失败: %v", id, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	global.Log.Infof("开始执行邮箱%s任务 [ID: %d]", job.Kind, job.ID)
+	if job.Kind == models.MailboxJobImport {
+		err = runImportJob(job)
+		deleteMailboxJobObject(job.SourceKey)
+	} else {
+		err = runExportJob(job)
+	}
+	job.Status = models.MailboxJobCompleted
+	if err != nil {
+		global.Log.Errorf("邮箱%s任务 [ID: %d] 失败: %v", job.Kind, job.ID, err)
+		job.Status, job.Error = models.MailboxJobFailed, err.Error()
+	}
+	_ = dao.FinishMailboxJob(job)
+	global.Log.Infof("邮箱%s任务 [ID: %d] 结束，成功 %d，跳过 %d，失败 %d", job.Kind, job.ID, job.Succeeded, job.Skipped, job.Failed)
+}
+
+// mailboxJobProgress 处理完一封邮件后计数，并定期保存进度
+func mailboxJobProgress(job *models.MailboxJob, succeeded, skipped bool) {
+	job.Processed++
+	switch {
+	case succeeded:
+		job.Succeeded++
+	case skipped:
+		job.Skipped++
+	default:
+		job.Failed++
+	}
+	if job.Processed%mailboxJobProgressInterval == 0 {
+		if err := dao.UpdateMailboxJobProgress(job); err != nil {
+			global.Log.Warnf("更新邮箱任务 [ID: %d] 进度失败: %v", job.ID, err)
+		}
+	}
+}
+
+// runImportJob 读取上传的文件，逐封导入
+func runImportJob(job *models.MailboxJob) error {
+	account, err := dao.GetAccountByID(job.EmailAccountID)
+	if err != nil {
+		return err
+	}
+	importOne := func(raw []byte, opts aws.ImportOptions) {
+		if opts.EmailType == "" {
+			opts.EmailType = job.Folders
+		}
+		imported, err := aws.ImportRawEmail(account, raw, opts)
+		if err != nil {
+			global.Log.Warnf("邮箱导入任务 [ID: %d] 导入第 %d 封邮件失败: %v", job.ID, job.Processed+1, err)
+		}
+		mailboxJobProgress(job, imported, err == nil && !imported)
+	}
+	out, err := aws.GetS3ObjectRange(job.SourceKey, "")
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+
+	switch job.Format {
+	case models.MailboxFormatMbox:
+		err = mailbox.ReadMbox(out.Body, maxImportMessageSize, func(m *mailbox.Message) error {
+			job.Total++
+			if m.Truncated {
+				global.Log.Warnf("邮箱导入任务 [ID: %d] 第 %d 封邮件超过大小限制", job.ID, job.Total)
+				mailboxJobProgress(job, false, false)
+				return nil
+			}
+			read, flagged := mailbox.MboxFlags(m.Raw)
+			importOne(m.Raw, aws.ImportOptions{Read: read, Flagged: flagged, Date: m.Date})
+			return nil
+		})
+		return err
+	case models.MailboxFormatEML:
+		job.Total = 1
+		raw, err := io.ReadAll(io.LimitReader(out.Body, maxImportMessageSize))
+		if err != nil {
+			return err
+		}
+		importOne(raw, aws.ImportOptions{Read: true})
+		return nil
+	case models.MailboxFormatZip:
+		return importZip(job, out.Body, importOne)
+	}
+	return ErrMailboxFormat
+}
+
+// importZip zip 需要随机读取，先下载到临时文件
+func importZip(job *models.MailboxJob, body io.Reader, importOne func([]byte, aws.ImportOptions)) error {
+	f, err := os.CreateTemp("", "mailbox-import-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	size, err := io.Copy(f, body)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return fmt.Errorf("invalid zip file: %w", err)
+	}
+	type zipMessage struct {
+		file  *zip.File
+		entry mailbox.ZipEntry
+	}
+	var messages []zipMessage
+	for _, file := range zr.File {
+		if entry, ok := mailbox.ParseZipEntry(file.Name); ok {
+			messages = append(messages, zipMessage{file: file, entry: entry})
+		}
+	}
+	job.Total = len(messages)
+	if err := dao.UpdateMailboxJobProgress(job); err != nil {
+		global.Log.Warnf("更新邮箱任务 [ID: %d] 进度失败: %v", job.ID, err)
+	}
+	for _, m := range messages {
+		if m.file.UncompressedSize64 > maxImportMessageSize {
+			global.Log.Warnf("邮箱导入任务 [ID: %d] 邮件 [ %s ] 超过大小限制", job.ID, m.file.Name)
+			mailboxJobProgress(job, false, false)
+			continue
+		}
+		raw, err := readZipFile(m.file)
+		if err != nil {
+			global.Log.Warnf("邮箱导入任务 [ID: %d] 读取 [ %s ] 失败: %v", job.ID, m.file.Name, err)
+			mailboxJobProgress(job, false, false)
+			continue
+		}
+		importOne(raw, aws.ImportOptions{EmailType: m.entry.Folder, Read: m.entry.Read, Flagged: m.entry.Flagged, Date: m.file.Modified})
+	}
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxImportMessageSize))
+}
+
+// runExportJob 将选定分类的邮件原文写入 zip，边生成边上传到 S3
+func runExportJob(job *models.MailboxJob) error {
+	folders := strings.Split(job.Folders, ",")
+	total, err := dao.CountEmailsInFolders(job.EmailAccountID, folders)
+	if err != nil {
+		return err
+	}
+	job.Total = int(total)
+	if err := dao.UpdateMailboxJobProgress(job); err != nil {
+		global.Log.Warnf("更新邮箱任务 [ID: %d] 进度失败: %v", job.ID, err)
+	}
+	key := fmt.Sprintf("mailbox-jobs/%d/export-%d-%s.zip", job.EmailAccountID, job.ID, job.Format)
+	maxSize := int64(global.Config.MailboxTransfer.MaxExportSizeMB) << 20
+	if maxSize <= 0 {
+		maxSize = 10240 << 20
+	}
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := writeExportZip(job, folders, pw)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	_, size, err := aws.StreamUploadToS3(key, "application/zip", pr, maxSize)
+	// 上传失败时结束写入
+	pr.CloseWithError(err)
+	if werr := <-done; werr != nil && err == nil {
+		err = werr
+	}
+	if err != nil {
+		return err
+	}
+	job.ResultKey, job.ResultSize = key, size
+	return nil
+}
+
+func writeExportZip(job *models.MailboxJob, folders []string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, folder := range folders {
+		var mbox *mailbox.MboxWriter
+		if job.Format == models.MailboxFormatMbox {
+			fw, err := zw.Create(folder + ".mbox")
+			if err != nil {
+				return err
+			}
+			mbox = mailbox.NewMboxWriter(fw)
+		}
+		var afterID uint
+		for {
+			emails, err := dao.GetEmailsForExport(job.EmailAccountID, folder, afterID, mailboxExportBatchSize)
+			if err != nil {
+				return err
+			}
+			if len(emails) == 0 {
+				break
+			}
+			for _, e := range emails {
+				afterID = e.ID
+				if e.S3Key == "" || e.S3Key == "N/A" {
+					mailboxJobProgress(job, false, true)
+					continue
+				}
+				raw, err := aws.GetS3ObjectBytes(e.S3Key)
+				if err != nil {
+					global.Log.Warnf("邮箱导出任务 [ID: %d] 读取邮件 [ID: %d] 原文失败: %v", job.ID, e.ID, err)
+					mailboxJobProgress(job, false, false)
+					continue
+				}
+				if mbox != nil {
+					err = mbox.Write(e.SenderEmail, e.ReceivedAt, raw)
+				} else {
+					err = writeMaildirEntry(zw, e, raw)
+				}
+				if err != nil {
+					return err
+				}
+				mailboxJobProgress(job, true, false)
+			}
+		}
+		if mbox != nil {
+			if err := mbox.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+func writeMaildirEntry(zw *zip.Writer, e models.EmailDetails, raw []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     mailbox.MaildirEntryName(e.EmailType, e.ID, e.ReceivedAt, utils.MailHostname(), e.IsRead, e.IsFlagged),
+		Method:   zip.Deflate,
+		Modified: e.ReceivedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(raw)
+	return err
+}
+
+func deleteMailboxJobObject(key string) {
+	if key == "" {
+		return
+	}
+	if err := aws.DeleteS3Object(key); err != nil {
+		global.Log.Warnf("删除邮箱任务文件 [ %s ] 失败: %v", key, err)
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"email/dao"
+	"email/models"
+	"email/service/aws"
+	"email/utils"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// 解析邮件头部时最多读取的字节数
+const maxRawHeaderBytes = 256 << 10
+
+// ErrRawMessageNotFound 邮件未保存原文
+var ErrRawMessageNotFound = errors.New("raw message is not stored for this email")
+
+// GetRawEmailProcess 获取邮件原文，返回的 Body 需由调用方关闭
+func GetRawEmailProcess(userID uint, emailID int) (*s3.GetObjectOutput, error) {
+	key, err := getRawEmailKey(userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+	return aws.GetS3ObjectRange(key, "")
+}
+
+// GetEmailHeadersProcess 解析邮件原文的头部，保留原始顺序与重复项
+func GetEmailHeadersProcess(userID uint, emailID int) ([]models.ParsedEmailHeader, error) {
+	out, err := GetRawEmailProcess(userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(out.Body, maxRawHeaderBytes))
+	if err != nil {
+		return nil, err
+	}
+	return utils.ParseRawHeaders(raw), nil
+}
+
+// getRawEmailKey 校验邮件归属并返回原文的 S3 对象键
+func getRawEmailKey(userID uint, emailID int) (string, error) {
+	e, err := dao.GetEmailDetailFullFileds(emailID, userID)
+	if err != nil {
+		return "", err
+	}
+	if e.S3Key == "" || e.S3Key == "N/A" {
+		return "", ErrRawMessageNotFound
+	}
+	return e.S3Key, nil
+}
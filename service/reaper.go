@@ -0,0 +1,184 @@
+package service
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 仍被引用或所属域名未开启回收的附件，推迟到该时长后再检查
+const attachmentRecheckInterval = 7 * 24 * time.Hour
+
+// ReaperSummary 一次附件回收任务的结果
+type ReaperSummary struct {
+	RunID          string
+	Scanned        int   // 检查的过期附件数
+	Kept           int   // 仍被引用或未开启回收而保留的附件数
+	Deleted        int   // 删除的附件数
+	ReclaimedBytes int64 // 释放的存储空间
+	AbortedUploads int   // 清理的过期上传会话数
+}
+
+// AttachmentReaperInit 定期执行附件回收任务
+func AttachmentReaperInit() {
+	interval := global.Config.AttachmentReaper.IntervalMinutes
+	if interval <= 0 {
+		global.Log.Info("附件回收任务未启用")
+		return
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	defer ticker.Stop()
+	for {
+		if _, err := RunAttachmentReaper(); err != nil {
+			global.Log.Errorf("附件回收任务失败: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// RunAttachmentReaper 回收过期且不再被任何邮件引用的附件，并清理过期的上传会话
+// 只有附件所有授权账户的域名都开启了回收，且已超过各域名的保留时长，才会删除
+// 没有授权记录的附件按 AttachmentReaper.reap_unowned 全局设置处理
+func RunAttachmentReaper() (*ReaperSummary, error) {
+	now := time.Now()
+	summary := &ReaperSummary{RunID: uuid.New().String()}
+	batchSize := global.Config.AttachmentReaper.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	aborted, err := reapUploadSessions(now, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	summary.AbortedUploads = aborted
+
+	reports := map[uint]*models.AttachmentReaperReport{}
+	var afterID uint
+	for {
+		atts, err := dao.GetExpiredAttachments(now, afterID, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(atts) == 0 {
+			break
+		}
+		afterID = atts[len(atts)-1].ID
+		summary.Scanned += len(atts)
+		if err := reapAttachments(now, atts, summary, reports); err != nil {
+			return nil, err
+		}
+		if len(atts) < batchSize {
+			break
+		}
+	}
+
+	list := make([]models.AttachmentReaperReport, 0, len(reports))
+	for _, r := range reports {
+		list = append(list, *r)
+	}
+	if err := dao.AddAttachmentReaperReports(list); err != nil {
+		global.Log.Errorf("保存附件回收报告失败: %v", err)
+	}
+	global.Log.Infof("附件回收完成 [ %s ]: 检查 %d 个，保留 %d 个，删除 %d 个，释放 %d 字节，清理上传会话 %d 个",
+		summary.RunID, summary.Scanned, summary.Kept, summary.Deleted, summary.ReclaimedBytes, summary.AbortedUploads)
+	return summary, nil
+}
+
+// reapAttachments 处理一批过期附件
+func reapAttachments(now time.Time, atts []models.Attachment, summary *ReaperSummary, reports map[uint]*models.AttachmentReaperReport) error {
+	referenced, err := dao.GetReferencedAttachmentHashes(atts)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(atts))
+	for _, a := range atts {
+		ids = append(ids, a.ID)
+	}
+	grantDomains, err := dao.GetAttachmentGrantDomains(ids)
+	if err != nil {
+		return err
+	}
+	var domainIDs []uint
+	for _, ds := range grantDomains {
+		domainIDs = append(domainIDs, ds...)
+	}
+	policies, err := dao.GetDomainPolicies(domainIDs)
+	if err != nil {
+		return err
+	}
+
+	cfg := global.Config.AttachmentReaper
+	var keep []uint
+	for _, a := range atts {
+		domains := grantDomains[a.ID]
+		if referenced[a.FileHash] {
+			keep = append(keep, a.ID)
+			continue
+		}
+		enabled, grace := true, 0
+		if len(domains) == 0 {
+			// 无法确定归属的附件只按全局设置回收
+			enabled, grace = cfg.ReapUnowned, cfg.UnownedGraceHours
+		}
+		for _, d := range domains {
+			p, ok := policies[d]
+			if !ok || !p.AttachmentReaperEnabled {
+				enabled = false
+				break
+			}
+			if p.OrphanGraceHours > grace {
+				grace = p.OrphanGraceHours
+			}
+		}
+		if !enabled {
+			keep = append(keep, a.ID)
+			continue
+		}
+		// 仍在保留期内，下次再检查
+		if a.ExpireTime.Add(time.Duration(grace) * time.Hour).After(now) {
+			continue
+		}
+		if err := aws.DeleteS3Object(a.S3StoragePath); err != nil {
+			global.Log.Errorf("删除附件 [ %s ] 失败: %v", a.S3StoragePath, err)
+			continue
+		}
+		if err := dao.DeleteAttachment(a.ID); err != nil {
+			global.Log.Errorf("删除附件记录 [ID: %d] 失败: %v", a.ID, err)
+			continue
+		}
+		summary.Deleted++
+		summary.ReclaimedBytes += a.FileSize
+		if len(domains) == 0 {
+			continue
+		}
+		// 回收量计入上传者所在域名
+		r, ok := reports[domains[0]]
+		if !ok {
+			r = &models.AttachmentReaperReport{RunID: summary.RunID, DomainID: domains[0]}
+			reports[domains[0]] = r
+		}
+		r.DeletedCount++
+		r.ReclaimedBytes += a.FileSize
+	}
+	summary.Kept += len(keep)
+	return dao.ExtendAttachmentExpire(keep, now.Add(attachmentRecheckInterval))
+}
+
+// reapUploadSessions 取消已过期的分片上传，释放 S3 中未合并的分片
+func reapUploadSessions(now time.Time, limit int) (int, error) {
+	sessions, err := dao.GetExpiredUploadSessions(now, limit)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range sessions {
+		aws.AbortMultipartUpload(s.S3Key, s.S3UploadID)
+		if err := dao.FinishUploadSession(s.ID, models.UploadStatusAborted, ""); err != nil {
+			global.Log.Errorf("更新上传会话 [ %s ] 状态失败: %v", s.ID, err)
+		}
+	}
+	return len(sessions), nil
+}
@@ -0,0 +1,260 @@
+package sanitize
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ScopeClass 清洗后的 HTML 外层容器的 class，<style> 中的选择器均限定在该容器内，避免邮件样式影响页面
+const ScopeClass = "mail-content"
+
+var (
+	cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssURLPattern     = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)\s]*))\s*\)`)
+	// @media / @supports 允许的条件写法
+	cssConditionPattern = regexp.MustCompile(`^[a-zA-Z0-9\s():,.\-]*$`)
+)
+
+// 值中出现即删除整条声明的内容
+var dangerousCSS = []string{"expression(", "javascript:", "vbscript:", "behavior", "-moz-binding", "image-set(", "@import", "\\", "{", "}", "<"}
+
+// 选择器中不允许出现的字符
+const invalidSelectorChars = "\\{}<;@"
+
+// stylesheet 清洗 <style> 中的样式表
+func (s *sanitizer) stylesheet(css string) string {
+	css = cssCommentPattern.ReplaceAllString(css, "")
+	// 未闭合的注释延续到样式表结尾
+	if i := strings.Index(css, "/*"); i >= 0 {
+		css = css[:i]
+	}
+	css = strings.ReplaceAll(css, "<", "")
+	return s.rules(css, 0)
+}
+
+// rules 逐条解析规则并重新输出，只保留完整闭合的规则块
+// 浏览器会在样式表结尾自动闭合未闭合的块，未闭合的块与块外的多余文本若原样保留会绕过清洗，因此直接丢弃
+func (s *sanitizer) rules(css string, depth int) string {
+	var b strings.Builder
+	for {
+		open, end := scanRule(css)
+		if end < 0 {
+			break
+		}
+		if open >= 0 {
+			if r := s.rule(strings.TrimSpace(css[:open]), css[open+1:end], depth); r != "" {
+				b.WriteString(r)
+				b.WriteString("\n")
+			}
+		}
+		// open < 0 时为 @import、@charset 等以分号结束的语句，一并丢弃
+		css = css[end+1:]
+	}
+	return b.String()
+}
+
+// scanRule 查找第一条规则，返回块的起止位置；以分号结束的语句 open 为 -1；没有完整的规则时 end 为 -1
+func scanRule(css string) (open, end int) {
+	var quote byte
+	open, depth := -1, 0
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote || c == '\n' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';' && open < 0:
+			return -1, i
+		case c == '{':
+			if open < 0 {
+				open = i
+			}
+			depth++
+		case c == '}':
+			if open < 0 {
+				// 多余的 }，与之前的内容一起丢弃
+				return -1, i
+			}
+			if depth--; depth == 0 {
+				return open, i
+			}
+		}
+	}
+	return open, -1
+}
+
+// rule 清洗一条规则：@media、@supports 递归清洗其中的规则，其他 @ 规则(@font-face、@keyframes 等)删除
+func (s *sanitizer) rule(prelude, block string, depth int) string {
+	if strings.HasPrefix(prelude, "@") {
+		name, cond, _ := strings.Cut(prelude[1:], " ")
+		name = strings.ToLower(name)
+		if (name != "media" && name != "supports") || depth > 0 || !cssConditionPattern.MatchString(cond) {
+			return ""
+		}
+		inner := s.rules(block, depth+1)
+		if inner == "" {
+			return ""
+		}
+		return "@" + name + " " + strings.TrimSpace(cond) + " {\n" + inner + "}"
+	}
+	if strings.ContainsAny(block, "{}") {
+		return ""
+	}
+	selectors := scopeSelectors(prelude)
+	decls := s.declarations(block)
+	if selectors == "" || decls == "" {
+		return ""
+	}
+	return selectors + " { " + decls + " }"
+}
+
+// scopeSelectors 将选择器限定在 ScopeClass 容器内，html、body、:root 替换为容器本身
+// 以 + 或 ~ 开头的选择器会匹配容器之外的元素，直接删除
+func scopeSelectors(prelude string) string {
+	var kept []string
+	for _, sel := range splitSelectors(prelude) {
+		sel = strings.TrimSpace(sel)
+		if sel == "" || strings.ContainsAny(sel, invalidSelectorChars) || sel[0] == '+' || sel[0] == '~' {
+			continue
+		}
+		if n := rootSelectorLen(sel); n > 0 {
+			kept = append(kept, "."+ScopeClass+sel[n:])
+		} else {
+			kept = append(kept, "."+ScopeClass+" "+sel)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
+
+// rootSelectorLen 选择器以 html、body 或 :root 开头时返回其长度
+func rootSelectorLen(sel string) int {
+	lower := strings.ToLower(sel)
+	for _, root := range []string{"html", "body", ":root"} {
+		if !strings.HasPrefix(lower, root) {
+			continue
+		}
+		if len(lower) == len(root) {
+			return len(root)
+		}
+		c := lower[len(root)]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return len(root)
+		}
+	}
+	return 0
+}
+
+// splitSelectors 按逗号拆分选择器列表，忽略括号、方括号与引号中的逗号
+func splitSelectors(prelude string) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(prelude); i++ {
+		c := prelude[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			if depth > 0 {
+				depth--
+			}
+		case c == ',' && depth == 0:
+			parts = append(parts, prelude[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, prelude[start:])
+}
+
+// declarations 清洗一组以分号分隔的 CSS 声明
+func (s *sanitizer) declarations(style string) string {
+	var kept []string
+	for _, decl := range splitDeclarations(style) {
+		i := strings.IndexByte(decl, ':')
+		if i < 0 {
+			continue
+		}
+		prop := strings.ToLower(strings.TrimSpace(decl[:i]))
+		val := strings.TrimSpace(decl[i+1:])
+		if prop == "" {
+			continue
+		}
+		if v, ok := s.declaration(prop, val); ok {
+			kept = append(kept, prop+": "+v)
+		}
+	}
+	return strings.Join(kept, "; ")
+}
+
+func (s *sanitizer) declaration(prop, val string) (string, bool) {
+	lower := strings.ToLower(val)
+	for _, d := range dangerousCSS {
+		if strings.Contains(prop, d) || strings.Contains(lower, d) {
+			return "", false
+		}
+	}
+	// 未闭合的字符串会吞掉之后的内容
+	if strings.Count(val, `"`)%2 != 0 || strings.Count(val, "'")%2 != 0 {
+		return "", false
+	}
+	// 防止邮件内容覆盖在页面之上伪造界面
+	if prop == "position" && !strings.Contains(lower, "static") && !strings.Contains(lower, "relative") {
+		return "", false
+	}
+	ok := true
+	val = cssURLPattern.ReplaceAllStringFunc(val, func(m string) string {
+		sub := cssURLPattern.FindStringSubmatch(m)
+		u, keep := s.image(strings.TrimSpace(sub[1] + sub[2] + sub[3]))
+		if !keep {
+			ok = false
+			return m
+		}
+		return `url("` + strings.ReplaceAll(u, `"`, "%22") + `")`
+	})
+	// 仍有无法识别的 url( 写法时删除
+	if !ok || strings.Count(strings.ToLower(val), "url(") != len(cssURLPattern.FindAllString(val, -1)) {
+		return "", false
+	}
+	return val, true
+}
+
+// splitDeclarations 按分号拆分声明，忽略括号与引号中的分号（如 data URI）
+func splitDeclarations(style string) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(style); i++ {
+		c := style[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == ';' && depth == 0:
+			parts = append(parts, style[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, style[start:])
+}
@@ -0,0 +1,65 @@
+package sanitize
+
+import "testing"
+
+func TestStylesheet(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"descendant selector", "p { color: red }", ".mail-content p { color: red }\n"},
+		{"selector list", "h1, .title > b { font-weight: bold }", ".mail-content h1, .mail-content .title > b { font-weight: bold }\n"},
+		{"body becomes container", "body { margin: 0 } body.dark p { color: white }",
+			".mail-content { margin: 0 }\n.mail-content.dark p { color: white }\n"},
+		{"root selectors", "html, :root { background: white }", ".mail-content, .mail-content { background: white }\n"},
+		{"body prefix is not body", "bodytext { color: red }", ".mail-content bodytext { color: red }\n"},
+		{"sibling of container dropped", "~ div, + p, a { color: red }", ".mail-content a { color: red }\n"},
+		{"child combinator", "> table { width: 100% }", ".mail-content > table { width: 100% }\n"},
+		{"comma in attribute selector", `a[title="a,b"] { color: red }`, ".mail-content a[title=\"a,b\"] { color: red }\n"},
+		{"media query", "@media (max-width: 600px) { td { display: block } }",
+			"@media (max-width: 600px) {\n.mail-content td { display: block }\n}\n"},
+		{"nested media dropped", "@media screen { @media print { p { color: red } } }", ""},
+		{"media condition with string dropped", `@media "x" { p { color: red } }`, ""},
+		{"keyframes dropped", "@keyframes spin { from { opacity: 0 } to { opacity: 1 } } p { color: red }", ".mail-content p { color: red }\n"},
+		{"charset dropped", "@charset \"utf-8\"; p { color: red }", ".mail-content p { color: red }\n"},
+		{"unclosed block dropped", "p { color: red } div { color: blue", ".mail-content p { color: red }\n"},
+		{"stray closing brace", "} p { color: red }", ".mail-content p { color: red }\n"},
+		{"trailing text dropped", "p { color: red } div", ".mail-content p { color: red }\n"},
+		{"nested block dropped", "p { color: red; div { color: blue } }", ""},
+		{"brace in string", `p::before { content: "}" } a { color: red }`, ".mail-content a { color: red }\n"},
+		{"unbalanced quote dropped", `p { font-family: "x; color: red }`, ""},
+		{"escaped selector dropped", `\70 { color: red }`, ""},
+		{"empty declarations dropped", "p { behavior: url(x.htc) }", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &sanitizer{res: &Result{}}
+			if got := s.stylesheet(tt.in); got != tt.want {
+				t.Errorf("stylesheet(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclarations(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"color: red; FONT-SIZE: 12px", "color: red; font-size: 12px"},
+		{"background: url(data:image/png;base64,AAAA); color: red", `background: url("data:image/png;base64,AAAA"); color: red`},
+		{"position: absolute; position: relative", "position: relative"},
+		{"color: red; -moz-binding: url(x.xml)", "color: red"},
+		{"background: url(javascript:alert(1))", ""},
+		{"width: 1px; }", "width: 1px"},
+		{"color: red } p { color: blue", ""},
+		{"no colon; : novalue", ""},
+	}
+	for _, tt := range tests {
+		s := &sanitizer{res: &Result{}}
+		if got := s.declarations(tt.in); got != tt.want {
+			t.Errorf("declarations(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package sanitize
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Options 清洗选项
+type Options struct {
+	AllowRemoteImages bool                // 是否加载远程图片，默认屏蔽以防止追踪
+	RewriteImage      func(string) string // 加载远程图片时改写图片地址，如经由图片代理，为空时保持原地址
+}
+
+// Result 清洗结果
+type Result struct {
+	HTML         string
+	RemoteImages int  // 正文中的远程图片数量
+	Blocked      bool // 是否有远程图片被屏蔽
+}
+
+// 允许保留的元素
+var allowedTags = map[string]bool{
+	"a": true, "abbr": true, "address": true, "article": true, "aside": true, "b": true, "bdi": true, "bdo": true,
+	"big": true, "blockquote": true, "br": true, "caption": true, "center": true, "cite": true, "code": true,
+	"col": true, "colgroup": true, "dd": true, "del": true, "dfn": true, "div": true, "dl": true, "dt": true,
+	"em": true, "figcaption": true, "figure": true, "font": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "i": true, "img": true,
+	"ins": true, "kbd": true, "li": true, "main": true, "mark": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "q": true, "s": true, "samp": true, "section": true, "small": true, "span": true,
+	"strike": true, "strong": true, "style": true, "sub": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "tr": true, "tt": true, "u": true, "ul": true,
+	"var": true, "wbr": true,
+}
+
+// 连同内容一起删除的元素，其余未知元素只去掉标签、保留内容
+var droppedTags = map[string]bool{
+	"script": true, "noscript": true, "template": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "param": true, "base": true, "link": true, "meta": true,
+	"title": true, "input": true, "button": true, "select": true, "option": true, "optgroup": true,
+	"textarea": true, "datalist": true, "output": true, "audio": true, "video": true, "source": true,
+	"track": true, "canvas": true, "svg": true, "math": true, "portal": true, "dialog": true,
+}
+
+// 允许保留的属性，事件处理器等其他属性一律删除
+var allowedAttrs = map[string]bool{
+	"align": true, "alt": true, "bgcolor": true, "border": true, "cellpadding": true, "cellspacing": true,
+	"class": true, "color": true, "cols": true, "colspan": true, "dir": true, "face": true, "height": true,
+	"hspace": true, "lang": true, "nowrap": true, "rows": true, "rowspan": true, "size": true, "span": true,
+	"start": true, "style": true, "summary": true, "title": true, "type": true, "valign": true,
+	"vspace": true, "width": true,
+}
+
+// 链接允许的协议
+var allowedLinkSchemes = []string{"http:", "https:", "mailto:", "tel:"}
+
+// 允许内嵌的 data URI 图片类型
+var dataImagePattern = regexp.MustCompile(`(?i)^data:image/(png|gif|jpe?g|webp|bmp);base64,`)
+
+// HTML 按白名单清洗邮件 HTML，删除脚本、事件处理器、表单与危险 CSS，并按选项屏蔽远程图片
+// 返回的 HTML 只包含 <style> 与 <body> 中的内容，外层为 class 为 ScopeClass 的 div，样式只作用于该容器内
+func HTML(body string, opts Options) Result {
+	res := Result{}
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		res.HTML = html.EscapeString(body)
+		return res
+	}
+	s := &sanitizer{opts: opts, res: &res}
+	var styles []*html.Node
+	var bodyNode *html.Node
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch {
+			case c.Data == "body":
+				bodyNode = c
+			case c.Data == "style" && bodyNode == nil:
+				styles = append(styles, c)
+			case c.Data == "html" || c.Data == "head":
+				find(c)
+			}
+		}
+	}
+	find(doc)
+	var b strings.Builder
+	b.WriteString(`<div class="` + ScopeClass + `">`)
+	for _, st := range styles {
+		st.Parent.RemoveChild(st)
+		s.cleanStyleElement(st)
+		html.Render(&b, st)
+	}
+	if bodyNode != nil {
+		s.clean(bodyNode)
+		for c := bodyNode.FirstChild; c != nil; c = c.NextSibling {
+			html.Render(&b, c)
+		}
+	}
+	b.WriteString("</div>")
+	res.HTML = b.String()
+	return res
+}
+
+type sanitizer struct {
+	opts Options
+	res  *Result
+}
+
+// clean 递归清洗子节点
+func (s *sanitizer) clean(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.TextNode:
+		case html.ElementNode:
+			tag := strings.ToLower(c.Data)
+			switch {
+			case c.Namespace != "" || droppedTags[tag]:
+				n.RemoveChild(c)
+			case tag == "style":
+				s.cleanStyleElement(c)
+			case allowedTags[tag]:
+				s.cleanAttrs(c, tag)
+				s.clean(c)
+			default:
+				// 未知元素与 form 等只保留内容
+				s.clean(c)
+				for gc := c.FirstChild; gc != nil; {
+					gnext := gc.NextSibling
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+					gc = gnext
+				}
+				n.RemoveChild(c)
+			}
+		default:
+			// 注释（包括 IE 条件注释）与 doctype
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+func (s *sanitizer) cleanStyleElement(n *html.Node) {
+	var css strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			css.WriteString(c.Data)
+		}
+	}
+	for n.FirstChild != nil {
+		n.RemoveChild(n.FirstChild)
+	}
+	n.Attr = nil
+	n.AppendChild(&html.Node{Type: html.TextNode, Data: s.stylesheet(css.String())})
+}
+
+func (s *sanitizer) cleanAttrs(n *html.Node, tag string) {
+	attrs := make([]html.Attribute, 0, len(n.Attr))
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		val := strings.TrimSpace(a.Val)
+		if a.Namespace != "" {
+			continue
+		}
+		switch {
+		case key == "href" && (tag == "a"):
+			if safeLink(val) {
+				attrs = append(attrs, html.Attribute{Key: key, Val: val})
+			}
+		case key == "src" && tag == "img":
+			if v, ok := s.image(val); ok {
+				attrs = append(attrs, html.Attribute{Key: key, Val: v})
+			}
+		case key == "background" && (tag == "table" || tag == "td" || tag == "th"):
+			if v, ok := s.image(val); ok {
+				attrs = append(attrs, html.Attribute{Key: key, Val: v})
+			}
+		case key == "style":
+			if v := s.declarations(val); v != "" {
+				attrs = append(attrs, html.Attribute{Key: key, Val: v})
+			}
+		case allowedAttrs[key]:
+			attrs = append(attrs, html.Attribute{Key: key, Val: a.Val})
+		}
+	}
+	if tag == "a" {
+		attrs = append(attrs,
+			html.Attribute{Key: "target", Val: "_blank"},
+			html.Attribute{Key: "rel", Val: "noopener noreferrer nofollow"})
+	}
+	n.Attr = attrs
+}
+
+// image 处理图片地址，返回改写后的地址以及是否保留
+func (s *sanitizer) image(src string) (string, bool) {
+	lower := strings.ToLower(src)
+	switch {
+	case dataImagePattern.MatchString(src), strings.HasPrefix(lower, "cid:"):
+		return src, true
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"), strings.HasPrefix(lower, "//"):
+		s.res.RemoteImages++
+		if !s.opts.AllowRemoteImages {
+			s.res.Blocked = true
+			return "", false
+		}
+		if strings.HasPrefix(src, "//") {
+			src = "https:" + src
+		}
+		if s.opts.RewriteImage != nil {
+			return s.opts.RewriteImage(src), true
+		}
+		return src, true
+	default:
+		return "", false
+	}
+}
+
+func safeLink(href string) bool {
+	if strings.HasPrefix(href, "#") {
+		return true
+	}
+	// 去掉空白与控制字符后再判断协议，防止 "java\tscript:" 之类的绕过
+	lower := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, href))
+	for _, scheme := range allowedLinkSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	return false
+}
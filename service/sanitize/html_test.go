@@ -0,0 +1,102 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+const scopeOpen = `<div class="mail-content">`
+
+func TestHTMLRemovesActiveContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		absent []string
+	}{
+		{"script", `<p>hi</p><script>alert(1)</script>`, []string{"script", "alert"}},
+		{"event handler", `<img src="cid:x" onerror="alert(1)"><div onclick="x()">a</div>`, []string{"onerror", "onclick"}},
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`, []string{"javascript"}},
+		{"obfuscated scheme", "<a href=\"java\tscript:alert(1)\">x</a>", []string{"script:"}},
+		{"iframe and form", `<iframe src="https://evil"></iframe><form action="https://evil"><input name="p"></form>`, []string{"iframe", "form", "input", "evil"}},
+		{"svg", `<svg><script>alert(1)</script></svg>`, []string{"svg", "alert"}},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=https://evil">`, []string{"meta", "evil"}},
+		{"conditional comment", `<!--[if IE]><script>alert(1)</script><![endif]-->`, []string{"alert", "<!--"}},
+		{"css expression", `<p style="width: expression(alert(1))">x</p>`, []string{"expression"}},
+		{"fixed position overlay", `<div style="position: fixed; top: 0">x</div>`, []string{"fixed"}},
+		{"style import", `<style>@import url(https://evil/x.css); p{color:red}</style>`, []string{"@import", "evil"}},
+		{"style breaks out of element", `<style>p{color:red}</style><style></style><script>alert(1)</script>`, []string{"alert"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HTML(tt.in, Options{AllowRemoteImages: true}).HTML
+			if !strings.HasPrefix(got, scopeOpen) || !strings.HasSuffix(got, "</div>") {
+				t.Errorf("output is not wrapped in the scope container: %s", got)
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(strings.ToLower(got), strings.ToLower(s)) {
+					t.Errorf("output contains %q: %s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLBlocksRemoteImages(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		remotes int
+	}{
+		{"img", `<img src="https://tracker.example/p.gif">`, 1},
+		{"protocol relative", `<img src="//tracker.example/p.gif">`, 1},
+		{"table background", `<table background="http://tracker.example/bg.png"><tr><td>x</td></tr></table>`, 1},
+		{"inline style", `<div style="background: url('https://tracker.example/a.png')">x</div>`, 1},
+		{"style block", `<style>div { background-image: url(https://tracker.example/b.png) }</style>`, 1},
+		{"style in media query", `<style>@media screen { td { background: url("https://tracker.example/c.png") } }</style>`, 1},
+		{"unclosed style block", `<style>div{background:url(https://tracker.example/p.gif)</style><p>x</p>`, 0},
+		{"unclosed media block", `<style>@media screen { div { color: red } p{background:url(https://tracker.example/p.gif)}</style>`, 0},
+		{"unclosed comment", `<style>p{color:red} /* a{background:url(https://tracker.example/p.gif)}</style>`, 0},
+		{"font face", `<style>@font-face { font-family: x; src: url(https://tracker.example/f.woff) }</style>`, 0},
+		{"image-set", `<div style="background-image: image-set('https://tracker.example/a.png' 1x)">x</div>`, 0},
+		{"escaped url", `<div style="background: u\72l(https://tracker.example/a.png)">x</div>`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := HTML(tt.in, Options{})
+			if strings.Contains(res.HTML, "tracker.example") {
+				t.Errorf("remote URL survived: %s", res.HTML)
+			}
+			if res.RemoteImages != tt.remotes {
+				t.Errorf("RemoteImages = %d, want %d", res.RemoteImages, tt.remotes)
+			}
+			if res.Blocked != (tt.remotes > 0) {
+				t.Errorf("Blocked = %v", res.Blocked)
+			}
+		})
+	}
+}
+
+func TestHTMLAllowsRemoteImages(t *testing.T) {
+	res := HTML(`<img src="//img.example/a.png"><p style="background:url(https://img.example/b.png)">x</p>`, Options{
+		AllowRemoteImages: true,
+		RewriteImage:      func(u string) string { return "/proxy?u=" + u },
+	})
+	for _, want := range []string{`src="/proxy?u=https://img.example/a.png"`, `url(&#34;/proxy?u=https://img.example/b.png&#34;)`} {
+		if !strings.Contains(res.HTML, want) {
+			t.Errorf("output does not contain %s: %s", want, res.HTML)
+		}
+	}
+	if res.Blocked || res.RemoteImages != 2 {
+		t.Errorf("Blocked = %v, RemoteImages = %d", res.Blocked, res.RemoteImages)
+	}
+}
+
+func TestHTMLKeepsSafeContent(t *testing.T) {
+	in := `<p class="x" style="color: red">Hello <a href="https://example.com">link</a> <img src="cid:logo" alt="logo"></p>`
+	got := HTML(in, Options{}).HTML
+	for _, want := range []string{`<p class="x" style="color: red">`, `href="https://example.com"`, `target="_blank"`, `rel="noopener noreferrer nofollow"`, `src="cid:logo"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %s: %s", want, got)
+		}
+	}
+}
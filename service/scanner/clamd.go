@@ -0,0 +1,124 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// INSTREAM 每次发送的数据块大小
+const clamdChunkSize = 64 * 1024
+
+// Clamd 通过 clamd 的 INSTREAM 命令扫描数据流
+type Clamd struct {
+	Network string
+	Address string
+}
+
+// NewClamd 创建 clamd 扫描器，network 为空时按地址判断使用 unix 或 tcp
+func NewClamd(network, address string) *Clamd {
+	if network == "" {
+		network = "tcp"
+		if strings.HasPrefix(address, "/") {
+			network = "unix"
+		}
+	}
+	return &Clamd{Network: network, Address: address}
+}
+
+// Ping 检查 clamd 是否可用
+func (c *Clamd) Ping(ctx context.Context) error {
+	reply, err := c.command(ctx, "zPING\x00", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected clamd reply: %s", reply)
+	}
+	return nil
+}
+
+// Scan 将数据按 [4 字节长度][数据] 分块发送，以长度为 0 的块结束
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	reply, err := c.command(ctx, "zINSTREAM\x00", r)
+	if err != nil {
+		return nil, err
+	}
+	return parseClamdReply(reply)
+}
+
+func (c *Clamd) command(ctx context.Context, cmd string, body io.Reader) (string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return "", fmt.Errorf("connect to clamd failed: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(defaultTimeout))
+	}
+	if _, err := io.WriteString(conn, cmd); err != nil {
+		return "", err
+	}
+	if body != nil {
+		if err := writeChunks(conn, body); err != nil {
+			// 超过 StreamMaxLength 时 clamd 会提前返回错误并关闭连接，优先读取其回复
+			if reply, rerr := readReply(conn); rerr == nil && reply != "" {
+				return reply, nil
+			}
+			return "", fmt.Errorf("send data to clamd failed: %w", err)
+		}
+	}
+	return readReply(conn)
+}
+
+func writeChunks(w io.Writer, r io.Reader) error {
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", fmt.Errorf("read clamd reply failed: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseClamdReply 解析 "stream: OK"、"stream: <名称> FOUND" 或 "<原因> ERROR"
+func parseClamdReply(reply string) (*Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("%w: %s", ErrScanFailed, strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, fmt.Errorf("%w: unexpected reply %q", ErrScanFailed, reply)
+	}
+}
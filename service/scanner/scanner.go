@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"email/global"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// 默认扫描超时时间
+const defaultTimeout = 60 * time.Second
+
+// ErrScanFailed 扫描器返回错误
+var ErrScanFailed = errors.New("scanner returned an error")
+
+// Result 扫描结果
+type Result struct {
+	Infected  bool
+	Signature string // 病毒特征名称
+}
+
+// Scanner 附件扫描器
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+var (
+	defaultScanner Scanner
+	once           sync.Once
+)
+
+// Default 返回根据配置创建的扫描器，未配置时返回 nil
+func Default() Scanner {
+	once.Do(func() {
+		cfg := global.Config.Scanner
+		switch cfg.Type {
+		case "clamd":
+			defaultScanner = NewClamd(cfg.Network, cfg.Address)
+		case "fake":
+			defaultScanner = Fake{}
+		case "":
+		default:
+			global.Log.Errorf("未知的扫描器类型 [ %s ]，附件将不被扫描", cfg.Type)
+		}
+	})
+	return defaultScanner
+}
+
+// Scan 使用默认扫描器扫描内容，未配置扫描器时返回 nil, nil
+func Scan(r io.Reader) (*Result, error) {
+	s := Default()
+	if s == nil {
+		return nil, nil
+	}
+	timeout := defaultTimeout
+	if t := global.Config.Scanner.TimeoutSeconds; t > 0 {
+		timeout = time.Duration(t) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Scan(ctx, r)
+}
+
+// EICAR 标准测试文件内容
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Fake 只识别 EICAR 测试文件的扫描器，用于本地开发与测试
+type Fake struct{}
+
+func (Fake) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if bytes.Contains(data, []byte(EICAR)) {
+		return &Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return &Result{}, nil
+}
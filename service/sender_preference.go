@@ -0,0 +1,62 @@
+package service
+
+import (
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/sanitize"
+	"email/utils"
+	"errors"
+	"strings"
+)
+
+// GetSenderPreferencesProcess 获取发件人偏好设置列表
+func GetSenderPreferencesProcess(userID uint) ([]models.SenderPreference, error) {
+	return dao.GetSenderPreferences(userID)
+}
+
+// SaveSenderPreferenceProcess 保存发件人偏好设置，sender 可以是邮件地址或 @域名
+func SaveSenderPreferenceProcess(userID uint, req models.SenderPreferenceRequest) (*models.SenderPreference, error) {
+	sender := strings.ToLower(strings.TrimSpace(req.Sender))
+	switch {
+	case strings.HasPrefix(sender, "@"):
+		if len(sender) < 4 || !strings.Contains(sender, ".") || strings.Count(sender, "@") > 1 {
+			return nil, errors.New("invalid sender domain")
+		}
+	default:
+		if err := utils.ValidateEmailAddress(sender); err != nil {
+			return nil, err
+		}
+	}
+	pref := &models.SenderPreference{EmailAccountID: userID, Sender: sender, AlwaysLoadImages: req.AlwaysLoadImages}
+	if err := dao.SaveSenderPreference(pref); err != nil {
+		return nil, err
+	}
+	return pref, nil
+}
+
+// DeleteSenderPreferenceProcess 删除发件人偏好设置
+func DeleteSenderPreferenceProcess(userID, id uint) error {
+	return dao.DeleteSenderPreference(userID, id)
+}
+
+// sanitizeEmailBody 清洗邮件正文，loadImages 为 false 时屏蔽远程图片，开启图片代理时远程图片经由代理加载
+func sanitizeEmailBody(email *models.EmailDetailsResponse, loadImages bool) {
+	if email.BodyHTML == "" {
+		return
+	}
+	opts := sanitize.Options{AllowRemoteImages: loadImages}
+	if global.Config.ImageProxy.Enabled {
+		opts.RewriteImage = SignImageProxyURL
+	}
+	res := sanitize.HTML(email.BodyHTML, opts)
+	email.BodyHTML = res.HTML
+	email.RemoteImagesBlocked = res.Blocked
+}
+
+// sanitizeEmailList 清洗列表中的邮件正文，列表中不加载远程图片
+func sanitizeEmailList(emails []models.EmailDetailsResponse) {
+	for i := range emails {
+		sanitizeEmailBody(&emails[i], false)
+	}
+}
@@ -2,11 +2,10 @@ package service
 
 import (
 	"bytes"
-	"crypto/tls"
 	"email/dao"
 	"email/global"
+	"email/models"
 	"email/service/aws"
-	"email/utils"
 	"errors"
 	"fmt"
 	"github.com/emersion/go-sasl"
@@ -15,18 +14,28 @@ import (
 	"io"
 	"log"
 	"net/mail"
-	"os"
 	"strings"
 	"time"
 )
 
-// Backend 结构体
-type Backend struct{}
+const (
+	smtpModeStartTLS = "starttls" // 明文连接，认证前需执行 STARTTLS
+	smtpModeImplicit = "implicit" // 连接即为 TLS
+)
+
+// Backend 结构体，每个监听端口一个
+type Backend struct {
+	allowInsecure bool // 是否允许未加密时发信
+}
 
 type Session struct {
 	authenticated bool
-	recipients    []string
-	from          string
+	user          string   // 认证的账户，连接期间不变
+	recipients    []string // 当前邮件的收件人，Reset 时清空
+	from          string   // 当前邮件的发件人(认证的账户)，Reset 时清空
+	tls           bool
+	allowInsecure bool
+	ip            string
 }
 
 type EmailError struct {
@@ -83,6 +92,17 @@ func (s *Session) validateAttachments(env *enmime.Envelope) error {
 			}
 		}
 	}
+	// 拒絕發送感染病毒的附件
+	for _, att := range append(env.Attachments, env.Inlines...) {
+		status, signature := aws.ScanAttachmentContent(att.FileName, att.Content)
+		if status == models.AttachmentScanInfected ||
+			(status == models.AttachmentScanFailed && global.Config.Scanner.FailClosed) {
+			return &EmailError{
+				Code:    "AttachmentInfected",
+				Message: fmt.Sprintf("附件 %s 未通過病毒掃描 %s", att.FileName, signature),
+			}
+		}
+	}
 	return nil
 }
 
@@ -97,6 +117,21 @@ func (s *Session) validateEmail(env *enmime.Envelope) error {
 	return nil
 }
 
+// 检查收件人是否位于禁止发送列表，发件人不属于本系统的域名时只检查全局列表
+func (s *Session) checkSuppression(recipients []string) error {
+	var domainID uint
+	if i := strings.LastIndex(s.from, "@"); i >= 0 {
+		if domain, err := dao.GetDomainDetailsByName(strings.ToLower(s.from[i+1:])); err == nil {
+			domainID = domain.ID
+		}
+	}
+	err := checkSuppressedRecipients(domainID, recipients)
+	if errors.Is(err, ErrRecipientSuppressed) {
+		return &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 7, 1}, Message: err.Error()}
+	}
+	return err
+}
+
 // 解析邮件地址列表
 func parseAddressList(addrList string) []string {
 	if addrList == "" {
@@ -143,9 +178,13 @@ func (s *Session) classifyRecipients(env *enmime.Envelope) (to, cc, bcc []string
 }
 
 // NewSession 实现 smtp.Backend 接口
-func (bkd *Backend) NewSession(_ *smtp.Conn) (smtp.Session, error) {
+func (bkd *Backend) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	_, isTLS := c.TLSConnectionState()
 	return &Session{
 		authenticated: false,
+		tls:           isTLS,
+		allowInsecure: bkd.allowInsecure,
+		ip:            remoteIP(c.Conn().RemoteAddr()),
 	}, nil
 }
 
@@ -157,9 +196,16 @@ func (bkd *Backend) AnonymousLogin(_ *smtp.Conn) (smtp.Session, error) {
 // Session 结构体，实现 smtp.Session 和 smtp.AuthSession 接口
 
 func (s *Session) Mail(from string, opts *smtp.MailOptions) error {
+	if !s.tls && !s.allowInsecure {
+		return &smtp.SMTPError{Code: 530, EnhancedCode: smtp.EnhancedCode{5, 7, 0}, Message: "Must issue a STARTTLS command first"}
+	}
 	if !s.authenticated {
 		return smtp.ErrAuthRequired
 	}
+	if err := checkMessageRate(s.user); err != nil {
+		return err
+	}
+	s.from, s.recipients = s.user, nil
 	log.Printf("新邮件来自: %s", from)
 	return nil
 }
@@ -168,6 +214,9 @@ func (s *Session) Rcpt(to string, opts *smtp.RcptOptions) error {
 	if !s.authenticated {
 		return smtp.ErrAuthRequired
 	}
+	if err := checkRecipientRate(s.user); err != nil {
+		return err
+	}
 	s.recipients = append(s.recipients, to)
 	global.Log.Infof("收到收件人: %s", to) // 可以看到所有收件人，包括密送
 	return nil
@@ -193,21 +242,38 @@ func (s *Session) Data(r io.Reader) error {
 	if err = s.validateEmail(env); err != nil {
 		return err
 	}
-	global.Log.Infof("邮件验证通过，发件人 =》 ", s.from)
+	global.Log.Infof("邮件验证通过，发件人 =》 %s", s.from)
+	if err = s.checkSuppression(envelopeRecipients(to, cc, bcc)); err != nil {
+		return err
+	}
+	smtpMetrics.Add("messages_accepted", 1)
 	// 6. 发送邮件
-	err = aws.SendEmailByAwsSesWithRawMessage(data, s.from, to, cc, bcc)
+	sesMessageID, err := aws.SendEmailByAwsSesWithRawMessage(data, s.from, to, cc, bcc)
 	if err != nil {
-		return fmt.Errorf("send email failed: %w", err)
+		if aws.IsPermanentSendError(err) {
+			return fmt.Errorf("send email failed: %w", err)
+		}
+		// 暂时无法发送时保存后加入发信队列，之后的延迟与失败以投递状态通知告知发件人
+		sent, saveErr := SaveThirtyPartySendEmailProcess(env, data, "", s.from, to, cc, bcc)
+		if saveErr != nil {
+			return fmt.Errorf("send email failed: %w", err)
+		}
+		if queueErr := aws.QueueOutboundEmail(sent, s.from, to, cc, bcc, err); queueErr != nil {
+			return fmt.Errorf("send email failed: %w", err)
+		}
+		return nil
 	}
 
 	// 7. 保存到数据库
-	SaveThirtyPartySendEmailProcess(env, s.from, to, cc, bcc)
+	SaveThirtyPartySendEmailProcess(env, data, sesMessageID, s.from, to, cc, bcc)
 
 	return nil
 }
 
+// Reset 在 RSET 与每封邮件的 DATA 结束后调用，只清空当前邮件的状态，认证在连接期间保持有效
 func (s *Session) Reset() {
-	s.authenticated = false
+	s.from = ""
+	s.recipients = nil
 }
 
 func (s *Session) Logout() error {
@@ -243,23 +309,20 @@ func (s *Session) Auth(mechanism string) (sasl.Server, error) {
 
 // authenticate 辅助函数，用于验证用户名和密码
 func (s *Session) authenticate(username, password string) error {
-	// 判断邮箱地址是否有效
-	if !utils.IsValidEmail(username) {
-		return errors.New("invalid email address")
+	if err := checkAuthThrottle(s.ip); err != nil {
+		return err
+	}
+	ad, err := AuthenticateMailAccount(username, password, s.ip, models.AuthFrontendSMTP)
+	if errors.Is(err, ErrAuthLocked) {
+		return &smtp.SMTPError{Code: 454, EnhancedCode: smtp.EnhancedCode{4, 7, 0}, Message: err.Error()}
 	}
-	ad, err := dao.IsAccountExist(username, strings.Split(username, "@")[1])
 	if err != nil {
+		recordAuthFailure(s.ip)
 		return err
 	}
-	// 验证密码
-	j := utils.CheckPasswordHash(password, ad.PasswordHash)
-	if j {
-		s.authenticated = true
-		s.from = ad.EmailAddress
-		return nil
-	}
-	return errors.New("invalid password")
-
+	s.authenticated = true
+	s.user = ad.EmailAddress
+	return nil
 }
 
 type LoginAuthenticator func(username, password string) error
@@ -304,47 +367,55 @@ func (a *loginServer) Next(response []byte) (challenge []byte, done bool, err er
 	return
 }
 
+// SmtpServerInit 按配置启动 SMTP 发信服务的各个监听端口，所有端口共用同一份证书
 func SmtpServerInit() {
-	// 加载共享的 TLS 证书
-	cert, err := tls.LoadX509KeyPair(
-		"/etc/letsencrypt/live/smtp.xxx.net/fullchain.pem",
-		"/etc/letsencrypt/live/smtp.xxx.net/privkey.pem")
+	c := global.Config.Smtp
+	certs, err := newCertReloader(c.CertFile, c.KeyFile)
 	if err != nil {
 		global.Log.Fatalf("加载 TLS 证书失败: %v", err)
 		return
 	}
+	tlsConfig, err := newSmtpTLSConfig(c, certs)
+	if err != nil {
+		global.Log.Fatalf("SMTP TLS 配置错误: %v", err)
+		return
+	}
+	go certs.watch()
+	smtpLimitInit(c)
+
+	for _, l := range c.Listeners {
+		var implicitTLS bool
+		switch l.Mode {
+		case "", smtpModeStartTLS:
+		case smtpModeImplicit:
+			implicitTLS = true
+		default:
+			global.Log.Errorf("SMTP 监听 [ %s ] 的 mode 必须为 starttls 或 implicit [ %s ]", l.Name, l.Mode)
+			continue
+		}
+		maxConnections := l.MaxConnections
+		if maxConnections <= 0 {
+			maxConnections = c.MaxConnections
+		}
 
-	// 共享的服务器配置
-	serverConfig := func(addr string) *smtp.Server {
-		be := &Backend{}
-		s := smtp.NewServer(be)
-		s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
-		s.Addr = addr
-		s.Domain = "smtp.xxx.net"
+		s := smtp.NewServer(&Backend{allowInsecure: l.AllowInsecureAuth && !implicitTLS})
+		s.TLSConfig = tlsConfig
+		s.Addr = l.Addr
+		s.Domain = c.Domain
 		s.ReadTimeout = 30 * time.Second
 		s.WriteTimeout = 30 * time.Second
 		s.MaxMessageBytes = 35 * 1024 * 1024
 		s.MaxRecipients = 50
-		s.AllowInsecureAuth = false
-		s.Debug = os.Stdout
-		return s
-	}
-
-	// 启动 17896 端口
-	go func() {
-		s := serverConfig("0.0.0.0:17896")
-		global.Log.Infof("SMTP 服务器启动在端口 17896")
-		if err := SmtpListenAndServe(s, global.Config.System.SmtpMaxConnections); err != nil {
-			global.Log.Errorf("端口 17896 服务器错误: %v", err)
-		}
-	}()
-
-	// 启动 587 端口
-	go func() {
-		s := serverConfig("0.0.0.0:587")
-		global.Log.Infof("SMTP 服务器启动在端口 587")
-		if err := SmtpListenAndServe(s, global.Config.System.SmtpMaxConnections); err != nil {
-			global.Log.Errorf("端口 587 服务器错误: %v", err)
+		s.AllowInsecureAuth = l.AllowInsecureAuth && !implicitTLS
+		if c.Debug {
+			s.Debug = &smtpDebugWriter{name: l.Name}
 		}
-	}()
+
+		go func() {
+			global.Log.Infof("SMTP 服务器 [ %s ] 启动在 %s (%s)", l.Name, s.Addr, l.Mode)
+			if err := SmtpListenAndServe(s, maxConnections, implicitTLS); err != nil {
+				global.Log.Errorf("SMTP 服务器 [ %s ] 错误: %v", l.Name, err)
+			}
+		}()
+	}
 }
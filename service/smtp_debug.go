@@ -0,0 +1,44 @@
+package service
+
+import (
+	"email/global"
+	"regexp"
+	"strings"
+)
+
+// 认证过程中客户端发送的内容只有一个 base64 字符串(用户名、密码或 PLAIN 凭证)，"=" 表示空的初始响应
+var saslResponsePattern = regexp.MustCompile(`^([A-Za-z0-9+/]+={0,2}|=)$`)
+
+// 不带参数的 SMTP 命令同样符合 base64 格式，不能隐藏
+var smtpBareCommands = map[string]bool{
+	"EHLO": true, "HELO": true, "LHLO": true, "DATA": true, "RSET": true,
+	"NOOP": true, "QUIT": true, "HELP": true, "STARTTLS": true,
+}
+
+// smtpDebugWriter 将 SMTP 会话内容逐行写入日志，隐藏认证信息
+// 同一端口的所有连接共用一个 writer，因此不依赖会话状态，只按每行的内容判断
+type smtpDebugWriter struct {
+	name string
+}
+
+func (w *smtpDebugWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\r\n"), "\n") {
+		global.Log.Infof("[SMTP %s] %s", w.name, redactSmtpLine(strings.TrimRight(line, "\r")))
+	}
+	return len(p), nil
+}
+
+// redactSmtpLine 隐藏 AUTH 命令中的初始响应与认证过程中客户端发送的内容
+func redactSmtpLine(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && strings.EqualFold(fields[0], "AUTH") {
+		if len(fields) > 2 {
+			return fields[0] + " " + fields[1] + " ***"
+		}
+		return line
+	}
+	if saslResponsePattern.MatchString(line) && !smtpBareCommands[strings.ToUpper(line)] {
+		return "***"
+	}
+	return line
+}
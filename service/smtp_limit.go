@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"email/config"
+	"email/global"
+	"expvar"
+	"net/http"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+// smtpMetrics SMTP 服务的计数器，通过 expvar 以 JSON 输出(/debug/vars 中的 smtp)
+var smtpMetrics = expvar.NewMap("smtp")
+
+var smtpIPConnections = &ipConnections{count: make(map[string]int)}
+
+// smtpLimits 按账户限制发信频率、按 IP 限制认证失败次数，未配置的限制为 nil
+var smtpLimits struct {
+	messages     *limiter.Limiter
+	recipients   *limiter.Limiter
+	authFailures *limiter.Limiter
+}
+
+var (
+	errMessageRateLimited = &smtp.SMTPError{Code: 451, EnhancedCode: smtp.EnhancedCode{4, 7, 1}, Message: "Message rate limit exceeded, try again later"}
+	errRcptRateLimited    = &smtp.SMTPError{Code: 452, EnhancedCode: smtp.EnhancedCode{4, 5, 3}, Message: "Recipient rate limit exceeded, try again later"}
+	errAuthThrottled      = &smtp.SMTPError{Code: 454, EnhancedCode: smtp.EnhancedCode{4, 7, 0}, Message: "Too many authentication failures, try again later"}
+)
+
+// smtpLimitInit 按配置初始化连接与频率限制
+func smtpLimitInit(c config.Smtp) {
+	smtpIPConnections.max = c.MaxConnectionsPerIP
+	store := memory.NewStore()
+	if c.MessagesPerHour > 0 {
+		smtpLimits.messages = limiter.New(store, limiter.Rate{Period: time.Hour, Limit: int64(c.MessagesPerHour)})
+	}
+	if c.RecipientsPerHour > 0 {
+		smtpLimits.recipients = limiter.New(store, limiter.Rate{Period: time.Hour, Limit: int64(c.RecipientsPerHour)})
+	}
+	if c.AuthFailuresPerIP > 0 {
+		window := time.Duration(c.AuthFailureWindowMinutes) * time.Minute
+		if window <= 0 {
+			window = 15 * time.Minute
+		}
+		smtpLimits.authFailures = limiter.New(store, limiter.Rate{Period: window, Limit: int64(c.AuthFailuresPerIP)})
+	}
+
+	if c.MetricsAddr != "" {
+		go func() {
+			global.Log.Infof("SMTP 指标服务启动在 %s", c.MetricsAddr)
+			if err := http.ListenAndServe(c.MetricsAddr, expvar.Handler()); err != nil {
+				global.Log.Errorf("SMTP 指标服务错误: %v", err)
+			}
+		}()
+	}
+}
+
+// remaining 返回 key 在当前时间窗口内剩余的次数，未配置限制时返回 -1
+func remaining(l *limiter.Limiter, key string) int64 {
+	if l == nil {
+		return -1
+	}
+	ctx, err := l.Peek(context.Background(), key)
+	if err != nil {
+		global.Log.Errorf("获取 SMTP 频率限制 [ %s ] 失败: %v", key, err)
+		return -1
+	}
+	return ctx.Remaining
+}
+
+// take 在当前时间窗口内为 key 计入 count 次，超过限制时返回 false，未配置限制时始终返回 true
+// 检查与计数在同一次操作中完成，并发的会话不会同时通过检查
+func take(l *limiter.Limiter, key string, count int64) bool {
+	if l == nil {
+		return true
+	}
+	ctx, err := l.Increment(context.Background(), key, count)
+	if err != nil {
+		global.Log.Errorf("更新 SMTP 频率限制 [ %s ] 失败: %v", key, err)
+		return true
+	}
+	return !ctx.Reached
+}
+
+func increment(l *limiter.Limiter, key string, count int) {
+	if l == nil || count <= 0 {
+		return
+	}
+	if _, err := l.Increment(context.Background(), key, int64(count)); err != nil {
+		global.Log.Errorf("更新 SMTP 频率限制 [ %s ] 失败: %v", key, err)
+	}
+}
+
+// checkMessageRate 在 MAIL 时为账户计入一封邮件，当前一小时内超过限制时拒绝
+func checkMessageRate(user string) error {
+	if !take(smtpLimits.messages, "msg:"+user, 1) {
+		smtpMetrics.Add("messages_rate_limited", 1)
+		return errMessageRateLimited
+	}
+	return nil
+}
+
+// checkRecipientRate 在 RCPT 时为账户计入一个收件人，当前一小时内超过限制时拒绝
+func checkRecipientRate(user string) error {
+	if !take(smtpLimits.recipients, "rcpt:"+user, 1) {
+		smtpMetrics.Add("recipients_rate_limited", 1)
+		return errRcptRateLimited
+	}
+	return nil
+}
+
+// checkAuthThrottle 该 IP 认证失败次数过多时暂时拒绝认证
+func checkAuthThrottle(ip string) error {
+	if remaining(smtpLimits.authFailures, "auth:"+ip) == 0 {
+		smtpMetrics.Add("auth_throttled", 1)
+		return errAuthThrottled
+	}
+	return nil
+}
+
+func recordAuthFailure(ip string) {
+	increment(smtpLimits.authFailures, "auth:"+ip, 1)
+	smtpMetrics.Add("auth_failures", 1)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"email/config"
+	"email/global"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/emersion/go-smtp"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	global.Log = logrus.New()
+	global.Log.SetOutput(io.Discard)
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// withSMTPLimits 按配置初始化频率限制，测试结束后取消限制
+func withSMTPLimits(t *testing.T, c config.Smtp) {
+	t.Helper()
+	smtpLimitInit(c)
+	t.Cleanup(func() {
+		smtpLimits.messages, smtpLimits.recipients, smtpLimits.authFailures = nil, nil, nil
+	})
+}
+
+func TestSessionResetKeepsAuthentication(t *testing.T) {
+	withSMTPLimits(t, config.Smtp{})
+	s := &Session{authenticated: true, tls: true, user: "user@example.com"}
+	for i := 0; i < 2; i++ {
+		if err := s.Mail("user@example.com", &smtp.MailOptions{}); err != nil {
+			t.Fatalf("message %d: Mail: %v", i, err)
+		}
+		for _, to := range []string{"a@example.com", "b@example.com"} {
+			if err := s.Rcpt(to, &smtp.RcptOptions{}); err != nil {
+				t.Fatalf("message %d: Rcpt: %v", i, err)
+			}
+		}
+		if s.from != "user@example.com" || len(s.recipients) != 2 {
+			t.Errorf("message %d: from = %q, recipients = %v", i, s.from, s.recipients)
+		}
+		s.Reset()
+		if s.from != "" || s.recipients != nil || !s.authenticated {
+			t.Errorf("after Reset: from = %q, recipients = %v, authenticated = %v", s.from, s.recipients, s.authenticated)
+		}
+	}
+}
+
+func TestSMTPRateLimits(t *testing.T) {
+	withSMTPLimits(t, config.Smtp{MessagesPerHour: 2, RecipientsPerHour: 3})
+	s := &Session{authenticated: true, tls: true, user: "limited@example.com"}
+
+	rcpt := func() error { return s.Rcpt("x@example.com", &smtp.RcptOptions{}) }
+	if err := s.Mail("", &smtp.MailOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rcpt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rcpt(); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset()
+	// 上一封邮件的收件人已计入，Reset 后不再重复计算
+	if err := s.Mail("", &smtp.MailOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rcpt(); err != nil {
+		t.Fatalf("third recipient: %v", err)
+	}
+	if err := rcpt(); !errors.Is(err, errRcptRateLimited) {
+		t.Errorf("fourth recipient: got %v, want %v", err, errRcptRateLimited)
+	}
+	s.Reset()
+	if err := s.Mail("", &smtp.MailOptions{}); !errors.Is(err, errMessageRateLimited) {
+		t.Errorf("third message: got %v, want %v", err, errMessageRateLimited)
+	}
+}
+
+func TestSMTPRateLimitConcurrent(t *testing.T) {
+	withSMTPLimits(t, config.Smtp{MessagesPerHour: 5})
+	var passed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if checkMessageRate("parallel@example.com") == nil {
+				passed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if passed.Load() != 5 {
+		t.Errorf("%d parallel sessions passed the limit of 5", passed.Load())
+	}
+}
@@ -1,21 +1,30 @@
 package service
 
 import (
+	"crypto/tls"
 	"email/global"
+	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/emersion/go-smtp"
 )
 
+// 拒绝连接时写入 421 的超时时间
+const rejectWriteTimeout = 10 * time.Second
+
 type limitListener struct {
 	net.Listener
+	server         *smtp.Server
+	implicitTLS    bool
 	maxConnections int
 	current        int
 	mu             sync.Mutex
 }
 
-func SmtpListenAndServe(s *smtp.Server, maxConnections int) error {
+// SmtpListenAndServe 监听并限制连接数，implicitTLS 为 true 时连接建立后直接进行 TLS 握手(465 端口)
+func SmtpListenAndServe(s *smtp.Server, maxConnections int, implicitTLS bool) error {
 	network := network(s)
 
 	addr := s.Addr
@@ -28,7 +37,7 @@ func SmtpListenAndServe(s *smtp.Server, maxConnections int) error {
 		return err
 	}
 
-	limitedListener := newLimitListener(listener, maxConnections)
+	limitedListener := newLimitListener(listener, s, maxConnections, implicitTLS)
 
 	return s.Serve(limitedListener)
 }
@@ -43,41 +52,76 @@ func network(s *smtp.Server) string {
 	return "tcp"
 }
 
-func newLimitListener(inner net.Listener, max int) *limitListener {
+func newLimitListener(inner net.Listener, s *smtp.Server, max int, implicitTLS bool) *limitListener {
 	return &limitListener{
 		Listener:       inner,
+		server:         s,
+		implicitTLS:    implicitTLS,
 		maxConnections: max,
 	}
 }
 
+// Accept 超过端口总连接数或单个 IP 的连接数时回复 421 后关闭连接，继续等待下一个连接
 func (l *limitListener) Accept() (net.Conn, error) {
-	conn, err := l.Listener.Accept()
-	if err != nil {
-		return nil, err
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := remoteIP(conn.RemoteAddr())
+		if reason := l.acquire(ip); reason != "" {
+			smtpMetrics.Add("connections_rejected_"+reason, 1)
+			global.Log.Infof("拒绝来自 %s 的 SMTP 连接: %s", ip, reason)
+			go l.reject(conn)
+			continue
+		}
+		smtpMetrics.Add("connections_total", 1)
+		smtpMetrics.Add("connections_active", 1)
+		var c net.Conn = &limitConn{Conn: conn, limitListener: l, ip: ip}
+		// TLS 需在最外层，go-smtp 以连接是否为 *tls.Conn 判断是否已加密
+		if l.implicitTLS {
+			c = tls.Server(c, l.server.TLSConfig)
+		}
+		return c, nil
 	}
+}
 
+// acquire 占用一个连接名额，失败时返回原因
+func (l *limitListener) acquire(ip string) string {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-
 	if l.maxConnections > 0 && l.current >= l.maxConnections {
-		global.Log.Infof("max connections limit reached")
-		conn.Close()
-		return conn, nil
+		return "max_connections"
+	}
+	if !smtpIPConnections.acquire(ip) {
+		return "per_ip"
 	}
-
 	l.current++
-	return &limitConn{Conn: conn, limitListener: l}, nil
+	return ""
 }
 
-func (l *limitListener) release() {
+func (l *limitListener) release(ip string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.current--
+	smtpIPConnections.release(ip)
+	smtpMetrics.Add("connections_active", -1)
+}
+
+// reject 以 421 代替问候语，客户端会稍后重试
+func (l *limitListener) reject(conn net.Conn) {
+	defer conn.Close()
+	if l.implicitTLS {
+		conn = tls.Server(conn, l.server.TLSConfig)
+	}
+	_ = conn.SetDeadline(time.Now().Add(rejectWriteTimeout))
+	_, _ = fmt.Fprintf(conn, "421 4.7.0 %s Too many connections, try again later\r\n", l.server.Domain)
 }
 
 type limitConn struct {
 	net.Conn
 	limitListener *limitListener
+	ip            string
 	mu            sync.Mutex
 	close         bool
 }
@@ -90,6 +134,44 @@ func (c *limitConn) Close() error {
 	}
 	err := c.Conn.Close()
 	c.close = true
-	c.limitListener.release()
+	c.limitListener.release(c.ip)
 	return err
 }
+
+// ipConnections 统计每个 IP 当前的连接数，所有监听端口共用
+type ipConnections struct {
+	mu    sync.Mutex
+	max   int
+	count map[string]int
+}
+
+func (t *ipConnections) acquire(ip string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.max > 0 && t.count[ip] >= t.max {
+		return false
+	}
+	t.count[ip]++
+	return true
+}
+
+func (t *ipConnections) release(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count[ip] <= 1 {
+		delete(t.count, ip)
+		return
+	}
+	t.count[ip]--
+}
+
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
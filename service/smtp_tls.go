@@ -0,0 +1,120 @@
+package service
+
+import (
+	"crypto/tls"
+	"email/config"
+	"email/global"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 证书目录变化后等待一段时间再加载，避免证书与私钥只更新了其中一个
+const certReloadDelay = 2 * time.Second
+
+// certReloader 保存当前使用的证书，证书文件变化时重新加载
+type certReloader struct {
+	certFile, keyFile string
+	mu                sync.RWMutex
+	cert              *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate 供 tls.Config 使用，每次握手时取当前证书
+func (r *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch 监控证书与私钥所在目录，certbot 续期时替换的是 live 目录下的符号链接，因此监控目录而不是文件
+func (r *certReloader) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		global.Log.Errorf("创建证书监控失败，证书不会自动重新加载: %v", err)
+		return
+	}
+	defer watcher.Close()
+	for _, dir := range []string{filepath.Dir(r.certFile), filepath.Dir(r.keyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			global.Log.Errorf("监控证书目录 %s 失败: %v", dir, err)
+		}
+	}
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename|fsnotify.Remove) != 0 {
+				timer = time.After(certReloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			global.Log.Errorf("证书监控错误: %v", err)
+		case <-timer:
+			timer = nil
+			if err := r.reload(); err != nil {
+				global.Log.Errorf("重新加载 TLS 证书失败，继续使用原证书: %v", err)
+				continue
+			}
+			global.Log.Infof("已重新加载 TLS 证书 %s", r.certFile)
+		}
+	}
+}
+
+// newSmtpTLSConfig 按配置生成 SMTP 服务使用的 TLS 配置
+func newSmtpTLSConfig(c config.Smtp, certs *certReloader) (*tls.Config, error) {
+	tlsConfig := &tls.Config{GetCertificate: certs.GetCertificate}
+
+	switch strings.TrimSpace(c.MinTLSVersion) {
+	case "", "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("min_tls_version must be one of 1.2, 1.3 [ %s ]", c.MinTLSVersion)
+	}
+
+	// 只允许 Go 认为安全的加密套件，TLS 1.3 的加密套件不可配置
+	if len(c.CipherSuites) > 0 {
+		supported := make(map[string]uint16)
+		for _, s := range tls.CipherSuites() {
+			supported[s.Name] = s.ID
+		}
+		for _, name := range c.CipherSuites {
+			id, ok := supported[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("unsupported or insecure cipher suite [ %s ]", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+	return tlsConfig, nil
+}
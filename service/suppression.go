@@ -0,0 +1,80 @@
+package service
+
+import (
+	"email/controller/response"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrRecipientSuppressed 收件人位于禁止发送列表中
+var ErrRecipientSuppressed = aws.ErrRecipientSuppressed
+
+// checkSuppressedRecipients 检查收件人是否位于全局或发件人所属域名的禁止发送列表中
+// 在调用任何发信方式之前检查，与使用 SES API 还是 SMTP 发送无关
+func checkSuppressedRecipients(domainID uint, recipients []string) error {
+	return aws.CheckSuppressedRecipients(domainID, recipients)
+}
+
+// isGlobalSuppressionAdmin 是否可以管理全局禁止发送列表
+func isGlobalSuppressionAdmin(emailAddress string) bool {
+	for _, admin := range global.Config.Suppression.GlobalAdmins {
+		if strings.EqualFold(strings.TrimSpace(admin), emailAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressionListDomainID 校验管理权限并返回列表对应的域名 ID，全局列表为 0
+func suppressionListDomainID(emailAddress, scope string) (uint, string, error) {
+	switch scope {
+	case "", models.SuppressionScopeDomain:
+		domainDetails, err := IsDomainAdmin(emailAddress)
+		if err != nil {
+			return 0, "", err
+		}
+		return domainDetails.ID, models.SuppressionScopeDomain, nil
+	case models.SuppressionScopeGlobal:
+		if !isGlobalSuppressionAdmin(emailAddress) {
+			return 0, "", errors.New("You do not have permission to manage the global suppression list.")
+		}
+		return 0, models.SuppressionScopeGlobal, nil
+	}
+	return 0, "", errors.New("scope must be one of domain, global")
+}
+
+// GetSuppressionsProcess 获取域名或全局的禁止发送列表
+func GetSuppressionsProcess(emailAddress, scope, address, page string) (*models.SuppressionList, error) {
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt <= 0 {
+		return nil, errors.New(response.IncorrectPageParameterCode.ErrMessage)
+	}
+	domainID, scope, err := suppressionListDomainID(emailAddress, scope)
+	if err != nil {
+		return nil, err
+	}
+	list, total, err := dao.GetSuppressions(domainID, strings.TrimSpace(address), pageInt, global.Config.API.EmailCountPerPage)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SuppressionList{Scope: scope, Suppressions: list, Total: total, Page: pageInt}, nil
+}
+
+// RemoveSuppressionsProcess 从域名或全局的禁止发送列表中移除记录，移除后可以再次向该地址发送
+func RemoveSuppressionsProcess(emailAddress string, req models.RemoveSuppressionsRequest) (*models.RemoveSuppressionsResult, error) {
+	domainID, scope, err := suppressionListDomainID(emailAddress, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := dao.DeleteSuppressions(domainID, req.IDs)
+	if err != nil {
+		return nil, err
+	}
+	global.Log.Infof("[ %s ] 从 %s 禁止发送列表中移除了 %d 条记录", emailAddress, scope, removed)
+	return &models.RemoveSuppressionsResult{Removed: removed}, nil
+}
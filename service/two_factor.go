@@ -0,0 +1,342 @@
+package service
+
+import (
+	"email/controller/response"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"email/utils"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 两步验证临时令牌的有效期
+const twoFactorTokenTTL = 5 * time.Minute
+
+var (
+	// ErrInvalidTwoFactorCode 验证码或恢复码错误，或验证码已被使用
+	ErrInvalidTwoFactorCode  = errors.New("invalid two-factor code")
+	errInvalidTwoFactorToken = errors.New("invalid or expired two-factor token, please sign in again")
+)
+
+// issueAccessToken 生成网页登录使用的 JWT
+func issueAccessToken(account *models.EmailAccount) (*models.AccessToken, error) {
+	expirationTime := time.Now().Add(time.Duration(global.Config.Jwt.ExpiredTime) * time.Hour * 24)
+	token, err := utils.GenerateJwtToken(account, expirationTime)
+	if err != nil {
+		return nil, errors.New(response.GenerateJwtTokenFailedCode.ErrMessage)
+	}
+	return &models.AccessToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expirationTime.Unix(),
+	}, nil
+}
+
+// domainRequiresTwoFactor 域名是否要求两步验证
+func domainRequiresTwoFactor(domainID uint) (bool, error) {
+	policy, err := dao.GetDomainPolicy(domainID)
+	if err != nil || policy == nil {
+		return false, err
+	}
+	return policy.RequireTwoFactor, nil
+}
+
+// twoFactorChallenge 密码验证通过后判断是否需要两步验证，需要时返回临时令牌，不需要时返回 nil
+func twoFactorChallenge(account *models.EmailAccount) (*models.TwoFactorChallenge, error) {
+	tf, err := dao.GetTwoFactor(account.ID)
+	if err != nil {
+		return nil, err
+	}
+	audience := ""
+	if tf != nil && tf.Enabled {
+		audience = models.TwoFactorTokenLogin
+	} else if required, err := domainRequiresTwoFactor(account.DomainID); err != nil {
+		return nil, err
+	} else if required {
+		audience = models.TwoFactorTokenSetup
+	}
+	if audience == "" {
+		return nil, nil
+	}
+	expirationTime := time.Now().Add(twoFactorTokenTTL)
+	token, err := utils.GenerateTwoFactorToken(account, audience, expirationTime)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		SetupRequired:     audience == models.TwoFactorTokenSetup,
+		TwoFactorToken:    token,
+		ExpiresIn:         expirationTime.Unix(),
+	}, nil
+}
+
+// accountByTwoFactorToken 解析临时令牌并获取账户
+func accountByTwoFactorToken(token, audience string) (*models.EmailAccount, error) {
+	claims, err := utils.ParseTwoFactorToken(token, audience)
+	if err != nil {
+		return nil, errInvalidTwoFactorToken
+	}
+	account, err := dao.GetAccountByID(claims.UserID)
+	if err != nil {
+		return nil, errInvalidTwoFactorToken
+	}
+	return account, nil
+}
+
+// verifyTwoFactorCode 校验 TOTP 验证码或恢复码，验证码与恢复码均只能使用一次
+func verifyTwoFactorCode(account *models.EmailAccount, code string) error {
+	tf, err := dao.GetTwoFactor(account.ID)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	secret, err := utils.DecryptSecret(tf.Secret)
+	if err != nil {
+		return err
+	}
+	if step, ok := utils.ValidateTOTP(secret, code, tf.LastUsedStep); ok {
+		// 多个实例同时收到同一验证码时只有一个成功
+		if used, err := dao.UseTwoFactorStep(account.ID, step); err != nil || !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	used, err := dao.UseRecoveryCode(account.ID, utils.HashRecoveryCode(code))
+	if err != nil || !used {
+		return ErrInvalidTwoFactorCode
+	}
+	global.Log.Warnf("账户 [ %s ] 使用恢复码完成了两步验证", account.EmailAddress)
+	return nil
+}
+
+// newRecoveryCodes 生成恢复码与对应的哈希
+func newRecoveryCodes() ([]string, []string) {
+	codes := utils.GenerateRecoveryCodes(models.TwoFactorRecoveryCodeCount)
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = utils.HashRecoveryCode(c)
+	}
+	return codes, hashes
+}
+
+// setupTwoFactor 生成新的 TOTP 密钥，确认验证码前不生效
+func setupTwoFactor(account *models.EmailAccount) (*models.TwoFactorSetup, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := dao.SaveTwoFactorSecret(account.ID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	return &models.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(account.DomainName, account.EmailAddress, secret),
+	}, nil
+}
+
+// enableTwoFactor 以验证器应用生成的验证码确认设置，返回恢复码
+func enableTwoFactor(account *models.EmailAccount, code string) ([]string, error) {
+	tf, err := dao.GetTwoFactor(account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, errors.New("two-factor authentication has not been set up")
+	}
+	if tf.Enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	secret, err := utils.DecryptSecret(tf.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, 0)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes, hashes := newRecoveryCodes()
+	if err := dao.EnableTwoFactor(account.ID, step, hashes); err != nil {
+		return nil, err
+	}
+	global.Log.Infof("账户 [ %s ] 启用了两步验证", account.EmailAddress)
+	return codes, nil
+}
+
+// TwoFactorLoginProcess 两步验证登录的第二步，验证码错误计入登录失败锁定
+func TwoFactorLoginProcess(req models.TwoFactorLoginRequest, ip string) (*models.AccessToken, error) {
+	account, err := accountByTwoFactorToken(req.TwoFactorToken, models.TwoFactorTokenLogin)
+	if err != nil {
+		return nil, err
+	}
+	account, err = authenticateWithLockout(account.EmailAddress, ip, models.AuthFrontendHTTP, func() (*models.EmailAccount, error) {
+		return account, verifyTwoFactorCode(account, req.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issueAccessToken(account)
+}
+
+// TwoFactorLoginSetupProcess 域名要求两步验证的账户在登录过程中设置
+func TwoFactorLoginSetupProcess(req models.TwoFactorLoginRequest) (*models.TwoFactorSetup, error) {
+	account, err := accountByTwoFactorToken(req.TwoFactorToken, models.TwoFactorTokenSetup)
+	if err != nil {
+		return nil, err
+	}
+	return setupTwoFactor(account)
+}
+
+// TwoFactorLoginEnableProcess 登录过程中确认设置，成功后完成登录并返回恢复码
+func TwoFactorLoginEnableProcess(req models.TwoFactorLoginRequest, ip string) (*models.TwoFactorSetupLogin, error) {
+	account, err := accountByTwoFactorToken(req.TwoFactorToken, models.TwoFactorTokenSetup)
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	account, err = authenticateWithLockout(account.EmailAddress, ip, models.AuthFrontendHTTP, func() (*models.EmailAccount, error) {
+		codes, err = enableTwoFactor(account, req.Code)
+		return account, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	token, err := issueAccessToken(account)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorSetupLogin{AccessToken: *token, RecoveryCodes: codes}, nil
+}
+
+// GetTwoFactorStatusProcess 获取账户的两步验证状态
+func GetTwoFactorStatusProcess(claims *models.CustomJwtClaims) (*models.TwoFactorStatus, error) {
+	account, err := dao.GetAccountByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.TwoFactorStatus{}
+	if status.RequiredByDomain, err = domainRequiresTwoFactor(account.DomainID); err != nil {
+		return nil, err
+	}
+	tf, err := dao.GetTwoFactor(account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.Enabled {
+		status.Enabled, status.EnabledAt = true, tf.EnabledAt
+		if status.RecoveryCodesRemaining, err = dao.CountUnusedRecoveryCodes(account.ID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// SetupTwoFactorProcess 已登录的账户开始设置两步验证
+func SetupTwoFactorProcess(claims *models.CustomJwtClaims) (*models.TwoFactorSetup, error) {
+	account, err := dao.GetAccountByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return setupTwoFactor(account)
+}
+
+// EnableTwoFactorProcess 已登录的账户确认设置，返回恢复码
+func EnableTwoFactorProcess(claims *models.CustomJwtClaims, req models.TwoFactorCodeRequest) (*models.TwoFactorRecoveryCodes, error) {
+	account, err := dao.GetAccountByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := enableTwoFactor(account, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactorProcess 关闭两步验证，需验证密码与验证码，域名要求两步验证时不能关闭
+func DisableTwoFactorProcess(claims *models.CustomJwtClaims, req models.DisableTwoFactorRequest, ip string) error {
+	account, err := dao.GetAccountByID(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if required, err := domainRequiresTwoFactor(account.DomainID); err != nil {
+		return err
+	} else if required {
+		return errors.New("Two-factor authentication is required by your domain administrator.")
+	}
+	_, err = authenticateWithLockout(account.EmailAddress, ip, models.AuthFrontendHTTP, func() (*models.EmailAccount, error) {
+		if _, err := dao.ValidateAccount(account.EmailAddress, req.Password); err != nil {
+			return nil, err
+		}
+		return account, verifyTwoFactorCode(account, req.Code)
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := dao.DeleteTwoFactor(account.ID); err != nil {
+		return err
+	}
+	global.Log.Infof("账户 [ %s ] 关闭了两步验证", account.EmailAddress)
+	return nil
+}
+
+// RegenerateRecoveryCodesProcess 验证后重新生成恢复码，原有恢复码作废
+func RegenerateRecoveryCodesProcess(claims *models.CustomJwtClaims, req models.TwoFactorCodeRequest, ip string) (*models.TwoFactorRecoveryCodes, error) {
+	account, err := dao.GetAccountByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = authenticateWithLockout(account.EmailAddress, ip, models.AuthFrontendHTTP, func() (*models.EmailAccount, error) {
+		return account, verifyTwoFactorCode(account, req.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes := newRecoveryCodes()
+	if err := dao.ReplaceRecoveryCodes(account.ID, hashes); err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// ResetTwoFactorProcess 域名管理员重置域名下账户的两步验证(如用户丢失设备与恢复码)，并通知该账户
+func ResetTwoFactorProcess(emailAddress string, req models.ResetTwoFactorRequest) error {
+	domainDetails, err := IsDomainAdmin(emailAddress)
+	if err != nil {
+		return err
+	}
+	target := strings.ToLower(strings.TrimSpace(req.EmailAddress))
+	account, err := dao.IsAccountExist(target, domainDetails.DomainName)
+	if err != nil || account.DomainID != domainDetails.ID {
+		return errors.New("The account does not belong to your domain.")
+	}
+	found, err := dao.DeleteTwoFactor(account.ID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("Two-factor authentication is not set up for this account.")
+	}
+	global.Log.Infof("[ %s ] 重置了账户 [ %s ] 的两步验证", emailAddress, account.EmailAddress)
+	text := fmt.Sprintf("Two-factor authentication for %s was reset by your domain administrator (%s).\n\n"+
+		"Your authenticator app and recovery codes no longer work. Set up two-factor authentication again after signing in.\n",
+		account.EmailAddress, emailAddress)
+	if err := aws.StoreNoticeEmail(account, "Two-factor authentication was reset", text); err != nil {
+		global.Log.Errorf("保存账户 [ %s ] 的两步验证重置提醒失败: %v", account.EmailAddress, err)
+	}
+	return nil
+}
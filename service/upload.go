@@ -0,0 +1,249 @@
+package service
+
+import (
+	"crypto/sha256"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/service/aws"
+	"email/service/shortlink"
+	"email/utils"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 上传会话有效期
+const uploadSessionTTL = 24 * time.Hour
+
+var (
+	// ErrUploadOffsetMismatch 分片偏移量与服务端已接收的字节数不一致
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match the received bytes")
+	// ErrUploadSessionClosed 上传会话已完成、已取消或已过期
+	ErrUploadSessionClosed = errors.New("upload session is no longer active")
+)
+
+// UploadAttachmentProcess 流式上传附件，边上传边计算哈希，内容重复时复用已有附件
+func UploadAttachmentProcess(userID uint, fileName, contentType string, r io.Reader) (*models.UploadedAttachment, error) {
+	objectKey := aws.GenerateS3ObjectKey(fileName)
+	hash, size, err := aws.StreamUploadToS3(objectKey, contentType, r, utils.GetMaxAttachmentSize())
+	if err != nil {
+		return nil, err
+	}
+	return saveUploadedAttachment(userID, objectKey, fileName, contentType, hash, size)
+}
+
+// CreateUploadSessionProcess 创建断点续传会话
+func CreateUploadSessionProcess(userID uint, req models.CreateUploadSessionRequest) (*models.UploadSession, error) {
+	if req.Size <= 0 {
+		return nil, errors.New("size must be greater than 0")
+	}
+	if req.Size > utils.GetMaxAttachmentSize() {
+		return nil, aws.ErrFileTooLarge
+	}
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	objectKey := aws.GenerateS3ObjectKey(req.FileName)
+	uploadID, err := aws.CreateMultipartUpload(objectKey, contentType)
+	if err != nil {
+		return nil, err
+	}
+	state, err := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	s := &models.UploadSession{
+		ID:             uuid.New().String(),
+		EmailAccountID: userID,
+		FileName:       req.FileName,
+		ContentType:    contentType,
+		Size:           req.Size,
+		ChunkSize:      aws.S3PartSize,
+		S3Key:          objectKey,
+		S3UploadID:     uploadID,
+		Parts:          []byte("[]"),
+		HashState:      state,
+		Status:         models.UploadStatusUploading,
+		ExpiresAt:      time.Now().Add(uploadSessionTTL),
+	}
+	if err := dao.AddUploadSession(s); err != nil {
+		aws.AbortMultipartUpload(objectKey, uploadID)
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetUploadSessionProcess 获取上传会话，客户端据此从 received_bytes 处续传
+func GetUploadSessionProcess(userID uint, id string) (*models.UploadSession, error) {
+	return dao.GetUploadSession(userID, id)
+}
+
+// UploadChunkProcess 接收一个分片，除最后一片外分片大小必须等于 chunk_size，全部接收后合并并保存为附件
+func UploadChunkProcess(userID uint, id string, offset int64, r io.Reader) (*models.UploadSession, *models.UploadedAttachment, error) {
+	s, err := dao.GetUploadSession(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.Status != models.UploadStatusUploading || time.Now().After(s.ExpiresAt) {
+		return nil, nil, ErrUploadSessionClosed
+	}
+	if offset != s.ReceivedBytes {
+		return s, nil, ErrUploadOffsetMismatch
+	}
+	expected := s.ChunkSize
+	if remain := s.Size - s.ReceivedBytes; remain < expected {
+		expected = remain
+	}
+	data, err := io.ReadAll(io.LimitReader(r, expected+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(data)) != expected {
+		return nil, nil, fmt.Errorf("chunk must be exactly %d bytes", expected)
+	}
+	h := sha256.New()
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(s.HashState); err != nil {
+		return nil, nil, err
+	}
+	h.Write(data)
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	partNumber := int32(offset/s.ChunkSize + 1)
+	etag, err := aws.UploadPart(s.S3Key, s.S3UploadID, partNumber, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var parts []models.UploadPart
+	_ = json.Unmarshal(s.Parts, &parts)
+	parts = append(parts, models.UploadPart{PartNumber: partNumber, ETag: etag})
+	partsJSON, _ := json.Marshal(parts)
+	ok, err := dao.AdvanceUploadSession(s.ID, offset, offset+expected, partsJSON, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrUploadOffsetMismatch
+	}
+	s.ReceivedBytes, s.Parts, s.HashState = offset+expected, partsJSON, state
+	if s.ReceivedBytes < s.Size {
+		return s, nil, nil
+	}
+	// 全部接收，合并分片
+	if err := aws.CompleteMultipartUpload(s.S3Key, s.S3UploadID, parts); err != nil {
+		return nil, nil, err
+	}
+	att, err := saveUploadedAttachment(userID, s.S3Key, s.FileName, s.ContentType, hex.EncodeToString(h.Sum(nil)), s.Size)
+	if err != nil {
+		// 未通过扫描的文件无法再续传，直接结束会话
+		if errors.Is(err, ErrAttachmentInfected) {
+			if ferr := dao.FinishUploadSession(s.ID, models.UploadStatusAborted, ""); ferr != nil {
+				global.Log.Warnf("更新上传会话 [ %s ] 状态失败: %v", s.ID, ferr)
+			}
+		}
+		return nil, nil, err
+	}
+	if err := dao.FinishUploadSession(s.ID, models.UploadStatusCompleted, att.Code); err != nil {
+		global.Log.Warnf("更新上传会话 [ %s ] 状态失败: %v", s.ID, err)
+	}
+	s.Status, s.AttachmentCode = models.UploadStatusCompleted, att.Code
+	return s, att, nil
+}
+
+// AbortUploadSessionProcess 取消上传会话
+func AbortUploadSessionProcess(userID uint, id string) error {
+	s, err := dao.GetUploadSession(userID, id)
+	if err != nil {
+		return err
+	}
+	if s.Status != models.UploadStatusUploading {
+		return ErrUploadSessionClosed
+	}
+	aws.AbortMultipartUpload(s.S3Key, s.S3UploadID)
+	return dao.FinishUploadSession(s.ID, models.UploadStatusAborted, "")
+}
+
+// StreamAttachmentProcess 校验访问权限后从 S3 读取附件，rangeHeader 为单个字节范围时返回部分内容
+func StreamAttachmentProcess(userID uint, code, rangeHeader string) (*models.Attachment, *s3.GetObjectOutput, error) {
+	att, err := getAccessibleAttachment(userID, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	// S3 不支持多段范围，此时返回完整内容
+	if !strings.HasPrefix(rangeHeader, "bytes=") || strings.Contains(rangeHeader, ",") {
+		rangeHeader = ""
+	}
+	out, err := aws.GetS3ObjectRange(att.S3StoragePath, rangeHeader)
+	if err != nil {
+		// 范围无效时仍返回附件信息，用于生成 Content-Range
+		return att, nil, err
+	}
+	return att, out, nil
+}
+
+// saveUploadedAttachment 保存上传完成的附件并授权给上传者，内容已存在时删除新上传的对象并复用已有附件
+func saveUploadedAttachment(userID uint, objectKey, fileName, contentType, hash string, size int64) (*models.UploadedAttachment, error) {
+	exist, err := dao.GetAttachmentByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	if exist == nil {
+		// 入库前扫描，感染的附件移入隔离区
+		scanStatus, signature := aws.ScanS3Object(objectKey)
+		var presignedURL string
+		if scanStatus == models.AttachmentScanInfected {
+			if objectKey, err = aws.QuarantineS3Object(objectKey); err != nil {
+				return nil, err
+			}
+		} else if presignedURL, err = aws.GeneratePresignedURL(objectKey); err != nil {
+			return nil, err
+		}
+		att := models.Attachment{
+			FileHash:       hash,
+			FileName:       fileName,
+			FileType:       contentType,
+			FileSize:       size,
+			S3FromEmailKey: "WEB_SEND",
+			ShortUrlCode:   shortlink.CreateShortLinkCode(objectKey),
+			DownloadURL:    presignedURL,
+			S3StoragePath:  objectKey,
+			ExpireTime:     utils.GetAttachmentExpireTime(),
+			ScanStatus:     scanStatus,
+			ScanSignature:  signature,
+		}
+		if err := dao.AddAttachmentToDBBatchPostgres([]models.Attachment{att}); err != nil {
+			return nil, err
+		}
+		// 并发上传相同内容时以先写入的记录为准
+		if exist, err = dao.GetAttachmentByHash(hash); err != nil {
+			return nil, err
+		}
+		if exist == nil {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+	if exist.S3StoragePath != objectKey {
+		if err := aws.DeleteS3Object(objectKey); err != nil {
+			global.Log.Warnf("删除重复附件 [ %s ] 失败: %v", objectKey, err)
+		}
+	}
+	if exist.IsBlocked() {
+		return nil, attachmentBlockedError(exist)
+	}
+	if err := dao.GrantAttachmentAccess(userID, models.AttachmentGrantUpload, hash); err != nil {
+		return nil, err
+	}
+	return &models.UploadedAttachment{FileName: fileName, Size: size, Type: contentType, Code: exist.ShortUrlCode}, nil
+}
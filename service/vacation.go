@@ -0,0 +1,48 @@
+package service
+
+import (
+	"email/dao"
+	"email/models"
+	"errors"
+)
+
+// GetVacationProcess 获取账户自动回复设置
+func GetVacationProcess(userID uint) (*models.VacationResponder, error) {
+	v, err := dao.GetVacationResponder(userID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		// 尚未设置时返回默认值
+		return &models.VacationResponder{EmailAccountID: userID, ResendIntervalDays: 7}, nil
+	}
+	return v, nil
+}
+
+// UpdateVacationProcess 更新账户自动回复设置
+func UpdateVacationProcess(userID uint, req models.UpdateVacationRequest) (*models.VacationResponder, error) {
+	if !req.EndAt.IsZero() && !req.EndAt.After(req.StartAt) {
+		return nil, errors.New("end_at must be later than start_at")
+	}
+	if req.Enabled && req.BodyText == "" && req.BodyHTML == "" {
+		return nil, errors.New("The body cannot be empty.")
+	}
+	// RFC 3834 建议同一发件人的回复间隔不少于 7 天
+	if req.ResendIntervalDays <= 0 {
+		req.ResendIntervalDays = 7
+	}
+	v := &models.VacationResponder{
+		EmailAccountID:     userID,
+		Enabled:            req.Enabled,
+		StartAt:            req.StartAt,
+		EndAt:              req.EndAt,
+		Subject:            req.Subject,
+		BodyText:           req.BodyText,
+		BodyHTML:           req.BodyHTML,
+		ResendIntervalDays: req.ResendIntervalDays,
+	}
+	if err := dao.SaveVacationResponder(v); err != nil {
+		return nil, err
+	}
+	return dao.GetVacationResponder(userID)
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"email/controller/response"
+	"email/dao"
+	"email/global"
+	"email/models"
+	"email/utils"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookNotFound Webhook 不存在或无权操作
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// 未配置时的默认值
+const (
+	defaultWebhookWorkers        = 4
+	defaultWebhookTimeoutSeconds = 10
+	defaultWebhookMaxAttempts    = 8
+)
+
+// 记录的响应内容最大长度
+const webhookResponseBodyLimit = 1024
+
+var errWebhookAddress = errors.New("webhook target resolves to a non-public address")
+
+// errWebhookDisabled 订阅已停用，不再重试
+var errWebhookDisabled = errors.New("webhook is disabled")
+
+// webhookClient 推送使用的客户端，默认只允许连接公网地址，不跟随重定向
+var webhookClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				if global.Config.Webhook.AllowPrivateTargets {
+					return nil
+				}
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return errWebhookAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        20,
+		IdleConnTimeout:     60 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// CreateWebhookProcess 创建 Webhook 订阅，返回的签名密钥只在创建时显示
+func CreateWebhookProcess(claims *models.CustomJwtClaims, req models.CreateWebhookRequest) (*models.WebhookDetails, error) {
+	if err := validateWebhook(req.URL, req.Events); err != nil {
+		return nil, err
+	}
+	w := &models.Webhook{
+		Scope:          models.WebhookScopeAccount,
+		EmailAccountID: claims.UserID,
+		URL:            req.URL,
+		Description:    req.Description,
+		Enabled:        true,
+		CreatedBy:      claims.EmailAddress,
+	}
+	switch req.Scope {
+	case "", models.WebhookScopeAccount:
+	case models.WebhookScopeDomain:
+		domainDetails, err := IsDomainAdmin(claims.EmailAddress)
+		if err != nil {
+			return nil, err
+		}
+		w.Scope, w.EmailAccountID, w.DomainID = models.WebhookScopeDomain, 0, domainDetails.ID
+	default:
+		return nil, errors.New("scope must be account or domain")
+	}
+	w.Events, _ = json.Marshal(req.Events)
+	secret, err := newWebhookSecret(w)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.AddWebhook(w); err != nil {
+		return nil, err
+	}
+	return &models.WebhookDetails{Webhook: *w, Secret: secret}, nil
+}
+
+// GetWebhooksProcess 获取账户的订阅，域名管理员同时返回域名订阅
+func GetWebhooksProcess(claims *models.CustomJwtClaims) ([]models.Webhook, error) {
+	var domainID uint
+	if domainDetails, err := IsDomainAdmin(claims.EmailAddress); err == nil {
+		domainID = domainDetails.ID
+	}
+	return dao.GetWebhooks(claims.UserID, domainID)
+}
+
+// UpdateWebhookProcess 更新 Webhook 订阅
+func UpdateWebhookProcess(claims *models.CustomJwtClaims, req models.UpdateWebhookRequest) (*models.WebhookDetails, error) {
+	w, err := getOwnedWebhook(claims, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != nil {
+		w.URL = *req.URL
+	}
+	events := req.Events
+	if events == nil {
+		_ = json.Unmarshal(w.Events, &events)
+	}
+	if err := validateWebhook(w.URL, events); err != nil {
+		return nil, err
+	}
+	w.Events, _ = json.Marshal(events)
+	if req.Description != nil {
+		w.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+	details := &models.WebhookDetails{}
+	if req.RotateSecret {
+		if details.Secret, err = newWebhookSecret(w); err != nil {
+			return nil, err
+		}
+	}
+	if err := dao.SaveWebhook(w); err != nil {
+		return nil, err
+	}
+	details.Webhook = *w
+	return details, nil
+}
+
+// DeleteWebhookProcess 删除 Webhook 订阅
+func DeleteWebhookProcess(claims *models.CustomJwtClaims, id uint) error {
+	w, err := getOwnedWebhook(claims, id)
+	if err != nil {
+		return err
+	}
+	return dao.DeleteWebhook(w.ID)
+}
+
+// GetWebhookDeliveriesProcess 分页获取 Webhook 的推送记录
+func GetWebhookDeliveriesProcess(claims *models.CustomJwtClaims, webhookID uint, page string) (*models.WebhookDeliveryList, error) {
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt <= 0 {
+		return nil, errors.New(response.IncorrectPageParameterCode.ErrMessage)
+	}
+	w, err := getOwnedWebhook(claims, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	list, total, err := dao.GetWebhookDeliveries(w.ID, pageInt, global.Config.API.EmailCountPerPage)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebhookDeliveryList{Deliveries: list, Total: total, Page: pageInt}, nil
+}
+
+// RedeliverWebhookProcess 重新推送一条记录，生成新的推送记录并沿用原事件 ID
+func RedeliverWebhookProcess(claims *models.CustomJwtClaims, deliveryID uint) (*models.WebhookDelivery, error) {
+	d, err := dao.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	if _, err := getOwnedWebhook(claims, d.WebhookID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	redelivery := &models.WebhookDelivery{
+		WebhookID:     d.WebhookID,
+		EventID:       d.EventID,
+		Event:         d.Event,
+		Payload:       d.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := dao.AddWebhookDelivery(redelivery); err != nil {
+		return nil, err
+	}
+	return redelivery, nil
+}
+
+// getOwnedWebhook 获取当前账户可管理的订阅：自己的账户订阅，或作为管理员的域名订阅
+func getOwnedWebhook(claims *models.CustomJwtClaims, id uint) (*models.Webhook, error) {
+	w, err := dao.GetWebhook(id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	switch w.Scope {
+	case models.WebhookScopeAccount:
+		if w.EmailAccountID == claims.UserID {
+			return w, nil
+		}
+	case models.WebhookScopeDomain:
+		if domainDetails, err := IsDomainAdmin(claims.EmailAddress); err == nil && domainDetails.ID == w.DomainID {
+			return w, nil
+		}
+	}
+	return nil, ErrWebhookNotFound
+}
+
+// validateWebhook 校验推送地址与订阅的事件
+func validateWebhook(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https url")
+	}
+	if len(events) == 0 {
+		return errors.New("at least one event is required")
+	}
+	for _, e := range events {
+		if !slices.Contains(models.WebhookEvents, e) {
+			return fmt.Errorf("unsupported event: %s", e)
+		}
+	}
+	return nil
+}
+
+// newWebhookSecret 生成签名密钥，加密后保存在订阅中，返回明文
+func newWebhookSecret(w *models.Webhook) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := "whsec_" + hex.EncodeToString(b)
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	w.Secret = encrypted
+	return secret, nil
+}
+
+// WebhookSignature 计算签名：HMAC-SHA256(密钥, "<时间戳>.<请求体>") 的十六进制
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// WebhookInit 定期领取到期的推送记录并发送，多实例部署时通过行锁避免重复推送
+func WebhookInit() {
+	workers := global.Config.Webhook.Workers
+	if workers <= 0 {
+		workers = defaultWebhookWorkers
+	}
+	lease := 2*webhookTimeout() + time.Minute
+	for {
+		list, err := dao.ClaimDueWebhookDeliveries(workers*5, lease)
+		if err != nil {
+			global.Log.Errorf("领取 Webhook 推送记录失败: %v", err)
+		}
+		if len(list) == 0 {
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for i := range list {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(d *models.WebhookDelivery) {
+				defer func() { <-sem; wg.Done() }()
+				deliverWebhook(d)
+			}(&list[i])
+		}
+		wg.Wait()
+	}
+}
+
+func webhookTimeout() time.Duration {
+	if s := global.Config.Webhook.TimeoutSeconds; s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return defaultWebhookTimeoutSeconds * time.Second
+}
+
+// deliverWebhook 发送一次推送并记录结果，失败时按指数退避安排下次推送
+func deliverWebhook(d *models.WebhookDelivery) {
+	maxAttempts := global.Config.Webhook.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+	d.Attempts++
+	d.ResponseCode, d.ResponseBody, d.Error = 0, "", ""
+	start := time.Now()
+	err := sendWebhook(d)
+	d.DurationMs = time.Since(start).Milliseconds()
+	now := time.Now()
+	switch {
+	case err == nil:
+		d.Status, d.DeliveredAt, d.NextAttemptAt = models.WebhookDeliverySucceeded, &now, nil
+	case errors.Is(err, ErrWebhookNotFound) || errors.Is(err, errWebhookDisabled) || d.Attempts >= maxAttempts:
+		d.Status, d.Error, d.NextAttemptAt = models.WebhookDeliveryFailed, err.Error(), nil
+	default:
+		// 30 秒起按 2 的幂增长，最长 6 小时
+		backoff := 30 * time.Second << (d.Attempts - 1)
+		if backoff > 6*time.Hour || backoff <= 0 {
+			backoff = 6 * time.Hour
+		}
+		next := now.Add(backoff)
+		d.Error, d.NextAttemptAt = err.Error(), &next
+	}
+	if err != nil {
+		global.Log.Warnf("Webhook 推送 [ID: %d] 第 %d 次失败: %v", d.ID, d.Attempts, err)
+	}
+	if err := dao.SaveWebhookDeliveryResult(d); err != nil {
+		global.Log.Errorf("保存 Webhook 推送 [ID: %d] 结果失败: %v", d.ID, err)
+	}
+}
+
+// sendWebhook 发送带签名的 POST 请求，2xx 视为成功
+func sendWebhook(d *models.WebhookDelivery) error {
+	w, err := dao.GetWebhook(d.WebhookID)
+	if err != nil {
+		return ErrWebhookNotFound
+	}
+	if !w.Enabled {
+		return errWebhookDisabled
+	}
+	secret, err := utils.DecryptSecret(w.Secret)
+	if err != nil {
+		return fmt.Errorf("decrypt webhook secret: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EmailWebhook/1.0")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-ID", d.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set("X-Webhook-Signature", fmt.Sprintf("t=%d,v1=%s", timestamp, WebhookSignature(secret, timestamp, d.Payload)))
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+	d.ResponseCode, d.ResponseBody = resp.StatusCode, strings.ReplaceAll(string(bytes.ToValidUTF8(body, nil)), "\x00", "")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"strings"
+)
+
+// 自动回复不应回应的发件人本地部分 (RFC 3834 第 2 节)
+var autoReplyIgnoreLocalParts = []string{"mailer-daemon", "postmaster", "noreply", "no-reply", "listserv", "majordomo"}
+
+// 表示邮件来自邮件列表的头部
+var mailingListHeaders = []string{"List-Id", "List-Unsubscribe", "List-Post", "List-Help", "List-Subscribe", "List-Owner", "List-Archive"}
+
+// IsAutoReplySuppressed 按 RFC 3834 判断是否不应对该邮件自动回复
+// getHeader 为邮件头读取函数，返回不应回复的原因，空字符串表示可以回复
+func IsAutoReplySuppressed(getHeader func(string) string) string {
+	// 自动生成的邮件 (Auto-Submitted 不为 no)
+	if v := strings.ToLower(strings.TrimSpace(getHeader("Auto-Submitted"))); v != "" && v != "no" {
+		return "auto-submitted"
+	}
+	// 批量 / 列表 / 垃圾优先级
+	switch strings.ToLower(strings.TrimSpace(getHeader("Precedence"))) {
+	case "bulk", "list", "junk":
+		return "precedence"
+	}
+	// 邮件列表
+	for _, h := range mailingListHeaders {
+		if getHeader(h) != "" {
+			return "mailing-list"
+		}
+	}
+	// Exchange 风格的自动回复抑制
+	if v := strings.ToLower(getHeader("X-Auto-Response-Suppress")); strings.Contains(v, "all") || strings.Contains(v, "oof") {
+		return "auto-response-suppress"
+	}
+	// 空的退信地址
+	if strings.TrimSpace(getHeader("Return-Path")) == "<>" {
+		return "null-return-path"
+	}
+	return ""
+}
+
+// IsAutoReplyIgnoredSender 判断发件人地址是否属于系统/列表类地址
+func IsAutoReplyIgnoredSender(address string) bool {
+	address = strings.ToLower(strings.TrimSpace(address))
+	parts := strings.Split(address, "@")
+	if len(parts) != 2 || parts[0] == "" {
+		return true
+	}
+	local := parts[0]
+	for _, p := range autoReplyIgnoreLocalParts {
+		if local == p {
+			return true
+		}
+	}
+	return strings.HasPrefix(local, "owner-") || strings.HasSuffix(local, "-request") || strings.HasSuffix(local, "-bounces")
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"net/textproto"
+	"testing"
+)
+
+func TestIsAutoReplySuppressed(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"plain message", map[string]string{"From": "alice@example.com"}, ""},
+		{"auto-submitted no", map[string]string{"Auto-Submitted": " No "}, ""},
+		{"auto-generated", map[string]string{"Auto-Submitted": "auto-generated"}, "auto-submitted"},
+		{"auto-replied", map[string]string{"Auto-Submitted": "Auto-Replied"}, "auto-submitted"},
+		{"precedence bulk", map[string]string{"Precedence": "bulk"}, "precedence"},
+		{"precedence list", map[string]string{"Precedence": " List"}, "precedence"},
+		{"precedence junk", map[string]string{"Precedence": "JUNK"}, "precedence"},
+		{"precedence first-class", map[string]string{"Precedence": "first-class"}, ""},
+		{"list-id", map[string]string{"List-Id": "<dev.lists.example.com>"}, "mailing-list"},
+		{"list-unsubscribe", map[string]string{"List-Unsubscribe": "<mailto:leave@example.com>"}, "mailing-list"},
+		{"list-post", map[string]string{"List-Post": "<mailto:dev@example.com>"}, "mailing-list"},
+		{"exchange suppress all", map[string]string{"X-Auto-Response-Suppress": "All"}, "auto-response-suppress"},
+		{"exchange suppress oof", map[string]string{"X-Auto-Response-Suppress": "DR, OOF"}, "auto-response-suppress"},
+		{"exchange suppress other", map[string]string{"X-Auto-Response-Suppress": "DR, RN"}, ""},
+		{"null return path", map[string]string{"Return-Path": "<>"}, "null-return-path"},
+		{"return path", map[string]string{"Return-Path": "<alice@example.com>"}, ""},
+		{"auto-submitted checked first", map[string]string{"Auto-Submitted": "auto-generated", "List-Id": "<x>"}, "auto-submitted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := textproto.MIMEHeader{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			if got := IsAutoReplySuppressed(h.Get); got != tt.want {
+				t.Errorf("IsAutoReplySuppressed(%v) = %q, want %q", tt.headers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAutoReplyIgnoredSender(t *testing.T) {
+	tests := map[string]bool{
+		"alice@example.com":          false,
+		" Bob@Example.com ":          false,
+		"MAILER-DAEMON@example.com":  true,
+		"postmaster@example.com":     true,
+		"noreply@example.com":        true,
+		"no-reply@example.com":       true,
+		"owner-dev@lists.example":    true,
+		"dev-request@lists.example":  true,
+		"dev-bounces@lists.example":  true,
+		"requester@example.com":      false,
+		"@example.com":               true,
+		"not-an-address":             true,
+		"a@b@example.com":            true,
+		"listserv@lists.example.com": true,
+	}
+	for address, want := range tests {
+		if got := IsAutoReplyIgnoredSender(address); got != want {
+			t.Errorf("IsAutoReplyIgnoredSender(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"email/models"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GenerateDeliveryReport 生成 RFC 3464 投递状态通知(multipart/report; report-type=delivery-status)，返回原始邮件与 Message-ID
+// 通知以 In-Reply-To 与 References 关联原邮件
+func GenerateDeliveryReport(r *models.DeliveryReport) (string, string) {
+	messageID := fmt.Sprintf("<%s@%s>", uuid.New().String(), r.ReportingMTA)
+	boundary := fmt.Sprintf("------------%s", uuid.New().String()[:16])
+
+	var subject, text strings.Builder
+	if r.Action == models.DSNActionDelayed {
+		subject.WriteString("Delayed Mail (still being retried): ")
+		text.WriteString("Your message has not yet been delivered to the following recipients.\n")
+		text.WriteString(fmt.Sprintf("Delivery will be retried until %s. No action is required on your part.\n\n", r.WillRetryUntil.Format(time.RFC1123Z)))
+	} else {
+		subject.WriteString("Undelivered Mail Returned to Sender: ")
+		text.WriteString("Your message could not be delivered to the following recipients:\n\n")
+	}
+	subject.WriteString(r.OriginalSubject)
+	for _, rcpt := range r.Recipients {
+		text.WriteString(rcpt.Address + "\n")
+		if rcpt.DiagnosticCode != "" {
+			text.WriteString("    " + dsnDiagnosticText(singleLine(rcpt.DiagnosticCode)) + "\n")
+		}
+	}
+
+	// 机器可读部分：先是报告本身的字段，之后每个收件人一段
+	var status strings.Builder
+	status.WriteString(fmt.Sprintf("Reporting-MTA: dns; %s\n", r.ReportingMTA))
+	if !r.ArrivalDate.IsZero() {
+		status.WriteString(fmt.Sprintf("Arrival-Date: %s\n", r.ArrivalDate.Format(time.RFC1123Z)))
+	}
+	for _, rcpt := range r.Recipients {
+		status.WriteString("\n")
+		status.WriteString(fmt.Sprintf("Final-Recipient: rfc822; %s\n", rcpt.Address))
+		status.WriteString(fmt.Sprintf("Action: %s\n", r.Action))
+		status.WriteString(fmt.Sprintf("Status: %s\n", rcpt.Status))
+		if rcpt.DiagnosticCode != "" {
+			status.WriteString(fmt.Sprintf("Diagnostic-Code: %s\n", singleLine(rcpt.DiagnosticCode)))
+		}
+		if !rcpt.LastAttempt.IsZero() {
+			status.WriteString(fmt.Sprintf("Last-Attempt-Date: %s\n", rcpt.LastAttempt.Format(time.RFC1123Z)))
+		}
+		if r.Action == models.DSNActionDelayed && !r.WillRetryUntil.IsZero() {
+			status.WriteString(fmt.Sprintf("Will-Retry-Until: %s\n", r.WillRetryUntil.Format(time.RFC1123Z)))
+		}
+	}
+
+	var threadHeaders string
+	if r.OriginalMessageID != "" {
+		threadHeaders = fmt.Sprintf("In-Reply-To: %s\nReferences: %s\n", r.OriginalMessageID, r.OriginalMessageID)
+	}
+
+	emailContent := fmt.Sprintf(`From: Mail Delivery System <MAILER-DAEMON@%s>
+To: %s
+Subject: %s
+Date: %s
+Message-ID: %s
+%sAuto-Submitted: auto-replied
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=delivery-status;
+ boundary="%s"
+
+--%s
+Content-Type: text/plain; charset=UTF-8
+Content-Transfer-Encoding: base64
+
+%s
+
+--%s
+Content-Type: message/delivery-status
+
+%s
+--%s
+Content-Type: text/rfc822-headers
+
+%s
+--%s--
+
+`,
+		r.ReportingMTA,
+		r.To,
+		mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())),
+		time.Now().Format(time.RFC1123Z),
+		messageID,
+		threadHeaders,
+		boundary,
+		boundary,
+		encodeBody(text.String()),
+		boundary,
+		status.String(),
+		boundary,
+		strings.TrimRight(strings.ReplaceAll(r.OriginalHeaders, "\r\n", "\n"), "\n"),
+		boundary,
+	)
+	return emailContent, messageID
+}
+
+// dsnDiagnosticText 去掉 "smtp; 550 ..." 中的类型前缀，用于正文
+func dsnDiagnosticText(v string) string {
+	if i := strings.IndexByte(v, ';'); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// singleLine 将多行内容合并为一行，避免破坏报告字段
+func singleLine(v string) string {
+	return strings.Join(strings.Fields(v), " ")
+}
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"bufio"
+	"email/models"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+type reportPart struct {
+	contentType string
+	body        string
+}
+
+// parseReport 解析投递状态通知，返回邮件头与各部分
+func parseReport(t *testing.T, raw string) (mail.Header, []reportPart) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/report" || params["report-type"] != "delivery-status" {
+		t.Fatalf("Content-Type = %s %v", mediaType, params)
+	}
+	var parts []reportPart
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := string(b)
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = string(decoded)
+		}
+		parts = append(parts, reportPart{contentType: p.Header.Get("Content-Type"), body: body})
+	}
+	return msg.Header, parts
+}
+
+// statusGroups 将 message/delivery-status 按空行拆分为报告字段与各收件人的字段
+func statusGroups(t *testing.T, body string) []textproto.MIMEHeader {
+	t.Helper()
+	var groups []textproto.MIMEHeader
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		h, err := textproto.NewReader(bufio.NewReader(strings.NewReader(block + "\n\n"))).ReadMIMEHeader()
+		if err != nil {
+			t.Fatalf("invalid status block %q: %v", block, err)
+		}
+		groups = append(groups, h)
+	}
+	return groups
+}
+
+func TestGenerateDeliveryReport(t *testing.T) {
+	arrival := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	last := arrival.Add(2 * time.Hour)
+	retryUntil := arrival.Add(72 * time.Hour)
+	recipients := []models.DeliveryReportRecipient{
+		{Address: "bob@example.com", Status: "5.1.1", DiagnosticCode: "smtp; 550 5.1.1\n user unknown", LastAttempt: last},
+		{Address: "carol@example.org", Status: "5.0.0"},
+	}
+	tests := []struct {
+		name        string
+		action      string
+		status      string
+		subject     string
+		textContain string
+	}{
+		{"failed", models.DSNActionFailed, "", "Undelivered Mail Returned to Sender: 季度报告", "could not be delivered"},
+		{"delayed", models.DSNActionDelayed, "4.0.0", "Delayed Mail (still being retried): 季度报告", "will be retried until " + retryUntil.Format(time.RFC1123Z)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcpts := append([]models.DeliveryReportRecipient(nil), recipients...)
+			if tt.status != "" {
+				for i := range rcpts {
+					rcpts[i].Status = tt.status
+				}
+			}
+			raw, messageID := GenerateDeliveryReport(&models.DeliveryReport{
+				ReportingMTA:      "mail.example.net",
+				To:                "alice@example.net",
+				Action:            tt.action,
+				ArrivalDate:       arrival,
+				WillRetryUntil:    retryUntil,
+				OriginalMessageID: "<orig@example.net>",
+				OriginalSubject:   "季度报告",
+				OriginalHeaders:   "From: alice@example.net\r\nTo: bob@example.com\r\nSubject: report\r\n",
+				Recipients:        rcpts,
+			})
+			header, parts := parseReport(t, raw)
+
+			if header.Get("Message-ID") != messageID || !strings.HasSuffix(messageID, "@mail.example.net>") {
+				t.Errorf("Message-ID = %q, returned %q", header.Get("Message-ID"), messageID)
+			}
+			if header.Get("To") != "alice@example.net" || header.Get("Auto-Submitted") != "auto-replied" {
+				t.Errorf("To = %q, Auto-Submitted = %q", header.Get("To"), header.Get("Auto-Submitted"))
+			}
+			if from, err := header.AddressList("From"); err != nil || from[0].Address != "MAILER-DAEMON@mail.example.net" {
+				t.Errorf("From = %q, %v", header.Get("From"), err)
+			}
+			if header.Get("In-Reply-To") != "<orig@example.net>" || header.Get("References") != "<orig@example.net>" {
+				t.Errorf("In-Reply-To = %q, References = %q", header.Get("In-Reply-To"), header.Get("References"))
+			}
+			if subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject")); err != nil || subject != tt.subject {
+				t.Errorf("Subject = %q, %v", subject, err)
+			}
+
+			if len(parts) != 3 {
+				t.Fatalf("got %d parts, want 3", len(parts))
+			}
+			if !strings.HasPrefix(parts[0].contentType, "text/plain") || parts[1].contentType != "message/delivery-status" || parts[2].contentType != "text/rfc822-headers" {
+				t.Errorf("part types = %q, %q, %q", parts[0].contentType, parts[1].contentType, parts[2].contentType)
+			}
+			text := parts[0].body
+			for _, want := range []string{tt.textContain, "bob@example.com", "    550 5.1.1 user unknown", "carol@example.org"} {
+				if !strings.Contains(text, want) {
+					t.Errorf("text part does not contain %q:\n%s", want, text)
+				}
+			}
+			if parts[2].body != "From: alice@example.net\nTo: bob@example.com\nSubject: report" {
+				t.Errorf("original headers = %q", parts[2].body)
+			}
+
+			groups := statusGroups(t, parts[1].body)
+			if len(groups) != 1+len(rcpts) {
+				t.Fatalf("got %d status groups, want %d:\n%s", len(groups), 1+len(rcpts), parts[1].body)
+			}
+			if groups[0].Get("Reporting-MTA") != "dns; mail.example.net" || groups[0].Get("Arrival-Date") != arrival.Format(time.RFC1123Z) {
+				t.Errorf("per-message fields = %v", groups[0])
+			}
+			for i, rcpt := range rcpts {
+				g := groups[i+1]
+				if g.Get("Final-Recipient") != "rfc822; "+rcpt.Address || g.Get("Action") != tt.action || g.Get("Status") != rcpt.Status {
+					t.Errorf("recipient %d fields = %v", i, g)
+				}
+				if rcpt.DiagnosticCode != "" && g.Get("Diagnostic-Code") != "smtp; 550 5.1.1 user unknown" {
+					t.Errorf("recipient %d Diagnostic-Code = %q", i, g.Get("Diagnostic-Code"))
+				}
+				if rcpt.DiagnosticCode == "" && g.Get("Diagnostic-Code") != "" {
+					t.Errorf("recipient %d unexpected Diagnostic-Code %q", i, g.Get("Diagnostic-Code"))
+				}
+				if wantLast := rcpt.LastAttempt; !wantLast.IsZero() && g.Get("Last-Attempt-Date") != wantLast.Format(time.RFC1123Z) {
+					t.Errorf("recipient %d Last-Attempt-Date = %q", i, g.Get("Last-Attempt-Date"))
+				}
+				retry := g.Get("Will-Retry-Until")
+				if tt.action == models.DSNActionDelayed && retry != retryUntil.Format(time.RFC1123Z) {
+					t.Errorf("recipient %d Will-Retry-Until = %q", i, retry)
+				}
+				if tt.action == models.DSNActionFailed && retry != "" {
+					t.Errorf("recipient %d failed report has Will-Retry-Until %q", i, retry)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateDeliveryReportWithoutOriginal(t *testing.T) {
+	raw, _ := GenerateDeliveryReport(&models.DeliveryReport{
+		ReportingMTA: "mail.example.net",
+		To:           "alice@example.net",
+		Action:       models.DSNActionFailed,
+		Recipients:   []models.DeliveryReportRecipient{{Address: "bob@example.com", Status: "5.0.0"}},
+	})
+	header, parts := parseReport(t, raw)
+	if header.Get("In-Reply-To") != "" || header.Get("References") != "" {
+		t.Errorf("unexpected thread headers: %q, %q", header.Get("In-Reply-To"), header.Get("References"))
+	}
+	groups := statusGroups(t, parts[1].body)
+	if len(groups) != 2 || groups[0].Get("Arrival-Date") != "" {
+		t.Errorf("status groups = %v", groups)
+	}
+}
@@ -286,13 +286,19 @@ func GenerateEmailRawMessage(content *models.EmailContent) (string, string) {
 	messageID := fmt.Sprintf("<%s@%s>", uuid.New().String(), strings.ReplaceAll(domain, ">", ""))
 	boundary := fmt.Sprintf("------------%s", uuid.New().String()[:16]) // 更标准的 boundary 格式
 
+	// 附加邮件头
+	var extraHeaders strings.Builder
+	for _, h := range content.Headers {
+		extraHeaders.WriteString(fmt.Sprintf("%s: %s\n", h.Name, h.Value))
+	}
+
 	// 3. 构建邮件内容
 	emailContent := fmt.Sprintf(`From: %s
 To: %s
 Subject: %s
 Date: %s
 Message-ID: %s
-MIME-Version: 1.0
+%sMIME-Version: 1.0
 Content-Type: multipart/alternative;
  boundary="%s"
 Content-Transfer-Encoding: 7bit
@@ -317,6 +323,7 @@ Content-Transfer-Encoding: base64
 		mime.QEncoding.Encode("utf-8", strings.TrimSpace(content.Subject)), // 去除可能的空格
 		time.Now().Format(time.RFC1123Z),
 		messageID,
+		extraHeaders.String(),
 		boundary,
 		boundary,
 		encodeBody(content.TextBody),
@@ -376,13 +383,33 @@ func decodeText(headerValue string) string {
 	return decoded
 }
 
+// MailHostname 生成 Message-ID 等使用的主机名，优先使用配置的 Smtp.domain，未配置时使用系统主机名
+func MailHostname() string {
+	if global.Config != nil && global.Config.Smtp.Domain != "" {
+		return global.Config.Smtp.Domain
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "localhost"
+}
+
 // 获取邮件附件最大大小
 func GetMaxAttachmentSize() int64 {
 	if global.Config != nil && global.Config.AWS.MaxFileSize != 0 {
 		return 1024 * 1024 * global.Config.AWS.MaxFileSize
 	}
 	// 返回默认值
-	return 30 * 1024 * 20 // 默认 20
+	return 20 * 1024 * 1024 // 默认 20MB
+}
+
+// GetAttachmentExpireTime 附件短码的过期时间，未被邮件引用的附件过期后可被回收
+func GetAttachmentExpireTime() time.Time {
+	hours := int64(7 * 24)
+	if global.Config != nil && global.Config.AWS.FileExpireTime != 0 {
+		hours = global.Config.AWS.FileExpireTime
+	}
+	return time.Now().Add(time.Duration(hours) * time.Hour)
 }
 
 // 判断邮件类型是否在指定范围内
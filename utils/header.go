@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"bytes"
+	"email/models"
+	"strings"
+)
+
+// SplitRawMessage 将原始邮件拆分为头部与正文，头部不含结尾空行
+func SplitRawMessage(raw []byte) (header, body []byte, newline string) {
+	newline = "\n"
+	crlf := bytes.Index(raw, []byte("\r\n\r\n"))
+	lf := bytes.Index(raw, []byte("\n\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return raw[:crlf], raw[crlf+4:], "\r\n"
+	case lf >= 0:
+		if bytes.Contains(raw[:lf], []byte("\r\n")) {
+			newline = "\r\n"
+		}
+		return raw[:lf], raw[lf+2:], newline
+	}
+	if bytes.Contains(raw, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	return raw, nil, newline
+}
+
+// RewriteHeaders 删除指定名称的头部，并在头部最前面插入新的头部，正文保持不变
+func RewriteHeaders(raw []byte, drop []string, add []models.EmailHeader) []byte {
+	header, body, nl := SplitRawMessage(raw)
+	var out bytes.Buffer
+	for _, h := range add {
+		out.WriteString(h.Name + ": " + h.Value + nl)
+	}
+	skipping := false
+	for _, line := range strings.Split(strings.ReplaceAll(string(header), "\r\n", "\n"), "\n") {
+		// 折行属于上一个头部
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			if !skipping {
+				out.WriteString(line + nl)
+			}
+			continue
+		}
+		skipping = false
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			name := strings.TrimSpace(line[:i])
+			for _, d := range drop {
+				if strings.EqualFold(name, d) {
+					skipping = true
+					break
+				}
+			}
+		}
+		if !skipping && line != "" {
+			out.WriteString(line + nl)
+		}
+	}
+	out.WriteString(nl)
+	out.Write(body)
+	return out.Bytes()
+}
+
+// ParseRawHeaders 解析原始邮件头部，折行合并为一行，保留原始顺序与重复的头部
+func ParseRawHeaders(raw []byte) []models.ParsedEmailHeader {
+	header, _, _ := SplitRawMessage(raw)
+	headers := make([]models.ParsedEmailHeader, 0)
+	for _, line := range strings.Split(strings.ReplaceAll(string(header), "\r\n", "\n"), "\n") {
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			if n := len(headers); n > 0 {
+				headers[n-1].Value += " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			continue
+		}
+		headers = append(headers, models.ParsedEmailHeader{
+			Name:  strings.TrimSpace(line[:i]),
+			Value: strings.TrimSpace(line[i+1:]),
+		})
+	}
+	for i := range headers {
+		headers[i].Decoded = decodeText(headers[i].Value)
+	}
+	return headers
+}
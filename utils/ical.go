@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ICalEvent iCalendar 中第一个 VEVENT 的主要字段 (RFC 5545)
+type ICalEvent struct {
+	Method    string // 日历对象的 METHOD，如 REQUEST、CANCEL
+	UID       string
+	Summary   string
+	Start     *time.Time
+	End       *time.Time
+	Recurring bool   // 含 RRULE 或 RDATE
+	Organizer string // ORGANIZER 的邮件地址(去掉 mailto:)，小写
+}
+
+// ParseICalendar 解析 iCalendar 文本，提取第一个事件
+func ParseICalendar(data string) (*ICalEvent, error) {
+	var ev ICalEvent
+	var stack []string
+	found, inCalendar := false, false
+	for _, line := range unfoldVCardLines(data) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, params, value, ok := splitVCardLine(line)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "BEGIN":
+			comp := strings.ToUpper(strings.TrimSpace(value))
+			if comp == "VCALENDAR" {
+				inCalendar = true
+			}
+			stack = append(stack, comp)
+			continue
+		case "END":
+			if len(stack) > 0 {
+				if stack[len(stack)-1] == "VEVENT" && len(stack) == 2 {
+					found = true
+				}
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if len(stack) == 1 && name == "METHOD" {
+			ev.Method = strings.ToUpper(strings.TrimSpace(value))
+			continue
+		}
+		// 只读取第一个 VEVENT 自身的属性，忽略 VALARM 等子组件
+		if found || len(stack) != 2 || stack[1] != "VEVENT" {
+			continue
+		}
+		switch name {
+		case "UID":
+			ev.UID = strings.TrimSpace(unescapeVCard(value))
+		case "SUMMARY":
+			ev.Summary = unescapeVCard(value)
+		case "DTSTART":
+			ev.Start = parseICalTime(params, value)
+		case "DTEND":
+			ev.End = parseICalTime(params, value)
+		case "RRULE", "RDATE":
+			ev.Recurring = true
+		case "ORGANIZER":
+			v := strings.TrimSpace(value)
+			if len(v) >= 7 && strings.EqualFold(v[:7], "mailto:") {
+				v = v[7:]
+			}
+			ev.Organizer = strings.ToLower(strings.TrimSpace(v))
+		}
+	}
+	if !inCalendar {
+		return nil, errors.New("data is not an icalendar object")
+	}
+	if !found {
+		return nil, errors.New("icalendar object has no VEVENT")
+	}
+	if ev.UID == "" {
+		return nil, errors.New("icalendar event is missing UID")
+	}
+	if ev.End == nil {
+		ev.End = ev.Start
+	}
+	return &ev, nil
+}
+
+// parseICalTime 解析 DATE-TIME / DATE 值，支持 UTC、TZID 与浮动时间
+func parseICalTime(params, value string) *time.Time {
+	value = strings.TrimSpace(value)
+	loc := time.UTC
+	for _, p := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(p, "=")
+		if ok && strings.EqualFold(k, "TZID") {
+			if l, err := time.LoadLocation(strings.Trim(v, `"`)); err == nil {
+				loc = l
+			}
+		}
+	}
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		var t time.Time
+		var err error
+		if strings.HasSuffix(layout, "Z") {
+			t, err = time.Parse(layout, value)
+		} else {
+			t, err = time.ParseInLocation(layout, value, loc)
+		}
+		if err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
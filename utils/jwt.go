@@ -57,14 +57,49 @@ func ParseJwtToken(tokenString string) (*models.CustomJwtClaims, error) {
 		return nil, fmt.Errorf("Parse token failed: %v", err)
 	}
 
-	// 验证token并提取声明
-	if claims, ok := token.Claims.(*models.CustomJwtClaims); ok && token.Valid {
+	// 验证token并提取声明，带 audience 的是登录过程中的临时令牌，不能用于访问接口
+	if claims, ok := token.Claims.(*models.CustomJwtClaims); ok && token.Valid && claims.Audience == "" {
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("Invalid token.")
 }
 
+// GenerateTwoFactorToken 生成两步验证过程中使用的临时令牌，audience 区分验证与首次设置
+func GenerateTwoFactorToken(account *models.EmailAccount, audience string, t time.Time) (string, error) {
+	claims := models.CustomJwtClaims{
+		EmailAddress: account.EmailAddress,
+		UserName:     account.UserName,
+		UserID:       account.ID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  audience,
+			ExpiresAt: t.Unix(),
+		},
+	}
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(global.Config.Jwt.SecretKey))
+	if err != nil {
+		return "", fmt.Errorf("Generate token failed: %v", err)
+	}
+	return tokenString, nil
+}
+
+// ParseTwoFactorToken 解析两步验证的临时令牌，audience 必须一致
+func ParseTwoFactorToken(tokenString, audience string) (*models.CustomJwtClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &models.CustomJwtClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(global.Config.Jwt.SecretKey), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Parse token failed: %v", err)
+	}
+	if claims, ok := token.Claims.(*models.CustomJwtClaims); ok && token.Valid && claims.Audience == audience {
+		return claims, nil
+	}
+	return nil, fmt.Errorf("Invalid token.")
+}
+
 // ValidateToken 验证JWT token
 func ValidateToken(tokenString string) (*jwt.Token, error) {
 	fmt.Print("hellp")
@@ -62,6 +62,22 @@ func GenerateSecurePassword() string {
 	return strings.Join(password, "")
 }
 
+// GenerateAppPassword 生成 16 位小写字母的应用专用密码，以 "-" 每 4 位分组便于输入
+func GenerateAppPassword() string {
+	groups := make([]string, 4)
+	for i := range groups {
+		for j := 0; j < 4; j++ {
+			groups[i] += secureRandomChar(lowerChars)
+		}
+	}
+	return strings.Join(groups, "-")
+}
+
+// NormalizeAppPassword 去掉用户输入时带上的分组符号与空格
+func NormalizeAppPassword(password string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(password))
+}
+
 // HashPassword 使用 bcrypt 对密码进行哈希
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"email/global"
+	"encoding/base64"
+	"errors"
+)
+
+// secretKey 由 JWT 密钥派生，用于加密需要保存的第三方凭证
+func secretKey() []byte {
+	key := sha256.Sum256([]byte("stored-secret\n" + global.Config.Jwt.SecretKey))
+	return key[:]
+}
+
+// EncryptSecret 使用 AES-GCM 加密凭证，返回 base64 编码的密文
+func EncryptSecret(plain string) (string, error) {
+	block, err := aes.NewCipher(secretKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 生成的密文
+func DecryptSecret(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(secretKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid secret")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试密钥 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 给出的是 8 位验证码，这里取末 6 位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+	// 小写及带填充的密钥
+	if got, err := totpCode(strings.ToLower(rfc6238Secret)+"====", 59/totpPeriod); err != nil || got != "287082" {
+		t.Errorf("totpCode(lowercase) = %s, %v", got, err)
+	}
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix() / totpPeriod
+	code := func(step int64) string {
+		c, err := totpCode(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	current := code(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		ok       bool
+	}{
+		{"current", current, 0, true},
+		{"with spaces", current[:3] + " " + current[3:], 0, true},
+		{"next step", code(now + 1), 0, true},
+		{"too old", code(now - 3), 0, false},
+		{"replay", current, now, false},
+		{"wrong length", current[:5], 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(secret, tt.code, tt.lastStep)
+			if ok != tt.ok {
+				t.Errorf("ValidateTOTP(%q, %d) = %v, want %v", tt.code, tt.lastStep, ok, tt.ok)
+			}
+			if ok && step <= tt.lastStep {
+				t.Errorf("step %d is not after lastStep %d", step, tt.lastStep)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(10)
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("unexpected recovery code format %q", c)
+		}
+		seen[c] = true
+	}
+	if len(seen) != len(codes) {
+		t.Error("recovery codes are not unique")
+	}
+	want := HashRecoveryCode("abcde-12345")
+	for _, in := range []string{"ABCDE-12345", "abcde12345", " abcde 12345"} {
+		if got := HashRecoveryCode(in); got != want {
+			t.Errorf("HashRecoveryCode(%q) differs from the canonical form", in)
+		}
+	}
+}
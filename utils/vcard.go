@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// VCard 联系人名片中使用到的字段 (RFC 6350)
+type VCard struct {
+	UID        string
+	FullName   string
+	Emails     []string
+	Phone      string
+	Note       string
+	Categories []string
+	Rev        time.Time
+}
+
+// ParseVCards 解析 vCard 文本，可包含多张名片，兼容 3.0 与 4.0
+func ParseVCards(data string) ([]VCard, error) {
+	var cards []VCard
+	var cur *VCard
+	var lastName string // N 属性，FN 缺失时使用
+	for _, line := range unfoldVCardLines(data) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, params, value, ok := splitVCardLine(line)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "BEGIN":
+			if strings.EqualFold(value, "VCARD") {
+				cur, lastName = &VCard{}, ""
+			}
+			continue
+		case "END":
+			if strings.EqualFold(value, "VCARD") && cur != nil {
+				if cur.FullName == "" {
+					cur.FullName = lastName
+				}
+				cards = append(cards, *cur)
+				cur = nil
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch name {
+		case "UID":
+			cur.UID = strings.TrimPrefix(unescapeVCard(value), "urn:uuid:")
+		case "FN":
+			cur.FullName = unescapeVCard(value)
+		case "N":
+			// 姓;名;中间名;前缀;后缀
+			parts := splitVCardValue(value, ';')
+			var names []string
+			for _, i := range []int{3, 1, 2, 0, 4} {
+				if i < len(parts) && parts[i] != "" {
+					names = append(names, parts[i])
+				}
+			}
+			lastName = strings.Join(names, " ")
+		case "EMAIL":
+			if v := strings.TrimSpace(unescapeVCard(value)); v != "" {
+				cur.Emails = append(cur.Emails, strings.TrimPrefix(v, "mailto:"))
+			}
+		case "TEL":
+			// 优先使用首选号码
+			v := strings.TrimPrefix(unescapeVCard(value), "tel:")
+			if cur.Phone == "" || strings.Contains(strings.ToUpper(params), "PREF") {
+				cur.Phone = v
+			}
+		case "NOTE":
+			cur.Note = unescapeVCard(value)
+		case "CATEGORIES":
+			for _, c := range splitVCardValue(value, ',') {
+				if c = strings.TrimSpace(c); c != "" {
+					cur.Categories = append(cur.Categories, c)
+				}
+			}
+		case "REV":
+			for _, layout := range []string{"20060102T150405Z", time.RFC3339} {
+				if t, err := time.Parse(layout, value); err == nil {
+					cur.Rev = t
+					break
+				}
+			}
+		}
+	}
+	if cur != nil {
+		return nil, errors.New("vcard is missing END:VCARD")
+	}
+	return cards, nil
+}
+
+// FormatVCard 生成 vCard 4.0 文本
+func FormatVCard(card VCard) string {
+	var b strings.Builder
+	write := func(line string) {
+		b.WriteString(foldVCardLine(line))
+		b.WriteString("\r\n")
+	}
+	write("BEGIN:VCARD")
+	write("VERSION:4.0")
+	write("UID:" + escapeVCard(card.UID))
+	write("FN:" + escapeVCard(card.FullName))
+	for i, e := range card.Emails {
+		if i == 0 {
+			write("EMAIL;PREF=1:" + escapeVCard(e))
+			continue
+		}
+		write("EMAIL:" + escapeVCard(e))
+	}
+	if card.Phone != "" {
+		write("TEL;VALUE=uri:tel:" + escapeVCard(card.Phone))
+	}
+	if card.Note != "" {
+		write("NOTE:" + escapeVCard(card.Note))
+	}
+	if len(card.Categories) > 0 {
+		cats := make([]string, 0, len(card.Categories))
+		for _, c := range card.Categories {
+			cats = append(cats, escapeVCard(c))
+		}
+		write("CATEGORIES:" + strings.Join(cats, ","))
+	}
+	if !card.Rev.IsZero() {
+		write("REV:" + card.Rev.UTC().Format("20060102T150405Z"))
+	}
+	write("END:VCARD")
+	return b.String()
+}
+
+// unfoldVCardLines 合并折行，以空格或制表符开头的行属于上一行
+func unfoldVCardLines(data string) []string {
+	raw := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	var lines []string
+	for _, l := range raw {
+		if len(l) > 0 && (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// foldVCardLine 按 75 字节折行，不拆分 UTF-8 字符
+func foldVCardLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
+
+// splitVCardLine 拆分属性名、参数与值，去掉 item1. 之类的分组前缀
+func splitVCardLine(line string) (name, params, value string, ok bool) {
+	i := strings.IndexByte(line, ':')
+	if i <= 0 {
+		return "", "", "", false
+	}
+	head := line[:i]
+	value = line[i+1:]
+	if j := strings.IndexByte(head, ';'); j >= 0 {
+		head, params = head[:j], head[j+1:]
+	}
+	if j := strings.LastIndexByte(head, '.'); j >= 0 {
+		head = head[j+1:]
+	}
+	return strings.ToUpper(strings.TrimSpace(head)), params, value, true
+}
+
+// splitVCardValue 按未转义的分隔符拆分并反转义
+func splitVCardValue(value string, sep byte) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			cur.WriteByte(value[i])
+			cur.WriteByte(value[i+1])
+			i++
+			continue
+		}
+		if value[i] == sep {
+			parts = append(parts, unescapeVCard(cur.String()))
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(value[i])
+	}
+	return append(parts, unescapeVCard(cur.String()))
+}
+
+func escapeVCard(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "\r\n", `\n`, "\n", `\n`, ",", `\,`, ";", `\;`)
+	return r.Replace(s)
+}
+
+func unescapeVCard(s string) string {
+	r := strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";")
+	return r.Replace(s)
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVCards(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []VCard
+	}{
+		{
+			name: "vcard 3.0 with folding and groups",
+			data: "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:urn:uuid:1234\r\nFN:Alice\r\n  Example\r\n" +
+				"item1.EMAIL;TYPE=INTERNET:alice@example.com\r\nEMAIL:mailto:alice@work.example\r\n" +
+				"TEL;TYPE=CELL:+1 555 0100\r\nTEL;TYPE=WORK,PREF:+1 555 0199\r\n" +
+				"NOTE:Line one\\nLine two\\, with comma\r\nCATEGORIES:Friends,Work\\,Team\r\n" +
+				"REV:20240102T030405Z\r\nEND:VCARD\r\n",
+			want: []VCard{{
+				UID:        "1234",
+				FullName:   "Alice Example",
+				Emails:     []string{"alice@example.com", "alice@work.example"},
+				Phone:      "+1 555 0199",
+				Note:       "Line one\nLine two, with comma",
+				Categories: []string{"Friends", "Work,Team"},
+				Rev:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			}},
+		},
+		{
+			name: "name from N and phone only",
+			data: "BEGIN:VCARD\nVERSION:4.0\nN:Doe;John;Q;Dr.;Jr.\nTEL;VALUE=uri:tel:+44-20-7946-0000\nEND:VCARD\n",
+			want: []VCard{{FullName: "Dr. John Q Doe Jr.", Phone: "+44-20-7946-0000"}},
+		},
+		{
+			name: "multiple cards and noise",
+			data: "garbage before\nBEGIN:VCARD\nFN:One\nEND:VCARD\n\nBEGIN:VCARD\nFN:Two;Semi\\;colon\nEMAIL:two@example.com\nEND:VCARD\n",
+			want: []VCard{{FullName: "One"}, {FullName: "Two;Semi;colon", Emails: []string{"two@example.com"}}},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVCards(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseVCards() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVCardsMissingEnd(t *testing.T) {
+	if _, err := ParseVCards("BEGIN:VCARD\nFN:Alice\n"); err == nil {
+		t.Error("expected an error for a card without END:VCARD")
+	}
+}
+
+func TestFormatVCardRoundTrip(t *testing.T) {
+	card := VCard{
+		UID:        "c0ffee",
+		FullName:   "张三, 李四; " + strings.Repeat("很长的名字", 10),
+		Emails:     []string{"zhang@example.com", "li@example.com"},
+		Phone:      "+86 10 1234 5678",
+		Note:       "multi\nline \\ note",
+		Categories: []string{"家人", "a,b"},
+		Rev:        time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+	text := FormatVCard(card)
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 bytes: %q", line)
+		}
+	}
+	cards, err := ParseVCards(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 || !reflect.DeepEqual(cards[0], card) {
+		t.Errorf("round trip = %+v, want %+v", cards, card)
+	}
+}